@@ -64,6 +64,9 @@ func main() {
 	// API Endpoints
 	testingAPI := api.InitTestingAPI(configuration)
 	e.POST("/testing", testingAPI.RunTest, rateLimit)
+	e.POST("/format", testingAPI.FormatCode, rateLimit)
+	e.POST("/schema", testingAPI.ContractSchemas, rateLimit)
+	e.POST("/lint", testingAPI.LintContract, rateLimit)
 
 	// Start REST API Service
 	go func() {
@@ -16,6 +16,117 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/format": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Format Michelson code written in \"micheline\" format (comments are preserved)",
+                "operationId": "post-format",
+                "parameters": [
+                    {
+                        "description": "Format Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.formatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success",
+                        "schema": {
+                            "$ref": "#/definitions/api.formatResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Fail",
+                        "schema": {
+                            "$ref": "#/definitions/error.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/lint": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Report static metrics and warnings (with their positions) about a contract",
+                "operationId": "post-lint",
+                "parameters": [
+                    {
+                        "description": "Lint Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.lintRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success",
+                        "schema": {
+                            "$ref": "#/definitions/lint.Report"
+                        }
+                    },
+                    "400": {
+                        "description": "Fail",
+                        "schema": {
+                            "$ref": "#/definitions/error.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/schema": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Build the JSON Schemas (of values written in annotated format) for the storage and every entrypoint of a contract",
+                "operationId": "post-schema",
+                "parameters": [
+                    {
+                        "description": "Schema Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.schemaRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success",
+                        "schema": {
+                            "$ref": "#/definitions/api.schemaResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Fail",
+                        "schema": {
+                            "$ref": "#/definitions/error.Error"
+                        }
+                    }
+                }
+            }
+        },
         "/testing": {
             "post": {
                 "consumes": [
@@ -76,12 +187,81 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "action": {},
+                "broken_invariants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/action.ActionResult"
+                    }
+                },
                 "result": {},
                 "status": {
                     "type": "string"
                 }
             }
         },
+        "api.formatRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "{ parameter unit ; storage unit ; code { CDR ; NIL operation ; PAIR } }"
+                },
+                "width": {
+                    "type": "integer",
+                    "example": 80
+                }
+            }
+        },
+        "api.formatResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.lintRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Michelson JSON or \"micheline\" (as a string)",
+                    "type": "array",
+                    "items": {
+                        "type": "object"
+                    }
+                },
+                "protocol": {
+                    "description": "protocol hash (the default protocol is used when omitted)",
+                    "type": "string"
+                }
+            }
+        },
+        "api.schemaRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Michelson JSON or \"micheline\" (as a string)",
+                    "type": "array",
+                    "items": {
+                        "type": "object"
+                    }
+                }
+            }
+        },
+        "api.schemaResponse": {
+            "type": "object",
+            "properties": {
+                "entrypoints": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/michelson.JSONSchema"
+                    }
+                },
+                "storage": {
+                    "$ref": "#/definitions/michelson.JSONSchema"
+                }
+            }
+        },
         "api.testSuiteRequest": {
             "type": "object",
             "properties": {
@@ -91,11 +271,55 @@ const docTemplate = `{
                         "$ref": "#/definitions/action.Action"
                     }
                 },
+                "backend": {
+                    "description": "Backend that executes the operations (\"mockup\" runs 'tezos-client', \"interpreter\" runs a subset of Michelson in process, \"node\" injects them in the configured node)",
+                    "type": "string",
+                    "enum": [
+                        "mockup",
+                        "interpreter",
+                        "node"
+                    ]
+                },
+                "invariants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/action.Action"
+                    }
+                },
                 "protocol": {
                     "type": "string"
                 }
             }
         },
+        "ast.Position": {
+            "type": "object",
+            "properties": {
+                "column": {
+                    "description": "column of the first character (starts at 1, 0 when unknown)",
+                    "type": "integer"
+                },
+                "end": {
+                    "description": "byte offset of the last character",
+                    "type": "integer"
+                },
+                "end_column": {
+                    "description": "column of the last character",
+                    "type": "integer"
+                },
+                "end_line": {
+                    "description": "line of the last character",
+                    "type": "integer"
+                },
+                "line": {
+                    "description": "line of the first character (starts at 1, 0 when unknown)",
+                    "type": "integer"
+                },
+                "pos": {
+                    "description": "byte offset of the first character",
+                    "type": "integer"
+                }
+            }
+        },
         "error.Error": {
             "type": "object",
             "properties": {
@@ -109,6 +333,115 @@ const docTemplate = `{
                     "example": "Some Error"
                 }
             }
+        },
+        "lint.Metrics": {
+            "type": "object",
+            "properties": {
+                "instruction_counts": {
+                    "description": "number of occurrences of each instruction",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "instructions": {
+                    "description": "number of instructions in the code and views",
+                    "type": "integer"
+                },
+                "max_nesting": {
+                    "description": "maximum depth of nested sequences",
+                    "type": "integer"
+                },
+                "size": {
+                    "description": "size (in bytes) of the binary representation of the script",
+                    "type": "integer"
+                }
+            }
+        },
+        "lint.Report": {
+            "type": "object",
+            "properties": {
+                "metrics": {
+                    "$ref": "#/definitions/lint.Metrics"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/lint.Warning"
+                    }
+                }
+            }
+        },
+        "lint.Warning": {
+            "type": "object",
+            "properties": {
+                "kind": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "position": {
+                    "$ref": "#/definitions/ast.Position"
+                }
+            }
+        },
+        "michelson.JSONSchema": {
+            "type": "object",
+            "properties": {
+                "$schema": {
+                    "type": "string"
+                },
+                "additionalProperties": {
+                    "description": "(false) or a schema"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "enum": {
+                    "type": "array",
+                    "items": {}
+                },
+                "format": {
+                    "type": "string"
+                },
+                "items": {
+                    "$ref": "#/definitions/michelson.JSONSchema"
+                },
+                "oneOf": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/michelson.JSONSchema"
+                    }
+                },
+                "pattern": {
+                    "type": "string"
+                },
+                "properties": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/michelson.JSONSchema"
+                    }
+                },
+                "propertyNames": {
+                    "$ref": "#/definitions/michelson.JSONSchema"
+                },
+                "required": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "uniqueItems": {
+                    "type": "boolean"
+                }
+            }
         }
     }
 }`
@@ -20,8 +20,11 @@ type testingAPI struct {
 }
 
 type testSuiteRequest struct {
-	Protocol string          `json:"protocol"`
-	Actions  []action.Action `json:"actions"`
+	Protocol string `json:"protocol"`
+	// Backend that executes the operations ("mockup" runs 'tezos-client', "interpreter" runs a subset of Michelson in process, "node" injects them in the configured node)
+	Backend    Mockup.BackendKind `json:"backend" enums:"mockup,interpreter,node"`
+	Actions    []action.Action    `json:"actions"`
+	Invariants []action.Action    `json:"invariants"`
 }
 
 // InitTestingAPI initializes the testing API
@@ -42,14 +45,16 @@ func InitTestingAPI(config config.Config) testingAPI {
 // @Failure  409      {object}  Error.Error          "Fail"
 // @Router   /testing [post]
 func (api *testingAPI) RunTest(ctx echo.Context) error {
-	var mockup Mockup.Mockup
+	var mockup Mockup.Backend
 	defer func() {
 		err := recover()
 		if err != nil {
 			logger.Debug("got an unexpected panic: %s", err)
 		}
 		// Teardown on exit
-		mockup.Teardown()
+		if mockup != nil {
+			mockup.Teardown()
+		}
 	}()
 
 	var request testSuiteRequest
@@ -57,6 +62,16 @@ func (api *testingAPI) RunTest(ctx echo.Context) error {
 		return Error.HttpError(http.StatusBadRequest, "request body is invalid.")
 	}
 
+	switch request.Backend {
+	case "", Mockup.MockupBackend, Mockup.InterpreterBackend:
+	case Mockup.NodeBackend:
+		if api.Config.Tezos.Node.RPC == "" {
+			return Error.HttpError(http.StatusBadRequest, fmt.Sprintf("backend (%s) is not configured.", request.Backend))
+		}
+	default:
+		return Error.HttpError(http.StatusBadRequest, fmt.Sprintf("unknown backend (%s).", request.Backend))
+	}
+
 	// Parse test actions
 	actions, err := action.GetActions(request.Actions)
 	if err != nil {
@@ -68,6 +83,29 @@ func (api *testingAPI) RunTest(ctx echo.Context) error {
 		}
 	}
 
+	// Parse suite invariants
+	invariants, err := action.GetInvariants(request.Invariants)
+	if err != nil {
+		switch err.(type) {
+		default:
+			return Error.HttpError(http.StatusBadRequest, err.Error())
+		case *echo.HTTPError:
+			return err
+		}
+	}
+
+	// Reject primitives that the protocol of the suite does not support
+	protocol := request.Protocol
+	if protocol == "" {
+		protocol = api.Config.Tezos.DefaultProtocol
+	}
+	if err := action.CheckPrimitives(request.Actions, actions, protocol); err != nil {
+		return err
+	}
+	if err := action.CheckPrimitives(request.Invariants, invariants, protocol); err != nil {
+		return err
+	}
+
 	prime, err := rand.Prime(rand.Reader, 64)
 	if err != nil {
 		logger.Debug("could not generate random prime. %s", err.Error())
@@ -75,7 +113,14 @@ func (api *testingAPI) RunTest(ctx echo.Context) error {
 	}
 
 	taskID := fmt.Sprintf("task_%d", prime)
-	mockup = Mockup.InitMockup(taskID, request.Protocol, api.Config)
+	switch request.Backend {
+	case Mockup.InterpreterBackend:
+		mockup = Mockup.InitOfflineMockup(taskID, request.Protocol, api.Config)
+	case Mockup.NodeBackend:
+		mockup = Mockup.InitNode(taskID, request.Protocol, api.Config)
+	default:
+		mockup = Mockup.InitMockup(taskID, request.Protocol, api.Config)
+	}
 
 	// Bootstrap mockup
 	err = mockup.Bootstrap()
@@ -84,5 +129,5 @@ func (api *testingAPI) RunTest(ctx echo.Context) error {
 		return Error.HttpError(http.StatusInternalServerError, "Could not bootstrap test environment.")
 	}
 
-	return ctx.JSON(http.StatusOK, action.ApplyActions(mockup, actions))
+	return ctx.JSON(http.StatusOK, action.ApplyActionsWithInvariants(mockup, actions, invariants))
 }
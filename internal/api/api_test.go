@@ -6,10 +6,13 @@ import (
 	"net/http/httptest"
 	"os"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
 	"github.com/romarq/tezos-sc-tester/internal/business/action"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/lint"
 	"github.com/romarq/tezos-sc-tester/internal/config"
 	"github.com/romarq/tezos-sc-tester/internal/logger"
 	"github.com/stretchr/testify/assert"
@@ -58,6 +61,39 @@ func TestAPI(t *testing.T) {
 		}
 	})
 
+	t.Run("Reject primitives unsupported by the protocol", func(t *testing.T) {
+		body := `{
+			"protocol": "PtLimaPtLMwfiLCZzo5V4bPXsNs3Kdm5ZKJDsTbkpS9cXNkvXQt",
+			"actions": [
+				{
+					"kind": "pack_data",
+					"payload": { "data": "{ UNIT ; BYTES ; DROP }", "type": "(lambda unit unit)" }
+				}
+			]
+		}`
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, TESTING_URL, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		err := api.RunTest(e.NewContext(req, rec))
+		e.HTTPErrorHandler(err, e.NewContext(req, rec))
+		assert.Equal(t, 400, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Primitive (BYTES) is not supported by protocol (Lima). (line 1, column 10)")
+	})
+
+	t.Run("Primitives of the FA2 test actions are supported", func(t *testing.T) {
+		request, err := getTestData("fa2_actions.json")
+		assert.Nil(t, err, "Must not fail")
+
+		var suite testSuiteRequest
+		assert.NoError(t, json.Unmarshal(request, &suite), "Must not fail")
+		actions, err := action.GetActions(suite.Actions)
+		assert.NoError(t, err, "Must not fail")
+		assert.NoError(t, action.CheckPrimitives(suite.Actions, actions, suite.Protocol))
+	})
+
 	t.Run("Run FA2 test actions", func(t *testing.T) {
 		request, err := getTestData("fa2_actions.json")
 		assert.Nil(t, err, "Must not fail")
@@ -81,11 +117,218 @@ func TestAPI(t *testing.T) {
 			assert.Equal(t, response.Status, action.Success, response.Result)
 		}
 
+		// The snapshot is only updated by successful runs (it would be emptied when 'tezos-client' is unavailable)
+		if t.Failed() {
+			return
+		}
 		snapshotBytes, err := json.MarshalIndent(actionResponses, "", "  ")
 		assert.Nil(t, err, "Must not fail")
 
 		assert.NoError(t, saveSnapshot("fa2_actions_response.json", snapshotBytes))
 	})
+
+	t.Run("Run test actions with the offline interpreter", func(t *testing.T) {
+		for _, file := range []string{"valid_request.json", "fa2_actions.json"} {
+			request, err := getTestData(file)
+			assert.Nil(t, err, "Must not fail")
+			var suite map[string]interface{}
+			assert.NoError(t, json.Unmarshal(request, &suite))
+			suite["backend"] = "interpreter"
+			request, err = json.Marshal(suite)
+			assert.NoError(t, err)
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, TESTING_URL, bytes.NewReader(request))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			err = api.RunTest(e.NewContext(req, rec))
+			assert.Nil(t, err, "Must not fail")
+			assert.Equal(t, 200, rec.Code)
+
+			var actionResponses []action.ActionResult
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &actionResponses))
+			assert.NotEmpty(t, actionResponses, file)
+			for _, response := range actionResponses {
+				assert.Equal(t, action.Success, response.Status, file, response.Action, response.Result)
+			}
+		}
+	})
+
+	t.Run("Reject unknown backends", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, TESTING_URL, strings.NewReader(`{ "backend": "sandbox", "actions": [] }`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		err := api.RunTest(e.NewContext(req, rec))
+		e.HTTPErrorHandler(err, e.NewContext(req, rec))
+		assert.Equal(t, 400, rec.Code)
+		assert.Contains(t, rec.Body.String(), "unknown backend (sandbox).")
+	})
+	t.Run("Reject the node backend when no node is configured", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, TESTING_URL, strings.NewReader(`{ "backend": "node", "actions": [] }`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		err := api.RunTest(e.NewContext(req, rec))
+		e.HTTPErrorHandler(err, e.NewContext(req, rec))
+		assert.Equal(t, 400, rec.Code)
+		assert.Contains(t, rec.Body.String(), "backend (node) is not configured.")
+	})
+}
+
+func TestFormat(t *testing.T) {
+	const FORMAT_URL = "/format"
+
+	api := InitTestingAPI(config.Config{})
+
+	format := func(body string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, FORMAT_URL, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		err := api.FormatCode(e.NewContext(req, rec))
+		if err != nil {
+			e.HTTPErrorHandler(err, e.NewContext(req, rec))
+		}
+		return rec
+	}
+
+	t.Run("Format Michelson code", func(t *testing.T) {
+		rec := format(`{ "code": "{ parameter unit ; storage unit ; # no state\n code { CDR ; NIL operation ; PAIR } }", "width": 30 }`)
+		assert.Equal(t, 200, rec.Code)
+
+		var response formatResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response), "Must not fail")
+		assert.Equal(t, "{ parameter unit ;\n  storage unit ; # no state\n  code { CDR ;\n         NIL operation ;\n         PAIR } }", response.Code)
+	})
+
+	t.Run("Syntax errors are reported with their position", func(t *testing.T) {
+		rec := format(`{ "code": "{ UNIT ;\n  DROP ) }" }`)
+		assert.Equal(t, 400, rec.Code)
+
+		var response struct {
+			Message string            `json:"message"`
+			Details []ast.SyntaxError `json:"details"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response), "Must not fail")
+		assert.Equal(t, "could not parse Michelson code.", response.Message)
+		assert.Len(t, response.Details, 1)
+		assert.Equal(t, 2, response.Details[0].Position.Line)
+		assert.Equal(t, 8, response.Details[0].Position.Column)
+		assert.Equal(t, "2 |   DROP ) }\n           ^", response.Details[0].Excerpt)
+	})
+}
+
+func TestSchema(t *testing.T) {
+	const SCHEMA_URL = "/schema"
+
+	api := InitTestingAPI(config.Config{})
+
+	schema := func(body string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, SCHEMA_URL, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		err := api.ContractSchemas(e.NewContext(req, rec))
+		if err != nil {
+			e.HTTPErrorHandler(err, e.NewContext(req, rec))
+		}
+		return rec
+	}
+
+	t.Run("Build the schemas of a contract", func(t *testing.T) {
+		rec := schema(`{
+			"code": [
+				{ "prim": "parameter", "args": [ { "prim": "or", "args": [ { "prim": "nat", "annots": [ "%add" ] }, { "prim": "unit", "annots": [ "%reset" ] } ] } ] },
+				{ "prim": "storage", "args": [ { "prim": "pair", "args": [ { "prim": "address", "annots": [ "%owner" ] }, { "prim": "nat", "annots": [ "%total" ] } ] } ] },
+				{ "prim": "code", "args": [ [ { "prim": "CDR" }, { "prim": "NIL", "args": [ { "prim": "operation" } ] }, { "prim": "PAIR" } ] ] }
+			]
+		}`)
+		assert.Equal(t, 200, rec.Code)
+
+		var response struct {
+			Storage struct {
+				Type     string   `json:"type"`
+				Required []string `json:"required"`
+			} `json:"storage"`
+			Entrypoints map[string]struct {
+				Title string `json:"title"`
+			} `json:"entrypoints"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response), "Must not fail")
+		assert.Equal(t, "object", response.Storage.Type)
+		assert.Equal(t, []string{"owner", "total"}, response.Storage.Required)
+		assert.Len(t, response.Entrypoints, 3)
+		assert.Equal(t, "add", response.Entrypoints["add"].Title)
+		assert.Equal(t, "reset", response.Entrypoints["reset"].Title)
+	})
+
+	t.Run("Invalid contracts are rejected", func(t *testing.T) {
+		rec := schema(`{ "code": [ { "prim": "parameter", "args": [ { "prim": "unit" } ] } ] }`)
+		assert.Equal(t, 400, rec.Code)
+
+		var response struct {
+			Message string `json:"message"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response), "Must not fail")
+		assert.Equal(t, "invalid contract. missing contract section (storage) (line 1, column 1). missing contract section (code) (line 1, column 1).", response.Message)
+	})
+
+	t.Run("Invalid types are rejected", func(t *testing.T) {
+		for _, code := range []string{
+			`"{ parameter unit ; storage (option) ; code {} }"`,
+			`"{ parameter (map nat) ; storage unit ; code {} }"`,
+			`"{ parameter (or (nat %a)) ; storage unit ; code {} }"`,
+			`"{ parameter unit ; storage (pair (nat %a) (nat %a)) ; code {} }"`,
+		} {
+			rec := schema(`{ "code": ` + code + ` }`)
+			assert.Equal(t, 400, rec.Code, code)
+		}
+	})
+}
+
+func TestLint(t *testing.T) {
+	const LINT_URL = "/lint"
+
+	api := InitTestingAPI(config.Config{})
+
+	lintCode := func(body string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, LINT_URL, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		err := api.LintContract(e.NewContext(req, rec))
+		if err != nil {
+			e.HTTPErrorHandler(err, e.NewContext(req, rec))
+		}
+		return rec
+	}
+
+	t.Run("Report warnings with their positions", func(t *testing.T) {
+		rec := lintCode(`{ "protocol": "PtLimaPtLMwfiLCZzo5V4bPXsNs3Kdm5ZKJDsTbkpS9cXNkvXQt", "code": "parameter unit ;\nstorage (option chest) ;\ncode { FAIL ; CDR ; NIL operation ; PAIR }" }`)
+		assert.Equal(t, 200, rec.Code)
+
+		var response lint.Report
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response), "Must not fail")
+		assert.Equal(t, 5, response.Metrics.Instructions)
+		assert.Len(t, response.Warnings, 2)
+		assert.Equal(t, lint.DeprecatedInstruction, response.Warnings[0].Kind)
+		assert.Equal(t, 2, response.Warnings[0].Position.Line)
+		assert.Equal(t, lint.UnreachableCode, response.Warnings[1].Kind)
+		assert.Equal(t, 3, response.Warnings[1].Position.Line)
+		assert.Equal(t, 15, response.Warnings[1].Position.Column)
+	})
+
+	t.Run("Invalid contracts are rejected", func(t *testing.T) {
+		rec := lintCode(`{ "code": "{ parameter unit }" }`)
+		assert.Equal(t, 400, rec.Code)
+	})
 }
 
 func getTestData(fileName string) ([]byte, error) {
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	Error "github.com/romarq/tezos-sc-tester/internal/error"
+)
+
+type formatRequest struct {
+	Code  string `json:"code" example:"{ parameter unit ; storage unit ; code { CDR ; NIL operation ; PAIR } }"`
+	Width int    `json:"width" example:"80"`
+}
+
+type formatResponse struct {
+	Code string `json:"code"`
+}
+
+// FormatCode - Format Michelson code (`/format`) godoc
+// @Summary  Format Michelson code written in "micheline" format (comments are preserved)
+// @ID       post-format
+// @Accept   json
+// @Produce  json
+// @Param    request  body      formatRequest   true  "Format Request"
+// @Success  200      {object}  formatResponse  "Success"
+// @Failure  400      {object}  Error.Error     "Fail"
+// @Router   /format [post]
+func (api *testingAPI) FormatCode(ctx echo.Context) error {
+	var request formatRequest
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&request); err != nil {
+		return Error.HttpError(http.StatusBadRequest, "request body is invalid.")
+	}
+
+	code, syntaxErrors := michelson.FormatMicheline(request.Code, request.Width)
+	if syntaxErrors != nil {
+		return Error.DetailedHttpError(http.StatusBadRequest, "could not parse Michelson code.", syntaxErrors)
+	}
+
+	return ctx.JSON(http.StatusOK, formatResponse{Code: code})
+}
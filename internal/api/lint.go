@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/lint"
+	Error "github.com/romarq/tezos-sc-tester/internal/error"
+)
+
+type lintRequest struct {
+	Protocol string          `json:"protocol"`                        // protocol hash (the default protocol is used when omitted)
+	Code     json.RawMessage `json:"code" swaggertype:"array,object"` // Michelson JSON or "micheline" (as a string)
+}
+
+// LintContract - Static analysis of a contract (`/lint`) godoc
+// @Summary  Report static metrics and warnings (with their positions) about a contract
+// @ID       post-lint
+// @Accept   json
+// @Produce  json
+// @Param    request  body      lintRequest  true  "Lint Request"
+// @Success  200      {object}  lint.Report  "Success"
+// @Failure  400      {object}  Error.Error  "Fail"
+// @Router   /lint [post]
+func (api *testingAPI) LintContract(ctx echo.Context) error {
+	var request lintRequest
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&request); err != nil || request.Code == nil {
+		return Error.HttpError(http.StatusBadRequest, "request body is invalid.")
+	}
+
+	code, err := michelson.ParseJSONOrMicheline(request.Code)
+	if err != nil {
+		return Error.HttpError(http.StatusBadRequest, fmt.Sprintf("invalid code. %s", err))
+	}
+
+	protocol := request.Protocol
+	if protocol == "" {
+		protocol = api.Config.Tezos.DefaultProtocol
+	}
+	report, err := lint.Lint(code, protocol)
+	if err != nil {
+		return Error.HttpError(http.StatusBadRequest, err.Error())
+	}
+
+	return ctx.JSON(http.StatusOK, report)
+}
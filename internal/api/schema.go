@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	Error "github.com/romarq/tezos-sc-tester/internal/error"
+)
+
+type schemaRequest struct {
+	Code json.RawMessage `json:"code" swaggertype:"array,object"` // Michelson JSON or "micheline" (as a string)
+}
+
+type schemaResponse struct {
+	Storage     *michelson.JSONSchema            `json:"storage"`
+	Entrypoints map[string]*michelson.JSONSchema `json:"entrypoints"`
+}
+
+// ContractSchemas - JSON Schemas of a contract (`/schema`) godoc
+// @Summary  Build the JSON Schemas (of values written in annotated format) for the storage and every entrypoint of a contract
+// @ID       post-schema
+// @Accept   json
+// @Produce  json
+// @Param    request  body      schemaRequest   true  "Schema Request"
+// @Success  200      {object}  schemaResponse  "Success"
+// @Failure  400      {object}  Error.Error     "Fail"
+// @Router   /schema [post]
+func (api *testingAPI) ContractSchemas(ctx echo.Context) error {
+	var request schemaRequest
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&request); err != nil || request.Code == nil {
+		return Error.HttpError(http.StatusBadRequest, "request body is invalid.")
+	}
+
+	code, err := michelson.ParseJSONOrMicheline(request.Code)
+	if err != nil {
+		return Error.HttpError(http.StatusBadRequest, fmt.Sprintf("invalid code. %s", err))
+	}
+	contract, err := michelson.ParseContract(code)
+	if err != nil {
+		return Error.HttpError(http.StatusBadRequest, fmt.Sprintf("invalid contract. %s", err))
+	}
+
+	response := schemaResponse{
+		Entrypoints: map[string]*michelson.JSONSchema{},
+	}
+	if response.Storage, err = michelson.JSONSchemaOf(contract.Storage); err != nil {
+		return Error.HttpError(http.StatusBadRequest, fmt.Sprintf("could not build the schema of the storage. %s", err))
+	}
+
+	entrypoints := contract.Entrypoints()
+	names := make([]string, 0, len(entrypoints))
+	for name := range entrypoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if response.Entrypoints[name], err = michelson.JSONSchemaOf(entrypoints[name]); err != nil {
+			return Error.HttpError(http.StatusBadRequest, fmt.Sprintf("could not build the schema of entrypoint (%s). %s", name, err))
+		}
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
@@ -2,30 +2,42 @@ package action
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/romarq/tezos-sc-tester/internal/business"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
 	Error "github.com/romarq/tezos-sc-tester/internal/error"
 )
 
 type (
 	ActionStatus string
 	ActionResult struct {
-		Status ActionStatus `json:"status"`
-		Action interface{}  `json:"action"`
-		Result interface{}  `json:"result,omitempty"`
+		Status           ActionStatus   `json:"status"`
+		Action           interface{}    `json:"action"`
+		Result           interface{}    `json:"result,omitempty"`
+		BrokenInvariants []ActionResult `json:"broken_invariants,omitempty"`
 	}
 	Action struct {
 		Kind    ActionKind      `json:"kind"`
 		Payload json.RawMessage `json:"payload"`
 	}
 	IAction interface {
-		Run(mockup business.Mockup) (interface{}, bool)
+		Run(mockup business.Backend) (interface{}, bool)
 		Unmarshal(action Action) error
 		Action() interface{}
 	}
+	// nestedActions is implemented by the actions that apply other actions,
+	// the invariants of the suite are checked after each nested action
+	nestedActions interface {
+		runNested(mockup business.Backend, checker *invariantChecker) (interface{}, bool)
+	}
+	// invariantChecker keeps the invariants of a suite and the ones that are already broken
+	invariantChecker struct {
+		invariants []IAction
+		broken     []bool
+	}
 )
 
 const (
@@ -65,6 +77,16 @@ func GetActions(rawActions []Action) ([]IAction, error) {
 			action = &ModifyChainIdAction{}
 		case PackData:
 			action = &PackDataAction{}
+		case ForEach:
+			action = &ForEachAction{}
+		case FuzzEntrypoint:
+			action = &FuzzEntrypointAction{}
+		case AssertBalanceChanges:
+			action = &AssertBalanceChangesAction{}
+		case GetEntrypoints:
+			action = &GetEntrypointsAction{}
+		case LintContract:
+			action = &LintContractAction{}
 		}
 
 		if err := action.Unmarshal(rawAction); err != nil {
@@ -73,38 +95,127 @@ func GetActions(rawActions []Action) ([]IAction, error) {
 		actions = append(actions, action)
 	}
 
+	// Fail fast on ill-typed values
+	if err := typecheckActions(rawActions, actions); err != nil {
+		return nil, err
+	}
+
 	return actions, nil
 }
 
+// GetInvariants unmarshal suite invariants (Only assertions can be used as invariants)
+func GetInvariants(rawInvariants []Action) ([]IAction, error) {
+	for _, rawInvariant := range rawInvariants {
+		switch rawInvariant.Kind {
+		case AssertAccountBalance, AssertContractStorage:
+		default:
+			return nil, Error.DetailedHttpError(http.StatusBadRequest, fmt.Sprintf("Action of kind (%s) cannot be used as an invariant.", rawInvariant.Kind), rawInvariant)
+		}
+	}
+
+	return GetActions(rawInvariants)
+}
+
 // ApplyActions executes each test action
-func ApplyActions(mockup business.Mockup, actions []IAction) []ActionResult {
-	responses := make([]ActionResult, 0)
+func ApplyActions(mockup business.Backend, actions []IAction) []ActionResult {
+	return ApplyActionsWithInvariants(mockup, actions, nil)
+}
+
+// ApplyActionsWithInvariants executes each test action and re-evaluates the
+// invariants after every action that changes the state (including the actions nested in other actions).
+// Each broken invariant is only reported once, on the first action after which it broke.
+func ApplyActionsWithInvariants(mockup business.Backend, actions []IAction, invariants []IAction) []ActionResult {
+	checker := &invariantChecker{
+		invariants: invariants,
+		broken:     make([]bool, len(invariants)),
+	}
+	return checker.apply(mockup, actions)
+}
 
+// apply executes each action and checks the invariants after the actions that change the state
+func (checker *invariantChecker) apply(mockup business.Backend, actions []IAction) []ActionResult {
+	responses := make([]ActionResult, 0)
 	for _, action := range actions {
-		result, ok := action.Run(mockup)
-		if ok {
-			responses = append(responses, buildResult(Success, result, action))
+		var result interface{}
+		var ok bool
+		if nested, isNested := action.(nestedActions); isNested {
+			result, ok = nested.runNested(mockup, checker)
 		} else {
-			responses = append(responses, buildResult(Failure, result, action))
+			result, ok = action.Run(mockup)
+		}
+		response := buildResult(Failure, result, action)
+		if ok {
+			response = buildResult(Success, result, action)
 		}
+
+		if changesState(action) {
+			checker.check(mockup, &response)
+		}
+
+		responses = append(responses, response)
 	}
 
 	return responses
 }
 
-func expandPlaceholders(mockup business.Mockup, str string) string {
+// check evaluates the invariants that are not broken yet, the response fails if any of them breaks
+func (checker *invariantChecker) check(mockup business.Backend, response *ActionResult) {
+	for i, invariant := range checker.invariants {
+		if checker.broken[i] || !invariantApplies(mockup, invariant) {
+			continue
+		}
+		if result, ok := invariant.Run(mockup); !ok {
+			checker.broken[i] = true
+			response.Status = Failure
+			response.BrokenInvariants = append(response.BrokenInvariants, buildResult(Failure, result, invariant))
+		}
+	}
+}
+
+// changesState checks if an action can change the state of contracts and accounts
+// (actions with nested actions are not listed, the invariants are checked after each of their nested actions)
+func changesState(action IAction) bool {
+	switch action.(type) {
+	case *CallContractAction, *OriginateContractAction, *CreateImplicitAccountAction, *ModifyBlockLevelAction, *ModifyBlockTimestampAction, *FuzzEntrypointAction:
+		return true
+	}
+	return false
+}
+
+// invariantApplies checks if the accounts and contracts referenced by an invariant already exist
+func invariantApplies(mockup business.Backend, invariant IAction) bool {
+	switch inv := invariant.(type) {
+	case *AssertAccountBalanceAction:
+		return mockup.ContainsAddress(inv.AccountName)
+	case *AssertContractStorageAction:
+		_, ok := mockup.GetContract(inv.ContractName)
+		return ok
+	}
+	return true
+}
+
+func expandPlaceholders(mockup business.Backend, str string) (string, error) {
 	// Expand addresses
-	b := business.ExpandAccountPlaceholders(mockup.Addresses, []byte(str))
+	b := business.ExpandAccountPlaceholders(mockup.GetAddresses(), []byte(str))
 	// Expand balances
-	b = business.ExpandBalancePlaceholders(mockup, b)
+	b, err := business.ExpandBalancePlaceholders(mockup, b)
+	if err != nil {
+		return "", err
+	}
 
-	return string(b)
+	return string(b), nil
 }
 
 // replaceBigMaps converts all 'big_map' types to 'map'
 // This is necessary for testing the storage updates
-func replaceBigMaps(str string) string {
-	return strings.ReplaceAll(str, "big_map", "map")
+func replaceBigMaps(node ast.Node) ast.Node {
+	return ast.Rewrite(node, func(node ast.Node) ast.Node {
+		if prim, ok := node.(ast.Prim); ok && prim.Prim == "big_map" {
+			prim.Prim = "map"
+			return prim
+		}
+		return node
+	})
 }
 
 func buildResult(status ActionStatus, result interface{}, action IAction) ActionResult {
@@ -114,9 +225,15 @@ func buildResult(status ActionStatus, result interface{}, action IAction) Action
 			"details": v,
 		}
 	case error:
-		result = map[string]interface{}{
+		details := map[string]interface{}{
 			"details": v.Error(),
 		}
+		// Failures reported by 'tezos-client' are classified
+		var clientErr *business.ClientError
+		if errors.As(v, &clientErr) {
+			details["error"] = clientErr
+		}
+		result = details
 	}
 
 	return ActionResult{
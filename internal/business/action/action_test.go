@@ -1,11 +1,21 @@
 package action
 
 import (
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math/big"
 	"testing"
 
+	"blockwatch.cc/tzgo/tezos"
 	"github.com/romarq/tezos-sc-tester/internal/business"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/binary"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/romarq/tezos-sc-tester/internal/config"
+	Error "github.com/romarq/tezos-sc-tester/internal/error"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -74,7 +84,7 @@ func TestApplyActions(t *testing.T) {
 				&CreateImplicitAccountActionMock{action_createImplicitAccount_alice},
 				&CreateImplicitAccountActionMock{action_createImplicitAccount_bob},
 			}
-			results := ApplyActions(business.Mockup{}, actions)
+			results := ApplyActions(newBackendMock(), actions)
 			assert.Equal(
 				t,
 				[]ActionResult{
@@ -97,15 +107,831 @@ func TestApplyActions(t *testing.T) {
 		})
 }
 
+func TestApplyActionsWithInvariants(t *testing.T) {
+	t.Run("Test ApplyActionsWithInvariants",
+		func(t *testing.T) {
+			action_createImplicitAccount_alice := CreateImplicitAccountAction{
+				Name:    "alice",
+				Balance: business.MutezOfFloat(big.NewFloat(10)),
+			}
+			invariant_bob := CreateImplicitAccountAction{
+				Name:    "bob",
+				Balance: business.MutezOfFloat(big.NewFloat(10)),
+			}
+			actions := []IAction{
+				&CreateImplicitAccountActionMock{action_createImplicitAccount_alice},
+				&ModifyBlockLevelAction{Level: 2},
+				&ModifyBlockLevelAction{Level: 3},
+			}
+			invariants := []IAction{
+				&CreateImplicitAccountActionMock{invariant_bob},
+			}
+			results := ApplyActionsWithInvariants(newBackendMock(), actions, invariants)
+			assert.Len(t, results, 3, "Validate number of results")
+			assert.Empty(t, results[0].BrokenInvariants, "Invariants are only evaluated after state changes")
+			assert.Equal(
+				t,
+				[]ActionResult{
+					{
+						Status: Failure,
+						Action: invariant_bob.Action(),
+						Result: map[string]interface{}{
+							"details": "ERROR",
+						},
+					},
+				},
+				results[1].BrokenInvariants,
+				"Validate broken invariants",
+			)
+			assert.Equal(t, Failure, results[1].Status, "Actions that break invariants fail")
+			assert.Empty(t, results[2].BrokenInvariants, "Broken invariants are only reported once")
+		})
+	t.Run("Invariants are evaluated after originations and account creations",
+		func(t *testing.T) {
+			mockup := newBackendMock()
+			assert.Nil(t, mockup.Bootstrap(), "Must not fail")
+
+			actions, err := GetActions([]Action{
+				{Kind: CreateImplicitAccount, Payload: json.RawMessage(`{ "name": "alice", "balance": "5" }`)},
+				{Kind: OriginateContract, Payload: json.RawMessage(`{
+					"name": "counter",
+					"balance": "0",
+					"code": [
+						{ "prim": "parameter", "args": [{ "prim": "nat" }] },
+						{ "prim": "storage", "args": [{ "prim": "nat" }] },
+						{ "prim": "code", "args": [[{ "prim": "CAR" }, { "prim": "NIL", "args": [{ "prim": "operation" }] }, { "prim": "PAIR" }]] }
+					],
+					"storage": { "int": "1" }
+				}`)},
+			})
+			assert.Nil(t, err, "Must not fail")
+			invariants, err := GetInvariants([]Action{
+				{Kind: AssertAccountBalance, Payload: json.RawMessage(`{ "account_name": "alice", "balance": "10" }`)},
+				{Kind: AssertContractStorage, Payload: json.RawMessage(`{ "contract_name": "counter", "storage": { "int": "0" } }`)},
+			})
+			assert.Nil(t, err, "Must not fail")
+
+			results := ApplyActionsWithInvariants(mockup, actions, invariants)
+			assert.Len(t, results, 2, "Validate number of results")
+			assert.Len(t, results[0].BrokenInvariants, 1, "Validate broken invariants (account creation)")
+			assert.Len(t, results[1].BrokenInvariants, 1, "Validate broken invariants (origination)")
+		})
+	t.Run("Invariants are evaluated after nested actions",
+		func(t *testing.T) {
+			mockup := newBackendMock()
+			assert.Nil(t, mockup.Bootstrap(), "Must not fail")
+
+			actions, err := GetActions([]Action{
+				{Kind: ForEach, Payload: json.RawMessage(`{
+					"bindings": [{ "balance": "5" }, { "balance": "10" }],
+					"actions": [
+						{ "kind": "create_implicit_account", "payload": { "name": "alice", "balance": "TEST__VARIABLE__balance" } }
+					]
+				}`)},
+			})
+			assert.Nil(t, err, "Must not fail")
+			invariants, err := GetInvariants([]Action{
+				{Kind: AssertAccountBalance, Payload: json.RawMessage(`{ "account_name": "alice", "balance": "10" }`)},
+			})
+			assert.Nil(t, err, "Must not fail")
+
+			results := ApplyActionsWithInvariants(mockup, actions, invariants)
+			assert.Len(t, results, 1, "Validate number of results")
+			assert.Equal(t, Failure, results[0].Status, "Actions that break invariants fail")
+			assert.Empty(t, results[0].BrokenInvariants, "Broken invariants are reported on the nested action")
+
+			iterations := results[0].Result.(map[string]interface{})["iterations"].([]ForEachIterationResult)
+			assert.Len(t, iterations[0].Results[0].BrokenInvariants, 1, "Validate broken invariants (first iteration)")
+			assert.Empty(t, iterations[1].Results[0].BrokenInvariants, "Broken invariants are only reported once")
+		})
+	t.Run("Invariants are not evaluated again after nested actions",
+		func(t *testing.T) {
+			mockup := newBackendMock()
+			assert.Nil(t, mockup.Bootstrap(), "Must not fail")
+
+			actions, err := GetActions([]Action{
+				{Kind: ForEach, Payload: json.RawMessage(`{
+					"bindings": [{ "name": "alice" }, { "name": "bob" }],
+					"actions": [
+						{ "kind": "create_implicit_account", "payload": { "name": "TEST__VARIABLE__name", "balance": "1" } }
+					]
+				}`)},
+			})
+			assert.Nil(t, err, "Must not fail")
+			invariant := &CountingInvariantMock{}
+
+			results := ApplyActionsWithInvariants(mockup, actions, []IAction{invariant})
+			assert.Equal(t, Success, results[0].Status, "Validate status")
+			assert.Equal(t, 2, invariant.runs, "The invariant is evaluated once per nested action")
+		})
+}
+
+func TestGetActionsTypecheck(t *testing.T) {
+	originate := Action{
+		Kind: OriginateContract,
+		Payload: json.RawMessage(`
+			{
+				"name": "contract_1",
+				"balance": "0",
+				"code": [
+					{ "prim": "parameter", "args": [ { "prim": "or", "args": [ { "prim": "nat", "annots": [ "%add" ] }, { "prim": "unit", "annots": [ "%reset" ] } ] } ] },
+					{ "prim": "storage", "args": [ { "prim": "pair", "args": [ { "prim": "address" }, { "prim": "nat" } ] } ] },
+					{ "prim": "code", "args": [ [ { "prim": "CDR" }, { "prim": "NIL", "args": [ { "prim": "operation" } ] }, { "prim": "PAIR" } ] ] }
+				],
+				"storage": { "prim": "Pair", "args": [ { "string": "TEST__ADDRESS_OF_ACCOUNT__bob" }, { "int": "0" } ] }
+			}
+		`),
+	}
+	t.Run("Test GetActions (Well-typed values)",
+		func(t *testing.T) {
+			_, err := GetActions([]Action{
+				originate,
+				{
+					Kind: CallContract,
+					Payload: json.RawMessage(`
+						{
+							"recipient": "contract_1",
+							"sender": "bob",
+							"entrypoint": "add",
+							"amount": "0",
+							"parameter": { "int": "1" }
+						}
+					`),
+				},
+				{
+					Kind: AssertContractStorage,
+					Payload: json.RawMessage(`
+						{
+							"contract_name": "contract_1",
+							"storage": { "prim": "Pair", "args": [ { "string": "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" }, { "int": "1" } ] }
+						}
+					`),
+				},
+			})
+			assert.Nil(t, err, "Must not fail")
+		})
+	t.Run("Test GetActions (Ill-typed values)",
+		func(t *testing.T) {
+			_, err := GetActions([]Action{
+				originate,
+				{
+					Kind: CallContract,
+					Payload: json.RawMessage(`
+						{
+							"recipient": "contract_1",
+							"sender": "bob",
+							"entrypoint": "add",
+							"amount": "0",
+							"parameter": { "int": "-1" }
+						}
+					`),
+				},
+			})
+			assert.NotNil(t, err, "Must fail")
+			assert.Equal(t, "ill-typed parameter for entrypoint (add). value -1 is not of type (nat) (line 1, column 1).", Error.Message(err), "Assert error message")
+
+			_, err = GetActions([]Action{
+				originate,
+				{
+					Kind: AssertContractStorage,
+					Payload: json.RawMessage(`
+						{
+							"contract_name": "contract_1",
+							"storage": { "prim": "Pair", "args": [ { "int": "1" }, { "int": "1" } ] }
+						}
+					`),
+				},
+			})
+			assert.NotNil(t, err, "Must fail")
+			assert.Equal(t, "ill-typed storage. value 1 is not of type (address) (at /args/0, line 1, column 29).", Error.Message(err), "Assert error message")
+
+			_, err = GetActions([]Action{
+				{
+					Kind: PackData,
+					Payload: json.RawMessage(`
+						{
+							"data": [ { "int": "2" }, { "int": "1" } ],
+							"type": { "prim": "set", "args": [ { "prim": "nat" } ] }
+						}
+					`),
+				},
+			})
+			assert.NotNil(t, err, "Must fail")
+			assert.Equal(t, "ill-typed data. set elements must be in strictly increasing order, 1 appears after 2 (at /1, line 1, column 19).", Error.Message(err), "Assert error message")
+		})
+	t.Run("Test GetActions (Ill-typed values in nested actions)",
+		func(t *testing.T) {
+			_, err := GetActions([]Action{
+				originate,
+				{
+					Kind: ForEach,
+					Payload: json.RawMessage(`
+						{
+							"bindings": [ { "value": { "int": "1" } }, { "value": { "int": "-1" } } ],
+							"actions": [
+								{
+									"kind": "call_contract",
+									"payload": {
+										"recipient": "contract_1",
+										"sender": "bob",
+										"entrypoint": "add",
+										"amount": "0",
+										"parameter": "TEST__VARIABLE__value"
+									}
+								}
+							]
+						}
+					`),
+				},
+			})
+			assert.NotNil(t, err, "Must fail")
+			assert.Equal(t, "invalid actions in iteration (1). ill-typed parameter for entrypoint (add). value -1 is not of type (nat) (line 1, column 1).", Error.Message(err), "Assert error message")
+
+			_, err = GetActions([]Action{
+				originate,
+				{
+					Kind: FuzzEntrypoint,
+					Payload: json.RawMessage(`
+						{
+							"contract_name": "contract_1",
+							"sender": "bob",
+							"entrypoint": "add",
+							"amount": "0",
+							"iterations": 1,
+							"invariants": [
+								{
+									"kind": "assert_contract_storage",
+									"payload": {
+										"contract_name": "contract_1",
+										"storage": { "int": "1" }
+									}
+								}
+							]
+						}
+					`),
+				},
+			})
+			assert.NotNil(t, err, "Must fail")
+			assert.Equal(t, "invalid invariants. ill-typed storage. value 1 is not of type (pair (address) (nat)) (line 1, column 1).", Error.Message(err), "Assert error message")
+		})
+}
+
+func TestGetActionsAnnotated(t *testing.T) {
+	originate := Action{
+		Kind: OriginateContract,
+		Payload: json.RawMessage(`
+			{
+				"name": "contract_1",
+				"balance": "0",
+				"code": [
+					{ "prim": "parameter", "args": [ { "prim": "or", "args": [ { "prim": "pair", "args": [ { "prim": "address", "annots": [ "%to" ] }, { "prim": "nat", "annots": [ "%value" ] } ], "annots": [ "%add" ] }, { "prim": "unit", "annots": [ "%reset" ] } ] } ] },
+					{ "prim": "storage", "args": [ { "prim": "pair", "args": [ { "prim": "address", "annots": [ "%owner" ] }, { "prim": "map", "args": [ { "prim": "address" }, { "prim": "nat" } ], "annots": [ "%balances" ] } ] } ] },
+					{ "prim": "code", "args": [ [ { "prim": "CDR" }, { "prim": "NIL", "args": [ { "prim": "operation" } ] }, { "prim": "PAIR" } ] ] }
+				],
+				"storage": { "owner": "TEST__ADDRESS_OF_ACCOUNT__bob", "balances": {} },
+				"format": "annotated"
+			}
+		`),
+	}
+	t.Run("Test GetActions (Annotated values)",
+		func(t *testing.T) {
+			actions, err := GetActions([]Action{
+				originate,
+				{
+					Kind: CallContract,
+					Payload: json.RawMessage(`
+						{
+							"recipient": "contract_1",
+							"sender": "bob",
+							"entrypoint": "add",
+							"amount": "0",
+							"parameter": { "to": "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", "value": 1 },
+							"format": "annotated"
+						}
+					`),
+				},
+				{
+					Kind: AssertContractStorage,
+					Payload: json.RawMessage(`
+						{
+							"contract_name": "contract_1",
+							"storage": { "owner": "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", "balances": { "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx": "1" } },
+							"format": "annotated"
+						}
+					`),
+				},
+				{
+					Kind: PackData,
+					Payload: json.RawMessage(`
+						{
+							"data": { "a": "1", "b": [ "x" ] },
+							"type": { "prim": "pair", "args": [ { "prim": "nat", "annots": [ "%a" ] }, { "prim": "list", "args": [ { "prim": "string" } ], "annots": [ "%b" ] } ] },
+							"format": "annotated"
+						}
+					`),
+				},
+			})
+			assert.Nil(t, err, "Must not fail")
+			assert.Equal(t, `(Pair "TEST__ADDRESS_OF_ACCOUNT__bob" {  })`, micheline.Print(actions[0].(*OriginateContractAction).Storage, ""))
+			// Annotated values that depend on a contract are validated without modifying the actions, they are parsed again when the actions run
+			assert.Nil(t, actions[1].(*CallContractAction).Parameter, "The parameter is parsed when the action runs")
+			assert.Nil(t, actions[2].(*AssertContractStorageAction).Storage, "The storage is parsed when the action runs")
+			contract, err := michelson.ParseContract(actions[0].(*OriginateContractAction).Code)
+			assert.Nil(t, err, "Must not fail")
+			parameter, err := actions[1].(*CallContractAction).parameterOf(contract)
+			assert.Nil(t, err, "Must not fail")
+			assert.Equal(t, `(Pair "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" 1)`, micheline.Print(parameter, ""))
+			storage, err := actions[2].(*AssertContractStorageAction).storageOf(contract)
+			assert.Nil(t, err, "Must not fail")
+			assert.Equal(t, `(Pair "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" { Elt "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" 1 })`, micheline.Print(storage, ""))
+			assert.Equal(t, `(Pair 1 { "x" })`, micheline.Print(actions[3].(*PackDataAction).Data, ""))
+		})
+	t.Run("Test GetActions (Invalid annotated values)",
+		func(t *testing.T) {
+			_, err := GetActions([]Action{
+				originate,
+				{
+					Kind: CallContract,
+					Payload: json.RawMessage(`
+						{
+							"recipient": "contract_1",
+							"sender": "bob",
+							"entrypoint": "add",
+							"amount": "0",
+							"parameter": { "to": "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" },
+							"format": "annotated"
+						}
+					`),
+				},
+			})
+			assert.NotNil(t, err, "Must fail")
+			assert.Equal(t, "invalid 'parameter'. missing field (value) (at /).", Error.Message(err), "Assert error message")
+
+			_, err = GetActions([]Action{
+				{
+					Kind: PackData,
+					Payload: json.RawMessage(`
+						{
+							"data": { "int": "1" },
+							"type": { "prim": "nat" },
+							"format": "micheline"
+						}
+					`),
+				},
+			})
+			assert.NotNil(t, err, "Must fail")
+			assert.Equal(t, "invalid format (micheline), expected one of [json, michelson, annotated].", Error.Message(err), "Assert error message")
+		})
+}
+
+func TestCheckPrimitives(t *testing.T) {
+	t.Run("Test CheckPrimitives (Annotated values)",
+		func(t *testing.T) {
+			rawActions := []Action{
+				{
+					Kind: OriginateContract,
+					Payload: json.RawMessage(`
+						{
+							"name": "contract_1",
+							"balance": "0",
+							"code": "{ parameter (lambda unit nat) ; storage unit ; code { CDR ; NIL operation ; PAIR } }",
+							"storage": "Unit",
+							"format": "michelson"
+						}
+					`),
+				},
+				{
+					Kind: CallContract,
+					Payload: json.RawMessage(`
+						{
+							"recipient": "contract_1",
+							"sender": "bob",
+							"entrypoint": "default",
+							"amount": "0",
+							"parameter": [ { "prim": "DROP" }, { "prim": "PUSH", "args": [ { "prim": "bytes" }, { "bytes": "01" } ] }, { "prim": "NAT" } ],
+							"format": "annotated"
+						}
+					`),
+				},
+			}
+			actions, err := GetActions(rawActions)
+			assert.Nil(t, err, "Must not fail")
+
+			assert.Nil(t, CheckPrimitives(rawActions, actions, ""), "Must not fail")
+			err = CheckPrimitives(rawActions, actions, "PtLimaPtLMwfiLCZzo5V4bPXsNs3Kdm5ZKJDsTbkpS9cXNkvXQt")
+			assert.NotNil(t, err, "Must fail")
+			assert.Contains(t, Error.Message(err), "Primitive (NAT) is not supported by protocol (Lima).", "Assert error message")
+		})
+}
+
+func TestGetActionsMicheline(t *testing.T) {
+	t.Run("Test GetActions (Micheline text is detected automatically)",
+		func(t *testing.T) {
+			actions, err := GetActions([]Action{
+				{
+					Kind: OriginateContract,
+					Payload: json.RawMessage(`
+						{
+							"name": "contract_1",
+							"balance": "0",
+							"code": "parameter (or (nat %add) (unit %reset)) ;\nstorage (pair address nat) ;\ncode { CDR ; NIL operation ; PAIR }",
+							"storage": "Pair \"TEST__ADDRESS_OF_ACCOUNT__bob\" 0"
+						}
+					`),
+				},
+				{
+					Kind: CallContract,
+					Payload: json.RawMessage(`
+						{
+							"recipient": "contract_1",
+							"sender": "bob",
+							"entrypoint": "add",
+							"amount": "0",
+							"parameter": "1",
+							"expect_failwith": { "string": "error" }
+						}
+					`),
+				},
+				{
+					Kind: PackData,
+					Payload: json.RawMessage(`
+						{
+							"data": "{ 1 ; 2 }",
+							"type": "set nat",
+							"format": "michelson"
+						}
+					`),
+				},
+			})
+			assert.Nil(t, err, "Must not fail")
+			assert.Equal(t, `{ parameter (or (nat %add) (unit %reset)); storage (pair (address) (nat)); code { CDR; NIL (operation); PAIR } }`, micheline.Print(actions[0].(*OriginateContractAction).Code, ""))
+			assert.Equal(t, `(Pair "TEST__ADDRESS_OF_ACCOUNT__bob" 0)`, micheline.Print(actions[0].(*OriginateContractAction).Storage, ""))
+			assert.Equal(t, `1`, micheline.Print(actions[1].(*CallContractAction).Parameter, ""))
+			assert.Equal(t, `"error"`, micheline.Print(actions[1].(*CallContractAction).ExpectFailwith, ""))
+			assert.Equal(t, `{ 1; 2 }`, micheline.Print(actions[2].(*PackDataAction).Data, ""))
+		})
+	t.Run("Test GetActions (Micheline errors are reported against the text)",
+		func(t *testing.T) {
+			_, err := GetActions([]Action{
+				{
+					Kind: PackData,
+					Payload: json.RawMessage(`
+						{
+							"data": "{ 1 ;\n  2 ) }",
+							"type": "set nat"
+						}
+					`),
+				},
+			})
+			assert.NotNil(t, err, "Must fail")
+			assert.Equal(t, "invalid michelson value. Expected token kind (Semi), but received (Close_paren). (line 2, column 5)\n2 |   2 ) }\n        ^", Error.Message(err), "Assert error message")
+
+			_, err = GetActions([]Action{
+				{
+					Kind: PackData,
+					Payload: json.RawMessage(`
+						{
+							"data": { "int": "1" },
+							"type": "nat",
+							"format": "michelson"
+						}
+					`),
+				},
+			})
+			assert.NotNil(t, err, "Must fail")
+			assert.Equal(t, `invalid michelson value. expected Michelson in "micheline" format (a string), but received { "int": "1" }.`, Error.Message(err), "Assert error message")
+		})
+}
+
+func TestReplaceBigMaps(t *testing.T) {
+	t.Run("Test replaceBigMaps (Only types are replaced)",
+		func(t *testing.T) {
+			code, err := michelson.ParseMicheline(`{ parameter (big_map %big_map nat string) ; storage unit ; code { PUSH string "big_map" ; FAILWITH } }`)
+			assert.Nil(t, err, "Must not fail")
+			assert.Equal(
+				t,
+				`{ parameter (map %big_map (nat) (string)); storage (unit); code { PUSH (string) "big_map"; FAILWITH } }`,
+				micheline.Print(replaceBigMaps(code), ""),
+				"Validate replaced types",
+			)
+		})
+}
+
+func TestGetInvariants(t *testing.T) {
+	t.Run("Test GetInvariants (Only assertions are allowed)",
+		func(t *testing.T) {
+			_, err := GetInvariants([]Action{
+				{
+					Kind: ModifyBlockLevel,
+					Payload: json.RawMessage(`
+						{
+							"level": 10
+						}
+					`),
+				},
+			})
+			assert.NotNil(t, err, "Must fail")
+			assert.Equal(t, "Action of kind (modify_block_level) cannot be used as an invariant.", Error.Message(err), "Assert error message")
+		})
+}
+
+func TestApplyActionsWithBackendMock(t *testing.T) {
+	mockup := newBackendMock()
+	assert.Nil(t, mockup.Bootstrap(), "Must not fail")
+
+	actions, err := GetActions([]Action{
+		{Kind: CreateImplicitAccount, Payload: json.RawMessage(`{ "name": "alice", "balance": "10" }`)},
+		{Kind: AssertAccountBalance, Payload: json.RawMessage(`{ "account_name": "alice", "balance": "10" }`)},
+		{Kind: OriginateContract, Payload: json.RawMessage(`{
+			"name": "counter",
+			"balance": "5",
+			"code": [
+				{ "prim": "parameter", "args": [{ "prim": "nat" }] },
+				{ "prim": "storage", "args": [{ "prim": "nat" }] },
+				{ "prim": "code", "args": [[{ "prim": "CAR" }, { "prim": "NIL", "args": [{ "prim": "operation" }] }, { "prim": "PAIR" }]] }
+			],
+			"storage": { "int": "1" }
+		}`)},
+		{Kind: AssertContractStorage, Payload: json.RawMessage(`{ "contract_name": "counter", "storage": { "int": "1" } }`)},
+		{Kind: ModifyBlockLevel, Payload: json.RawMessage(`{ "level": 10 }`)},
+	})
+	assert.Nil(t, err, "Must not fail")
+
+	results := ApplyActions(mockup, actions)
+	assert.Len(t, results, 5, "Validate number of results")
+	for _, result := range results {
+		assert.Equal(t, Success, result.Status, "Validate action status (%+v)", result)
+	}
+	balance, err := mockup.GetBalance("counter")
+	assert.Nil(t, err, "Must not fail")
+	assert.Equal(t, "5", balance.String(), "Validate contract balance")
+	// The executing block comes after the head block
+	assert.Equal(t, int32(9), mockup.level, "Validate head block level")
+
+	t.Run("Values are printed in the format of the action",
+		func(t *testing.T) {
+			actions, err := GetActions([]Action{
+				{Kind: AssertContractStorage, Payload: json.RawMessage(`{ "contract_name": "counter", "storage": "2", "format": "michelson" }`)},
+			})
+			assert.Nil(t, err, "Must not fail")
+			results := ApplyActions(mockup, actions)
+			assert.Equal(t, Failure, results[0].Status, "Validate action status")
+			b, err := json.Marshal(results[0].Result)
+			assert.Nil(t, err, "Must not fail")
+			assert.JSONEq(t, `{ "expected": "2", "actual": "1" }`, string(b), "Validate expected and actual storages")
+		})
+	t.Run("Expected FAILWITH errors are compared with the emitted value",
+		func(t *testing.T) {
+			mockup.transferErr = errors.New("script reached FAILWITH instruction\nwith \"NOT_ALLOWED\"\n")
+			defer func() { mockup.transferErr = nil }()
+
+			actions, err := GetActions([]Action{
+				{Kind: CallContract, Payload: json.RawMessage(`{
+					"sender": "alice",
+					"recipient": "counter",
+					"entrypoint": "default",
+					"amount": "0",
+					"parameter": { "int": "2" },
+					"expect_failwith": { "string": "NOT_ALLOWED" }
+				}`)},
+			})
+			assert.Nil(t, err, "Must not fail")
+			results := ApplyActions(mockup, actions)
+			assert.Equal(t, Success, results[0].Status, "Validate action status (%+v)", results[0])
+		})
+	t.Run("Failures reported by 'tezos-client' are classified",
+		func(t *testing.T) {
+			mockup.transferErr = business.ParseClientError("Error:\n  Balance of contract tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx too low (0.00001) to spend 1\n")
+			defer func() { mockup.transferErr = nil }()
+
+			actions, err := GetActions([]Action{
+				{Kind: CallContract, Payload: json.RawMessage(`{
+					"sender": "alice",
+					"recipient": "counter",
+					"entrypoint": "default",
+					"amount": "1000000",
+					"parameter": { "int": "2" }
+				}`)},
+			})
+			assert.Nil(t, err, "Must not fail")
+			results := ApplyActions(mockup, actions)
+			assert.Equal(t, Failure, results[0].Status, "Validate action status")
+			b, err := json.Marshal(results[0].Result)
+			assert.Nil(t, err, "Must not fail")
+			assert.JSONEq(
+				t,
+				`{
+					"details": "Error:\n  Balance of contract tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx too low (0.00001) to spend 1\n",
+					"error": {
+						"kind": "balance_too_low",
+						"contract": "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx",
+						"balance": "10",
+						"amount": "1000000"
+					}
+				}`,
+				string(b),
+				"Validate classified error",
+			)
+		})
+}
+
 // Mocks
 
 type CreateImplicitAccountActionMock struct {
 	CreateImplicitAccountAction
 }
 
-func (action CreateImplicitAccountActionMock) Run(mockup business.Mockup) (interface{}, bool) {
+func (action CreateImplicitAccountActionMock) Run(mockup business.Backend) (interface{}, bool) {
 	if action.Name == "bob" {
 		return "ERROR", false
 	}
 	return map[string]interface{}{}, true
 }
+
+// CountingInvariantMock is an invariant that always holds and counts its evaluations
+type CountingInvariantMock struct {
+	runs int
+}
+
+func (action *CountingInvariantMock) Run(mockup business.Backend) (interface{}, bool) {
+	action.runs += 1
+	return map[string]interface{}{}, true
+}
+
+func (action *CountingInvariantMock) Unmarshal(ac Action) error { return nil }
+
+func (action *CountingInvariantMock) Action() interface{} { return nil }
+
+// BackendMock is an in-memory backend, contracts are not executed (calls only move funds)
+type BackendMock struct {
+	business.Session
+	balances  map[string]*big.Int
+	storages  map[string]ast.Node
+	transfers []business.CallContractArgument
+	level     int32
+	timestamp string
+	chainID   string
+	// Error returned by the next transfers
+	transferErr error
+}
+
+func newBackendMock() *BackendMock {
+	return &BackendMock{
+		Session: business.InitSession("task", "", config.Config{
+			Tezos: config.TezosConfig{
+				RevealFee:  1000,
+				Originator: "bootstrap1",
+			},
+		}),
+		balances: map[string]*big.Int{},
+		storages: map[string]ast.Node{},
+	}
+}
+
+func (m *BackendMock) Bootstrap() error {
+	m.CacheAccountAddress("bootstrap1", "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx")
+	m.balances["tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"] = big.NewInt(1_000_000_000)
+	return nil
+}
+
+func (m *BackendMock) Teardown() error { return nil }
+
+func (m *BackendMock) UpdateChainID(chainID string) error {
+	m.chainID = chainID
+	return nil
+}
+
+func (m *BackendMock) UpdateHeadBlockLevel(level int32) error {
+	m.level = level
+	return nil
+}
+
+func (m *BackendMock) UpdateHeadBlockTimestamp(timestamp string) error {
+	m.timestamp = timestamp
+	return nil
+}
+
+func (m *BackendMock) ImportSecret(privateKey string, walletName string) error {
+	key, err := tezos.ParsePrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+	m.CacheAccountAddress(walletName, key.Address().String())
+	return nil
+}
+
+func (m *BackendMock) RevealWallet(walletName string, revealFee business.Mutez) error {
+	return m.move(walletName, "", revealFee.Int())
+}
+
+func (m *BackendMock) Transfer(arg business.CallContractArgument) error {
+	if m.transferErr != nil {
+		return m.transferErr
+	}
+	m.transfers = append(m.transfers, arg)
+	return m.move(arg.Source, arg.Recipient, arg.Amount.Int())
+}
+
+func (m *BackendMock) Originate(sender string, contractName string, amount business.Mutez, code string, storage string) (string, error) {
+	storageNode, err := michelson.ParseMicheline(storage)
+	if err != nil {
+		return "", err
+	}
+	hash := tezos.Digest([]byte(contractName))
+	address := tezos.NewAddress(tezos.AddressTypeContract, hash[:20]).String()
+	m.storages[address] = storageNode
+	return address, m.move(sender, address, amount.Int())
+}
+
+func (m *BackendMock) GetBalance(name string) (business.Mutez, error) {
+	balance, ok := m.balances[m.resolve(name)]
+	if !ok {
+		balance = new(big.Int)
+	}
+	return business.MutezOfFloat(new(big.Float).SetInt(balance)), nil
+}
+
+func (m *BackendMock) GetFeesPaid(name string) business.Mutez {
+	return business.MutezOfFloat(big.NewFloat(0))
+}
+
+func (m *BackendMock) GetContractStorage(contractName string) (ast.Node, error) {
+	storage, ok := m.storages[m.resolve(contractName)]
+	if !ok {
+		return nil, fmt.Errorf("unknown contract (%s).", contractName)
+	}
+	return storage, nil
+}
+
+func (m *BackendMock) NormalizeData(data string, dataType string, mode business.ParsingMode) (ast.Node, error) {
+	value, err := michelson.ParseMicheline(data)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := michelson.ParseMicheline(dataType)
+	if err != nil {
+		return nil, err
+	}
+	return michelson.Normalize(value, typ, mode)
+}
+
+func (m *BackendMock) SerializeData(data string, dataType string) (string, error) {
+	value, err := michelson.ParseMicheline(data)
+	if err != nil {
+		return "", err
+	}
+	typ, err := michelson.ParseMicheline(dataType)
+	if err != nil {
+		return "", err
+	}
+	packed, err := binary.Pack(value, typ)
+	return "0x" + hex.EncodeToString(packed), err
+}
+
+func (m *BackendMock) Snapshot() (business.StateSnapshot, error) {
+	balances := make(map[string]*big.Int, len(m.balances))
+	for address, balance := range m.balances {
+		balances[address] = balance
+	}
+	storages := make(map[string]ast.Node, len(m.storages))
+	for address, storage := range m.storages {
+		storages[address] = storage
+	}
+	return BackendMock{balances: balances, storages: storages}, nil
+}
+
+func (m *BackendMock) Restore(snapshot business.StateSnapshot) error {
+	state := snapshot.(BackendMock)
+	copied, _ := state.Snapshot()
+	m.balances, m.storages = copied.(BackendMock).balances, copied.(BackendMock).storages
+	return nil
+}
+
+func (m *BackendMock) Discard(snapshot business.StateSnapshot) error {
+	return nil
+}
+
+func (m *BackendMock) resolve(name string) string {
+	if address, ok := m.GetAddresses()[name]; ok {
+		return address
+	}
+	return name
+}
+
+// move transfers funds between accounts (funds sent to "" are burnt)
+func (m *BackendMock) move(from string, to string, amount *big.Int) error {
+	from = m.resolve(from)
+	balance, ok := m.balances[from]
+	if !ok || balance.Cmp(amount) < 0 {
+		return errors.New("balance too low.")
+	}
+	m.balances[from] = new(big.Int).Sub(balance, amount)
+	if to != "" {
+		to = m.resolve(to)
+		if _, ok := m.balances[to]; !ok {
+			m.balances[to] = new(big.Int)
+		}
+		m.balances[to] = new(big.Int).Add(m.balances[to], amount)
+	}
+	return nil
+}
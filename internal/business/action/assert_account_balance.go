@@ -52,8 +52,11 @@ func (action AssertAccountBalanceAction) Action() interface{} {
 }
 
 // Perform the action
-func (action AssertAccountBalanceAction) Run(mockup business.Mockup) (interface{}, bool) {
-	balance := mockup.GetBalance(action.AccountName)
+func (action AssertAccountBalanceAction) Run(mockup business.Backend) (interface{}, bool) {
+	balance, err := mockup.GetBalance(action.AccountName)
+	if err != nil {
+		return err, false
+	}
 
 	if balance.String() != action.Balance.String() {
 		return map[string]string{
@@ -0,0 +1,192 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/romarq/tezos-sc-tester/internal/business"
+	Error "github.com/romarq/tezos-sc-tester/internal/error"
+	"github.com/romarq/tezos-sc-tester/internal/utils"
+)
+
+type (
+	BalanceChangeJSON struct {
+		AccountName string `json:"account_name"`
+		Change      string `json:"change"`
+		ExcludeFees bool   `json:"exclude_fees,omitempty"`
+	}
+	BalanceChange struct {
+		AccountName string
+		Change      business.Mutez
+		ExcludeFees bool
+	}
+	balanceSnapshot struct {
+		Balance business.Mutez
+		Fees    business.Mutez
+	}
+	AssertBalanceChangesAction struct {
+		json struct {
+			Kind    ActionKind `json:"kind"`
+			Payload struct {
+				Changes []BalanceChangeJSON `json:"changes"`
+				Actions []Action            `json:"actions"`
+			} `json:"payload"`
+		}
+		Changes []BalanceChange
+		Actions []IAction
+	}
+)
+
+// Unmarshal action
+func (action *AssertBalanceChangesAction) Unmarshal(ac Action) error {
+	action.json.Kind = ac.Kind
+	err := json.Unmarshal(ac.Payload, &action.json.Payload)
+	if err != nil {
+		return err
+	}
+
+	// Validate action
+	if err = action.validate(); err != nil {
+		return err
+	}
+
+	// "changes" field
+	action.Changes, err = parseBalanceChanges(action.json.Payload.Changes)
+	if err != nil {
+		return err
+	}
+
+	// "actions" field
+	action.Actions, err = GetActions(action.json.Payload.Actions)
+	if err != nil {
+		return fmt.Errorf("invalid actions. %s", Error.Message(err))
+	}
+
+	return nil
+}
+
+// Marshal returns the JSON of the action (cached)
+func (action AssertBalanceChangesAction) Action() interface{} {
+	return action.json
+}
+
+// Run performs action (Applies the nested actions and asserts the balance changes)
+func (action AssertBalanceChangesAction) Run(mockup business.Backend) (interface{}, bool) {
+	return action.runNested(mockup, &invariantChecker{})
+}
+
+// runNested applies the nested actions, the invariants are checked after each of them
+func (action AssertBalanceChangesAction) runNested(mockup business.Backend, checker *invariantChecker) (interface{}, bool) {
+	snapshots, err := recordBalances(mockup, action.Changes)
+	if err != nil {
+		return err, false
+	}
+
+	results := checker.apply(mockup, action.Actions)
+	for _, result := range results {
+		if result.Status == Failure {
+			return map[string]interface{}{
+				"results": results,
+			}, false
+		}
+	}
+
+	changes, ok, err := assertBalanceChanges(mockup, action.Changes, snapshots)
+	if err != nil {
+		return err, false
+	}
+	return map[string]interface{}{
+		"balance_changes": changes,
+		"results":         results,
+	}, ok
+}
+
+// validate validates the action fields before interpreting them
+func (action AssertBalanceChangesAction) validate() error {
+	missingFields := make([]string, 0)
+	if len(action.json.Payload.Changes) == 0 {
+		missingFields = append(missingFields, "changes")
+	}
+	if len(action.json.Payload.Actions) == 0 {
+		missingFields = append(missingFields, "actions")
+	}
+
+	if len(missingFields) > 0 {
+		return fmt.Errorf("Action of kind (%s) misses the following fields [%s].", AssertBalanceChanges, strings.Join(missingFields, ", "))
+	}
+
+	return nil
+}
+
+// parseBalanceChanges validates and interprets the expected balance changes
+func parseBalanceChanges(changes []BalanceChangeJSON) ([]BalanceChange, error) {
+	balanceChanges := make([]BalanceChange, len(changes))
+	for i, change := range changes {
+		if change.AccountName == "" {
+			return nil, fmt.Errorf("balance changes must specify an 'account_name'.")
+		}
+		if err := utils.ValidateString(STRING_IDENTIFIER_REGEX, change.AccountName); err != nil {
+			return nil, err
+		}
+
+		value, err := business.MutezOfString(change.Change)
+		if err != nil {
+			return nil, err
+		}
+		balanceChanges[i] = BalanceChange{
+			AccountName: change.AccountName,
+			Change:      value,
+			ExcludeFees: change.ExcludeFees,
+		}
+	}
+
+	return balanceChanges, nil
+}
+
+// recordBalances records the balances (and fees paid) of the accounts referenced by the balance changes
+func recordBalances(mockup business.Backend, changes []BalanceChange) (map[string]balanceSnapshot, error) {
+	snapshots := map[string]balanceSnapshot{}
+	for _, change := range changes {
+		balance, err := mockup.GetBalance(change.AccountName)
+		if err != nil {
+			return nil, err
+		}
+		snapshots[change.AccountName] = balanceSnapshot{
+			Balance: balance,
+			Fees:    mockup.GetFeesPaid(change.AccountName),
+		}
+	}
+	return snapshots, nil
+}
+
+// assertBalanceChanges compares the expected balance changes with the actual changes since the snapshots were recorded
+func assertBalanceChanges(mockup business.Backend, changes []BalanceChange, snapshots map[string]balanceSnapshot) ([]map[string]string, bool, error) {
+	success := true
+	results := make([]map[string]string, 0)
+	for _, change := range changes {
+		snapshot := snapshots[change.AccountName]
+
+		balance, err := mockup.GetBalance(change.AccountName)
+		if err != nil {
+			return nil, false, err
+		}
+		actual := business.SubMutez(balance, snapshot.Balance)
+		if change.ExcludeFees {
+			// Add back the fees paid by the account during the operation
+			actual = business.AddMutez(actual, business.SubMutez(mockup.GetFeesPaid(change.AccountName), snapshot.Fees))
+		}
+
+		result := map[string]string{
+			"account_name": change.AccountName,
+			"expected":     change.Change.Int().String(),
+			"actual":       actual.Int().String(),
+		}
+		if result["expected"] != result["actual"] {
+			success = false
+		}
+		results = append(results, result)
+	}
+
+	return results, success, nil
+}
@@ -0,0 +1,71 @@
+package action
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshal_AssertBalanceChangesAction(t *testing.T) {
+	t.Run("Test AssertBalanceChangesAction Unmarshal (Valid)",
+		func(t *testing.T) {
+			rawAction := Action{
+				Kind: AssertBalanceChanges,
+				Payload: json.RawMessage(`
+					{
+						"changes": [
+							{ "account_name": "bob", "change": "-10", "exclude_fees": true },
+							{ "account_name": "contract_1", "change": "10" }
+						],
+						"actions": [
+							{
+								"kind": "call_contract",
+								"payload": {
+									"recipient":	"contract_1",
+									"sender":		"bob",
+									"entrypoint":	"default",
+									"amount":		"10",
+									"parameter":	{ "prim": "Unit" }
+								}
+							}
+						]
+					}
+				`),
+			}
+			action := AssertBalanceChangesAction{}
+			err := action.Unmarshal(rawAction)
+			assert.Nil(t, err, "Must not fail")
+			assert.Len(t, action.Actions, 1, "Assert nested actions")
+			assert.Len(t, action.Changes, 2, "Assert balance changes")
+			assert.Equal(t, "bob", action.Changes[0].AccountName, "Assert account name")
+			assert.Equal(t, "-10", action.Changes[0].Change.String(), "Assert change")
+			assert.True(t, action.Changes[0].ExcludeFees, "Assert exclude fees")
+			assert.False(t, action.Changes[1].ExcludeFees, "Assert exclude fees")
+		})
+	t.Run("Test AssertBalanceChangesAction Unmarshal (Invalid change)",
+		func(t *testing.T) {
+			action := AssertBalanceChangesAction{}
+			err := action.Unmarshal(Action{
+				Kind: AssertBalanceChanges,
+				Payload: json.RawMessage(`
+					{
+						"changes": [{ "account_name": "bob", "change": "abc" }],
+						"actions": [{ "kind": "modify_block_level", "payload": { "level": 10 } }]
+					}
+				`),
+			})
+			assert.NotNil(t, err, "Must fail (Invalid change)")
+			assert.Equal(t, "invalid mutez value: abc.", err.Error(), "Assert error message")
+		})
+	t.Run("Test AssertBalanceChangesAction Unmarshal (Missing fields)",
+		func(t *testing.T) {
+			action := AssertBalanceChangesAction{}
+			err := action.Unmarshal(Action{
+				Kind:    AssertBalanceChanges,
+				Payload: json.RawMessage(`{}`),
+			})
+			assert.NotNil(t, err, "Must fail (Missing fields)")
+			assert.Equal(t, "Action of kind (assert_balance_changes) misses the following fields [changes, actions].", err.Error(), "Assert error message")
+		})
+}
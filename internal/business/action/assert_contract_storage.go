@@ -8,7 +8,6 @@ import (
 	"github.com/romarq/tezos-sc-tester/internal/business"
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
-	MichelsonJSON "github.com/romarq/tezos-sc-tester/internal/business/michelson/json"
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
 	"github.com/romarq/tezos-sc-tester/internal/logger"
 	"github.com/romarq/tezos-sc-tester/internal/utils"
@@ -20,10 +19,12 @@ type AssertContractStorageAction struct {
 		Payload struct {
 			ContractName string          `json:"contract_name"`
 			Storage      json.RawMessage `json:"storage"`
+			Format       string          `json:"format,omitempty"`
 		} `json:"payload"`
 	}
 	ContractName string
 	Storage      ast.Node
+	Format       business.MichelsonFormat
 }
 
 // Unmarshal action
@@ -42,11 +43,19 @@ func (action *AssertContractStorageAction) Unmarshal(ac Action) error {
 	// "contract_name" field
 	action.ContractName = action.json.Payload.ContractName
 
-	// "storage" field
-	action.Storage, err = michelson.ParseJSON(action.json.Payload.Storage)
+	// "format" field
+	action.Format, err = parseFormat(action.json.Payload.Format)
 	if err != nil {
-		logger.Debug("%+v", action.json.Payload.Storage)
-		return fmt.Errorf("invalid michelson. %s", err)
+		return err
+	}
+
+	// "storage" field (annotated values are parsed once the storage type is known)
+	if action.Format != business.Annotated {
+		action.Storage, err = parseMichelson(action.json.Payload.Storage, action.Format)
+		if err != nil {
+			logger.Debug("%+v", action.json.Payload.Storage)
+			return fmt.Errorf("invalid michelson. %s", err)
+		}
 	}
 
 	return nil
@@ -58,7 +67,7 @@ func (action AssertContractStorageAction) Action() interface{} {
 }
 
 // Perform the action
-func (action AssertContractStorageAction) Run(mockup business.Mockup) (result interface{}, success bool) {
+func (action AssertContractStorageAction) Run(mockup business.Backend) (result interface{}, success bool) {
 	defer func() {
 		if err := recover(); err != nil {
 			result = err
@@ -74,31 +83,51 @@ func (action AssertContractStorageAction) Run(mockup business.Mockup) (result in
 		return errMsg, false
 	}
 
-	actualStorageJSON, err := MichelsonJSON.Print(storage, "", "  ")
+	// Get the storage type (the expected data needs to be normalize against the type)
+	contract, isKnownContract := mockup.GetContract(action.ContractName)
+	storageType := contract.Storage
+
+	if action.Storage == nil {
+		if !isKnownContract {
+			return unknownContractType(action.ContractName), false
+		}
+		if action.Storage, err = action.storageOf(contract); err != nil {
+			return err, false
+		}
+	}
+
+	actualStorageJSON, err := printValue(storage, action.Format, storageType)
 	if err != nil {
 		err = fmt.Errorf("failed to print actual contract storage to JSON. %s", err)
 		logger.Debug("[%s] %s", AssertContractStorage, err)
 		return err, false
 	}
 
-	// Get the storage type (the expected data needs to be normalize against the type)
-	storageTypeMicheline := micheline.Print(mockup.GetCachedContract(action.ContractName).StorageType, "")
-
-	expectedStorageMicheline := expandPlaceholders(mockup, micheline.Print(action.Storage, ""))
-	expectedStorageAST, err := mockup.NormalizeData(expectedStorageMicheline, storageTypeMicheline, business.Readable)
+	expectedStorageMicheline, err := expandPlaceholders(mockup, micheline.Print(action.Storage, ""))
+	if err != nil {
+		logger.Debug("[%s] %s", AssertContractStorage, err)
+		return err, false
+	}
+	expectedStorageAST, err := normalizeStorage(mockup, expectedStorageMicheline, storageType)
 	if err != nil {
 		err = fmt.Errorf("failed to parse 'micheline'. %s", err)
 		logger.Debug("[%s] %s", AssertContractStorage, err)
 		return err, false
 	}
-	expectedStorageJSON, err := MichelsonJSON.Print(expectedStorageAST, "", "  ")
+	if storageType != nil {
+		// The storage returned by tezos-client is already normalized, this only ensures both values are represented the same way
+		if normalized, err := michelson.Normalize(storage, storageType, business.Readable); err == nil {
+			storage = normalized
+		}
+	}
+	expectedStorageJSON, err := printValue(expectedStorageAST, action.Format, storageType)
 	if err != nil {
 		err = fmt.Errorf("failed to print expected contract storage to JSON. %s", err)
 		logger.Debug("[%s] %s", AssertContractStorage, err)
 		return err, false
 	}
 
-	if expectedStorageAST.String() != storage.String() {
+	if !ast.Equal(expectedStorageAST, storage, ast.IgnorePositions) {
 		return map[string]json.RawMessage{
 			"expected": expectedStorageJSON,
 			"actual":   actualStorageJSON,
@@ -110,6 +139,34 @@ func (action AssertContractStorageAction) Run(mockup business.Mockup) (result in
 	}, true
 }
 
+// storageOf parses a storage written in annotated format, using the storage type of the contract
+func (action AssertContractStorageAction) storageOf(contract michelson.Contract) (ast.Node, error) {
+	storage, err := parseValue(action.json.Payload.Storage, action.Format, contract.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid michelson. %s", err)
+	}
+	return storage, nil
+}
+
+// normalizeStorage normalizes the expected storage in-process,
+// tezos-client is only used if the storage type is unknown or if the value cannot be normalized in-process
+func normalizeStorage(mockup business.Backend, storageMicheline string, storageType ast.Node) (ast.Node, error) {
+	storage, err := michelson.ParseMicheline(storageMicheline)
+	if err != nil {
+		return nil, err
+	}
+
+	if storageType != nil {
+		normalized, err := michelson.Normalize(storage, storageType, business.Readable)
+		if err == nil {
+			return normalized, nil
+		}
+		logger.Debug("[%s] could not normalize storage in-process. %s", AssertContractStorage, err)
+	}
+
+	return mockup.NormalizeData(storageMicheline, micheline.Print(storageType, ""), business.Readable)
+}
+
 // validate validates the action fields before interpreting them
 func (action AssertContractStorageAction) validate() error {
 	missingFields := make([]string, 0)
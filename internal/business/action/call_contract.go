@@ -3,6 +3,7 @@ package action
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/romarq/tezos-sc-tester/internal/business"
@@ -18,20 +19,24 @@ type CallContractAction struct {
 	json struct {
 		Kind    ActionKind `json:"kind"`
 		Payload struct {
-			Recipient      string          `json:"recipient"`
-			Sender         string          `json:"sender"`
-			Entrypoint     string          `json:"entrypoint"`
-			Amount         string          `json:"amount"`
-			Parameter      json.RawMessage `json:"parameter"`
-			ExpectFailwith json.RawMessage `json:"expect_failwith,omitempty"`
+			Recipient            string              `json:"recipient"`
+			Sender               string              `json:"sender"`
+			Entrypoint           string              `json:"entrypoint"`
+			Amount               string              `json:"amount"`
+			Parameter            json.RawMessage     `json:"parameter"`
+			ExpectFailwith       json.RawMessage     `json:"expect_failwith,omitempty"`
+			ExpectBalanceChanges []BalanceChangeJSON `json:"expect_balance_changes,omitempty"`
+			Format               string              `json:"format,omitempty"`
 		} `json:"payload"`
 	}
-	Recipient      string
-	Sender         string
-	Entrypoint     string
-	Amount         business.Mutez
-	Parameter      ast.Node
-	ExpectFailwith ast.Node
+	Recipient            string
+	Sender               string
+	Entrypoint           string
+	Amount               business.Mutez
+	Parameter            ast.Node
+	ExpectFailwith       ast.Node
+	ExpectBalanceChanges []BalanceChange
+	Format               business.MichelsonFormat
 }
 
 // Unmarshal action
@@ -60,22 +65,36 @@ func (action *CallContractAction) Unmarshal(ac Action) error {
 		return err
 	}
 
-	// "parameter" field
-	action.Parameter, err = michelson.ParseJSON(action.json.Payload.Parameter)
+	// "format" field
+	action.Format, err = parseFormat(action.json.Payload.Format)
 	if err != nil {
-		logger.Debug("%+v", action.json.Payload.Parameter)
-		return fmt.Errorf("invalid 'parameter'. %s", err)
+		return err
+	}
+
+	// "parameter" field (annotated parameters are parsed once the type of the entrypoint is known)
+	if action.Format != business.Annotated {
+		action.Parameter, err = parseMichelson(action.json.Payload.Parameter, action.Format)
+		if err != nil {
+			logger.Debug("%+v", action.json.Payload.Parameter)
+			return fmt.Errorf("invalid 'parameter'. %s", err)
+		}
 	}
 
 	// "expect_failwith" field
 	if action.json.Payload.ExpectFailwith != nil {
-		action.ExpectFailwith, err = michelson.ParseJSON(action.json.Payload.ExpectFailwith)
+		action.ExpectFailwith, err = parseMichelson(action.json.Payload.ExpectFailwith, action.Format)
 		if err != nil {
 			logger.Debug("%+v", action.json.Payload.ExpectFailwith)
 			return fmt.Errorf("invalid 'expect_failwith'. %s", err)
 		}
 	}
 
+	// "expect_balance_changes" field
+	action.ExpectBalanceChanges, err = parseBalanceChanges(action.json.Payload.ExpectBalanceChanges)
+	if err != nil {
+		return fmt.Errorf("invalid 'expect_balance_changes'. %s", err)
+	}
+
 	return nil
 }
 
@@ -85,10 +104,32 @@ func (action CallContractAction) Action() interface{} {
 }
 
 // Perform the action
-func (action CallContractAction) Run(mockup business.Mockup) (interface{}, bool) {
-	parameterMicheline := replaceBigMaps(micheline.Print(action.Parameter, ""))
-	parameterMicheline = expandPlaceholders(mockup, parameterMicheline)
-	err := mockup.Transfer(business.CallContractArgument{
+func (action CallContractAction) Run(mockup business.Backend) (interface{}, bool) {
+	contract, isKnownContract := mockup.GetContract(action.Recipient)
+	if action.Parameter == nil {
+		if !isKnownContract {
+			return unknownContractType(action.Recipient), false
+		}
+		parameter, err := action.parameterOf(contract)
+		if err != nil {
+			return err, false
+		}
+		action.Parameter = parameter
+	}
+
+	parameterMicheline := micheline.Print(replaceBigMaps(action.Parameter), "")
+	parameterMicheline, err := expandPlaceholders(mockup, parameterMicheline)
+	if err != nil {
+		return err, false
+	}
+	if err := action.validateParameter(mockup, parameterMicheline); err != nil {
+		return err, false
+	}
+	balances, err := recordBalances(mockup, action.ExpectBalanceChanges)
+	if err != nil {
+		return err, false
+	}
+	err = mockup.Transfer(business.CallContractArgument{
 		Recipient:  action.Recipient,
 		Source:     action.Sender,
 		Entrypoint: action.Entrypoint,
@@ -100,15 +141,15 @@ func (action CallContractAction) Run(mockup business.Mockup) (interface{}, bool)
 			// The transfer was not expected to fail
 			return err, false
 		}
-		// The transfer was expected to fail.
-		// Extract the error emitted with (FAILWITH), the error is a micheline value
-		michelineError, err := utils.ExtractFailWithError(err.Error())
-		if err != nil {
-			return err, false
+		// The transfer was expected to fail with (FAILWITH), other failures are reported as they are
+		clientErr := business.ClassifyError(err)
+		if clientErr.Kind != business.ScriptRejected || clientErr.With == nil {
+			return clientErr, false
 		}
+		michelineError := clientErr.With
 
 		// Validate the error against the user input
-		if michelineError.String() != action.ExpectFailwith.String() {
+		if !ast.Equal(michelineError, action.ExpectFailwith, ast.IgnorePositions) {
 			michelsonJson, err := MichelsonJSON.Print(michelineError, "", "  ")
 			if err != nil {
 				errMsg := fmt.Sprintf("failed to print (FAILWITH) result to michelson JSON. %s", err.Error())
@@ -127,15 +168,80 @@ func (action CallContractAction) Run(mockup business.Mockup) (interface{}, bool)
 		return fmt.Errorf("could not fetch storage for contract (%s).", action.Recipient), false
 	}
 
-	actualStorageJSON, err := MichelsonJSON.Print(storage, "", "  ")
+	actualStorageJSON, err := printValue(storage, action.Format, contract.Storage)
 	if err != nil {
 		logger.Debug("[%s] %s", AssertContractStorage, err.Error())
 		return fmt.Errorf("failed to print actual contract storage to JSON"), false
 	}
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"storage": actualStorageJSON,
-	}, true
+	}
+
+	if len(action.ExpectBalanceChanges) > 0 {
+		balanceChanges, ok, err := assertBalanceChanges(mockup, action.ExpectBalanceChanges, balances)
+		if err != nil {
+			return err, false
+		}
+		result["balance_changes"] = balanceChanges
+		if !ok {
+			return result, false
+		}
+	}
+
+	return result, true
+}
+
+// validateParameter validates the entrypoint and parameter against the parameter type of the recipient.
+// (Only applies to contracts originated in the test suite)
+func (action CallContractAction) validateParameter(mockup business.Backend, parameterMicheline string) error {
+	contract, ok := mockup.GetContract(action.Recipient)
+	if !ok {
+		return nil
+	}
+
+	entrypointType, err := getEntrypointType(action.Recipient, contract, action.Entrypoint)
+	if err != nil {
+		return err
+	}
+
+	parameter, err := michelson.ParseMicheline(parameterMicheline)
+	if err != nil {
+		return fmt.Errorf("invalid 'parameter'. %s", err)
+	}
+	if err := michelson.ValidateValue(parameter, entrypointType); err != nil {
+		return fmt.Errorf("ill-typed parameter for entrypoint (%s). %s", action.Entrypoint, err)
+	}
+
+	return nil
+}
+
+// parameterOf parses a parameter written in annotated format, using the type of the entrypoint
+func (action CallContractAction) parameterOf(contract michelson.Contract) (ast.Node, error) {
+	entrypointType, err := getEntrypointType(action.Recipient, contract, action.Entrypoint)
+	if err != nil {
+		return nil, err
+	}
+	parameter, err := parseValue(action.json.Payload.Parameter, action.Format, entrypointType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'parameter'. %s", err)
+	}
+	return parameter, nil
+}
+
+// getEntrypointType gets the parameter type of a contract entrypoint
+func getEntrypointType(contractName string, contract michelson.Contract, entrypoint string) (ast.Node, error) {
+	entrypoints := contract.Entrypoints()
+	entrypointType, ok := entrypoints[entrypoint]
+	if !ok {
+		names := make([]string, 0)
+		for name := range entrypoints {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("contract (%s) does not have entrypoint (%s). Available entrypoints: [%s].", contractName, entrypoint, strings.Join(names, ", "))
+	}
+	return entrypointType, nil
 }
 
 func (action CallContractAction) validate() error {
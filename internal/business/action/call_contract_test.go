@@ -47,12 +47,38 @@ func TestUnmarshal_CallContractAction(t *testing.T) {
 			assert.Equal(
 				t,
 				ast.Prim{
-					Prim: "Unit",
+					Position: ast.Position{Pos: 0, End: 17, Line: 1, Column: 1, EndLine: 1, EndColumn: 18},
+					Prim:     "Unit",
 				},
 				action.Parameter,
 				"Assert parameter",
 			)
 		})
+	t.Run("Test CallContractAction Unmarshal (With balance changes)",
+		func(t *testing.T) {
+			rawAction := Action{
+				Kind: CallContract,
+				Payload: json.RawMessage(`
+					{
+						"recipient":	"contract_1",
+						"sender":		"sender_name",
+						"entrypoint":	"do_something",
+						"amount":		"10",
+						"parameter":	{ "prim": "Unit" },
+						"expect_balance_changes": [
+							{ "account_name": "sender_name", "change": "-10", "exclude_fees": true }
+						]
+					}
+				`),
+			}
+			action := CallContractAction{}
+			err := action.Unmarshal(rawAction)
+			assert.Nil(t, err, "Must not fail")
+			assert.Len(t, action.ExpectBalanceChanges, 1, "Assert balance changes")
+			assert.Equal(t, "sender_name", action.ExpectBalanceChanges[0].AccountName, "Assert account name")
+			assert.Equal(t, "-10", action.ExpectBalanceChanges[0].Change.String(), "Assert change")
+			assert.True(t, action.ExpectBalanceChanges[0].ExcludeFees, "Assert exclude fees")
+		})
 	t.Run("Test CallContractAction Unmarshal (Invalid name)",
 		func(t *testing.T) {
 			rawAction := Action{
@@ -54,44 +54,48 @@ func (action CreateImplicitAccountAction) Action() interface{} {
 }
 
 // Perform action (Creates an implicit account)
-func (action CreateImplicitAccountAction) Run(mockup business.Mockup) (interface{}, bool) {
+func (action CreateImplicitAccountAction) Run(mockup business.Backend) (interface{}, bool) {
 	if mockup.ContainsAddress(action.Name) {
 		return fmt.Sprintf("Name (%s) is already in use.", action.Name), false
 	}
 
 	keyPair, err := utils.GenerateKey()
 	if err != nil {
-		logger.Debug("[Task #%s] - %s", mockup.TaskID, err)
+		logger.Debug("[Task #%s] - %s", mockup.GetTaskID(), err)
 		return "Could not generate wallet.", false
 	}
 
 	// Import private key
 	privateKey := keyPair.String()
 	if err = mockup.ImportSecret(privateKey, action.Name); err != nil {
-		logger.Debug("[Task #%s] - %s", mockup.TaskID, err)
+		logger.Debug("[Task #%s] - %s", mockup.GetTaskID(), err)
 		return "Could not import wallet.", false
 	}
 
 	// Fund wallet
 	address := keyPair.Address().String()
-	revealCost := business.MutezOfFloat(big.NewFloat(mockup.Config.Tezos.RevealFee))
+	revealCost := business.MutezOfFloat(big.NewFloat(mockup.GetConfig().Tezos.RevealFee))
 	if err = mockup.Transfer(business.CallContractArgument{
 		Recipient: address,
-		Source:    mockup.Config.Tezos.Originator,
+		Source:    mockup.GetConfig().Tezos.Originator,
 		Amount:    business.AddMutez(action.Balance, revealCost), // Increments revealFee which will be debited when revealing the wallet
 	}); err != nil {
-		logger.Debug("[Task #%s] - %s", mockup.TaskID, err)
+		logger.Debug("[Task #%s] - %s", mockup.GetTaskID(), err)
 		return "Could not fund wallet.", false
 	}
 
 	// Reveal wallet
 	if err = mockup.RevealWallet(action.Name, revealCost); err != nil {
-		logger.Debug("[Task #%s] - %s", mockup.TaskID, err)
+		logger.Debug("[Task #%s] - %s", mockup.GetTaskID(), err)
 		return "Could not reveal wallet.", false
 	}
 
 	// Confirm that the wallet was funded with the expected amount
-	walletBalance := mockup.GetBalance(action.Name)
+	walletBalance, err := mockup.GetBalance(action.Name)
+	if err != nil {
+		logger.Debug("[Task #%s] - %s", mockup.GetTaskID(), err)
+		return "Could not fetch the wallet balance.", false
+	}
 	// Verify the wallet balance
 	if walletBalance.String() != action.Balance.String() {
 		err := fmt.Sprintf("Account balance mismatch %s <> %s.", action.Balance, walletBalance.String())
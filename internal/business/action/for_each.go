@@ -0,0 +1,129 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/romarq/tezos-sc-tester/internal/business"
+	Error "github.com/romarq/tezos-sc-tester/internal/error"
+	"github.com/romarq/tezos-sc-tester/internal/utils"
+)
+
+type (
+	ForEachAction struct {
+		json struct {
+			Kind    ActionKind `json:"kind"`
+			Payload struct {
+				Bindings []map[string]json.RawMessage `json:"bindings"`
+				Actions  []Action                     `json:"actions"`
+			} `json:"payload"`
+		}
+		Iterations []ForEachIteration
+	}
+	ForEachIteration struct {
+		Bindings map[string]json.RawMessage
+		Actions  []IAction
+	}
+	ForEachIterationResult struct {
+		Bindings map[string]json.RawMessage `json:"bindings"`
+		Results  []ActionResult             `json:"results"`
+	}
+)
+
+// Unmarshal action
+func (action *ForEachAction) Unmarshal(ac Action) error {
+	action.json.Kind = ac.Kind
+	err := json.Unmarshal(ac.Payload, &action.json.Payload)
+	if err != nil {
+		return err
+	}
+
+	// Validate action
+	if err = action.validate(); err != nil {
+		return err
+	}
+
+	rawActions, err := json.Marshal(action.json.Payload.Actions)
+	if err != nil {
+		return err
+	}
+
+	// "bindings" field (Each binding row produces an iteration)
+	action.Iterations = make([]ForEachIteration, len(action.json.Payload.Bindings))
+	for i, bindings := range action.json.Payload.Bindings {
+		// Expand the variable placeholders before parsing the nested actions
+		var expandedActions []Action
+		err = json.Unmarshal(business.ExpandVariablePlaceholders(bindings, rawActions), &expandedActions)
+		if err != nil {
+			return fmt.Errorf("could not expand the bindings of iteration (%d). %s", i, err)
+		}
+
+		actions, err := GetActions(expandedActions)
+		if err != nil {
+			return fmt.Errorf("invalid actions in iteration (%d). %s", i, Error.Message(err))
+		}
+
+		action.Iterations[i] = ForEachIteration{
+			Bindings: bindings,
+			Actions:  actions,
+		}
+	}
+
+	return nil
+}
+
+// Marshal returns the JSON of the action (cached)
+func (action ForEachAction) Action() interface{} {
+	return action.json
+}
+
+// Run performs action (Applies the nested actions for each binding row)
+func (action ForEachAction) Run(mockup business.Backend) (interface{}, bool) {
+	return action.runNested(mockup, &invariantChecker{})
+}
+
+// runNested applies the nested actions of each iteration, the invariants are checked after each of them
+func (action ForEachAction) runNested(mockup business.Backend, checker *invariantChecker) (interface{}, bool) {
+	success := true
+	iterations := make([]ForEachIterationResult, 0)
+	for _, iteration := range action.Iterations {
+		results := checker.apply(mockup, iteration.Actions)
+		for _, result := range results {
+			success = success && result.Status == Success
+		}
+
+		iterations = append(iterations, ForEachIterationResult{
+			Bindings: iteration.Bindings,
+			Results:  results,
+		})
+	}
+
+	return map[string]interface{}{
+		"iterations": iterations,
+	}, success
+}
+
+// validate validates the action fields before interpreting them
+func (action ForEachAction) validate() error {
+	missingFields := make([]string, 0)
+	if len(action.json.Payload.Bindings) == 0 {
+		missingFields = append(missingFields, "bindings")
+	}
+	for _, bindings := range action.json.Payload.Bindings {
+		for variableName := range bindings {
+			if err := utils.ValidateString(STRING_IDENTIFIER_REGEX, variableName); err != nil {
+				return err
+			}
+		}
+	}
+	if len(action.json.Payload.Actions) == 0 {
+		missingFields = append(missingFields, "actions")
+	}
+
+	if len(missingFields) > 0 {
+		return fmt.Errorf("Action of kind (%s) misses the following fields [%s].", ForEach, strings.Join(missingFields, ", "))
+	}
+
+	return nil
+}
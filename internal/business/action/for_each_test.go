@@ -0,0 +1,79 @@
+package action
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshal_ForEachAction(t *testing.T) {
+	t.Run("Test ForEachAction Unmarshal (Valid)",
+		func(t *testing.T) {
+			rawAction := Action{
+				Kind: ForEach,
+				Payload: json.RawMessage(`
+					{
+						"bindings": [
+							{ "amount": "1", "value": { "int": "1" } },
+							{ "amount": "2", "value": { "int": "2" } }
+						],
+						"actions": [
+							{
+								"kind": "call_contract",
+								"payload": {
+									"recipient":	"contract_1",
+									"sender":		"bob",
+									"entrypoint":	"default",
+									"amount":		"TEST__VARIABLE__amount",
+									"parameter":	"TEST__VARIABLE__value"
+								}
+							}
+						]
+					}
+				`),
+			}
+			action := ForEachAction{}
+			err := action.Unmarshal(rawAction)
+			assert.Nil(t, err, "Must not fail")
+			assert.Len(t, action.Iterations, 2, "Assert iterations")
+			for i, value := range []string{"1", "2"} {
+				assert.Len(t, action.Iterations[i].Actions, 1, "Assert nested actions")
+				callContract := action.Iterations[i].Actions[0].(*CallContractAction)
+				assert.Equal(t, value, callContract.Amount.String(), "Assert amount")
+				assert.Equal(t, ast.Int{Position: ast.Position{Pos: 0, End: 13, Line: 1, Column: 1, EndLine: 1, EndColumn: 14}, Value: value}, callContract.Parameter, "Assert parameter")
+			}
+		})
+	t.Run("Test ForEachAction Unmarshal (Invalid nested action)",
+		func(t *testing.T) {
+			rawAction := Action{
+				Kind: ForEach,
+				Payload: json.RawMessage(`
+					{
+						"bindings": [{ "name": "bob" }],
+						"actions": [
+							{
+								"kind": "create_implicit_account",
+								"payload": { "name": "TEST__VARIABLE__name TEST__VARIABLE__name" }
+							}
+						]
+					}
+				`),
+			}
+			action := ForEachAction{}
+			err := action.Unmarshal(rawAction)
+			assert.NotNil(t, err, "Must fail (nested action is invalid)")
+			assert.Equal(t, "invalid actions in iteration (0). String (bob bob) does not match pattern '^[a-zA-Z0-9_]+$'.", err.Error(), "Assert error message")
+		})
+	t.Run("Test ForEachAction Unmarshal (Missing fields)",
+		func(t *testing.T) {
+			action := ForEachAction{}
+			err := action.Unmarshal(Action{
+				Kind:    ForEach,
+				Payload: json.RawMessage(`{}`),
+			})
+			assert.NotNil(t, err, "Must fail (Missing fields)")
+			assert.Equal(t, err.Error(), "Action of kind (for_each) misses the following fields [bindings, actions].", "Assert error message")
+		})
+}
@@ -0,0 +1,75 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/romarq/tezos-sc-tester/internal/business"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	MichelsonJSON "github.com/romarq/tezos-sc-tester/internal/business/michelson/json"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+)
+
+// parseFormat parses the "format" field of a payload.
+//
+// Without a format, Michelson fields are detected automatically (a JSON string is parsed in "micheline" format).
+func parseFormat(format string) (business.MichelsonFormat, error) {
+	switch business.MichelsonFormat(format) {
+	case "", business.JSON, business.Michelson, business.Annotated:
+		return business.MichelsonFormat(format), nil
+	}
+	return "", fmt.Errorf("invalid format (%s), expected one of [%s, %s, %s].", format, business.JSON, business.Michelson, business.Annotated)
+}
+
+// parseMichelson parses Michelson (code, types or values) written in "json" or "micheline" format.
+//
+// Annotated JSON only applies to values, other fields are detected automatically.
+func parseMichelson(raw json.RawMessage, format business.MichelsonFormat) (ast.Node, error) {
+	switch format {
+	case business.JSON:
+		return michelson.ParseJSON(raw)
+	case business.Michelson:
+		var michelsonMicheline string
+		if err := json.Unmarshal(raw, &michelsonMicheline); err != nil {
+			return nil, fmt.Errorf("expected Michelson in \"micheline\" format (a string), but received %s.", raw)
+		}
+		return michelson.ParseMicheline(michelsonMicheline)
+	}
+	return michelson.ParseJSONOrMicheline(raw)
+}
+
+// parseValue parses a value written in a given format (annotated values can only be parsed if their type is known)
+func parseValue(raw json.RawMessage, format business.MichelsonFormat, typ ast.Node) (ast.Node, error) {
+	if format == business.Annotated {
+		return michelson.ParseAnnotatedJSON(raw, typ)
+	}
+	return parseMichelson(raw, format)
+}
+
+// printValue prints a value in a given format ("micheline" values are written as a JSON string),
+// values are printed in Michelson JSON if their type is unknown and the format is not "micheline"
+func printValue(value ast.Node, format business.MichelsonFormat, typ ast.Node) (json.RawMessage, error) {
+	switch {
+	case format == business.Michelson && typ == nil:
+		return json.Marshal(micheline.Print(value, ""))
+	case format == business.Michelson:
+		// Optimized values are written in their readable form
+		michelsonMicheline, err := michelson.PrintMicheline(value, typ, "")
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(michelsonMicheline)
+	case typ == nil:
+		return MichelsonJSON.Print(value, "", "  ")
+	case format == business.Annotated:
+		return michelson.AnnotatedJSONOf(value, typ)
+	}
+	// Optimized values are written in their readable form
+	return michelson.PrintJSON(value, typ, "", "  ")
+}
+
+// unknownContractType is returned when an annotated value targets a contract that was not originated in the test suite
+func unknownContractType(contractName string) error {
+	return fmt.Errorf("the type of contract (%s) is unknown, values in annotated format can only be used with contracts originated in the test suite.", contractName)
+}
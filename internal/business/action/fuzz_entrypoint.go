@@ -0,0 +1,323 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/romarq/tezos-sc-tester/internal/business"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/generator"
+	MichelsonJSON "github.com/romarq/tezos-sc-tester/internal/business/michelson/json"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	Error "github.com/romarq/tezos-sc-tester/internal/error"
+	"github.com/romarq/tezos-sc-tester/internal/logger"
+	"github.com/romarq/tezos-sc-tester/internal/utils"
+)
+
+type FuzzEntrypointAction struct {
+	json struct {
+		Kind    ActionKind `json:"kind"`
+		Payload struct {
+			ContractName string   `json:"contract_name"`
+			Sender       string   `json:"sender"`
+			Entrypoint   string   `json:"entrypoint"`
+			Amount       string   `json:"amount"`
+			Seed         *int64   `json:"seed,omitempty"`
+			Iterations   *int     `json:"iterations,omitempty"`
+			Invariants   []Action `json:"invariants,omitempty"`
+		} `json:"payload"`
+	}
+	ContractName string
+	Sender       string
+	Entrypoint   string
+	Amount       business.Mutez
+	Seed         int64
+	Iterations   int
+	Invariants   []IAction
+}
+
+const (
+	DEFAULT_FUZZ_ITERATIONS = 20
+	MAX_FUZZ_ITERATIONS     = 100
+	MAX_SHRINK_ATTEMPTS     = 50
+)
+
+// Unmarshal action
+func (action *FuzzEntrypointAction) Unmarshal(ac Action) error {
+	action.json.Kind = ac.Kind
+	err := json.Unmarshal(ac.Payload, &action.json.Payload)
+	if err != nil {
+		return err
+	}
+
+	// Validate action
+	if err = action.validate(); err != nil {
+		return err
+	}
+
+	// "contract_name" field
+	action.ContractName = action.json.Payload.ContractName
+	// "sender" field
+	action.Sender = action.json.Payload.Sender
+
+	// "entrypoint" field
+	action.Entrypoint = action.json.Payload.Entrypoint
+	if action.Entrypoint == "" {
+		action.Entrypoint = michelson.DEFAULT_ENTRYPOINT
+	}
+
+	// "amount" field
+	if action.json.Payload.Amount == "" {
+		action.json.Payload.Amount = "0"
+	}
+	action.Amount, err = business.MutezOfString(action.json.Payload.Amount)
+	if err != nil {
+		return err
+	}
+
+	// "seed" field (A random seed is picked if none is provided, it gets reported for reproducibility)
+	if action.json.Payload.Seed == nil {
+		seed := time.Now().UnixNano()
+		action.json.Payload.Seed = &seed
+	}
+	action.Seed = *action.json.Payload.Seed
+
+	// "iterations" field
+	action.Iterations = DEFAULT_FUZZ_ITERATIONS
+	if action.json.Payload.Iterations != nil {
+		action.Iterations = *action.json.Payload.Iterations
+	}
+
+	// "invariants" field
+	action.Invariants, err = GetActions(action.json.Payload.Invariants)
+	if err != nil {
+		return fmt.Errorf("invalid invariants. %s", Error.Message(err))
+	}
+
+	return nil
+}
+
+// Marshal returns the JSON of the action (cached)
+func (action FuzzEntrypointAction) Action() interface{} {
+	return action.json
+}
+
+// Run performs action (Calls an entrypoint with random values and checks the invariants after each call)
+func (action FuzzEntrypointAction) Run(mockup business.Backend) (interface{}, bool) {
+	contract, ok := mockup.GetContract(action.ContractName)
+	if !ok {
+		return fmt.Errorf("contract (%s) is unknown.", action.ContractName), false
+	}
+	parameterType, ok := contract.EntrypointType(action.Entrypoint)
+	if !ok {
+		return fmt.Errorf("contract (%s) does not have entrypoint (%s).", action.ContractName, action.Entrypoint), false
+	}
+
+	addresses := make([]string, 0)
+	parameterTypes := map[string]ast.Node{}
+	for name, address := range mockup.GetAddresses() {
+		addresses = append(addresses, address)
+		if contract, ok := mockup.GetContract(name); ok {
+			parameterTypes[address], _ = contract.EntrypointType(michelson.DEFAULT_ENTRYPOINT)
+		}
+	}
+	g := generator.InitGenerator(action.Seed, addresses, parameterTypes)
+
+	// The state before the call is kept, shrink candidates are applied to the same state.
+	// Calls rejected by the contract leave the state untouched, so the state is only copied again after accepted calls
+	var before business.StateSnapshot
+	stale := true
+	defer func() {
+		discard(mockup, before)
+	}()
+
+	accepted, rejected := 0, 0
+	for i := 0; i < action.Iterations; i++ {
+		value, err := g.Generate(parameterType)
+		if err != nil {
+			return fmt.Errorf("could not generate values for entrypoint (%s). %s", action.Entrypoint, err), false
+		}
+
+		if stale {
+			discard(mockup, before)
+			if before, err = mockup.Snapshot(); err != nil {
+				logger.Debug("[%s] the state before the call cannot be copied. %s", FuzzEntrypoint, err)
+				before = nil
+			}
+			stale = false
+		}
+		wasRejected, err := action.call(mockup, value)
+		if err != nil {
+			return map[string]interface{}{
+				"seed":      action.Seed,
+				"iteration": i,
+				"input":     printJSON(value),
+				"details":   err.Error(),
+			}, false
+		}
+		if wasRejected {
+			rejected += 1
+			continue
+		}
+		accepted += 1
+		stale = true
+
+		if results, broken := action.checkInvariants(mockup); broken {
+			// The failing input is reported as is when the backend cannot copy its state (e.g. the node backend)
+			minimalInput, minimalResults, shrunk := value, results, false
+			if before != nil {
+				minimalInput, minimalResults, shrunk = action.shrink(mockup, before, value, parameterType, results)
+			} else {
+				logger.Debug("[%s] the failing input cannot be shrunk.", FuzzEntrypoint)
+			}
+			return map[string]interface{}{
+				"seed":                  action.Seed,
+				"iteration":             i,
+				"input":                 printJSON(value),
+				"minimal_failing_input": printJSON(minimalInput),
+				"shrunk":                shrunk,
+				"invariants":            minimalResults,
+			}, false
+		}
+	}
+
+	result := map[string]interface{}{
+		"seed":       action.Seed,
+		"iterations": action.Iterations,
+		"accepted":   accepted,
+		"rejected":   rejected,
+	}
+	// The invariants were never checked if the contract rejected every call
+	if accepted == 0 {
+		result["details"] = "the contract rejected every call, the invariants were never checked."
+		return result, false
+	}
+	return result, true
+}
+
+// call calls the entrypoint with a given value,
+// the call is considered rejected if the contract fails with (FAILWITH)
+func (action FuzzEntrypointAction) call(mockup business.Backend, value ast.Node) (rejected bool, err error) {
+	err = mockup.Transfer(business.CallContractArgument{
+		Recipient:  action.ContractName,
+		Source:     action.Sender,
+		Entrypoint: action.Entrypoint,
+		Amount:     action.Amount,
+		Parameter:  micheline.Print(value, ""),
+	})
+	if err != nil {
+		if business.ClassifyError(err).Kind == business.ScriptRejected {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// checkInvariants applies the invariants and reports if any of them is broken
+func (action FuzzEntrypointAction) checkInvariants(mockup business.Backend) ([]ActionResult, bool) {
+	results := ApplyActions(mockup, action.Invariants)
+	for _, result := range results {
+		if result.Status == Failure {
+			return results, true
+		}
+	}
+	return results, false
+}
+
+// shrink searches for a simpler input that still breaks the invariants.
+// Every candidate is applied to the state that preceded the failing call, the state after the failing call is restored at the end.
+// It reports whether the candidates could be tried.
+func (action FuzzEntrypointAction) shrink(mockup business.Backend, before business.StateSnapshot, value ast.Node, typ ast.Node, results []ActionResult) (ast.Node, []ActionResult, bool) {
+	after, err := mockup.Snapshot()
+	if err != nil {
+		logger.Debug("[%s] the failing input cannot be shrunk. %s", FuzzEntrypoint, err)
+		return value, results, false
+	}
+	defer func() {
+		if err := mockup.Restore(after); err != nil {
+			logger.Debug("[%s] could not restore the state after shrinking. %s", FuzzEntrypoint, err)
+		}
+		discard(mockup, after)
+	}()
+
+	attempts := 0
+	for attempts < MAX_SHRINK_ATTEMPTS {
+		improved := false
+		for _, candidate := range generator.Shrink(value, typ) {
+			if attempts >= MAX_SHRINK_ATTEMPTS {
+				break
+			}
+			attempts += 1
+
+			if err := mockup.Restore(before); err != nil {
+				logger.Debug("[%s] could not restore the state before the failing call. %s", FuzzEntrypoint, err)
+				return value, results, false
+			}
+			rejected, err := action.call(mockup, candidate)
+			if err != nil || rejected {
+				continue
+			}
+			if candidateResults, broken := action.checkInvariants(mockup); broken {
+				value, results, improved = candidate, candidateResults, true
+				break
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+
+	return value, results, true
+}
+
+// discard deletes a copy of the state that will not be restored anymore
+func discard(mockup business.Backend, snapshot business.StateSnapshot) {
+	if snapshot == nil {
+		return
+	}
+	if err := mockup.Discard(snapshot); err != nil {
+		logger.Debug("[%s] could not discard a copy of the state. %s", FuzzEntrypoint, err)
+	}
+}
+
+// validate validates the action fields before interpreting them
+func (action FuzzEntrypointAction) validate() error {
+	missingFields := make([]string, 0)
+	if action.json.Payload.ContractName == "" {
+		missingFields = append(missingFields, "contract_name")
+	} else if err := utils.ValidateString(STRING_IDENTIFIER_REGEX, action.json.Payload.ContractName); err != nil {
+		return err
+	}
+	if action.json.Payload.Sender == "" {
+		missingFields = append(missingFields, "sender")
+	} else if err := utils.ValidateString(STRING_IDENTIFIER_REGEX, action.json.Payload.Sender); err != nil {
+		return err
+	}
+	if action.json.Payload.Entrypoint != "" {
+		if err := utils.ValidateString(ENTRYPOINT_REGEX, action.json.Payload.Entrypoint); err != nil {
+			return err
+		}
+	}
+	if iterations := action.json.Payload.Iterations; iterations != nil && (*iterations < 1 || *iterations > MAX_FUZZ_ITERATIONS) {
+		return fmt.Errorf("The number of iterations must be between 1 and %d.", MAX_FUZZ_ITERATIONS)
+	}
+
+	if len(missingFields) > 0 {
+		return fmt.Errorf("Action of kind (%s) misses the following fields [%s].", FuzzEntrypoint, strings.Join(missingFields, ", "))
+	}
+
+	return nil
+}
+
+// printJSON prints a Michelson value to JSON (used for reporting)
+func printJSON(node ast.Node) json.RawMessage {
+	michelsonJSON, err := MichelsonJSON.Print(node, "", "  ")
+	if err != nil {
+		logger.Debug("failed to print michelson value to JSON. %s", err)
+	}
+	return michelsonJSON
+}
@@ -0,0 +1,286 @@
+package action
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business"
+	"github.com/romarq/tezos-sc-tester/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshal_FuzzEntrypointAction(t *testing.T) {
+	t.Run("Test FuzzEntrypointAction Unmarshal (Valid)",
+		func(t *testing.T) {
+			rawAction := Action{
+				Kind: FuzzEntrypoint,
+				Payload: json.RawMessage(`
+					{
+						"contract_name":	"contract_1",
+						"sender":			"bob",
+						"seed":				10,
+						"invariants": [
+							{
+								"kind": "assert_account_balance",
+								"payload": {
+									"account_name":	"contract_1",
+									"balance":		"0"
+								}
+							}
+						]
+					}
+				`),
+			}
+			action := FuzzEntrypointAction{}
+			err := action.Unmarshal(rawAction)
+			assert.Nil(t, err, "Must not fail")
+			assert.Equal(t, "contract_1", action.ContractName, "Assert contract name")
+			assert.Equal(t, "bob", action.Sender, "Assert sender")
+			assert.Equal(t, "default", action.Entrypoint, "Assert entrypoint")
+			assert.Equal(t, "0", action.Amount.String(), "Assert amount")
+			assert.Equal(t, int64(10), action.Seed, "Assert seed")
+			assert.Equal(t, DEFAULT_FUZZ_ITERATIONS, action.Iterations, "Assert iterations")
+			assert.Len(t, action.Invariants, 1, "Assert invariants")
+		})
+	t.Run("Test FuzzEntrypointAction Unmarshal (Too many iterations)",
+		func(t *testing.T) {
+			action := FuzzEntrypointAction{}
+			err := action.Unmarshal(Action{
+				Kind: FuzzEntrypoint,
+				Payload: json.RawMessage(`
+					{
+						"contract_name":	"contract_1",
+						"sender":			"bob",
+						"iterations":		1000
+					}
+				`),
+			})
+			assert.NotNil(t, err, "Must fail (Too many iterations)")
+			assert.Equal(t, "The number of iterations must be between 1 and 100.", err.Error(), "Assert error message")
+		})
+	t.Run("Test FuzzEntrypointAction Unmarshal (No iterations)",
+		func(t *testing.T) {
+			action := FuzzEntrypointAction{}
+			err := action.Unmarshal(Action{
+				Kind: FuzzEntrypoint,
+				Payload: json.RawMessage(`
+					{
+						"contract_name":	"contract_1",
+						"sender":			"bob",
+						"iterations":		0
+					}
+				`),
+			})
+			assert.NotNil(t, err, "Must fail (No iterations)")
+			assert.Equal(t, "The number of iterations must be between 1 and 100.", err.Error(), "Assert error message")
+		})
+	t.Run("Test FuzzEntrypointAction Unmarshal (Missing fields)",
+		func(t *testing.T) {
+			action := FuzzEntrypointAction{}
+			err := action.Unmarshal(Action{
+				Kind:    FuzzEntrypoint,
+				Payload: json.RawMessage(`{}`),
+			})
+			assert.NotNil(t, err, "Must fail (Missing fields)")
+			assert.Equal(t, "Action of kind (fuzz_entrypoint) misses the following fields [contract_name, sender].", err.Error(), "Assert error message")
+		})
+}
+
+func TestRun_FuzzEntrypointAction(t *testing.T) {
+	t.Run("Test FuzzEntrypointAction Run (Candidates are shrunk from the state before the failing call)",
+		func(t *testing.T) {
+			mockup := business.InitOfflineMockup("task", "", config.Config{
+				Tezos: config.TezosConfig{
+					BaseDirectory: "../../../tezos-bin",
+					Originator:    "bootstrap1",
+				},
+			})
+			assert.Nil(t, mockup.Bootstrap(), "Must not fail")
+
+			// The contract accumulates its parameters and pays 1 mutez to the sender once the total reaches 2000000000
+			actions, err := GetActions([]Action{
+				{
+					Kind: OriginateContract,
+					Payload: json.RawMessage(`{
+						"name": "accumulator",
+						"balance": "10",
+						"code": "{ parameter nat ; storage nat ; code { UNPAIR ; ADD ; DUP ; PUSH nat 2000000000 ; COMPARE ; LE ; IF { NIL operation ; SENDER ; CONTRACT unit ; IF_NONE { PUSH string \"NOT_IMPLICIT\" ; FAILWITH } {} ; PUSH mutez 1 ; UNIT ; TRANSFER_TOKENS ; CONS } { NIL operation } ; PAIR } }",
+						"storage": "0",
+						"format": "michelson"
+					}`),
+				},
+				{
+					Kind: FuzzEntrypoint,
+					Payload: json.RawMessage(`{
+						"contract_name": "accumulator",
+						"sender": "bootstrap1",
+						"seed": 1,
+						"iterations": 100,
+						"invariants": [
+							{ "kind": "assert_account_balance", "payload": { "account_name": "accumulator", "balance": "10" } }
+						]
+					}`),
+				},
+			})
+			assert.Nil(t, err, "Must not fail")
+
+			results := ApplyActions(mockup, actions)
+			assert.Equal(t, Success, results[0].Status, "Validate origination (%+v)", results[0])
+			assert.Equal(t, Failure, results[1].Status, "The invariant must break")
+			result := results[1].Result.(map[string]interface{})
+
+			assert.Greater(t, result["iteration"], 0, "The invariant must break because of the previous calls")
+			// Applied to the state after the failing call, any input (including 0) would break the invariant
+			var minimalInput map[string]string
+			assert.Nil(t, json.Unmarshal(result["minimal_failing_input"].(json.RawMessage), &minimalInput), "Must not fail")
+			assert.NotEqual(t, "0", minimalInput["int"], "Validate minimal failing input (%+v)", result)
+			assert.Equal(t, true, result["shrunk"], "The failing input must be shrunk")
+			// The state after the failing call is kept
+			balance, err := mockup.GetBalance("accumulator")
+			assert.Nil(t, err, "Must not fail")
+			assert.Equal(t, "9", balance.String(), "Validate contract balance")
+		})
+	t.Run("Test FuzzEntrypointAction Run (Contracts are picked by parameter type)",
+		func(t *testing.T) {
+			mockup := business.InitOfflineMockup("task", "", config.Config{
+				Tezos: config.TezosConfig{
+					BaseDirectory: "../../../tezos-bin",
+					Originator:    "bootstrap1",
+				},
+			})
+			assert.Nil(t, mockup.Bootstrap(), "Must not fail")
+
+			// Only the receiver accepts the parameters sent by the forwarder
+			actions, err := GetActions([]Action{
+				{
+					Kind: OriginateContract,
+					Payload: json.RawMessage(`{
+						"name": "receiver",
+						"balance": "0",
+						"code": "{ parameter nat ; storage nat ; code { CAR ; NIL operation ; PAIR } }",
+						"storage": "0",
+						"format": "michelson"
+					}`),
+				},
+				{
+					Kind: OriginateContract,
+					Payload: json.RawMessage(`{
+						"name": "forwarder",
+						"balance": "0",
+						"code": "{ parameter (contract nat) ; storage unit ; code { UNPAIR ; PUSH mutez 0 ; PUSH nat 1 ; TRANSFER_TOKENS ; NIL operation ; SWAP ; CONS ; PAIR } }",
+						"storage": "Unit",
+						"format": "michelson"
+					}`),
+				},
+				{
+					Kind: FuzzEntrypoint,
+					Payload: json.RawMessage(`{
+						"contract_name": "forwarder",
+						"sender": "bootstrap1",
+						"seed": 1,
+						"iterations": 10
+					}`),
+				},
+			})
+			assert.Nil(t, err, "Must not fail")
+
+			results := ApplyActions(mockup, actions)
+			assert.Equal(t, Success, results[2].Status, "Validate fuzzing (%+v)", results[2])
+			assert.Equal(t, 10, results[2].Result.(map[string]interface{})["accepted"], "Every call must be accepted")
+		})
+	t.Run("Test FuzzEntrypointAction Run (Failing inputs are not shrunk when the state cannot be copied)",
+		func(t *testing.T) {
+			offline := business.InitOfflineMockup("task", "", config.Config{
+				Tezos: config.TezosConfig{
+					BaseDirectory: "../../../tezos-bin",
+					Originator:    "bootstrap1",
+				},
+			})
+			mockup := noSnapshotBackend{offline}
+			assert.Nil(t, mockup.Bootstrap(), "Must not fail")
+
+			actions, err := GetActions([]Action{
+				{
+					Kind: OriginateContract,
+					Payload: json.RawMessage(`{
+						"name": "store",
+						"balance": "0",
+						"code": "{ parameter nat ; storage nat ; code { CAR ; NIL operation ; PAIR } }",
+						"storage": "0",
+						"format": "michelson"
+					}`),
+				},
+				{
+					Kind: FuzzEntrypoint,
+					Payload: json.RawMessage(`{
+						"contract_name": "store",
+						"sender": "bootstrap1",
+						"seed": 1,
+						"invariants": [
+							{ "kind": "assert_contract_storage", "payload": { "contract_name": "store", "storage": "0", "format": "michelson" } }
+						]
+					}`),
+				},
+			})
+			assert.Nil(t, err, "Must not fail")
+
+			results := ApplyActions(mockup, actions)
+			assert.Equal(t, Failure, results[1].Status, "The invariant must break")
+			result := results[1].Result.(map[string]interface{})
+			assert.Equal(t, false, result["shrunk"], "The failing input cannot be shrunk")
+			assert.Equal(t, result["input"], result["minimal_failing_input"], "The failing input is reported as is")
+		})
+	t.Run("Test FuzzEntrypointAction Run (Contracts rejecting every call)",
+		func(t *testing.T) {
+			mockup := business.InitOfflineMockup("task", "", config.Config{
+				Tezos: config.TezosConfig{
+					BaseDirectory: "../../../tezos-bin",
+					Originator:    "bootstrap1",
+				},
+			})
+			assert.Nil(t, mockup.Bootstrap(), "Must not fail")
+
+			actions, err := GetActions([]Action{
+				{
+					Kind: OriginateContract,
+					Payload: json.RawMessage(`{
+						"name": "closed",
+						"balance": "0",
+						"code": "{ parameter nat ; storage unit ; code { PUSH string \"CLOSED\" ; FAILWITH } }",
+						"storage": "Unit",
+						"format": "michelson"
+					}`),
+				},
+				{
+					Kind: FuzzEntrypoint,
+					Payload: json.RawMessage(`{
+						"contract_name": "closed",
+						"sender": "bootstrap1",
+						"seed": 1,
+						"iterations": 5,
+						"invariants": [
+							{ "kind": "assert_account_balance", "payload": { "account_name": "closed", "balance": "0" } }
+						]
+					}`),
+				},
+			})
+			assert.Nil(t, err, "Must not fail")
+
+			results := ApplyActions(mockup, actions)
+			assert.Equal(t, Failure, results[1].Status, "The invariants were never checked")
+			result := results[1].Result.(map[string]interface{})
+			assert.Equal(t, 0, result["accepted"], "Validate accepted calls")
+			assert.Equal(t, 5, result["rejected"], "Validate rejected calls")
+			assert.Equal(t, "the contract rejected every call, the invariants were never checked.", result["details"], "Validate details")
+		})
+}
+
+// noSnapshotBackend is a backend that cannot copy its state (like the node backend)
+type noSnapshotBackend struct {
+	business.Backend
+}
+
+func (noSnapshotBackend) Snapshot() (business.StateSnapshot, error) {
+	return nil, errors.New("unsupported")
+}
@@ -0,0 +1,85 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/romarq/tezos-sc-tester/internal/business"
+	MichelsonJSON "github.com/romarq/tezos-sc-tester/internal/business/michelson/json"
+	"github.com/romarq/tezos-sc-tester/internal/logger"
+	"github.com/romarq/tezos-sc-tester/internal/utils"
+)
+
+type GetEntrypointsAction struct {
+	json struct {
+		Kind    ActionKind `json:"kind"`
+		Payload struct {
+			ContractName string `json:"contract_name"`
+		} `json:"payload"`
+	}
+	ContractName string
+}
+
+// Unmarshal action
+func (action *GetEntrypointsAction) Unmarshal(ac Action) error {
+	action.json.Kind = ac.Kind
+	err := json.Unmarshal(ac.Payload, &action.json.Payload)
+	if err != nil {
+		return err
+	}
+
+	// Validate action
+	if err = action.validate(); err != nil {
+		return err
+	}
+
+	// "contract_name" field
+	action.ContractName = action.json.Payload.ContractName
+
+	return nil
+}
+
+// Marshal returns the JSON of the action (cached)
+func (action GetEntrypointsAction) Action() interface{} {
+	return action.json
+}
+
+// Run performs action (Lists the entrypoints of a contract)
+func (action GetEntrypointsAction) Run(mockup business.Backend) (interface{}, bool) {
+	contract, ok := mockup.GetContract(action.ContractName)
+	if !ok {
+		return fmt.Errorf("contract (%s) is unknown.", action.ContractName), false
+	}
+
+	entrypoints := map[string]json.RawMessage{}
+	for name, entrypointType := range contract.Entrypoints() {
+		typeJSON, err := MichelsonJSON.Print(entrypointType, "", "  ")
+		if err != nil {
+			err = fmt.Errorf("failed to print the type of entrypoint (%s) to JSON. %s", name, err)
+			logger.Debug("[%s] %s", GetEntrypoints, err)
+			return err, false
+		}
+		entrypoints[name] = typeJSON
+	}
+
+	return map[string]interface{}{
+		"entrypoints": entrypoints,
+	}, true
+}
+
+// validate validates the action fields before interpreting them
+func (action GetEntrypointsAction) validate() error {
+	missingFields := make([]string, 0)
+	if action.json.Payload.ContractName == "" {
+		missingFields = append(missingFields, "contract_name")
+	} else if err := utils.ValidateString(STRING_IDENTIFIER_REGEX, action.json.Payload.ContractName); err != nil {
+		return err
+	}
+
+	if len(missingFields) > 0 {
+		return fmt.Errorf("Action of kind (%s) misses the following fields [%s].", GetEntrypoints, strings.Join(missingFields, ", "))
+	}
+
+	return nil
+}
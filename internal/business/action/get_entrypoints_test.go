@@ -0,0 +1,31 @@
+package action
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshal_GetEntrypointsAction(t *testing.T) {
+	t.Run("Test GetEntrypointsAction Unmarshal (Valid)",
+		func(t *testing.T) {
+			action := GetEntrypointsAction{}
+			err := action.Unmarshal(Action{
+				Kind:    GetEntrypoints,
+				Payload: json.RawMessage(`{ "contract_name": "contract_1" }`),
+			})
+			assert.Nil(t, err, "Must not fail")
+			assert.Equal(t, "contract_1", action.ContractName, "Assert contract name")
+		})
+	t.Run("Test GetEntrypointsAction Unmarshal (Missing fields)",
+		func(t *testing.T) {
+			action := GetEntrypointsAction{}
+			err := action.Unmarshal(Action{
+				Kind:    GetEntrypoints,
+				Payload: json.RawMessage(`{}`),
+			})
+			assert.NotNil(t, err, "Must fail (Missing fields)")
+			assert.Equal(t, "Action of kind (get_entrypoints) misses the following fields [contract_name].", err.Error(), "Assert error message")
+		})
+}
@@ -12,4 +12,9 @@ const (
 	ModifyBlockTimestamp  ActionKind = "modify_block_timestamp"
 	ModifyChainID         ActionKind = "modify_chain_id"
 	PackData              ActionKind = "pack_data"
+	ForEach               ActionKind = "for_each"
+	FuzzEntrypoint        ActionKind = "fuzz_entrypoint"
+	AssertBalanceChanges  ActionKind = "assert_balance_changes"
+	GetEntrypoints        ActionKind = "get_entrypoints"
+	LintContract          ActionKind = "lint_contract"
 )
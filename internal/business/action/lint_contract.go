@@ -0,0 +1,74 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/romarq/tezos-sc-tester/internal/business"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/lint"
+	"github.com/romarq/tezos-sc-tester/internal/logger"
+)
+
+type LintContractAction struct {
+	json struct {
+		Kind    ActionKind `json:"kind"`
+		Payload struct {
+			Code json.RawMessage `json:"code"`
+		} `json:"payload"`
+	}
+	Code ast.Node
+}
+
+// Unmarshal action
+func (action *LintContractAction) Unmarshal(ac Action) error {
+	action.json.Kind = ac.Kind
+	err := json.Unmarshal(ac.Payload, &action.json.Payload)
+	if err != nil {
+		return err
+	}
+
+	// Validate action
+	if err = action.validate(); err != nil {
+		return err
+	}
+
+	// "code" field
+	action.Code, err = parseMichelson(action.json.Payload.Code, "")
+	if err != nil {
+		logger.Debug("%+v", action.json.Payload.Code)
+		return fmt.Errorf("invalid code. %s", err)
+	}
+
+	return nil
+}
+
+// Marshal returns the JSON of the action (cached)
+func (action LintContractAction) Action() interface{} {
+	return action.json
+}
+
+// Run performs action (Reports static metrics and warnings about a contract, for the protocol of the mockup)
+func (action LintContractAction) Run(mockup business.Backend) (interface{}, bool) {
+	report, err := lint.Lint(action.Code, mockup.GetProtocol())
+	if err != nil {
+		return fmt.Sprintf("could not lint contract. %s", err), false
+	}
+
+	return report, true
+}
+
+// validate validates the action fields before interpreting them
+func (action LintContractAction) validate() error {
+	missingFields := make([]string, 0)
+	if action.json.Payload.Code == nil {
+		missingFields = append(missingFields, "code")
+	}
+
+	if len(missingFields) > 0 {
+		return fmt.Errorf("Action of kind (%s) misses the following fields [%s].", LintContract, strings.Join(missingFields, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package action
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/lint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintContractAction(t *testing.T) {
+	t.Run("Test LintContractAction Unmarshal (Valid)",
+		func(t *testing.T) {
+			action := LintContractAction{}
+			err := action.Unmarshal(Action{
+				Kind:    LintContract,
+				Payload: json.RawMessage(`{ "code": "parameter (or (nat %a) int) ; storage unit ; code { FAIL ; UNIT }" }`),
+			})
+			assert.Nil(t, err, "Must not fail")
+
+			result, ok := action.Run(newBackendMock())
+			assert.True(t, ok, "Must not fail")
+			report := result.(lint.Report)
+			assert.Len(t, report.Warnings, 2, "Assert warnings")
+			assert.Equal(t, lint.MissingEntrypointAnnotation, report.Warnings[0].Kind, "Assert warning kind")
+			assert.Equal(t, lint.UnreachableCode, report.Warnings[1].Kind, "Assert warning kind")
+		})
+	t.Run("Test LintContractAction Unmarshal (Missing fields)",
+		func(t *testing.T) {
+			action := LintContractAction{}
+			err := action.Unmarshal(Action{
+				Kind:    LintContract,
+				Payload: json.RawMessage(`{}`),
+			})
+			assert.NotNil(t, err, "Must fail (Missing fields)")
+			assert.Equal(t, "Action of kind (lint_contract) misses the following fields [code].", err.Error(), "Assert error message")
+		})
+	t.Run("Test LintContractAction Run (Invalid contract)",
+		func(t *testing.T) {
+			action := LintContractAction{}
+			err := action.Unmarshal(Action{
+				Kind:    LintContract,
+				Payload: json.RawMessage(`{ "code": "{ parameter unit }" }`),
+			})
+			assert.Nil(t, err, "Must not fail")
+
+			result, ok := action.Run(newBackendMock())
+			assert.False(t, ok, "Must fail")
+			assert.Regexp(t, "^could not lint contract. invalid contract.", result, "Assert error message")
+		})
+}
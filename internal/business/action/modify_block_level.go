@@ -45,7 +45,7 @@ func (action ModifyBlockLevelAction) Action() interface{} {
 }
 
 // Perform the action
-func (action ModifyBlockLevelAction) Run(mockup business.Mockup) (interface{}, bool) {
+func (action ModifyBlockLevelAction) Run(mockup business.Backend) (interface{}, bool) {
 	// Update the level of the head block
 	// The transfer operation will create a new block
 	err := mockup.UpdateHeadBlockLevel(action.Level - 1)
@@ -49,7 +49,7 @@ func (action ModifyBlockTimestampAction) Action() interface{} {
 }
 
 // Perform the action
-func (action ModifyBlockTimestampAction) Run(mockup business.Mockup) (interface{}, bool) {
+func (action ModifyBlockTimestampAction) Run(mockup business.Backend) (interface{}, bool) {
 	// Update the timestamp of the head block
 	// Subtract one second because the next block will increment
 	// the timestamp by one second
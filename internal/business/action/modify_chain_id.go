@@ -44,7 +44,7 @@ func (action ModifyChainIdAction) Action() interface{} {
 }
 
 // Perform the action
-func (action ModifyChainIdAction) Run(mockup business.Mockup) (interface{}, bool) {
+func (action ModifyChainIdAction) Run(mockup business.Backend) (interface{}, bool) {
 	err := mockup.UpdateChainID(action.ChainID)
 	if err != nil {
 		return err, false
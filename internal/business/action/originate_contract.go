@@ -8,6 +8,7 @@ import (
 	"github.com/romarq/tezos-sc-tester/internal/business"
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/binary"
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
 	"github.com/romarq/tezos-sc-tester/internal/logger"
 	"github.com/romarq/tezos-sc-tester/internal/utils"
@@ -21,12 +22,14 @@ type OriginateContractAction struct {
 			Balance string          `json:"balance"`
 			Code    json.RawMessage `json:"code"`
 			Storage json.RawMessage `json:"storage"`
+			Format  string          `json:"format,omitempty"`
 		} `json:"payload"`
 	}
 	Name    string
 	Balance business.Mutez
 	Code    ast.Node
 	Storage ast.Node
+	Format  business.MichelsonFormat
 }
 
 // Unmarshal action
@@ -51,18 +54,33 @@ func (action *OriginateContractAction) Unmarshal(ac Action) error {
 		return err
 	}
 
+	// "format" field
+	action.Format, err = parseFormat(action.json.Payload.Format)
+	if err != nil {
+		return err
+	}
+
 	// "code" field
-	action.Code, err = michelson.ParseJSON(action.json.Payload.Code)
+	action.Code, err = parseMichelson(action.json.Payload.Code, action.Format)
 	if err != nil {
 		logger.Debug("%+v", action.json.Payload.Code)
-		return fmt.Errorf("invalid code.")
+		return fmt.Errorf("invalid code. %s", err)
 	}
 
 	// "storage" field
-	action.Storage, err = michelson.ParseJSON(action.json.Payload.Storage)
+	var storageType ast.Node
+	if action.Format == business.Annotated {
+		// Annotated values are driven by the storage type
+		contract, err := michelson.ParseContract(action.Code)
+		if err != nil {
+			return fmt.Errorf("invalid contract. %s", err)
+		}
+		storageType = contract.Storage
+	}
+	action.Storage, err = parseValue(action.json.Payload.Storage, action.Format, storageType)
 	if err != nil {
 		logger.Debug("%+v", action.json.Payload.Storage)
-		return fmt.Errorf("invalid storage.")
+		return fmt.Errorf("invalid storage. %s", err)
 	}
 
 	return nil
@@ -74,31 +92,48 @@ func (action OriginateContractAction) Action() interface{} {
 }
 
 // Run performs action (Originates a contract)
-func (action OriginateContractAction) Run(mockup business.Mockup) (interface{}, bool) {
+func (action OriginateContractAction) Run(mockup business.Backend) (interface{}, bool) {
 	if mockup.ContainsAddress(action.Name) {
 		return fmt.Sprintf("Name (%s) is already in use.", action.Name), false
 	}
 
-	codeMicheline := replaceBigMaps(micheline.Print(action.Code, ""))
-	codeMicheline = expandPlaceholders(mockup, codeMicheline)
-	storageMicheline := expandPlaceholders(mockup, micheline.Print(action.Storage, ""))
-	address, err := mockup.Originate(mockup.Config.Tezos.Originator, action.Name, action.Balance, codeMicheline, storageMicheline)
+	contract, err := michelson.ParseContract(action.Code)
 	if err != nil {
-		logger.Debug("[Task #%s] - %s", mockup.TaskID, err)
-		return fmt.Sprintf("could not originate contract. %s", err), false
+		return fmt.Sprintf("invalid contract. %s", err), false
 	}
 
-	// Cache contract info
-	mockup.CacheAccountAddress(action.Name, address)
-	err = mockup.CacheContract(action.Name, action.Code)
+	codeMicheline := micheline.Print(replaceBigMaps(action.Code), "")
+	codeMicheline, err = expandPlaceholders(mockup, codeMicheline)
+	if err != nil {
+		return fmt.Errorf("could not originate contract. %w", err), false
+	}
+	storageMicheline, err := expandPlaceholders(mockup, micheline.Print(action.Storage, ""))
 	if err != nil {
-		logger.Debug("[Task #%s] - %s", mockup.TaskID, err)
-		return err, false
+		return fmt.Errorf("could not originate contract. %w", err), false
 	}
+	address, err := mockup.Originate(mockup.GetConfig().Tezos.Originator, action.Name, action.Balance, codeMicheline, storageMicheline)
+	if err != nil {
+		logger.Debug("[Task #%s] - %s", mockup.GetTaskID(), err)
+		return fmt.Errorf("could not originate contract. %w", err), false
+	}
+
+	// Cache contract info
+	mockup.CacheAccountAddress(action.Name, address)
+	mockup.CacheContract(action.Name, contract)
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"address": address,
-	}, true
+	}
+	// The code hash is computed from the originated code (after placeholders got expanded)
+	if code, err := michelson.ParseMicheline(codeMicheline); err != nil {
+		logger.Debug("[Task #%s] - %s", mockup.GetTaskID(), err)
+	} else if codeHash, err := binary.ScriptHash(code); err != nil {
+		logger.Debug("[Task #%s] - could not hash contract code. %s", mockup.GetTaskID(), err)
+	} else {
+		result["code_hash"] = codeHash
+	}
+
+	return result, true
 }
 
 // validate validates the action fields before interpreting them
@@ -1,6 +1,7 @@
 package action
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -8,6 +9,7 @@ import (
 	"github.com/romarq/tezos-sc-tester/internal/business"
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/binary"
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
 	"github.com/romarq/tezos-sc-tester/internal/logger"
 )
@@ -16,12 +18,14 @@ type PackDataAction struct {
 	json struct {
 		Kind    ActionKind `json:"kind"`
 		Payload struct {
-			Data json.RawMessage `json:"data"`
-			Type json.RawMessage `json:"type"`
+			Data   json.RawMessage `json:"data"`
+			Type   json.RawMessage `json:"type"`
+			Format string          `json:"format,omitempty"`
 		} `json:"payload"`
 	}
-	Data ast.Node
-	Type ast.Node
+	Data   ast.Node
+	Type   ast.Node
+	Format business.MichelsonFormat
 }
 
 // Unmarshal action
@@ -37,18 +41,24 @@ func (action *PackDataAction) Unmarshal(ac Action) error {
 		return err
 	}
 
-	// "data" field
-	action.Data, err = michelson.ParseJSON(action.json.Payload.Data)
+	// "format" field
+	action.Format, err = parseFormat(action.json.Payload.Format)
 	if err != nil {
-		logger.Debug("%+v", action.json.Payload.Data)
-		return fmt.Errorf("invalid michelson value.")
+		return err
 	}
 
 	// "type" field
-	action.Type, err = michelson.ParseJSON(action.json.Payload.Type)
+	action.Type, err = parseMichelson(action.json.Payload.Type, action.Format)
 	if err != nil {
 		logger.Debug("%+v", action.json.Payload.Type)
-		return fmt.Errorf("invalid michelson type.")
+		return fmt.Errorf("invalid michelson type. %s", err)
+	}
+
+	// "data" field
+	action.Data, err = parseValue(action.json.Payload.Data, action.Format, action.Type)
+	if err != nil {
+		logger.Debug("%+v", action.json.Payload.Data)
+		return fmt.Errorf("invalid michelson value. %s", err)
 	}
 
 	return nil
@@ -60,18 +70,36 @@ func (action PackDataAction) Action() interface{} {
 }
 
 // Run performs action (Serializes a michelson value)
-func (action PackDataAction) Run(mockup business.Mockup) (interface{}, bool) {
-	dataMicheline := expandPlaceholders(mockup, micheline.Print(action.Data, ""))
-	typeMicheline := expandPlaceholders(mockup, micheline.Print(action.Type, ""))
+func (action PackDataAction) Run(mockup business.Backend) (interface{}, bool) {
+	dataMicheline, err := expandPlaceholders(mockup, micheline.Print(action.Data, ""))
+	if err != nil {
+		return fmt.Sprintf("could not serialize michelson data. %s", err), false
+	}
+	typeMicheline, err := expandPlaceholders(mockup, micheline.Print(action.Type, ""))
+	if err != nil {
+		return fmt.Sprintf("could not serialize michelson data. %s", err), false
+	}
+
+	// Placeholders are expanded in the micheline representation, the nodes need to be parsed again
+	data, err := michelson.ParseMicheline(dataMicheline)
+	if err != nil {
+		return fmt.Sprintf("could not serialize michelson data. %s", err), false
+	}
+	typ, err := michelson.ParseMicheline(typeMicheline)
+	if err != nil {
+		return fmt.Sprintf("could not serialize michelson data. %s", err), false
+	}
 
-	byteString, err := mockup.SerializeData(dataMicheline, typeMicheline)
+	b, err := binary.Pack(data, typ)
 	if err != nil {
-		logger.Debug("[Task #%s] - %s", mockup.TaskID, err)
+		logger.Debug("[Task #%s] - %s", mockup.GetTaskID(), err)
 		return fmt.Sprintf("could not serialize michelson data. %s", err), false
 	}
 
 	return map[string]string{
-		"bytes": byteString,
+		"bytes": "0x" + hex.EncodeToString(b),
+		// Script expression hash, used to index big maps
+		"hash": binary.ScriptExprHashOfBytes(b),
 	}, true
 }
 
@@ -0,0 +1,90 @@
+package action
+
+import (
+	"net/http"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	Error "github.com/romarq/tezos-sc-tester/internal/error"
+)
+
+// CheckPrimitives verifies that the michelson of the actions only uses primitives supported by the protocol of the suite.
+// Nested actions (for_each, assert_balance_changes and fuzz_entrypoint) are reported as the action that contains them.
+// The contracts originated by the actions are tracked, so that the values written in annotated format can also be checked.
+func CheckPrimitives(rawActions []Action, actions []IAction, protocol string) error {
+	contracts := map[string]michelson.Contract{}
+	for i, action := range actions {
+		nodes, err := michelsonOf(action, contracts)
+		if err != nil {
+			return Error.DetailedHttpError(http.StatusBadRequest, err.Error(), rawActions[i])
+		}
+		for _, node := range nodes {
+			if err := michelson.CheckPrimitives(node, protocol); err != nil {
+				return Error.DetailedHttpError(http.StatusBadRequest, err.Error(), rawActions[i])
+			}
+		}
+	}
+
+	return nil
+}
+
+// michelsonOf collects the michelson nodes of an action and of its nested actions.
+// Annotated values are parsed as soon as the contract they refer to is known (they are skipped otherwise)
+func michelsonOf(action IAction, contracts map[string]michelson.Contract) ([]ast.Node, error) {
+	switch action := action.(type) {
+	case *OriginateContractAction:
+		if contract, err := michelson.ParseContract(action.Code); err == nil {
+			contracts[action.Name] = contract
+		}
+		return []ast.Node{action.Code, action.Storage}, nil
+	case *CallContractAction:
+		parameter := action.Parameter
+		if contract, ok := contracts[action.Recipient]; ok && parameter == nil {
+			var err error
+			if parameter, err = action.parameterOf(contract); err != nil {
+				return nil, err
+			}
+		}
+		return []ast.Node{parameter, action.ExpectFailwith}, nil
+	case *AssertContractStorageAction:
+		storage := action.Storage
+		if contract, ok := contracts[action.ContractName]; ok && storage == nil {
+			var err error
+			if storage, err = action.storageOf(contract); err != nil {
+				return nil, err
+			}
+		}
+		return []ast.Node{storage}, nil
+	case *PackDataAction:
+		return []ast.Node{action.Data, action.Type}, nil
+	case *LintContractAction:
+		return []ast.Node{action.Code}, nil
+	case *ForEachAction:
+		nodes := make([]ast.Node, 0)
+		for _, iteration := range action.Iterations {
+			iterationNodes, err := michelsonOfActions(iteration.Actions, contracts)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, iterationNodes...)
+		}
+		return nodes, nil
+	case *AssertBalanceChangesAction:
+		return michelsonOfActions(action.Actions, contracts)
+	case *FuzzEntrypointAction:
+		return michelsonOfActions(action.Invariants, contracts)
+	}
+	return nil, nil
+}
+
+func michelsonOfActions(actions []IAction, contracts map[string]michelson.Contract) ([]ast.Node, error) {
+	nodes := make([]ast.Node, 0)
+	for _, nested := range actions {
+		nestedNodes, err := michelsonOf(nested, contracts)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, nestedNodes...)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,118 @@
+package action
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/romarq/tezos-sc-tester/internal/business"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	Error "github.com/romarq/tezos-sc-tester/internal/error"
+)
+
+// typecheckActions validates the michelson values of the actions against their types before any action runs.
+// The contracts originated by the actions are tracked, so that calls and storage assertions can also be validated.
+func typecheckActions(rawActions []Action, actions []IAction) error {
+	contracts := map[string]michelson.Contract{}
+	typechecker := michelson.Typechecker{
+		IgnoreValue: isPlaceholder,
+	}
+
+	for i, action := range actions {
+		if err := typecheckAction(action, contracts, typechecker); err != nil {
+			return Error.DetailedHttpError(http.StatusBadRequest, err.Error(), rawActions[i])
+		}
+	}
+
+	return nil
+}
+
+// typecheckAction validates the michelson values of an action and of its nested actions.
+// Annotated values are parsed to be validated, the action itself is left unchanged (values are parsed again when the action runs).
+func typecheckAction(action IAction, contracts map[string]michelson.Contract, typechecker michelson.Typechecker) error {
+	switch action := action.(type) {
+	case *OriginateContractAction:
+		contract, err := michelson.ParseContract(action.Code)
+		if err != nil {
+			// The code gets rejected when the contract is originated
+			return nil
+		}
+		contracts[action.Name] = contract
+		if err = typechecker.Check(action.Storage, contract.Storage); err != nil {
+			return fmt.Errorf("ill-typed storage. %s", err)
+		}
+	case *CallContractAction:
+		contract, ok := contracts[action.Recipient]
+		if !ok {
+			return nil
+		}
+		entrypointType, err := getEntrypointType(action.Recipient, contract, action.Entrypoint)
+		if err != nil {
+			return err
+		}
+		parameter := action.Parameter
+		if parameter == nil {
+			// Annotated parameters are parsed as soon as the contract is known
+			if parameter, err = action.parameterOf(contract); err != nil {
+				return err
+			}
+		}
+		if err = typechecker.Check(parameter, entrypointType); err != nil {
+			return fmt.Errorf("ill-typed parameter for entrypoint (%s). %s", action.Entrypoint, err)
+		}
+	case *AssertContractStorageAction:
+		contract, ok := contracts[action.ContractName]
+		if !ok {
+			return nil
+		}
+		storage := action.Storage
+		if storage == nil {
+			// Annotated storages are parsed as soon as the contract is known
+			var err error
+			if storage, err = action.storageOf(contract); err != nil {
+				return err
+			}
+		}
+		if err := typechecker.Check(storage, contract.Storage); err != nil {
+			return fmt.Errorf("ill-typed storage. %s", err)
+		}
+	case *PackDataAction:
+		if err := typechecker.Check(action.Data, action.Type); err != nil {
+			return fmt.Errorf("ill-typed data. %s", err)
+		}
+	case *ForEachAction:
+		for i, iteration := range action.Iterations {
+			for _, nested := range iteration.Actions {
+				if err := typecheckAction(nested, contracts, typechecker); err != nil {
+					return fmt.Errorf("invalid actions in iteration (%d). %s", i, err)
+				}
+			}
+		}
+	case *AssertBalanceChangesAction:
+		for _, nested := range action.Actions {
+			if err := typecheckAction(nested, contracts, typechecker); err != nil {
+				return fmt.Errorf("invalid actions. %s", err)
+			}
+		}
+	case *FuzzEntrypointAction:
+		for _, nested := range action.Invariants {
+			if err := typecheckAction(nested, contracts, typechecker); err != nil {
+				return fmt.Errorf("invalid invariants. %s", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isPlaceholder checks if a value is a placeholder (Placeholders are only expanded when the actions run)
+func isPlaceholder(value ast.Node) bool {
+	switch node := value.(type) {
+	case ast.String:
+		return strings.HasPrefix(node.Value, business.PLACEHOLDER_PREFIX)
+	case ast.Int:
+		return strings.HasPrefix(node.Value, business.PLACEHOLDER_PREFIX)
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package business
+
+import (
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/config"
+)
+
+type (
+	// Backend executes the operations of a test suite (Mockup runs 'tezos-client', OfflineMockup runs the offline interpreter, Node calls the RPC of a node)
+	Backend interface {
+		// Environment
+		Bootstrap() error
+		Teardown() error
+		GetTaskID() string
+		GetConfig() config.Config
+		GetProtocol() string
+		// Accounts and contracts known by the test suite
+		GetAddresses() map[string]string
+		ContainsAddress(name string) bool
+		CacheAccountAddress(name string, address string)
+		CacheContract(name string, contract michelson.Contract)
+		GetContract(name string) (michelson.Contract, bool)
+		// Context modifications
+		UpdateChainID(chainID string) error
+		UpdateHeadBlockLevel(level int32) error
+		UpdateHeadBlockTimestamp(timestamp string) error
+		// Operations
+		ImportSecret(privateKey string, walletName string) error
+		RevealWallet(walletName string, revealFee Mutez) error
+		Transfer(arg CallContractArgument) error
+		Originate(sender string, contractName string, amount Mutez, code string, storage string) (string, error)
+		// Queries
+		GetBalance(name string) (Mutez, error)
+		GetFeesPaid(name string) Mutez
+		GetContractStorage(contractName string) (ast.Node, error)
+		NormalizeData(data string, dataType string, mode ParsingMode) (ast.Node, error)
+		SerializeData(dataNode string, typeNode string) (string, error)
+		// State of the chain (used to replay operations from the same state)
+		Snapshot() (StateSnapshot, error)
+		Restore(snapshot StateSnapshot) error
+		Discard(snapshot StateSnapshot) error
+	}
+	// StateSnapshot is an opaque copy of the state of a backend
+	StateSnapshot interface{}
+	// BackendKind selects the backend of a test suite
+	BackendKind string
+	// Session keeps the accounts and contracts known by a test suite, backends embed it
+	Session struct {
+		TaskID    string
+		Protocol  string
+		Config    config.Config
+		Addresses map[string]string
+		contracts map[string]michelson.Contract
+	}
+)
+
+const (
+	// Operations are executed by 'tezos-client' in mockup mode
+	MockupBackend BackendKind = "mockup"
+	// Operations are executed in process by the offline interpreter (a subset of Michelson is supported)
+	InterpreterBackend BackendKind = "interpreter"
+	// Operations are injected in the node configured in "tezos.node" (e.g. a sandboxed node)
+	NodeBackend BackendKind = "node"
+)
+
+var (
+	_ Backend = (*Mockup)(nil)
+	_ Backend = (*OfflineMockup)(nil)
+	_ Backend = (*Node)(nil)
+)
+
+func InitSession(taskID string, protocol string, cfg config.Config) Session {
+	return Session{
+		TaskID:    taskID,
+		Protocol:  protocol,
+		Config:    cfg,
+		Addresses: map[string]string{},
+		contracts: map[string]michelson.Contract{},
+	}
+}
+
+// GetTaskID gives the identifier of the test suite
+func (s Session) GetTaskID() string {
+	return s.TaskID
+}
+
+// GetConfig gives the configuration of the application
+func (s Session) GetConfig() config.Config {
+	return s.Config
+}
+
+// GetProtocol gives the protocol being used in the test suite (the default protocol if none was requested)
+func (s Session) GetProtocol() string {
+	if s.Protocol == "" {
+		return s.Config.Tezos.DefaultProtocol
+	}
+	return s.Protocol
+}
+
+// GetAddresses gives the addresses of the known accounts (indexed by name)
+func (s Session) GetAddresses() map[string]string {
+	return s.Addresses
+}
+
+// ContainsAddress checks if address exists
+func (s Session) ContainsAddress(name string) bool {
+	return s.Addresses[name] != ""
+}
+
+// CacheAddress caches the address of a contract by name
+func (s Session) CacheAccountAddress(name string, address string) {
+	s.Addresses[name] = address
+}
+
+// CacheContract caches the sections of an originated contract
+func (s Session) CacheContract(name string, contract michelson.Contract) {
+	s.contracts[name] = contract
+}
+
+// GetContract gets an originated contract from cache
+func (s Session) GetContract(name string) (michelson.Contract, bool) {
+	contract, ok := s.contracts[name]
+	return contract, ok
+}
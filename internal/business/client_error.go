@@ -0,0 +1,169 @@
+package business
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/interpreter"
+	MichelsonJSON "github.com/romarq/tezos-sc-tester/internal/business/michelson/json"
+	"github.com/romarq/tezos-sc-tester/internal/utils"
+)
+
+// ClientErrorKind classifies the failures reported by 'tezos-client'
+type ClientErrorKind string
+
+const (
+	ScriptRejected    ClientErrorKind = "script_rejected"
+	BalanceTooLow     ClientErrorKind = "balance_too_low"
+	GasExhausted      ClientErrorKind = "gas_exhausted"
+	StorageExhausted  ClientErrorKind = "storage_exhausted"
+	BurnCapExceeded   ClientErrorKind = "burn_cap_exceeded"
+	IllTypedParameter ClientErrorKind = "ill_typed_parameter"
+	IllTypedData      ClientErrorKind = "ill_typed_data"
+	IllTypedContract  ClientErrorKind = "ill_typed_contract"
+	UnknownContract   ClientErrorKind = "unknown_contract"
+	CounterInThePast  ClientErrorKind = "counter_in_the_past"
+	ProtocolMismatch  ClientErrorKind = "protocol_mismatch"
+	UnclassifiedError ClientErrorKind = "unclassified"
+)
+
+// ClientError is a failure reported by 'tezos-client', the fields that do not apply to its kind are left empty
+type ClientError struct {
+	Kind    ClientErrorKind
+	Message string // Output of 'tezos-client'
+	// Contract involved in the failure (the rejecting contract, the unknown contract or the contract without enough funds)
+	Contract string
+	// Value emitted with (FAILWITH)
+	With ast.Node
+	// Balance of the contract and amount it tried to spend (balance_too_low), Amount is also the amount to burn (burn_cap_exceeded)
+	Balance *Mutez
+	Amount  *Mutez
+	// Expected and found values (counters for counter_in_the_past, protocols for protocol_mismatch)
+	Expected string
+	Found    string
+}
+
+// clientErrorPattern recognizes a kind of failure, the named groups fill the fields of the error
+type clientErrorPattern struct {
+	kind    ClientErrorKind
+	pattern *regexp.Regexp
+}
+
+// Patterns are tried in order, a rejected script is reported as such even if other errors are listed
+var clientErrorPatterns = []clientErrorPattern{
+	{ScriptRejected, regexp.MustCompile(`(?s)(?:.*Runtime error in contract (?P<contract>\w+).*?)?script reached FAILWITH instruction`)},
+	{BalanceTooLow, regexp.MustCompile(`Balance of contract (?P<contract>\w+) too low \(ꜩ?(?P<balance>[\d.]+)\) to spend ꜩ?(?P<amount>[\d.]+)`)},
+	{GasExhausted, regexp.MustCompile(`(?i)gas limit exceeded|gas_exhausted|not enough gas`)},
+	{StorageExhausted, regexp.MustCompile(`(?i)storage limit exceeded|storage_exhausted`)},
+	{BurnCapExceeded, regexp.MustCompile(`will burn ꜩ?(?P<amount>\d+(?:\.\d+)?) which is higher than the configured burn cap`)},
+	{IllTypedParameter, regexp.MustCompile(`Invalid argument passed to contract (?P<contract>\w+)`)},
+	{IllTypedContract, regexp.MustCompile(`(?i)ill typed contract`)},
+	{IllTypedData, regexp.MustCompile(`(?i)ill typed data`)},
+	{UnknownContract, regexp.MustCompile(`(?i)(?:contract (?P<contract>\w+) does not exist|no contract or key named (?P<contract>\w+)|unknown contract)`)},
+	{CounterInThePast, regexp.MustCompile(`Counter (?P<found>\d+) already used for contract (?P<contract>\w+) \(expected (?P<expected>\d+)\)|(?i)counter_in_the_past`)},
+	{ProtocolMismatch, regexp.MustCompile(`(?i)protocol (?:with hash )?(?P<found>\w+) (?:not found|is not available)|protocol mismatch|unknown protocol`)},
+}
+
+// ParseClientError classifies the error output of 'tezos-client'
+func ParseClientError(output string) *ClientError {
+	e := &ClientError{
+		Kind:    UnclassifiedError,
+		Message: output,
+	}
+
+	for _, p := range clientErrorPatterns {
+		match := p.pattern.FindStringSubmatch(output)
+		if match == nil {
+			continue
+		}
+		e.Kind = p.kind
+		for i, name := range p.pattern.SubexpNames() {
+			if match[i] == "" {
+				continue
+			}
+			switch name {
+			case "contract":
+				e.Contract = match[i]
+			case "expected":
+				e.Expected = match[i]
+			case "found":
+				e.Found = match[i]
+			case "balance", "amount":
+				tez, err := TezOfString(match[i])
+				if err != nil {
+					continue
+				}
+				mutez := tez.ToMutez()
+				if name == "balance" {
+					e.Balance = &mutez
+				} else {
+					e.Amount = &mutez
+				}
+			}
+		}
+		break
+	}
+
+	if e.Kind == ScriptRejected {
+		if with, err := utils.ExtractFailWithError(output); err == nil {
+			e.With = with
+		}
+	}
+
+	return e
+}
+
+// ClassifyError gives the classification of an error,
+// errors that were not produced by 'tezos-client' or by the interpreter are classified from their message
+func ClassifyError(err error) *ClientError {
+	var clientErr *ClientError
+	if errors.As(err, &clientErr) {
+		return clientErr
+	}
+	var failwith interpreter.FailwithError
+	if errors.As(err, &failwith) {
+		return &ClientError{
+			Kind:    ScriptRejected,
+			Message: err.Error(),
+			With:    failwith.Value,
+		}
+	}
+	return ParseClientError(err.Error())
+}
+
+// Error gives the output of 'tezos-client'
+func (e *ClientError) Error() string {
+	return e.Message
+}
+
+// MarshalJSON writes the kind of the error along with the fields that apply to it
+func (e *ClientError) MarshalJSON() ([]byte, error) {
+	fields := map[string]interface{}{
+		"kind": e.Kind,
+	}
+	if e.Contract != "" {
+		fields["contract"] = e.Contract
+	}
+	if e.With != nil {
+		with, err := MichelsonJSON.Print(e.With, "", "")
+		if err != nil {
+			return nil, err
+		}
+		fields["with"] = with
+	}
+	if e.Balance != nil {
+		fields["balance"] = e.Balance.Int().String()
+	}
+	if e.Amount != nil {
+		fields["amount"] = e.Amount.Int().String()
+	}
+	if e.Expected != "" {
+		fields["expected"] = e.Expected
+	}
+	if e.Found != "" {
+		fields["found"] = e.Found
+	}
+	return json.Marshal(fields)
+}
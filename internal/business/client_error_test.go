@@ -0,0 +1,145 @@
+package business
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/interpreter"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseClientError(t *testing.T) {
+	t.Run("Rejected scripts keep the rejecting contract and the emitted value", func(t *testing.T) {
+		output := `This simulation failed:
+  Manager signed operations:
+    From: tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx
+    Fee to the baker: ꜩ0
+    Transaction:
+      Amount: ꜩ0
+      From: tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx
+      To: KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1
+      Entrypoint: add
+      Parameter: 11
+      This operation FAILED.
+
+Runtime error in contract KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1:
+  1: { parameter nat ;
+  2:   storage nat ;
+  3:   code { CAR ; PUSH string "TOO_LARGE" ; FAILWITH } }
+At line 3 characters 40 to 48,
+script reached FAILWITH instruction
+with (Pair "TOO_LARGE" 11)
+Fatal error:
+  transfer simulation failed
+`
+		e := ParseClientError(output)
+		assert.Equal(t, ScriptRejected, e.Kind)
+		assert.Equal(t, "KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1", e.Contract)
+		assert.Equal(t, `(Pair "TOO_LARGE" 11)`, micheline.Print(e.With, ""))
+		assert.Equal(t, output, e.Error())
+	})
+	t.Run("Rejections of nested calls report the contract that reached FAILWITH", func(t *testing.T) {
+		output := `This simulation failed:
+  Manager signed operations:
+    From: tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx
+    Fee to the baker: ꜩ0
+    Transaction:
+      Amount: ꜩ0
+      From: tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx
+      To: KT1RJ6PbjHpwc3M5rw5s2Nbmefwbuwbdxton
+      Entrypoint: forward
+      Parameter: 11
+      This operation FAILED.
+
+Runtime error in contract KT1RJ6PbjHpwc3M5rw5s2Nbmefwbuwbdxton:
+  1: { parameter nat ;
+  2:   storage address ;
+  3:   code { UNPAIR ; DIP { DUP ; CONTRACT nat ; ASSERT_SOME ; PUSH mutez 0 } ; TRANSFER_TOKENS ; NIL operation ; SWAP ; CONS ; PAIR } }
+At line 3 characters 86 to 101,
+Runtime error in contract KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1:
+  1: { parameter nat ;
+  2:   storage nat ;
+  3:   code { CAR ; PUSH string "TOO_LARGE" ; FAILWITH } }
+At line 3 characters 40 to 48,
+script reached FAILWITH instruction
+with (Pair "TOO_LARGE" 11)
+Fatal error:
+  transfer simulation failed
+`
+		e := ParseClientError(output)
+		assert.Equal(t, ScriptRejected, e.Kind)
+		assert.Equal(t, "KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1", e.Contract)
+		assert.Equal(t, `(Pair "TOO_LARGE" 11)`, micheline.Print(e.With, ""))
+	})
+	t.Run("Balance too low", func(t *testing.T) {
+		e := ParseClientError("Error:\n  Balance of contract tz1faswCTDciRzE4oJ9jn2Vm2dvjeyA9fUzU too low (0.5) to spend 1.25\n")
+		assert.Equal(t, BalanceTooLow, e.Kind)
+		assert.Equal(t, "tz1faswCTDciRzE4oJ9jn2Vm2dvjeyA9fUzU", e.Contract)
+		assert.Equal(t, "500000", e.Balance.Int().String())
+		assert.Equal(t, "1250000", e.Amount.Int().String())
+	})
+	t.Run("Burn cap exceeded", func(t *testing.T) {
+		e := ParseClientError("Error:\n  The operation will burn ꜩ1.5 which is higher than the configured burn cap (ꜩ1).\n   Use `--burn-cap 1.5` to emit this operation.\n")
+		assert.Equal(t, BurnCapExceeded, e.Kind)
+		assert.Equal(t, "1500000", e.Amount.Int().String())
+		assert.Nil(t, e.Balance)
+	})
+	t.Run("Counter in the past", func(t *testing.T) {
+		e := ParseClientError("Error:\n  Counter 5 already used for contract tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx (expected 7)\n")
+		assert.Equal(t, CounterInThePast, e.Kind)
+		assert.Equal(t, "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", e.Contract)
+		assert.Equal(t, "7", e.Expected)
+		assert.Equal(t, "5", e.Found)
+	})
+	t.Run("Unknown contracts", func(t *testing.T) {
+		e := ParseClientError("Error:\n  The contract KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1 does not exist.\n")
+		assert.Equal(t, UnknownContract, e.Kind)
+		assert.Equal(t, "KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1", e.Contract)
+
+		e = ParseClientError("Error:\n  no contract or key named bob\n")
+		assert.Equal(t, UnknownContract, e.Kind)
+		assert.Equal(t, "bob", e.Contract)
+	})
+	t.Run("Protocol mismatch", func(t *testing.T) {
+		e := ParseClientError("Error:\n  Requested protocol with hash PtJakart2xVj7pYXJBXrqHgd82rdkLey5ZeeGwDgPp9rhQUbSqY not found in available mockup environments.\n")
+		assert.Equal(t, ProtocolMismatch, e.Kind)
+		assert.Equal(t, "PtJakart2xVj7pYXJBXrqHgd82rdkLey5ZeeGwDgPp9rhQUbSqY", e.Found)
+	})
+	t.Run("Kinds without fields", func(t *testing.T) {
+		for output, kind := range map[string]ClientErrorKind{
+			"Error:\n  Gas limit exceeded during typechecking or execution.\n  Try again with a higher gas limit.\n":                  GasExhausted,
+			"Error:\n  Storage limit exceeded during typechecking or execution.\n":                                                    StorageExhausted,
+			"Error:\n  Invalid argument passed to contract KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1.\n  At (unshown) location 0, value\n": IllTypedParameter,
+			"Error:\n  Ill typed contract:\n    1: { parameter nat ; storage nat ; code { CDR } }\n":                                  IllTypedContract,
+			"Error:\n  Ill typed data: 1: \"a\"\n  is not an expression of type nat\n":                                                IllTypedData,
+			"Error:\n  Unexpected server answer\n":                                                                                    UnclassifiedError,
+		} {
+			assert.Equal(t, kind, ParseClientError(output).Kind, output)
+		}
+	})
+}
+
+func TestClassifyError(t *testing.T) {
+	clientErr := ParseClientError("Error:\n  Gas limit exceeded during typechecking or execution.\n")
+	wrapped := fmt.Errorf("could not originate contract. %w", clientErr)
+	assert.Same(t, clientErr, ClassifyError(wrapped))
+
+	// Errors from other backends are classified from their message
+	e := ClassifyError(errors.New("call to contract (KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1) failed. script reached FAILWITH instruction\nwith 1\n"))
+	assert.Equal(t, ScriptRejected, e.Kind)
+	assert.Equal(t, "1", micheline.Print(e.With, ""))
+
+	// Rejections of the interpreter keep their value
+	failwith := interpreter.FailwithError{Value: ast.String{Value: "TOO_LARGE"}}
+	e = ClassifyError(fmt.Errorf("call to contract (KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1) failed. %w", failwith))
+	assert.Equal(t, ScriptRejected, e.Kind)
+	assert.Equal(t, `"TOO_LARGE"`, micheline.Print(e.With, ""))
+
+	b, err := json.Marshal(ParseClientError("Error:\n  Balance of contract tz1faswCTDciRzE4oJ9jn2Vm2dvjeyA9fUzU too low (0.5) to spend 1\n"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"kind":"balance_too_low","contract":"tz1faswCTDciRzE4oJ9jn2Vm2dvjeyA9fUzU","balance":"500000","amount":"1000000"}`, string(b))
+}
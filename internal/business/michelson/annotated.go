@@ -0,0 +1,517 @@
+package michelson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	MichelsonJSON "github.com/romarq/tezos-sc-tester/internal/business/michelson/json"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+)
+
+// Annotated JSON is a "friendly" representation of Michelson values, driven by their types:
+//
+// - pairs are objects keyed by the field annotations of their leaves (nested pairs without annotations
+// are flattened, leaves without annotations are keyed by their index);
+// - lists and sets are arrays;
+// - maps and big maps are objects (keys that are not strings, numbers, bytes or booleans are written in "micheline" format);
+// - ors are objects with a single key ({ "Left": ... } or { "Right": ... });
+// - options are null (None) or the value itself (Some), when the value is also an option it is wrapped
+// in an object ({ "Some": ... }) so that (None) and (Some None) are distinct;
+// - numbers are strings, booleans are booleans, unit is an empty object, bytes are hexadecimal strings (prefixed with 0x);
+// - lambdas are written in Michelson JSON.
+
+type (
+	// orderedObject is a JSON object that keeps the order of its keys
+	orderedObject []objectEntry
+	objectEntry   struct {
+		key   string
+		value interface{}
+	}
+)
+
+// AnnotatedJSONOf converts a value of a given type into annotated JSON
+func AnnotatedJSONOf(value ast.Node, typ ast.Node) (json.RawMessage, error) {
+	readable, err := Normalize(value, typ, Readable)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := toAnnotated(readable, typ)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(obj, "", "  ")
+}
+
+// ParseAnnotatedJSON converts annotated JSON into a value of a given type.
+//
+// The contents of numbers and domain specific strings are not validated, so that placeholders can be used.
+func ParseAnnotatedJSON(raw json.RawMessage, typ ast.Node) (ast.Node, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var obj interface{}
+	if err := decoder.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("could not deserialize JSON: %s.", err)
+	}
+	return fromAnnotated(obj, typ, "")
+}
+
+func toAnnotated(value ast.Node, typ ast.Node) (interface{}, error) {
+	t, ok := typ.(ast.Prim)
+	if !ok {
+		return nil, fmt.Errorf("invalid type: %s.", typ.String())
+	}
+
+	switch t.Prim {
+	case "unit":
+		return orderedObject{}, nil
+	case "bool":
+		return isPrim(value, "True", 0), nil
+	case "option":
+		if !isPrim(value, "Some", 1) {
+			return nil, nil
+		}
+		obj, err := toAnnotated(value.(ast.Prim).Arguments[0], t.Arguments[0])
+		if err != nil || !isOptionType(t.Arguments[0]) {
+			return obj, err
+		}
+		return orderedObject{{key: "Some", value: obj}}, nil
+	case "or":
+		prim := value.(ast.Prim)
+		branch := t.Arguments[0]
+		if prim.Prim == "Right" {
+			branch = t.Arguments[1]
+		}
+		obj, err := toAnnotated(prim.Arguments[0], branch)
+		if err != nil {
+			return nil, err
+		}
+		return orderedObject{{key: prim.Prim, value: obj}}, nil
+	case "pair":
+		if _, err := pairFieldsOf(t); err != nil {
+			return nil, err
+		}
+		obj := orderedObject{}
+		index := 0
+		if err := pairToAnnotated(value, t, &obj, &index); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case "list", "set":
+		seq := value.(ast.Sequence)
+		elements := make([]interface{}, len(seq.Elements))
+		for i, el := range seq.Elements {
+			var err error
+			if elements[i], err = toAnnotated(el, t.Arguments[0]); err != nil {
+				return nil, err
+			}
+		}
+		return elements, nil
+	case "map", "big_map":
+		seq, ok := value.(ast.Sequence)
+		if !ok {
+			// Big map identifier
+			return scalarToAnnotated(value), nil
+		}
+		obj := orderedObject{}
+		for _, el := range seq.Elements {
+			elt := el.(ast.Prim)
+			val, err := toAnnotated(elt.Arguments[1], t.Arguments[1])
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, objectEntry{key: annotatedKeyOf(elt.Arguments[0]), value: val})
+		}
+		return obj, nil
+	case "lambda":
+		return MichelsonJSON.Print(value, "", "")
+	case "never", "operation", "ticket", "sapling_state", "sapling_transaction", "sapling_transaction_deprecated":
+		return nil, fmt.Errorf("values of type (%s) cannot be written in annotated JSON.", t.Prim)
+	}
+
+	return scalarToAnnotated(value), nil
+}
+
+// pairToAnnotated collects the leaves of a pair into an object
+func pairToAnnotated(value ast.Node, t ast.Prim, obj *orderedObject, index *int) error {
+	elements := pairElements(value)
+	if len(elements) < 2 {
+		return fmt.Errorf("invalid pair: %s.", value.String())
+	}
+	children := []struct {
+		value ast.Node
+		typ   ast.Node
+	}{
+		{value: elements[0], typ: t.Arguments[0]},
+		{value: combOf("Pair", elements[1:]), typ: combOf("pair", t.Arguments[1:])},
+	}
+
+	for _, child := range children {
+		field := fieldOf(child.typ)
+		if field == "" && isPairType(child.typ) {
+			if err := pairToAnnotated(child.value, child.typ.(ast.Prim), obj, index); err != nil {
+				return err
+			}
+			continue
+		}
+
+		val, err := toAnnotated(child.value, child.typ)
+		if err != nil {
+			return err
+		}
+		if field == "" {
+			field = fmt.Sprint(*index)
+		}
+		*index++
+		*obj = append(*obj, objectEntry{key: field, value: val})
+	}
+
+	return nil
+}
+
+func fromAnnotated(obj interface{}, typ ast.Node, path string) (ast.Node, error) {
+	t, ok := typ.(ast.Prim)
+	if !ok {
+		return nil, fmt.Errorf("invalid type: %s.", typ.String())
+	}
+
+	switch t.Prim {
+	case "unit":
+		return ast.Prim{Prim: "Unit"}, nil
+	case "bool":
+		b, ok := obj.(bool)
+		if !ok {
+			return nil, annotatedError(obj, t, path)
+		}
+		if b {
+			return ast.Prim{Prim: "True"}, nil
+		}
+		return ast.Prim{Prim: "False"}, nil
+	case "option":
+		if obj == nil {
+			return ast.Prim{Prim: "None"}, nil
+		}
+		if isOptionType(t.Arguments[0]) {
+			// Options of options are wrapped ({ "Some": ... })
+			m, ok := obj.(map[string]interface{})
+			if !ok || len(m) != 1 || !hasKey(m, "Some") {
+				return nil, annotatedError(obj, t, path)
+			}
+			obj, path = m["Some"], path+"/Some"
+		}
+		value, err := fromAnnotated(obj, t.Arguments[0], path)
+		if err != nil {
+			return nil, err
+		}
+		return ast.Prim{Prim: "Some", Arguments: []ast.Node{value}}, nil
+	case "or":
+		m, ok := obj.(map[string]interface{})
+		if !ok || len(m) != 1 {
+			return nil, annotatedError(obj, t, path)
+		}
+		for key, val := range m {
+			var branch ast.Node
+			switch key {
+			case "Left":
+				branch = t.Arguments[0]
+			case "Right":
+				branch = t.Arguments[1]
+			default:
+				return nil, annotatedError(obj, t, path)
+			}
+			value, err := fromAnnotated(val, branch, path+"/"+key)
+			if err != nil {
+				return nil, err
+			}
+			return ast.Prim{Prim: key, Arguments: []ast.Node{value}}, nil
+		}
+	case "pair":
+		if _, err := pairFieldsOf(t); err != nil {
+			return nil, err
+		}
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			return nil, annotatedError(obj, t, path)
+		}
+		index := 0
+		return pairFromAnnotated(m, t, path, &index)
+	case "list", "set":
+		arr, ok := obj.([]interface{})
+		if !ok {
+			return nil, annotatedError(obj, t, path)
+		}
+		elements := make([]ast.Node, len(arr))
+		for i, el := range arr {
+			var err error
+			if elements[i], err = fromAnnotated(el, t.Arguments[0], fmt.Sprintf("%s/%d", path, i)); err != nil {
+				return nil, err
+			}
+		}
+		if t.Prim == "set" {
+			sortValues(elements, t.Arguments[0], func(node ast.Node) ast.Node { return node })
+		}
+		return ast.Sequence{Elements: elements}, nil
+	case "map", "big_map":
+		if t.Prim == "big_map" {
+			// Big map identifier
+			switch id := obj.(type) {
+			case json.Number:
+				return ast.Int{Value: id.String()}, nil
+			case string:
+				return ast.Int{Value: id}, nil
+			}
+		}
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			return nil, annotatedError(obj, t, path)
+		}
+		elements := make([]ast.Node, 0, len(m))
+		for key, val := range m {
+			k, err := annotatedKeyFrom(key, t.Arguments[0], path)
+			if err != nil {
+				return nil, err
+			}
+			v, err := fromAnnotated(val, t.Arguments[1], path+"/"+escapeKey(key))
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, ast.Prim{Prim: "Elt", Arguments: []ast.Node{k, v}})
+		}
+		// JSON objects are not ordered, Michelson requires map keys to be sorted
+		sortValues(elements, t.Arguments[0], func(node ast.Node) ast.Node { return node.(ast.Prim).Arguments[0] })
+		return ast.Sequence{Elements: elements}, nil
+	case "lambda":
+		raw, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		return ParseJSON(raw)
+	case "int", "nat", "mutez", "bls12_381_fr":
+		switch v := obj.(type) {
+		case json.Number:
+			return ast.Int{Value: v.String()}, nil
+		case string:
+			if t.Prim == "bls12_381_fr" && strings.HasPrefix(v, "0x") {
+				return ast.Bytes{Value: v[2:]}, nil
+			}
+			return ast.Int{Value: v}, nil
+		}
+	case "timestamp":
+		switch v := obj.(type) {
+		case json.Number:
+			return ast.Int{Value: v.String()}, nil
+		case string:
+			return ast.String{Value: v}, nil
+		}
+	case "bytes", "chest", "chest_key", "bls12_381_g1", "bls12_381_g2":
+		if v, ok := obj.(string); ok {
+			return ast.Bytes{Value: strings.TrimPrefix(v, "0x")}, nil
+		}
+	case "string", "address", "contract", "key", "key_hash", "signature", "chain_id", "tx_rollup_l2_address":
+		if v, ok := obj.(string); ok {
+			return ast.String{Value: v}, nil
+		}
+	default:
+		return nil, fmt.Errorf("values of type (%s) cannot be written in annotated JSON.", t.Prim)
+	}
+
+	return nil, annotatedError(obj, t, path)
+}
+
+// pairFromAnnotated builds a pair from the leaves of an object
+func pairFromAnnotated(obj map[string]interface{}, t ast.Prim, path string, index *int) (ast.Node, error) {
+	arguments := make([]ast.Node, 0, 2)
+	for _, typ := range []ast.Node{t.Arguments[0], combOf("pair", t.Arguments[1:])} {
+		field := fieldOf(typ)
+		if field == "" && isPairType(typ) {
+			value, err := pairFromAnnotated(obj, typ.(ast.Prim), path, index)
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, value)
+			continue
+		}
+
+		if field == "" {
+			field = fmt.Sprint(*index)
+		}
+		*index++
+		val, ok := obj[field]
+		if !ok {
+			return nil, fmt.Errorf("missing field (%s) (at %s).", field, pointerOrRoot(path))
+		}
+		value, err := fromAnnotated(val, typ, path+"/"+escapeKey(field))
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, value)
+	}
+
+	return ast.Prim{Prim: "Pair", Arguments: arguments}, nil
+}
+
+// scalarToAnnotated converts numbers, strings and bytes
+func scalarToAnnotated(value ast.Node) interface{} {
+	switch v := value.(type) {
+	case ast.Int:
+		return v.Value
+	case ast.String:
+		return v.Value
+	case ast.Bytes:
+		return "0x" + v.Value
+	}
+	return micheline.Print(value, "")
+}
+
+// annotatedKeyOf converts a map key into a JSON object key
+func annotatedKeyOf(key ast.Node) string {
+	switch k := key.(type) {
+	case ast.Int:
+		return k.Value
+	case ast.String:
+		return k.Value
+	case ast.Bytes:
+		return "0x" + k.Value
+	case ast.Prim:
+		if k.Prim == "True" || k.Prim == "False" {
+			return strings.ToLower(k.Prim)
+		}
+	}
+	return micheline.Print(key, "")
+}
+
+// annotatedKeyFrom converts a JSON object key into a map key
+func annotatedKeyFrom(key string, typ ast.Node, path string) (ast.Node, error) {
+	t := typ.(ast.Prim)
+	switch t.Prim {
+	case "int", "nat", "mutez":
+		return ast.Int{Value: key}, nil
+	case "bool":
+		switch key {
+		case "true":
+			return ast.Prim{Prim: "True"}, nil
+		case "false":
+			return ast.Prim{Prim: "False"}, nil
+		}
+		return nil, annotatedError(key, t, path)
+	case "bytes":
+		return ast.Bytes{Value: strings.TrimPrefix(key, "0x")}, nil
+	case "string", "address", "key", "key_hash", "signature", "chain_id", "timestamp":
+		return ast.String{Value: key}, nil
+	}
+
+	// Other keys are written in "micheline" format
+	node, err := ParseMicheline(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid map key (%s) (at %s). %s", key, pointerOrRoot(path), err)
+	}
+	return node, nil
+}
+
+// sortValues sorts nodes by the Michelson order of their keys (values that cannot be compared are kept in place)
+func sortValues(nodes []ast.Node, typ ast.Node, keyOf func(ast.Node) ast.Node) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		cmp, err := CompareValues(keyOf(nodes[i]), keyOf(nodes[j]), typ)
+		return err == nil && cmp < 0
+	})
+}
+
+func fieldOf(typ ast.Node) string {
+	if prim, ok := typ.(ast.Prim); ok {
+		for _, annotation := range prim.Annotations {
+			if annotation.Kind == ast.FieldAnnotation && len(annotation.Value) > 1 {
+				return annotation.Value[1:]
+			}
+		}
+	}
+	return ""
+}
+
+// pairFieldsOf gives the keys of the object representing a pair (see pairToAnnotated),
+// keys must be distinct since they identify the leaves of the pair
+func pairFieldsOf(t ast.Prim) ([]string, error) {
+	fields := make([]string, 0)
+	seen := map[string]bool{}
+	var collect func(pair ast.Prim) error
+	collect = func(pair ast.Prim) error {
+		for _, typ := range []ast.Node{pair.Arguments[0], combOf("pair", pair.Arguments[1:])} {
+			field := fieldOf(typ)
+			if field == "" && isPairType(typ) {
+				if err := collect(typ.(ast.Prim)); err != nil {
+					return err
+				}
+				continue
+			}
+			if field == "" {
+				field = fmt.Sprint(len(fields))
+			}
+			if seen[field] {
+				return fmt.Errorf("duplicate field (%s) in type %s.", field, micheline.Print(t, ""))
+			}
+			seen[field] = true
+			fields = append(fields, field)
+		}
+		return nil
+	}
+	return fields, collect(t)
+}
+
+func isOptionType(typ ast.Node) bool {
+	prim, ok := typ.(ast.Prim)
+	return ok && prim.Prim == "option"
+}
+
+func hasKey(m map[string]interface{}, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+func isPairType(typ ast.Node) bool {
+	prim, ok := typ.(ast.Prim)
+	return ok && prim.Prim == "pair" && len(prim.Arguments) >= 2
+}
+
+func annotatedError(obj interface{}, t ast.Prim, path string) error {
+	b, _ := json.Marshal(obj)
+	return fmt.Errorf("value %s is not of type %s (at %s).", b, micheline.Print(stripAnnotations(t), ""), pointerOrRoot(path))
+}
+
+func pointerOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// escapeKey escapes an object key to be used in a JSON pointer (RFC 6901)
+func escapeKey(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// MarshalJSON writes the entries in order
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("{")
+	for i, entry := range o {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		key, err := json.Marshal(entry.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(entry.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}")
+	return b.Bytes(), nil
+}
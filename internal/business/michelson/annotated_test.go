@@ -0,0 +1,176 @@
+package michelson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotatedJSON(t *testing.T) {
+	type test struct {
+		Value     string
+		Type      string
+		Annotated string
+	}
+
+	tests := []test{
+		{
+			Value:     `(Pair 1 "a" True)`,
+			Type:      `(pair (nat %amount) (string %name) (bool %active))`,
+			Annotated: `{"amount":"1","name":"a","active":true}`,
+		},
+		{
+			// Nested pairs without annotations are flattened
+			Value:     `(Pair (Pair 1 2) 3)`,
+			Type:      `(pair (pair (nat %a) (nat %b)) (nat %c))`,
+			Annotated: `{"a":"1","b":"2","c":"3"}`,
+		},
+		{
+			Value:     `(Pair (Pair 1 2) 3)`,
+			Type:      `(pair (pair %inner (nat %a) nat) nat)`,
+			Annotated: `{"inner":{"a":"1","1":"2"},"1":"3"}`,
+		},
+		{
+			Value:     `{ Elt "a" (Some 0x01) ; Elt "b" None }`,
+			Type:      `(map string (option bytes))`,
+			Annotated: `{"a":"0x01","b":null}`,
+		},
+		{
+			// Options of options are wrapped, so that (None) and (Some None) are distinct
+			Value:     `{ None ; Some None ; Some (Some 1) }`,
+			Type:      `(list (option (option nat)))`,
+			Annotated: `[null,{"Some":null},{"Some":"1"}]`,
+		},
+		{
+			Value:     `(Some (Some None))`,
+			Type:      `(option (option (option nat)))`,
+			Annotated: `{"Some":{"Some":null}}`,
+		},
+		{
+			Value:     `{ Elt (Pair 1 2) Unit }`,
+			Type:      `(big_map (pair nat nat) unit)`,
+			Annotated: `{"(Pair 1 2)":{}}`,
+		},
+		{
+			Value:     `10`,
+			Type:      `(big_map nat nat)`,
+			Annotated: `"10"`,
+		},
+		{
+			Value:     `{ Left 1 ; Right (Left "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx") }`,
+			Type:      `(list (or nat (or address unit)))`,
+			Annotated: `[{"Left":"1"},{"Right":{"Left":"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"}}]`,
+		},
+		{
+			Value:     `{ -1 ; 2 }`,
+			Type:      `(set int)`,
+			Annotated: `["-1","2"]`,
+		},
+		{
+			Value:     `{ Elt False 2 ; Elt True 1 }`,
+			Type:      `(map bool nat)`,
+			Annotated: `{"false":"2","true":"1"}`,
+		},
+		{
+			Value:     `{ DROP ; UNIT }`,
+			Type:      `(lambda nat unit)`,
+			Annotated: `[{"prim":"DROP"},{"prim":"UNIT"}]`,
+		},
+	}
+
+	for _, test := range tests {
+		value, err := ParseMicheline(test.Value)
+		assert.NoError(t, err)
+		typ, err := ParseMicheline(test.Type)
+		assert.NoError(t, err)
+
+		annotated, err := AnnotatedJSONOf(value, typ)
+		if assert.NoError(t, err, test.Value) {
+			assert.JSONEq(t, test.Annotated, string(annotated), test.Value)
+		}
+
+		parsed, err := ParseAnnotatedJSON(json.RawMessage(test.Annotated), typ)
+		if assert.NoError(t, err, test.Annotated) {
+			expected, err := Normalize(value, typ, Readable)
+			assert.NoError(t, err)
+			actual, err := Normalize(parsed, typ, Readable)
+			assert.NoError(t, err)
+			assert.Equal(t, micheline.Print(expected, ""), micheline.Print(actual, ""), test.Annotated)
+		}
+	}
+
+	t.Run("Keys are written in order", func(t *testing.T) {
+		typ, err := ParseMicheline(`(pair (nat %z) (nat %a))`)
+		assert.NoError(t, err)
+		value, err := ParseMicheline(`(Pair 1 2)`)
+		assert.NoError(t, err)
+
+		annotated, err := AnnotatedJSONOf(value, typ)
+		assert.NoError(t, err)
+		assert.Equal(t, "{\n  \"z\": \"1\",\n  \"a\": \"2\"\n}", string(annotated))
+	})
+
+	t.Run("Map keys are sorted", func(t *testing.T) {
+		typ, err := ParseMicheline(`(map nat string)`)
+		assert.NoError(t, err)
+
+		value, err := ParseAnnotatedJSON(json.RawMessage(`{"10": "b", "2": "a", "1": "c"}`), typ)
+		assert.NoError(t, err)
+		assert.Equal(t, `{ Elt 1 "c"; Elt 2 "a"; Elt 10 "b" }`, micheline.Print(value, ""))
+	})
+
+	t.Run("Numbers are accepted for integers", func(t *testing.T) {
+		typ, err := ParseMicheline(`(pair (int %a) (mutez %b))`)
+		assert.NoError(t, err)
+
+		value, err := ParseAnnotatedJSON(json.RawMessage(`{"a": -1, "b": 100}`), typ)
+		assert.NoError(t, err)
+		assert.Equal(t, `(Pair -1 100)`, micheline.Print(value, ""))
+	})
+
+	t.Run("Invalid values", func(t *testing.T) {
+		typ, err := ParseMicheline(`(pair (nat %a) (list %b (option bool)))`)
+		assert.NoError(t, err)
+
+		_, err = ParseAnnotatedJSON(json.RawMessage(`{"a": "1"}`), typ)
+		assert.EqualError(t, err, `missing field (b) (at /).`)
+
+		_, err = ParseAnnotatedJSON(json.RawMessage(`{"a": "1", "b": [true, "x"]}`), typ)
+		assert.EqualError(t, err, `value "x" is not of type (bool) (at /b/1).`)
+
+		typ, err = ParseMicheline(`(option (option nat))`)
+		assert.NoError(t, err)
+
+		_, err = ParseAnnotatedJSON(json.RawMessage(`"1"`), typ)
+		assert.EqualError(t, err, `value "1" is not of type (option (option (nat))) (at /).`)
+
+		_, err = ParseAnnotatedJSON(json.RawMessage(`{"a": `), typ)
+		assert.EqualError(t, err, `could not deserialize JSON: unexpected EOF.`)
+	})
+
+	t.Run("Duplicate fields", func(t *testing.T) {
+		typ, err := ParseMicheline(`(pair (nat %a) (nat %a))`)
+		assert.NoError(t, err)
+
+		value, err := ParseMicheline(`(Pair 1 2)`)
+		assert.NoError(t, err)
+
+		_, err = AnnotatedJSONOf(value, typ)
+		assert.EqualError(t, err, `duplicate field (a) in type (pair (nat %a) (nat %a)).`)
+
+		_, err = ParseAnnotatedJSON(json.RawMessage(`{"a": "1"}`), typ)
+		assert.EqualError(t, err, `duplicate field (a) in type (pair (nat %a) (nat %a)).`)
+	})
+
+	t.Run("Unsupported types", func(t *testing.T) {
+		typ, err := ParseMicheline(`(list operation)`)
+		assert.NoError(t, err)
+
+		_, err = ParseAnnotatedJSON(json.RawMessage(`[]`), typ)
+		assert.NoError(t, err)
+		_, err = ParseAnnotatedJSON(json.RawMessage(`[{}]`), typ)
+		assert.EqualError(t, err, `values of type (operation) cannot be written in annotated JSON.`)
+	})
+}
@@ -10,31 +10,51 @@ type (
 		String() string
 	}
 	Position struct {
-		Pos int
-		End int
+		Pos       int `json:"pos"`        // byte offset of the first character
+		End       int `json:"end"`        // byte offset of the last character
+		Line      int `json:"line"`       // line of the first character (starts at 1, 0 when unknown)
+		Column    int `json:"column"`     // column of the first character (starts at 1, 0 when unknown)
+		EndLine   int `json:"end_line"`   // line of the last character
+		EndColumn int `json:"end_column"` // column of the last character
 	}
 	Int struct {
 		Position
-		Value string
+		Comments *Trivia // comments attached to the node (nil when there are none)
+		Value    string
 	}
 	String struct {
 		Position
-		Value string
+		Comments *Trivia // comments attached to the node (nil when there are none)
+		Value    string
 	}
 	Bytes struct {
 		Position
-		Value string
+		Comments *Trivia // comments attached to the node (nil when there are none)
+		Value    string
 	}
 	Sequence struct {
 		Position
-		Elements []Node // list of elements in the sequence
+		Comments *Trivia // comments attached to the node (nil when there are none)
+		Elements []Node  // list of elements in the sequence
 	}
 	Prim struct {
 		Position
+		Comments    *Trivia // comments attached to the node (nil when there are none)
 		Prim        string
 		Annotations []Annotation
 		Arguments   []Node
 	}
+	// Comment is a line (# ...) or block (/* ... */) comment, the text includes the delimiters
+	Comment struct {
+		Position
+		Text string
+	}
+	// Trivia holds the comments surrounding a node, they are kept to format sources without losing them
+	Trivia struct {
+		Leading  []Comment // comments written before the node
+		Trailing []Comment // comments written after the node, on the line where it ends
+		Inner    []Comment // comments written before the closing brace of a sequence
+	}
 	AnnotationKind uint8
 	Annotation     struct {
 		Position
@@ -49,6 +69,62 @@ const (
 	FieldAnnotation
 )
 
+// PositionOf returns the position of a node in its source (empty for nodes that were not parsed)
+func PositionOf(node Node) Position {
+	switch n := node.(type) {
+	case Int:
+		return n.Position
+	case String:
+		return n.Position
+	case Bytes:
+		return n.Position
+	case Sequence:
+		return n.Position
+	case Prim:
+		return n.Position
+	}
+	return Position{}
+}
+
+// TriviaOf returns the comments attached to a node (nil when there are none)
+func TriviaOf(node Node) *Trivia {
+	switch n := node.(type) {
+	case Int:
+		return n.Comments
+	case String:
+		return n.Comments
+	case Bytes:
+		return n.Comments
+	case Sequence:
+		return n.Comments
+	case Prim:
+		return n.Comments
+	}
+	return nil
+}
+
+// WithTrivia returns a copy of a node with the given comments attached
+func WithTrivia(node Node, trivia *Trivia) Node {
+	switch n := node.(type) {
+	case Int:
+		n.Comments = trivia
+		return n
+	case String:
+		n.Comments = trivia
+		return n
+	case Bytes:
+		n.Comments = trivia
+		return n
+	case Sequence:
+		n.Comments = trivia
+		return n
+	case Prim:
+		n.Comments = trivia
+		return n
+	}
+	return node
+}
+
 func (n Bytes) String() string  { return fmt.Sprintf("Bytes(%s)", n.Value) }
 func (n String) String() string { return fmt.Sprintf("String(%s)", n.Value) }
 func (n Int) String() string    { return fmt.Sprintf("Int(%s)", n.Value) }
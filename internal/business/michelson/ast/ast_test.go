@@ -0,0 +1,116 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEqual(t *testing.T) {
+	node := Prim{
+		Position:    Position{Pos: 0, End: 20},
+		Prim:        "Pair",
+		Annotations: []Annotation{{Position: Position{Pos: 6, End: 7}, Kind: FieldAnnotation, Value: "%a"}},
+		Arguments: []Node{
+			Int{Position: Position{Pos: 9, End: 9}, Value: "1"},
+			Sequence{Position: Position{Pos: 11, End: 19}, Elements: []Node{String{Value: "big_map"}, Bytes{Value: "00"}}},
+		},
+	}
+	withoutPositions := Prim{
+		Prim:        "Pair",
+		Annotations: []Annotation{{Kind: FieldAnnotation, Value: "%a"}},
+		Arguments: []Node{
+			Int{Value: "1"},
+			Sequence{Elements: []Node{String{Value: "big_map"}, Bytes{Value: "00"}}},
+		},
+	}
+	withoutAnnotations := Prim{
+		Prim: "Pair",
+		Arguments: []Node{
+			Int{Value: "1"},
+			Sequence{Elements: []Node{String{Value: "big_map"}, Bytes{Value: "00"}}},
+		},
+	}
+
+	assert.True(t, Equal(node, node))
+	assert.False(t, Equal(node, withoutPositions))
+	assert.True(t, Equal(node, withoutPositions, IgnorePositions))
+	assert.False(t, Equal(node, withoutAnnotations, IgnorePositions))
+	assert.True(t, Equal(node, withoutAnnotations, IgnorePositions, IgnoreAnnotations))
+	assert.False(t, Equal(Int{Value: "1"}, String{Value: "1"}))
+	assert.False(t, Equal(Sequence{Elements: []Node{Int{Value: "1"}}}, Sequence{}))
+	assert.True(t, Equal(Prim{Prim: "Unit"}, Prim{Prim: "Unit", Arguments: []Node{}}))
+}
+
+func TestWalk(t *testing.T) {
+	node := Prim{
+		Prim: "pair",
+		Arguments: []Node{
+			Prim{Prim: "big_map", Arguments: []Node{Prim{Prim: "nat"}, Prim{Prim: "string"}}},
+			Prim{Prim: "list", Arguments: []Node{Prim{Prim: "nat"}}},
+		},
+	}
+
+	visited := make([]string, 0)
+	Walk(node, func(node Node) bool {
+		prim := node.(Prim)
+		visited = append(visited, prim.Prim)
+		// Skip the children of (big_map)
+		return prim.Prim != "big_map"
+	})
+	assert.Equal(t, []string{"pair", "big_map", "list", "nat"}, visited)
+}
+
+func TestRewrite(t *testing.T) {
+	node := Sequence{
+		Elements: []Node{
+			Prim{Prim: "big_map", Annotations: []Annotation{{Kind: FieldAnnotation, Value: "%big_map"}}, Arguments: []Node{Prim{Prim: "nat"}, Prim{Prim: "nat"}}},
+			String{Value: "big_map"},
+		},
+	}
+
+	rewritten := Rewrite(node, func(node Node) Node {
+		if prim, ok := node.(Prim); ok && prim.Prim == "big_map" {
+			prim.Prim = "map"
+			return prim
+		}
+		return node
+	})
+
+	assert.Equal(
+		t,
+		"Sequence([Prim(map, [%big_map], [Prim(nat, [], []), Prim(nat, [], [])]), String(big_map)])",
+		rewritten.String(),
+	)
+	// The original node is not modified
+	assert.Equal(t, "big_map", node.Elements[0].(Prim).Prim)
+}
+
+func TestSource(t *testing.T) {
+	source := NewSource("{ UNIT ;\n\tDROP }")
+
+	line, column := source.LineColumn(10)
+	assert.Equal(t, 2, line)
+	assert.Equal(t, 2, column)
+
+	assert.Equal(t, Position{Pos: 2, End: 5, Line: 1, Column: 3, EndLine: 1, EndColumn: 6}, source.Span(2, 5))
+
+	err := source.Error(10, "unknown primitive.")
+	err.Pointer = "/1"
+	assert.Equal(t, "2 | \tDROP }\n    \t^", err.Excerpt)
+	assert.EqualError(t, err, "unknown primitive. (at /1, line 2, column 2)\n2 | \tDROP }\n    \t^")
+}
+
+func TestPartialSource(t *testing.T) {
+	source := NewPartialSource()
+	source.AddLine(9)
+
+	line, column := source.LineColumn(10)
+	assert.Equal(t, 2, line)
+	assert.Equal(t, 2, column)
+
+	// The text is not kept, errors do not have excerpts
+	err := source.Error(10, "unknown primitive.")
+	assert.Equal(t, "", err.Excerpt)
+	assert.Equal(t, "2 | \tDROP }\n    \t^", ExcerptOf("\tDROP }", err.Position))
+}
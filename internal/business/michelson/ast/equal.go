@@ -0,0 +1,71 @@
+package ast
+
+// EqualOption customizes the comparison of nodes
+type EqualOption uint8
+
+const (
+	// IgnorePositions ignores the position of nodes and annotations
+	IgnorePositions EqualOption = 1 << iota
+	// IgnoreAnnotations ignores the annotations of primitives
+	IgnoreAnnotations
+)
+
+// Equal checks if two nodes are structurally equal
+func Equal(a Node, b Node, options ...EqualOption) bool {
+	var flags EqualOption
+	for _, option := range options {
+		flags |= option
+	}
+	return equal(a, b, flags)
+}
+
+func equal(a Node, b Node, flags EqualOption) bool {
+	ignorePositions := flags&IgnorePositions != 0
+
+	switch left := a.(type) {
+	case Int:
+		right, ok := b.(Int)
+		return ok && left.Value == right.Value && (ignorePositions || left.Position == right.Position)
+	case String:
+		right, ok := b.(String)
+		return ok && left.Value == right.Value && (ignorePositions || left.Position == right.Position)
+	case Bytes:
+		right, ok := b.(Bytes)
+		return ok && left.Value == right.Value && (ignorePositions || left.Position == right.Position)
+	case Sequence:
+		right, ok := b.(Sequence)
+		if !ok || len(left.Elements) != len(right.Elements) || (!ignorePositions && left.Position != right.Position) {
+			return false
+		}
+		for i := range left.Elements {
+			if !equal(left.Elements[i], right.Elements[i], flags) {
+				return false
+			}
+		}
+		return true
+	case Prim:
+		right, ok := b.(Prim)
+		if !ok || left.Prim != right.Prim || len(left.Arguments) != len(right.Arguments) || (!ignorePositions && left.Position != right.Position) {
+			return false
+		}
+		if flags&IgnoreAnnotations == 0 {
+			if len(left.Annotations) != len(right.Annotations) {
+				return false
+			}
+			for i := range left.Annotations {
+				l, r := left.Annotations[i], right.Annotations[i]
+				if l.Kind != r.Kind || l.Value != r.Value || (!ignorePositions && l.Position != r.Position) {
+					return false
+				}
+			}
+		}
+		for i := range left.Arguments {
+			if !equal(left.Arguments[i], right.Arguments[i], flags) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return a == nil && b == nil
+}
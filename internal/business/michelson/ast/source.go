@@ -0,0 +1,144 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type (
+	// Source indexes the lines of a source text to convert byte offsets into line/column positions
+	Source struct {
+		text    string
+		lines   []int // byte offset of the beginning of each line
+		partial bool  // the text is not kept, lines are indexed while the source is read (see AddLine)
+	}
+	// SyntaxError describes an error found while parsing a source text
+	SyntaxError struct {
+		Message  string   `json:"message"`
+		Position Position `json:"position"`
+		Pointer  string   `json:"pointer,omitempty"` // JSON pointer of the offending element (only for JSON sources)
+		Excerpt  string   `json:"excerpt,omitempty"` // source line of the error with a caret under the offending character
+	}
+)
+
+// NewSource indexes a source text
+func NewSource(text string) Source {
+	lines := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return Source{
+		text:  text,
+		lines: lines,
+	}
+}
+
+// NewPartialSource creates a source indexed while it is read, its text is not kept (excerpts cannot be rendered)
+func NewPartialSource() Source {
+	return Source{
+		lines:   []int{0},
+		partial: true,
+	}
+}
+
+// AddLine records the byte offset of the beginning of a line (only for partial sources)
+func (s *Source) AddLine(offset int) {
+	if s.partial {
+		s.lines = append(s.lines, offset)
+	}
+}
+
+// LineColumn converts a byte offset into a line/column pair (both start at 1)
+func (s Source) LineColumn(offset int) (line int, column int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(s.text) && !s.partial {
+		offset = len(s.text)
+	}
+	// Index of the first line starting after the offset
+	i := sort.Search(len(s.lines), func(i int) bool { return s.lines[i] > offset })
+	return i, offset - s.lines[i-1] + 1
+}
+
+// Span builds a position with lines and columns from byte offsets
+func (s Source) Span(pos int, end int) Position {
+	line, column := s.LineColumn(pos)
+	endLine, endColumn := s.LineColumn(end)
+	return Position{
+		Pos:       pos,
+		End:       end,
+		Line:      line,
+		Column:    column,
+		EndLine:   endLine,
+		EndColumn: endColumn,
+	}
+}
+
+// Excerpt renders the line of a position with a caret under its column
+func (s Source) Excerpt(position Position) string {
+	if position.Line < 1 || position.Line > len(s.lines) || s.partial {
+		return ""
+	}
+	begin := s.lines[position.Line-1]
+	end := len(s.text)
+	if position.Line < len(s.lines) {
+		end = s.lines[position.Line] - 1
+	}
+	return ExcerptOf(s.text[begin:end], position)
+}
+
+// ExcerptOf renders a line with a caret under the column of a position (the line must be the line of the position)
+func ExcerptOf(line string, position Position) string {
+	line = strings.TrimRight(line, "\r")
+
+	gutter := fmt.Sprintf("%d | ", position.Line)
+	// Tabs are kept under the caret, so that it stays aligned with the offending character
+	padding := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, line[:min(position.Column-1, len(line))])
+
+	return fmt.Sprintf("%s%s\n%s%s^", gutter, line, strings.Repeat(" ", len(gutter)), padding)
+}
+
+// Error builds a syntax error at a given byte offset
+func (s Source) Error(offset int, message string) SyntaxError {
+	position := s.Span(offset, offset)
+	return SyntaxError{
+		Message:  message,
+		Position: position,
+		Excerpt:  s.Excerpt(position),
+	}
+}
+
+func (e SyntaxError) Error() string {
+	location := make([]string, 0)
+	if e.Pointer != "" {
+		location = append(location, "at "+e.Pointer)
+	}
+	if e.Position.Line > 0 {
+		location = append(location, fmt.Sprintf("line %d, column %d", e.Position.Line, e.Position.Column))
+	}
+
+	message := e.Message
+	if len(location) > 0 {
+		message = fmt.Sprintf("%s (%s)", message, strings.Join(location, ", "))
+	}
+	if e.Excerpt != "" {
+		message = message + "\n" + e.Excerpt
+	}
+	return message
+}
+
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
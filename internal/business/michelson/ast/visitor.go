@@ -0,0 +1,47 @@
+package ast
+
+// Walk traverses a node in depth-first order, calling visit for each node.
+// The children of a node are only visited if visit returns true.
+func Walk(node Node, visit func(node Node) bool) {
+	if node == nil || !visit(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case Sequence:
+		for _, el := range n.Elements {
+			Walk(el, visit)
+		}
+	case Prim:
+		for _, arg := range n.Arguments {
+			Walk(arg, visit)
+		}
+	}
+}
+
+// Rewrite rebuilds a node bottom-up, the children of a node are rewritten before the node itself.
+// The original node is not modified.
+func Rewrite(node Node, rewrite func(node Node) Node) Node {
+	switch n := node.(type) {
+	case Sequence:
+		elements := make([]Node, len(n.Elements))
+		for i, el := range n.Elements {
+			elements[i] = Rewrite(el, rewrite)
+		}
+		n.Elements = elements
+		return rewrite(n)
+	case Prim:
+		if n.Arguments != nil {
+			arguments := make([]Node, len(n.Arguments))
+			for i, arg := range n.Arguments {
+				arguments[i] = Rewrite(arg, rewrite)
+			}
+			n.Arguments = arguments
+		}
+		return rewrite(n)
+	case nil:
+		return nil
+	}
+
+	return rewrite(node)
+}
@@ -0,0 +1,167 @@
+package base58
+
+import (
+	"fmt"
+	"strings"
+
+	"blockwatch.cc/tzgo/tezos"
+)
+
+const (
+	// Length of an optimized address (tag + hash), the entrypoint is appended after it
+	ADDRESS_LENGTH = 22
+	// Length of an optimized public key hash (tag + hash)
+	KEY_HASH_LENGTH = 21
+	// Length of an optimized signature
+	SIGNATURE_LENGTH = 64
+	// Length of an optimized chain identifier
+	CHAIN_ID_LENGTH = 4
+)
+
+// IsEncoded checks if values of a given type are written in base58 in their readable representation
+func IsEncoded(typ string) bool {
+	switch typ {
+	case "address", "contract", "key_hash", "key", "signature", "chain_id":
+		return true
+	}
+	return false
+}
+
+// Encode converts the optimized representation (bytes) of a value of a given type to its base58 representation
+func Encode(typ string, b []byte) (string, error) {
+	switch typ {
+	case "address", "contract":
+		return EncodeAddress(b)
+	case "key_hash":
+		return EncodeKeyHash(b)
+	case "key":
+		return EncodeKey(b)
+	case "signature":
+		return EncodeSignature(b)
+	case "chain_id":
+		return EncodeChainID(b)
+	}
+	return "", fmt.Errorf("values of type (%s) are not encoded in base58.", typ)
+}
+
+// Decode converts the base58 representation of a value of a given type to its optimized representation (bytes)
+func Decode(typ string, s string) ([]byte, error) {
+	switch typ {
+	case "address", "contract":
+		return DecodeAddress(s)
+	case "key_hash":
+		return DecodeKeyHash(s)
+	case "key":
+		return DecodeKey(s)
+	case "signature":
+		return DecodeSignature(s)
+	case "chain_id":
+		return DecodeChainID(s)
+	}
+	return nil, fmt.Errorf("values of type (%s) are not encoded in base58.", typ)
+}
+
+// EncodeAddress encodes an address (implicit or originated), followed by an optional entrypoint ("KT1...%entrypoint")
+func EncodeAddress(b []byte) (string, error) {
+	if len(b) < ADDRESS_LENGTH {
+		return "", fmt.Errorf("expected at least %d bytes.", ADDRESS_LENGTH)
+	}
+	a := tezos.Address{}
+	if err := a.UnmarshalBinary(b[:ADDRESS_LENGTH]); err != nil {
+		return "", err
+	}
+	address := a.String()
+	if entrypoint := string(b[ADDRESS_LENGTH:]); entrypoint != "" {
+		address += "%" + entrypoint
+	}
+	return address, nil
+}
+
+// DecodeAddress decodes an address (implicit or originated), followed by an optional entrypoint ("KT1...%entrypoint")
+func DecodeAddress(s string) ([]byte, error) {
+	address, entrypoint, _ := strings.Cut(s, "%")
+	a, err := tezos.ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if !a.IsValid() {
+		return nil, fmt.Errorf("unknown address type.")
+	}
+	return append(a.Bytes22(), entrypoint...), nil
+}
+
+// EncodeKeyHash encodes a public key hash (tz1, tz2, tz3, ...)
+func EncodeKeyHash(b []byte) (string, error) {
+	if len(b) != KEY_HASH_LENGTH {
+		return "", fmt.Errorf("expected %d bytes.", KEY_HASH_LENGTH)
+	}
+	a := tezos.Address{}
+	if err := a.UnmarshalBinary(b); err != nil {
+		return "", err
+	}
+	return a.String(), nil
+}
+
+// DecodeKeyHash decodes a public key hash (tz1, tz2, tz3, ...)
+func DecodeKeyHash(s string) ([]byte, error) {
+	a, err := tezos.ParseAddress(s)
+	if err != nil {
+		return nil, err
+	}
+	if !a.IsValid() || a.Type == tezos.AddressTypeContract {
+		return nil, fmt.Errorf("not a public key hash.")
+	}
+	return a.Bytes(), nil
+}
+
+// EncodeKey encodes a public key (edpk, sppk, p2pk, ...)
+func EncodeKey(b []byte) (string, error) {
+	k, err := tezos.DecodeKey(b)
+	if err != nil {
+		return "", err
+	}
+	return k.String(), nil
+}
+
+// DecodeKey decodes a public key (edpk, sppk, p2pk, ...)
+func DecodeKey(s string) ([]byte, error) {
+	k, err := tezos.ParseKey(s)
+	if err != nil {
+		return nil, err
+	}
+	return k.Bytes(), nil
+}
+
+// EncodeSignature encodes a signature, optimized signatures do not keep their curve so they are encoded as generic signatures (sig...)
+func EncodeSignature(b []byte) (string, error) {
+	if len(b) != SIGNATURE_LENGTH {
+		return "", fmt.Errorf("expected %d bytes.", SIGNATURE_LENGTH)
+	}
+	return tezos.NewSignature(tezos.SignatureTypeGeneric, b).Generic(), nil
+}
+
+// DecodeSignature decodes a signature (edsig, spsig1, p2sig, sig, ...)
+func DecodeSignature(s string) ([]byte, error) {
+	sig, err := tezos.ParseSignature(s)
+	if err != nil {
+		return nil, err
+	}
+	return sig.Data, nil
+}
+
+// EncodeChainID encodes a chain identifier (Net...)
+func EncodeChainID(b []byte) (string, error) {
+	if len(b) != CHAIN_ID_LENGTH {
+		return "", fmt.Errorf("expected %d bytes.", CHAIN_ID_LENGTH)
+	}
+	return tezos.NewChainIdHash(b).String(), nil
+}
+
+// DecodeChainID decodes a chain identifier (Net...)
+func DecodeChainID(s string) ([]byte, error) {
+	chainID, err := tezos.ParseChainIdHash(s)
+	if err != nil {
+		return nil, err
+	}
+	return chainID.Bytes(), nil
+}
@@ -0,0 +1,66 @@
+package base58
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecs(t *testing.T) {
+	type test struct {
+		Type   string
+		Base58 string
+		Hex    string
+	}
+
+	tests := []test{
+		{Type: "address", Base58: "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", Hex: "000002298c03ed7d454a101eb7022bc95f7e5f41ac78"},
+		{Type: "address", Base58: "KT1BEqzn5Wx8uJrZNvuS9DVHmLvG9td3fDLi", Hex: "011d23c1d3d2f8a4ea5e8784b8f7ecf2ad304c0fe600"},
+		{Type: "contract", Base58: "KT1BEqzn5Wx8uJrZNvuS9DVHmLvG9td3fDLi%transfer", Hex: "011d23c1d3d2f8a4ea5e8784b8f7ecf2ad304c0fe6007472616e73666572"},
+		{Type: "key_hash", Base58: "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", Hex: "0002298c03ed7d454a101eb7022bc95f7e5f41ac78"},
+		{Type: "chain_id", Base58: "NetXdQprcVkpaWU", Hex: "7a06a770"},
+	}
+
+	for _, test := range tests {
+		b, err := Decode(test.Type, test.Base58)
+		assert.NoError(t, err, test.Base58)
+		assert.Equal(t, test.Hex, hex.EncodeToString(b), test.Base58)
+
+		s, err := Encode(test.Type, b)
+		assert.NoError(t, err, test.Hex)
+		assert.Equal(t, test.Base58, s, test.Hex)
+	}
+
+	t.Run("Keys and signatures", func(t *testing.T) {
+		key := "edpkuBknW28nW72KG6RoHtYW7p12T6GKc7nAbwYX5m8Wd9sDVC9yav"
+		b, err := DecodeKey(key)
+		assert.NoError(t, err)
+		assert.Len(t, b, 33)
+		s, err := EncodeKey(b)
+		assert.NoError(t, err)
+		assert.Equal(t, key, s)
+
+		// Optimized signatures do not keep their curve
+		signature, err := EncodeSignature(make([]byte, SIGNATURE_LENGTH))
+		assert.NoError(t, err)
+		assert.Regexp(t, "^sig", signature)
+		b, err = DecodeSignature(signature)
+		assert.NoError(t, err)
+		assert.Equal(t, make([]byte, SIGNATURE_LENGTH), b)
+	})
+
+	t.Run("Invalid values", func(t *testing.T) {
+		_, err := DecodeKeyHash("KT1BEqzn5Wx8uJrZNvuS9DVHmLvG9td3fDLi")
+		assert.EqualError(t, err, "not a public key hash.")
+
+		_, err = EncodeAddress([]byte{0, 0})
+		assert.EqualError(t, err, "expected at least 22 bytes.")
+
+		_, err = EncodeChainID([]byte{0})
+		assert.EqualError(t, err, "expected 4 bytes.")
+
+		_, err = Decode("nat", "1")
+		assert.EqualError(t, err, "values of type (nat) are not encoded in base58.")
+	})
+}
@@ -0,0 +1,204 @@
+package binary
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(t *testing.T, s string) ast.Node {
+	parser := micheline.InitParser(s)
+	node := parser.Parse()
+	assert.NoError(t, parser.Error(), s)
+	return node
+}
+
+func TestEncode(t *testing.T) {
+	t.Run("Encode and decode nodes", func(t *testing.T) {
+		tests := []struct {
+			Micheline string
+			Bytes     string
+		}{
+			{Micheline: `Unit`, Bytes: "030b"},
+			{Micheline: `0`, Bytes: "0000"},
+			{Micheline: `1`, Bytes: "0001"},
+			{Micheline: `-1`, Bytes: "0041"},
+			{Micheline: `100`, Bytes: "00a401"},
+			{Micheline: `-100`, Bytes: "00e401"},
+			{Micheline: `1000000`, Bytes: "0080897a"},
+			{Micheline: `"hello"`, Bytes: "010000000568656c6c6f"},
+			{Micheline: `0xab`, Bytes: "0a00000001ab"},
+			{Micheline: `{ 1 ; 2 }`, Bytes: "020000000400010002"},
+			{Micheline: `(Some 1)`, Bytes: "05090001"},
+			{Micheline: `(Pair 1 2)`, Bytes: "070700010002"},
+			{Micheline: `(Pair 1 2 3)`, Bytes: "09070000000600010002000300000000"},
+			{Micheline: `{ DROP ; UNIT }`, Bytes: "0200000004032003" + "4f"},
+			{Micheline: `(pair %p (nat :n) unit)`, Bytes: "08650462000000023a6e036c000000022570"},
+		}
+		for _, test := range tests {
+			b, err := Encode(parse(t, test.Micheline))
+			assert.NoError(t, err, test.Micheline)
+			assert.Equal(t, test.Bytes, hex.EncodeToString(b), test.Micheline)
+
+			node, err := Decode(b)
+			assert.NoError(t, err, test.Micheline)
+			assert.Equal(t, micheline.Print(parse(t, test.Micheline), ""), micheline.Print(node, ""), test.Micheline)
+		}
+	})
+	t.Run("Invalid nodes", func(t *testing.T) {
+		_, err := Encode(ast.Prim{Prim: "NOT_A_PRIM"})
+		assert.EqualError(t, err, "unknown primitive (NOT_A_PRIM).")
+
+		_, err = Decode([]byte{0x01, 0x00, 0x00})
+		assert.EqualError(t, err, "unexpected end of bytes at offset (1).")
+
+		_, err = Decode([]byte{0x03, 0xff})
+		assert.EqualError(t, err, "unknown primitive code (0xff) at offset (1).")
+
+		_, err = Decode([]byte{0x0b})
+		assert.EqualError(t, err, "unexpected tag (0x0b) at offset (0).")
+
+		_, err = Decode([]byte{0x00, 0x01, 0x00})
+		assert.EqualError(t, err, "unexpected trailing bytes at offset (2).")
+	})
+}
+
+// recordedKeys are big map keys returned by mainnet and testnet nodes, along with their packed bytes and their hash
+var recordedKeys = []struct {
+	Value string
+	Type  string
+	Bytes string
+	Hash  string
+}{
+	{Value: `1`, Type: `nat`, Bytes: "050001", Hash: "expru2dKqDfZG8hu4wNGkiyunvq2hdSKuVYtcKta7BWP6Q18oNxKjS"},
+	{Value: `5`, Type: `nat`, Bytes: "050005", Hash: "exprtqoNj2hRg8PsPMaXLcy3dXjMM3B7nHKrRNqpfjbYpMbULbRj8k"},
+	{Value: `""`, Type: `string`, Bytes: "050100000000", Hash: "expru5X1yxJG6ezR2uHMotwMLNmSzQyh5t1vUnhjx4cS6Pv9qE1Sdo"},
+	{Value: `"contents"`, Type: `string`, Bytes: "050100000008636f6e74656e7473", Hash: "expruHaUexqggzjieEW3fqfSfa7jsc2hBj6YYjDcSKGdftGaTe96JK"},
+	{Value: `0x6275726e`, Type: `bytes`, Bytes: "050a000000046275726e", Hash: "exprvERy5jnK6VW2D4pebxrYU5Zn26HGK1T6Ht8fY9mqzX8svXQyCV"},
+	{Value: `"tz1VDxykfBK5TZ1GKNtJ82PYoXJQso7W2Yjz"`, Type: `address`, Bytes: "050a00000016000069242cd5609305367d4c3b1eaeeef27d0bdd501a", Hash: "exprtgwFRdyXNw6XCgX3xh4RDALn7bQdNfd5P6tGd7CqsbykuQxGVE"},
+	{Value: `"tz1fJHFn6sWEd3NnBPngACuw2dggTv6nQZ7g"`, Type: `key_hash`, Bytes: "050a0000001500d7a65a64734fda77378e05925fd621aaa92dc7cb", Hash: "exprtyDajtX61LBnSYHX3R4VaXxUJ8mqzXpExBNpvTjPiUDSdtKqav"},
+	{Value: `"edpkuZ7ERiU5B8knLqQsVMH86j9RLMUyHyL665oCXDkPQxF7HGqSeJ"`, Type: `key`, Bytes: "050a000000210078149c2d111816aaef9e329970c344fc32375dd5eff99eeeed3b37a9d51beacd", Hash: "exprv1Vjr2jWEzSALFrHaoubi3jELpXvnMtGNG4ZJPDMRHxrQtyBDW"},
+	{Value: `(Pair "tz1WqzwqqGBtJucJFUxzaYMSUyPVK3vMDRUW" 1)`, Type: `(pair address nat)`, Bytes: "0507070a0000001600007aeceae4d35ac8c91dc036db4ca11d7c138bbb570001", Hash: "expruH6udUv4hSCy8GhBAnasiAQokKqqpfbKHN3rTF4M6aJF952FqR"},
+	{Value: `(Pair "tz1e8UHsPa8BCwtDxJ6mkba8dBToij36Bz7x" "standard")`, Type: `(pair address string)`, Bytes: "0507070a000000160000cad347b67981ca596566addb8a0a6b0de85f780b01000000087374616e64617264", Hash: "exprtscVvGue7Arm94takZnYay7QayUBmWqJ1Q5TEGyx69g28rvi1k"},
+	{Value: `(Pair "tz1bhFWjzYLw9uDnHLaQAYLo4ydfuCkVZaBr" "KT195Mo1aBBLvzMVGQhiSWRDngp5PoQANavy" 105)`, Type: `(pair address (pair address nat))`, Bytes: "0507070a000000160000b01df6826da714e86b2068307e1046f98ab1caac07070a00000016010568382620bf0ddaef43a8579b0258936a8d09c90000a901", Hash: "exprvJySERNmqH83bcK8BBwe6Xe94bWVdLPdeUQk2u3ZtBWpEKE9Gi"},
+}
+
+func TestPack(t *testing.T) {
+	t.Run("Big map keys recorded from nodes", func(t *testing.T) {
+		for _, test := range recordedKeys {
+			typ := parse(t, test.Type)
+			b, err := Pack(parse(t, test.Value), typ)
+			assert.NoError(t, err, test.Value)
+			assert.Equal(t, test.Bytes, hex.EncodeToString(b), test.Value)
+
+			unpacked, err := Unpack(b, typ)
+			assert.NoError(t, err, test.Value)
+			assert.Equal(t, micheline.Print(parse(t, test.Value), ""), micheline.Print(unpacked, ""), test.Value)
+		}
+	})
+	t.Run("Packed values can be unpacked", func(t *testing.T) {
+		tests := []struct {
+			Value    string
+			Type     string
+			Readable string
+		}{
+			{Value: `Unit`, Type: `unit`},
+			{Value: `(Pair 1 2 3)`, Type: `(pair nat nat nat)`},
+			{Value: `{ 1 ; 2 ; 3 }`, Type: `(pair nat nat nat)`, Readable: `(Pair 1 2 3)`},
+			{Value: `(Pair 1 (Pair 2 3))`, Type: `(pair nat (pair nat nat))`, Readable: `(Pair 1 2 3)`},
+			{Value: `(Left (Some "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"))`, Type: `(or (option address) unit)`},
+			{Value: `"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx%transfer"`, Type: `address`},
+			{Value: `"NetXdQprcVkpaWU"`, Type: `chain_id`},
+			{Value: `"1970-01-01T00:00:00Z"`, Type: `timestamp`},
+			{Value: `"2019-09-26T10:59:51Z"`, Type: `timestamp`},
+			{Value: `{ Elt "a" 1 }`, Type: `(map string nat)`},
+			{Value: `{ DROP ; UNIT }`, Type: `(lambda unit unit)`},
+			{Value: `{ FAIL }`, Type: `(lambda unit unit)`, Readable: `{ { UNIT ; FAILWITH } }`},
+		}
+		for _, test := range tests {
+			typ := parse(t, test.Type)
+			b, err := Pack(parse(t, test.Value), typ)
+			assert.NoError(t, err, test.Value)
+
+			unpacked, err := Unpack(b, typ)
+			assert.NoError(t, err, test.Value)
+			expected := test.Value
+			if test.Readable != "" {
+				expected = test.Readable
+			}
+			assert.Equal(t, micheline.Print(parse(t, expected), ""), micheline.Print(unpacked, ""), test.Value)
+		}
+	})
+
+	t.Run("Keys and signatures", func(t *testing.T) {
+		key := `"edpkuBknW28nW72KG6RoHtYW7p12T6GKc7nAbwYX5m8Wd9sDVC9yav"`
+		b, err := Pack(parse(t, key), parse(t, `key`))
+		assert.NoError(t, err)
+		// 0x05 + bytes tag + length (33 bytes: curve tag + public key)
+		assert.Equal(t, "050a0000002100", hex.EncodeToString(b[:7]))
+		unpacked, err := Unpack(b, parse(t, `key`))
+		assert.NoError(t, err)
+		assert.Equal(t, key, micheline.Print(unpacked, ""))
+
+		signature := ast.Bytes{Value: hex.EncodeToString(make([]byte, 64))}
+		readable, err := Readable(signature, parse(t, `signature`))
+		assert.NoError(t, err)
+		b, err = Pack(readable, parse(t, `signature`))
+		assert.NoError(t, err)
+		assert.Equal(t, "050a00000040"+signature.Value, hex.EncodeToString(b))
+	})
+	t.Run("Invalid values", func(t *testing.T) {
+		_, err := Pack(parse(t, `"tz1invalid"`), parse(t, `address`))
+		assert.Error(t, err)
+
+		_, err = Pack(parse(t, `"KT1BEqzn5Wx8uJrZNvuS9DVHmLvG9td3fDLi"`), parse(t, `key_hash`))
+		assert.EqualError(t, err, "invalid key_hash (KT1BEqzn5Wx8uJrZNvuS9DVHmLvG9td3fDLi). not a public key hash.")
+
+		_, err = Pack(parse(t, `(Pair 1 2)`), parse(t, `(or nat nat)`))
+		assert.EqualError(t, err, "value (Pair 1 2) is not of type (or (nat) (nat)).")
+
+		_, err = Unpack([]byte{0x00, 0x00}, parse(t, `nat`))
+		assert.EqualError(t, err, "packed values must start with (0x05).")
+	})
+}
+
+func TestHash(t *testing.T) {
+	for _, test := range recordedKeys {
+		hash, err := ScriptExprHash(parse(t, test.Value), parse(t, test.Type))
+		assert.NoError(t, err, test.Value)
+		assert.Equal(t, test.Hash, hash, test.Value)
+	}
+
+	t.Run("Script hash", func(t *testing.T) {
+		code := parse(t, `{ parameter unit ; storage unit ; code { FAIL } }`)
+		hash, err := ScriptHash(code)
+		assert.NoError(t, err)
+		// Scripts are hashed like packed values (checked against the recorded keys), without the (0x05) prefix
+		b, err := Encode(parse(t, `{ parameter unit ; storage unit ; code { { UNIT ; FAILWITH } } }`))
+		assert.NoError(t, err)
+		assert.Equal(t, ScriptExprHashOfBytes(b), hash)
+
+		// Macros are expanded before hashing
+		expanded, err := ScriptHash(parse(t, `{ parameter unit ; storage unit ; code { { UNIT ; FAILWITH } } }`))
+		assert.NoError(t, err)
+		assert.Equal(t, expanded, hash)
+
+		// Pairing macros expand to (DIP { ... }) like octez, not (DIP 1 { ... })
+		pairing := `{ parameter (pair nat (pair nat nat)) ; storage (pair nat (pair nat nat)) ; code { CAR ; UNPAPAIR ; PAPAIR ; NIL operation ; PAIR } }`
+		hash, err = ScriptHash(parse(t, pairing))
+		assert.NoError(t, err)
+		expanded, err = ScriptHash(parse(t, `{ parameter (pair nat (pair nat nat)) ; storage (pair nat (pair nat nat)) ; code { CAR ; { UNPAIR ; DIP { UNPAIR } } ; { DIP { PAIR } ; PAIR } ; NIL operation ; PAIR } }`))
+		assert.NoError(t, err)
+		assert.Equal(t, expanded, hash)
+	})
+	t.Run("Operation hash", func(t *testing.T) {
+		assert.Equal(t, "oohDPhCuF5sH6mZqq94Nn43TWW8EbW52xUDWtdGaFL125BVNQUT", OperationHash(make([]byte, 10)))
+	})
+	t.Run("Invalid values", func(t *testing.T) {
+		_, err := ScriptExprHash(parse(t, `(Pair 1 2)`), parse(t, `(or nat nat)`))
+		assert.EqualError(t, err, "value (Pair 1 2) is not of type (or (nat) (nat)).")
+	})
+}
@@ -0,0 +1,205 @@
+package binary
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/primitives"
+)
+
+type decoder struct {
+	bytes  []byte
+	offset int
+}
+
+// Decode deserializes a Micheline node from its binary representation
+func Decode(b []byte) (ast.Node, error) {
+	d := decoder{bytes: b}
+	node, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	if d.offset != len(d.bytes) {
+		return nil, fmt.Errorf("unexpected trailing bytes at offset (%d).", d.offset)
+	}
+	return node, nil
+}
+
+func (d *decoder) decode() (ast.Node, error) {
+	tag, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case tagInt:
+		v, err := d.readZarith()
+		if err != nil {
+			return nil, err
+		}
+		return ast.Int{Value: v.String()}, nil
+	case tagString:
+		s, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		return ast.String{Value: string(s)}, nil
+	case tagBytes:
+		s, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		return ast.Bytes{Value: hex.EncodeToString(s)}, nil
+	case tagSequence:
+		elements, err := d.readNodes()
+		if err != nil {
+			return nil, err
+		}
+		return ast.Sequence{Elements: elements}, nil
+	case tagPrimNoArgsNoAnnots, tagPrimNoArgsAnnots,
+		tagPrimOneArgNoAnnots, tagPrimOneArgAnnots,
+		tagPrimTwoArgsNoAnnots, tagPrimTwoArgsAnnots,
+		tagPrimGeneric:
+		return d.decodePrim(tag)
+	}
+
+	return nil, fmt.Errorf("unexpected tag (0x%02x) at offset (%d).", tag, d.offset-1)
+}
+
+func (d *decoder) decodePrim(tag byte) (ast.Node, error) {
+	code, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	primitive, ok := primitives.ByCode(code)
+	if !ok {
+		return nil, fmt.Errorf("unknown primitive code (0x%02x) at offset (%d).", code, d.offset-1)
+	}
+	prim := ast.Prim{
+		Prim:      primitive.Name,
+		Arguments: []ast.Node{},
+	}
+
+	if tag == tagPrimGeneric {
+		if prim.Arguments, err = d.readNodes(); err != nil {
+			return nil, err
+		}
+	} else {
+		// Tags 0x03..0x08 encode the number of arguments and the presence of annotations
+		arguments := int(tag-tagPrimNoArgsNoAnnots) / 2
+		for i := 0; i < arguments; i++ {
+			arg, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			prim.Arguments = append(prim.Arguments, arg)
+		}
+	}
+
+	if tag == tagPrimGeneric || (tag-tagPrimNoArgsNoAnnots)%2 == 1 {
+		annotations, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		if prim.Annotations, err = parseAnnotations(string(annotations)); err != nil {
+			return nil, err
+		}
+	}
+
+	return prim, nil
+}
+
+// readNodes reads a list of nodes prefixed by its length in bytes
+func (d *decoder) readNodes() ([]ast.Node, error) {
+	content, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+
+	inner := decoder{bytes: content}
+	nodes := make([]ast.Node, 0)
+	for inner.offset < len(inner.bytes) {
+		node, err := inner.decode()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+func (d *decoder) readByte() (byte, error) {
+	if d.offset >= len(d.bytes) {
+		return 0, fmt.Errorf("unexpected end of bytes at offset (%d).", d.offset)
+	}
+	b := d.bytes[d.offset]
+	d.offset += 1
+	return b, nil
+}
+
+// readString reads a value prefixed by its length (4 bytes, big-endian)
+func (d *decoder) readString() ([]byte, error) {
+	if d.offset+4 > len(d.bytes) {
+		return nil, fmt.Errorf("unexpected end of bytes at offset (%d).", d.offset)
+	}
+	length := int(binary.BigEndian.Uint32(d.bytes[d.offset:]))
+	d.offset += 4
+
+	if length > len(d.bytes)-d.offset {
+		return nil, fmt.Errorf("unexpected end of bytes at offset (%d).", d.offset)
+	}
+	s := d.bytes[d.offset : d.offset+length]
+	d.offset += length
+	return s, nil
+}
+
+// readZarith reads a signed arbitrary-precision integer
+func (d *decoder) readZarith() (*big.Int, error) {
+	b, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	negative := b&0x40 != 0
+	v := big.NewInt(int64(b & 0x3f))
+	shift := uint(6)
+	for b&0x80 != 0 {
+		if b, err = d.readByte(); err != nil {
+			return nil, err
+		}
+		v.Or(v, new(big.Int).Lsh(big.NewInt(int64(b&0x7f)), shift))
+		shift += 7
+	}
+
+	if negative {
+		v.Neg(v)
+	}
+	return v, nil
+}
+
+func parseAnnotations(s string) ([]ast.Annotation, error) {
+	annotations := make([]ast.Annotation, 0)
+	for _, annot := range strings.Fields(s) {
+		var kind ast.AnnotationKind
+		switch annot[0] {
+		case ':':
+			kind = ast.TypeAnnotation
+		case '@':
+			kind = ast.VariableAnnotation
+		case '%':
+			kind = ast.FieldAnnotation
+		default:
+			return nil, fmt.Errorf("invalid annotation (%s).", annot)
+		}
+		annotations = append(annotations, ast.Annotation{
+			Kind:  kind,
+			Value: annot,
+		})
+	}
+	return annotations, nil
+}
@@ -0,0 +1,150 @@
+package binary
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/primitives"
+)
+
+// Tags of the binary Micheline encoding
+const (
+	tagInt                 byte = 0x00
+	tagString              byte = 0x01
+	tagSequence            byte = 0x02
+	tagPrimNoArgsNoAnnots  byte = 0x03
+	tagPrimNoArgsAnnots    byte = 0x04
+	tagPrimOneArgNoAnnots  byte = 0x05
+	tagPrimOneArgAnnots    byte = 0x06
+	tagPrimTwoArgsNoAnnots byte = 0x07
+	tagPrimTwoArgsAnnots   byte = 0x08
+	tagPrimGeneric         byte = 0x09
+	tagBytes               byte = 0x0a
+)
+
+// Encode serializes a Micheline node to its binary representation
+func Encode(node ast.Node) ([]byte, error) {
+	return encode(make([]byte, 0), node)
+}
+
+func encode(b []byte, node ast.Node) ([]byte, error) {
+	switch n := node.(type) {
+	case ast.Int:
+		v, ok := new(big.Int).SetString(n.Value, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer (%s).", n.Value)
+		}
+		return append(append(b, tagInt), encodeZarith(v)...), nil
+	case ast.String:
+		return appendString(append(b, tagString), n.Value), nil
+	case ast.Bytes:
+		bytes, err := hex.DecodeString(n.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bytes (0x%s).", n.Value)
+		}
+		return appendString(append(b, tagBytes), string(bytes)), nil
+	case ast.Sequence:
+		content := make([]byte, 0)
+		for _, el := range n.Elements {
+			var err error
+			if content, err = encode(content, el); err != nil {
+				return nil, err
+			}
+		}
+		return appendString(append(b, tagSequence), string(content)), nil
+	case ast.Prim:
+		return encodePrim(b, n)
+	}
+
+	return nil, fmt.Errorf("unexpected node (%v).", node)
+}
+
+func encodePrim(b []byte, prim ast.Prim) ([]byte, error) {
+	primitive, ok := primitives.Lookup(prim.Prim)
+	if !ok {
+		return nil, fmt.Errorf("unknown primitive (%s).", prim.Prim)
+	}
+
+	annotations := make([]string, len(prim.Annotations))
+	for i, annot := range prim.Annotations {
+		annotations[i] = annot.Value
+	}
+	hasAnnotations := len(annotations) > 0
+
+	var tag byte
+	switch {
+	case len(prim.Arguments) == 0 && !hasAnnotations:
+		tag = tagPrimNoArgsNoAnnots
+	case len(prim.Arguments) == 0:
+		tag = tagPrimNoArgsAnnots
+	case len(prim.Arguments) == 1 && !hasAnnotations:
+		tag = tagPrimOneArgNoAnnots
+	case len(prim.Arguments) == 1:
+		tag = tagPrimOneArgAnnots
+	case len(prim.Arguments) == 2 && !hasAnnotations:
+		tag = tagPrimTwoArgsNoAnnots
+	case len(prim.Arguments) == 2:
+		tag = tagPrimTwoArgsAnnots
+	default:
+		tag = tagPrimGeneric
+	}
+
+	b = append(b, tag, primitive.Code)
+	if tag == tagPrimGeneric {
+		// Arguments are prefixed by their length
+		content := make([]byte, 0)
+		for _, arg := range prim.Arguments {
+			var err error
+			if content, err = encode(content, arg); err != nil {
+				return nil, err
+			}
+		}
+		b = appendString(b, string(content))
+	} else {
+		for _, arg := range prim.Arguments {
+			var err error
+			if b, err = encode(b, arg); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// The generic encoding always includes the annotations (even if empty)
+	if hasAnnotations || tag == tagPrimGeneric {
+		b = appendString(b, strings.Join(annotations, " "))
+	}
+
+	return b, nil
+}
+
+// appendString appends a value prefixed by its length (4 bytes, big-endian)
+func appendString(b []byte, s string) []byte {
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(s)))
+	return append(append(b, length...), s...)
+}
+
+// encodeZarith encodes a signed arbitrary-precision integer
+// (The first byte holds the sign and 6 bits, subsequent bytes hold 7 bits)
+func encodeZarith(v *big.Int) []byte {
+	abs := new(big.Int).Abs(v)
+
+	first := byte(new(big.Int).And(abs, big.NewInt(0x3f)).Uint64())
+	if v.Sign() < 0 {
+		first |= 0x40
+	}
+	abs.Rsh(abs, 6)
+
+	b := []byte{first}
+	for abs.Sign() != 0 {
+		b[len(b)-1] |= 0x80
+		b = append(b, byte(new(big.Int).And(abs, big.NewInt(0x7f)).Uint64()))
+		abs.Rsh(abs, 7)
+	}
+
+	return b
+}
@@ -0,0 +1,45 @@
+package binary
+
+import (
+	"blockwatch.cc/tzgo/tezos"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/macros"
+)
+
+// ScriptExprHash computes the script expression hash (expr...) of a value of a given type.
+//
+// Big maps are indexed by the script expression hash of their keys.
+func ScriptExprHash(value ast.Node, typ ast.Node) (string, error) {
+	packed, err := Pack(value, typ)
+	if err != nil {
+		return "", err
+	}
+	return ScriptExprHashOfBytes(packed), nil
+}
+
+// ScriptExprHashOfBytes computes the script expression hash (expr...) of already packed bytes
+func ScriptExprHashOfBytes(packed []byte) string {
+	digest := tezos.Digest(packed)
+	return tezos.NewExprHash(digest[:]).String()
+}
+
+// ScriptHash computes the hash of a contract code (the hash returned by "tezos-client get contract script hash").
+//
+// Macros are expanded, since contracts are stored with the instructions the protocol executes.
+func ScriptHash(code ast.Node) (string, error) {
+	expanded, err := macros.Expand(code)
+	if err != nil {
+		return "", err
+	}
+	b, err := Encode(expanded)
+	if err != nil {
+		return "", err
+	}
+	return ScriptExprHashOfBytes(b), nil
+}
+
+// OperationHash computes the hash (o...) of a forged operation (including its signature)
+func OperationHash(forged []byte) string {
+	digest := tezos.Digest(forged)
+	return tezos.NewOpHash(digest[:]).String()
+}
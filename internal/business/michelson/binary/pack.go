@@ -0,0 +1,250 @@
+package binary
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/base58"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/macros"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+)
+
+// Prefix of packed values
+const PACK_PREFIX byte = 0x05
+
+// Pack serializes a value of a given type the same way the (PACK) instruction does,
+// domain specific values (addresses, keys, timestamps, ...) use their optimized encoding
+func Pack(value ast.Node, typ ast.Node) ([]byte, error) {
+	optimized, err := Optimize(value, typ)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := Encode(optimized)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{PACK_PREFIX}, b...), nil
+}
+
+// Unpack deserializes a packed value of a given type,
+// domain specific values are converted back to their readable representation
+func Unpack(b []byte, typ ast.Node) (ast.Node, error) {
+	if len(b) == 0 || b[0] != PACK_PREFIX {
+		return nil, fmt.Errorf("packed values must start with (0x%02x).", PACK_PREFIX)
+	}
+
+	value, err := Decode(b[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return Readable(value, typ)
+}
+
+// Optimize converts a value of a given type to its optimized representation
+func Optimize(value ast.Node, typ ast.Node) (ast.Node, error) {
+	return convert(value, typ, optimizeLeaf, true)
+}
+
+// Readable converts a value of a given type to its readable representation
+func Readable(value ast.Node, typ ast.Node) (ast.Node, error) {
+	return convert(value, typ, readableLeaf, false)
+}
+
+type leafConverter func(value ast.Node, typ string) (ast.Node, error)
+
+// convert traverses a value along its type and converts the domain specific leaves.
+// Optimized pairs are encoded as nested binary pairs, readable pairs are flattened.
+func convert(value ast.Node, t ast.Node, leaf leafConverter, binaryPairs bool) (ast.Node, error) {
+	typ, ok := t.(ast.Prim)
+	if !ok {
+		return nil, fmt.Errorf("invalid type: %s.", micheline.Print(t, ""))
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("value %s is not of type %s.", micheline.Print(value, ""), micheline.Print(t, ""))
+	}
+
+	switch typ.Prim {
+	case "option":
+		if prim, ok := value.(ast.Prim); ok && prim.Prim == "Some" && len(prim.Arguments) == 1 && len(typ.Arguments) == 1 {
+			arg, err := convert(prim.Arguments[0], typ.Arguments[0], leaf, binaryPairs)
+			if err != nil {
+				return nil, err
+			}
+			return ast.Prim{Prim: "Some", Arguments: []ast.Node{arg}}, nil
+		}
+		return value, nil
+	case "or":
+		prim, ok := value.(ast.Prim)
+		if !ok || len(prim.Arguments) != 1 || len(typ.Arguments) != 2 {
+			return nil, mismatch()
+		}
+		branch := typ.Arguments[0]
+		if prim.Prim == "Right" {
+			branch = typ.Arguments[1]
+		} else if prim.Prim != "Left" {
+			return nil, mismatch()
+		}
+		arg, err := convert(prim.Arguments[0], branch, leaf, binaryPairs)
+		if err != nil {
+			return nil, err
+		}
+		return ast.Prim{Prim: prim.Prim, Arguments: []ast.Node{arg}}, nil
+	case "pair":
+		return convertPair(value, typ, leaf, binaryPairs)
+	case "list", "set":
+		seq, ok := value.(ast.Sequence)
+		if !ok || len(typ.Arguments) != 1 {
+			return nil, mismatch()
+		}
+		elements := make([]ast.Node, len(seq.Elements))
+		for i, el := range seq.Elements {
+			var err error
+			if elements[i], err = convert(el, typ.Arguments[0], leaf, binaryPairs); err != nil {
+				return nil, err
+			}
+		}
+		return ast.Sequence{Elements: elements}, nil
+	case "map", "big_map":
+		seq, ok := value.(ast.Sequence)
+		if !ok || len(typ.Arguments) != 2 {
+			// Big maps can be referenced by their identifier
+			return value, nil
+		}
+		elements := make([]ast.Node, len(seq.Elements))
+		for i, el := range seq.Elements {
+			elt, ok := el.(ast.Prim)
+			if !ok || elt.Prim != "Elt" || len(elt.Arguments) != 2 {
+				return nil, fmt.Errorf("value %s is not a map entry.", micheline.Print(el, ""))
+			}
+			key, err := convert(elt.Arguments[0], typ.Arguments[0], leaf, binaryPairs)
+			if err != nil {
+				return nil, err
+			}
+			val, err := convert(elt.Arguments[1], typ.Arguments[1], leaf, binaryPairs)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = ast.Prim{Prim: "Elt", Arguments: []ast.Node{key, val}}
+		}
+		return ast.Sequence{Elements: elements}, nil
+	case "address", "contract", "key_hash", "key", "signature", "chain_id", "timestamp":
+		return leaf(value, typ.Prim)
+	case "lambda":
+		// Lambdas are represented by the instructions the protocol executes
+		return macros.Expand(value)
+	}
+
+	return value, nil
+}
+
+// convertPair converts the elements of a pair (written with a Pair prim or as a sequence)
+func convertPair(value ast.Node, typ ast.Prim, leaf leafConverter, binaryPairs bool) (ast.Node, error) {
+	var elements []ast.Node
+	switch node := value.(type) {
+	case ast.Prim:
+		if node.Prim == "Pair" {
+			elements = node.Arguments
+		}
+	case ast.Sequence:
+		elements = node.Elements
+	}
+	if len(elements) < 2 || len(typ.Arguments) < 2 {
+		return nil, fmt.Errorf("value %s is not of type %s.", micheline.Print(value, ""), micheline.Print(typ, ""))
+	}
+
+	first, err := convert(elements[0], typ.Arguments[0], leaf, binaryPairs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Right combs: (pair a b c) == (pair a (pair b c))
+	restType := typ.Arguments[1]
+	if len(typ.Arguments) > 2 {
+		restType = ast.Prim{Prim: "pair", Arguments: typ.Arguments[1:]}
+	}
+	restValue := elements[1]
+	if len(elements) > 2 {
+		restValue = ast.Prim{Prim: "Pair", Arguments: elements[1:]}
+	}
+	rest, err := convert(restValue, restType, leaf, binaryPairs)
+	if err != nil {
+		return nil, err
+	}
+
+	if !binaryPairs {
+		// Flatten combs
+		if prim, ok := rest.(ast.Prim); ok && prim.Prim == "Pair" && isComb(restType) {
+			return ast.Prim{Prim: "Pair", Arguments: append([]ast.Node{first}, prim.Arguments...)}, nil
+		}
+	}
+	return ast.Prim{Prim: "Pair", Arguments: []ast.Node{first, rest}}, nil
+}
+
+func isComb(typ ast.Node) bool {
+	prim, ok := typ.(ast.Prim)
+	return ok && prim.Prim == "pair"
+}
+
+// optimizeLeaf converts a readable domain specific value to its optimized representation
+func optimizeLeaf(value ast.Node, typ string) (ast.Node, error) {
+	s, ok := value.(ast.String)
+	if !ok {
+		// Already optimized
+		return value, nil
+	}
+
+	if typ == "timestamp" {
+		t, err := time.Parse(time.RFC3339, s.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s (%s). %s", typ, s.Value, err)
+		}
+		return ast.Int{Value: fmt.Sprint(t.Unix())}, nil
+	}
+	if !base58.IsEncoded(typ) {
+		return value, nil
+	}
+
+	b, err := base58.Decode(typ, s.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s (%s). %s", typ, s.Value, err)
+	}
+	return bytesOf(b), nil
+}
+
+// readableLeaf converts an optimized domain specific value to its readable representation
+func readableLeaf(value ast.Node, typ string) (ast.Node, error) {
+	if i, ok := value.(ast.Int); ok && typ == "timestamp" {
+		seconds, ok := new(big.Int).SetString(i.Value, 10)
+		if !ok || !seconds.IsInt64() {
+			return value, nil
+		}
+		return ast.String{Value: time.Unix(seconds.Int64(), 0).UTC().Format(time.RFC3339)}, nil
+	}
+
+	bytes, ok := value.(ast.Bytes)
+	if !ok || !base58.IsEncoded(typ) {
+		// Already readable
+		return value, nil
+	}
+	b, err := hex.DecodeString(bytes.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bytes (0x%s).", bytes.Value)
+	}
+
+	s, err := base58.Encode(typ, b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid optimized %s (0x%s). %s", typ, bytes.Value, err)
+	}
+	return ast.String{Value: s}, nil
+}
+
+func bytesOf(b []byte) ast.Bytes {
+	return ast.Bytes{Value: hex.EncodeToString(b)}
+}
@@ -0,0 +1,193 @@
+package michelson
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+)
+
+type (
+	// Contract is a Michelson script split into its sections
+	Contract struct {
+		Parameter ast.Node // parameter type
+		Storage   ast.Node // storage type
+		Code      ast.Node
+		Views     []View
+	}
+	// View is an on-chain view (view "name" input output { body })
+	View struct {
+		Name   string
+		Input  ast.Node
+		Output ast.Node
+		Body   ast.Node
+	}
+	// Entrypoint is a node of the entrypoint tree, which follows the (or) branches of the parameter type
+	Entrypoint struct {
+		Name     string // name of the entrypoint (empty if the branch has no field annotation)
+		Type     ast.Node
+		Branches []Entrypoint // left and right branches (only for (or) types)
+	}
+	// ContractError describes an invalid section of a contract
+	ContractError struct {
+		Position ast.Position
+		Message  string
+	}
+	// ContractErrors lists all errors found while parsing a contract
+	ContractErrors []ContractError
+)
+
+// sectionArity is the number of arguments expected by each section of a contract
+var sectionArity = map[string]int{
+	"parameter": 1,
+	"storage":   1,
+	"code":      1,
+	"view":      4,
+}
+
+// ParseContract extracts the sections of a contract, all invalid (duplicate, missing or unknown) sections are reported
+func ParseContract(code ast.Node) (Contract, error) {
+	contract := Contract{}
+	errors := ContractErrors{}
+
+	seq, ok := code.(ast.Sequence)
+	if !ok {
+		errors.add(code, "contract code must be a sequence of sections")
+		return contract, errors
+	}
+
+	sections := map[string]bool{}
+	views := map[string]bool{}
+	for _, el := range seq.Elements {
+		prim, ok := el.(ast.Prim)
+		if !ok {
+			errors.add(el, "unexpected contract section (%s)", micheline.Print(el, ""))
+			continue
+		}
+
+		arity, ok := sectionArity[prim.Prim]
+		if !ok {
+			errors.add(el, "unknown contract section (%s)", prim.Prim)
+			continue
+		}
+		if len(prim.Arguments) != arity {
+			errors.add(el, "section (%s) expects %d argument(s), but received %d", prim.Prim, arity, len(prim.Arguments))
+			continue
+		}
+		if prim.Prim != "view" && sections[prim.Prim] {
+			errors.add(el, "duplicate contract section (%s)", prim.Prim)
+			continue
+		}
+		sections[prim.Prim] = true
+
+		switch prim.Prim {
+		case "parameter":
+			contract.Parameter = prim.Arguments[0]
+		case "storage":
+			contract.Storage = prim.Arguments[0]
+		case "code":
+			contract.Code = prim.Arguments[0]
+		case "view":
+			name, ok := prim.Arguments[0].(ast.String)
+			if !ok {
+				errors.add(prim.Arguments[0], "view name must be a string")
+				continue
+			}
+			if views[name.Value] {
+				errors.add(el, "duplicate view (%s)", name.Value)
+				continue
+			}
+			views[name.Value] = true
+			contract.Views = append(contract.Views, View{
+				Name:   name.Value,
+				Input:  prim.Arguments[1],
+				Output: prim.Arguments[2],
+				Body:   prim.Arguments[3],
+			})
+		}
+	}
+
+	for _, section := range []string{"parameter", "storage", "code"} {
+		if !sections[section] {
+			errors.add(code, "missing contract section (%s)", section)
+		}
+	}
+
+	if len(errors) > 0 {
+		return contract, errors
+	}
+	return contract, nil
+}
+
+// Entrypoints returns the entrypoints of the contract (and their types)
+func (c Contract) Entrypoints() map[string]ast.Node {
+	return GetEntrypoints(c.Parameter)
+}
+
+// EntrypointType returns the type of a given entrypoint
+func (c Contract) EntrypointType(entrypoint string) (ast.Node, bool) {
+	typ, ok := c.Entrypoints()[entrypoint]
+	return typ, ok
+}
+
+// EntrypointTree returns the entrypoint tree of the contract, rooted at the parameter type
+func (c Contract) EntrypointTree() Entrypoint {
+	return entrypointTreeOf(c.Parameter)
+}
+
+// View returns a view by name
+func (c Contract) View(name string) (View, bool) {
+	for _, view := range c.Views {
+		if view.Name == name {
+			return view, true
+		}
+	}
+	return View{}, false
+}
+
+func entrypointTreeOf(typ ast.Node) Entrypoint {
+	entrypoint := Entrypoint{
+		Type: typ,
+	}
+
+	prim, ok := typ.(ast.Prim)
+	if !ok {
+		return entrypoint
+	}
+	for _, annotation := range prim.Annotations {
+		if annotation.Kind == ast.FieldAnnotation && len(annotation.Value) > 1 {
+			entrypoint.Name = annotation.Value[1:]
+			break
+		}
+	}
+	if prim.Prim == "or" {
+		for _, arg := range prim.Arguments {
+			entrypoint.Branches = append(entrypoint.Branches, entrypointTreeOf(arg))
+		}
+	}
+
+	return entrypoint
+}
+
+func (errors *ContractErrors) add(node ast.Node, format string, args ...interface{}) {
+	*errors = append(*errors, ContractError{
+		Position: ast.PositionOf(node),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (e ContractError) Error() string {
+	if e.Position.Line > 0 {
+		return fmt.Sprintf("%s (line %d, column %d).", e.Message, e.Position.Line, e.Position.Column)
+	}
+	return e.Message + "."
+}
+
+func (e ContractErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, " ")
+}
@@ -0,0 +1,67 @@
+package michelson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseContract(t *testing.T) {
+	t.Run("Valid contract", func(t *testing.T) {
+		code, err := ParseMicheline(`
+			{
+				storage (pair (nat %counter) (address %admin));
+				parameter (or (or (nat %increment) (nat %decrement)) (unit %reset));
+				code { CDR ; NIL operation ; PAIR };
+				view "counter" unit nat { CDR ; CAR }
+			}
+		`)
+		assert.NoError(t, err)
+
+		contract, err := ParseContract(code)
+		assert.NoError(t, err)
+		assert.Equal(t, "Prim(pair, [], [Prim(nat, [%counter], []), Prim(address, [%admin], [])])", contract.Storage.String())
+		assert.Equal(t, "Sequence([Prim(CDR, [], []), Prim(NIL, [], [Prim(operation, [], [])]), Prim(PAIR, [], [])])", contract.Code.String())
+		assert.Len(t, contract.Entrypoints(), 4)
+
+		entrypointType, ok := contract.EntrypointType("decrement")
+		assert.True(t, ok)
+		assert.Equal(t, "Prim(nat, [%decrement], [])", entrypointType.String())
+
+		tree := contract.EntrypointTree()
+		assert.Equal(t, "", tree.Name)
+		assert.Len(t, tree.Branches, 2)
+		assert.Equal(t, "increment", tree.Branches[0].Branches[0].Name)
+		assert.Equal(t, "decrement", tree.Branches[0].Branches[1].Name)
+		assert.Equal(t, "reset", tree.Branches[1].Name)
+		assert.Empty(t, tree.Branches[1].Branches)
+
+		view, ok := contract.View("counter")
+		assert.True(t, ok)
+		assert.Equal(t, "Prim(unit, [], [])", view.Input.String())
+		assert.Equal(t, "Prim(nat, [], [])", view.Output.String())
+		assert.Equal(t, "Sequence([Prim(CDR, [], []), Prim(CAR, [], [])])", view.Body.String())
+	})
+	t.Run("Invalid sections are reported", func(t *testing.T) {
+		code, err := ParseMicheline("{ storage unit ;\n  storage nat ;\n  code {} ;\n  view \"v\" unit unit {} ;\n  view \"v\" unit unit {} ;\n  other unit }")
+		assert.NoError(t, err)
+
+		_, err = ParseContract(code)
+		assert.EqualError(t, err, "duplicate contract section (storage) (line 2, column 3). duplicate view (v) (line 5, column 3). unknown contract section (other) (line 6, column 3). missing contract section (parameter) (line 1, column 1).")
+		assert.Len(t, err.(ContractErrors), 4)
+	})
+	t.Run("Contract code must be a sequence", func(t *testing.T) {
+		code, err := ParseMicheline(`parameter unit`)
+		assert.NoError(t, err)
+
+		_, err = ParseContract(code)
+		assert.EqualError(t, err, "contract code must be a sequence of sections (line 1, column 1).")
+	})
+	t.Run("Sections must be primitives", func(t *testing.T) {
+		code, err := ParseMicheline(`{ parameter unit ; storage unit ; code {} ; { Pair 1 "a" } }`)
+		assert.NoError(t, err)
+
+		_, err = ParseContract(code)
+		assert.EqualError(t, err, `unexpected contract section ({ Pair 1 "a" }) (line 1, column 45).`)
+	})
+}
@@ -0,0 +1,42 @@
+package michelson
+
+import (
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+)
+
+const DEFAULT_ENTRYPOINT = "default"
+
+// GetEntrypoints extracts the entrypoints (and their types) from the parameter type of a contract
+func GetEntrypoints(parameterType ast.Node) map[string]ast.Node {
+	entrypoints := map[string]ast.Node{}
+	collectEntrypoints(parameterType, entrypoints)
+
+	// The root of the parameter type is the default entrypoint,
+	// unless one of the branches is annotated with %default
+	if _, ok := entrypoints[DEFAULT_ENTRYPOINT]; !ok {
+		entrypoints[DEFAULT_ENTRYPOINT] = parameterType
+	}
+
+	return entrypoints
+}
+
+func collectEntrypoints(node ast.Node, entrypoints map[string]ast.Node) {
+	prim, ok := node.(ast.Prim)
+	if !ok {
+		return
+	}
+
+	for _, annotation := range prim.Annotations {
+		if annotation.Kind == ast.FieldAnnotation && len(annotation.Value) > 1 {
+			entrypoints[annotation.Value[1:]] = prim
+			break
+		}
+	}
+
+	// Only the branches of (or) types can be entrypoints
+	if prim.Prim == "or" {
+		for _, arg := range prim.Arguments {
+			collectEntrypoints(arg, entrypoints)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package michelson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEntrypoints(t *testing.T) {
+	t.Run("Parameter without annotations", func(t *testing.T) {
+		parameterType, err := ParseMicheline(`(pair nat string)`)
+		assert.NoError(t, err)
+
+		entrypoints := GetEntrypoints(parameterType)
+		assert.Len(t, entrypoints, 1)
+		assert.Equal(t, parameterType, entrypoints["default"])
+	})
+	t.Run("Parameter with annotated branches", func(t *testing.T) {
+		parameterType, err := ParseMicheline(`(or (or (nat %increment) (nat %decrement)) (unit %reset))`)
+		assert.NoError(t, err)
+
+		entrypoints := GetEntrypoints(parameterType)
+		assert.Len(t, entrypoints, 4)
+		assert.Equal(t, "Prim(nat, [%increment], [])", entrypoints["increment"].String())
+		assert.Equal(t, "Prim(nat, [%decrement], [])", entrypoints["decrement"].String())
+		assert.Equal(t, "Prim(unit, [%reset], [])", entrypoints["reset"].String())
+		assert.Equal(t, parameterType, entrypoints["default"])
+	})
+	t.Run("Parameter with an explicit default entrypoint", func(t *testing.T) {
+		parameterType, err := ParseMicheline(`(or (unit %default) (nat %other))`)
+		assert.NoError(t, err)
+
+		entrypoints := GetEntrypoints(parameterType)
+		assert.Len(t, entrypoints, 2)
+		assert.Equal(t, "Prim(unit, [%default], [])", entrypoints["default"].String())
+	})
+}
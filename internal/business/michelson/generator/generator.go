@@ -0,0 +1,316 @@
+package generator
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+)
+
+type Generator struct {
+	rand           *rand.Rand
+	addresses      []string
+	parameterTypes map[string]ast.Node // address -> parameter type of the default entrypoint (originated contracts)
+}
+
+const (
+	// Collections nested deeper than this level are generated empty
+	MAX_DEPTH = 3
+	// Maximum number of elements in generated collections
+	MAX_COLLECTION_SIZE = 5
+	// Maximum length of generated strings and bytes
+	MAX_STRING_LENGTH = 16
+	// Chain identifier used for values of type (chain_id)
+	CHAIN_ID = "NetXdQprcVkpaWU"
+)
+
+var (
+	maxMutez  = new(big.Int).SetInt64(9223372036854775807)
+	edgeCases = []int64{0, 1, -1, 2, 255, 256, 65535, 1 << 31, -(1 << 31), 1 << 62}
+	alphabet  = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_ ")
+)
+
+// InitGenerator creates a deterministic value generator.
+// Values of type (address), (contract) and (key_hash) are picked from the given addresses,
+// values of type (contract) are only picked from the implicit accounts and the contracts (given by their parameter types) that accept them.
+func InitGenerator(seed int64, addresses []string, parameterTypes map[string]ast.Node) Generator {
+	// Sort addresses to make the generation independent from the input order
+	sorted := append([]string{}, addresses...)
+	sort.Strings(sorted)
+
+	return Generator{
+		rand:           rand.New(rand.NewSource(seed)),
+		addresses:      sorted,
+		parameterTypes: parameterTypes,
+	}
+}
+
+// Generate generates a random value of a given Michelson type
+func (g *Generator) Generate(t ast.Node) (ast.Node, error) {
+	return g.generate(t, 0)
+}
+
+func (g *Generator) generate(t ast.Node, depth int) (ast.Node, error) {
+	typ, ok := t.(ast.Prim)
+	if !ok {
+		return nil, fmt.Errorf("invalid type: %s.", t)
+	}
+
+	switch typ.Prim {
+	case "unit":
+		return ast.Prim{Prim: "Unit"}, nil
+	case "bool":
+		if g.rand.Intn(2) == 0 {
+			return ast.Prim{Prim: "False"}, nil
+		}
+		return ast.Prim{Prim: "True"}, nil
+	case "int":
+		return ast.Int{Value: g.integer().String()}, nil
+	case "nat", "timestamp":
+		return ast.Int{Value: new(big.Int).Abs(g.integer()).String()}, nil
+	case "mutez":
+		value := new(big.Int).Abs(g.integer())
+		if value.Cmp(maxMutez) > 0 {
+			value = maxMutez
+		}
+		return ast.Int{Value: value.String()}, nil
+	case "string":
+		return ast.String{Value: g.string()}, nil
+	case "bytes":
+		return ast.Bytes{Value: g.bytes()}, nil
+	case "chain_id":
+		return ast.String{Value: CHAIN_ID}, nil
+	case "address", "key_hash":
+		return g.address(typ.Prim == "key_hash")
+	case "contract":
+		if err := expectArguments(typ, 1); err != nil {
+			return nil, err
+		}
+		return g.contract(typ.Arguments[0])
+	case "option":
+		if err := expectArguments(typ, 1); err != nil {
+			return nil, err
+		}
+		if g.rand.Intn(4) == 0 {
+			return ast.Prim{Prim: "None"}, nil
+		}
+		value, err := g.generate(typ.Arguments[0], depth)
+		if err != nil {
+			return nil, err
+		}
+		return ast.Prim{Prim: "Some", Arguments: []ast.Node{value}}, nil
+	case "or":
+		if err := expectArguments(typ, 2); err != nil {
+			return nil, err
+		}
+		branch, prim := 0, "Left"
+		if g.rand.Intn(2) == 1 {
+			branch, prim = 1, "Right"
+		}
+		value, err := g.generate(typ.Arguments[branch], depth)
+		if err != nil {
+			return nil, err
+		}
+		return ast.Prim{Prim: prim, Arguments: []ast.Node{value}}, nil
+	case "pair":
+		if len(typ.Arguments) < 2 {
+			return nil, fmt.Errorf("type (pair) expects at least 2 arguments.")
+		}
+		arguments := make([]ast.Node, len(typ.Arguments))
+		for i, argType := range typ.Arguments {
+			value, err := g.generate(argType, depth)
+			if err != nil {
+				return nil, err
+			}
+			arguments[i] = value
+		}
+		return ast.Prim{Prim: "Pair", Arguments: arguments}, nil
+	case "list":
+		if err := expectArguments(typ, 1); err != nil {
+			return nil, err
+		}
+		elements := make([]ast.Node, g.collectionSize(depth, true))
+		for i := range elements {
+			value, err := g.generate(typ.Arguments[0], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = value
+		}
+		return ast.Sequence{Elements: elements}, nil
+	case "set":
+		if err := expectArguments(typ, 1); err != nil {
+			return nil, err
+		}
+		keys, err := g.keys(typ.Arguments[0], depth)
+		if err != nil {
+			return nil, err
+		}
+		return ast.Sequence{Elements: keys}, nil
+	case "map", "big_map":
+		if err := expectArguments(typ, 2); err != nil {
+			return nil, err
+		}
+		keys, err := g.keys(typ.Arguments[0], depth)
+		if err != nil {
+			return nil, err
+		}
+		elements := make([]ast.Node, len(keys))
+		for i, key := range keys {
+			value, err := g.generate(typ.Arguments[1], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = ast.Prim{Prim: "Elt", Arguments: []ast.Node{key, value}}
+		}
+		return ast.Sequence{Elements: elements}, nil
+	}
+
+	return nil, fmt.Errorf("cannot generate values of type (%s).", typ.Prim)
+}
+
+// keys generates a sorted list of distinct keys (Used by sets and maps)
+func (g *Generator) keys(t ast.Node, depth int) ([]ast.Node, error) {
+	typ, ok := t.(ast.Prim)
+	if !ok {
+		return nil, fmt.Errorf("invalid type: %s.", t)
+	}
+
+	// The generator only knows how to order a few comparable types,
+	// collections of other types contain at most one element.
+	orderable := isOrderable(typ.Prim)
+
+	size := g.collectionSize(depth, orderable)
+	keys := make([]ast.Node, 0, size)
+	seen := map[string]bool{}
+	for i := 0; i < size; i++ {
+		key, err := g.generate(typ, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[key.String()] {
+			seen[key.String()] = true
+			keys = append(keys, key)
+		}
+	}
+
+	if orderable {
+		sort.SliceStable(keys, func(i, j int) bool {
+			return compareKeys(keys[i], keys[j]) < 0
+		})
+	}
+
+	return keys, nil
+}
+
+func (g *Generator) collectionSize(depth int, allowMany bool) int {
+	if depth >= MAX_DEPTH {
+		return 0
+	}
+	if !allowMany {
+		return g.rand.Intn(2)
+	}
+	return g.rand.Intn(MAX_COLLECTION_SIZE + 1)
+}
+
+func (g *Generator) integer() *big.Int {
+	switch g.rand.Intn(3) {
+	case 0:
+		return big.NewInt(int64(g.rand.Intn(21) - 10))
+	case 1:
+		return big.NewInt(edgeCases[g.rand.Intn(len(edgeCases))])
+	default:
+		return big.NewInt(g.rand.Int63n(1<<32) - (1 << 31))
+	}
+}
+
+func (g *Generator) string() string {
+	runes := make([]rune, g.rand.Intn(MAX_STRING_LENGTH+1))
+	for i := range runes {
+		runes[i] = alphabet[g.rand.Intn(len(alphabet))]
+	}
+	return string(runes)
+}
+
+func (g *Generator) bytes() string {
+	b := make([]byte, g.rand.Intn(MAX_STRING_LENGTH+1))
+	g.rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (g *Generator) address(implicitOnly bool) (ast.Node, error) {
+	candidates := make([]string, 0)
+	for _, address := range g.addresses {
+		if !implicitOnly || address[0:2] == "tz" {
+			candidates = append(candidates, address)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("there are no known addresses to generate values from.")
+	}
+	return ast.String{Value: candidates[g.rand.Intn(len(candidates))]}, nil
+}
+
+// contract picks an address that accepts a given parameter type, implicit accounts only accept (unit)
+func (g *Generator) contract(parameterType ast.Node) (ast.Node, error) {
+	candidates := make([]string, 0)
+	for _, address := range g.addresses {
+		var accepted ast.Node = ast.Prim{Prim: "unit"}
+		if strings.HasPrefix(address, "KT1") {
+			accepted = g.parameterTypes[address]
+		}
+		if accepted != nil && ast.Equal(accepted, parameterType, ast.IgnorePositions, ast.IgnoreAnnotations) {
+			candidates = append(candidates, address)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("there are no known contracts that accept parameters of type %s.", micheline.Print(parameterType, ""))
+	}
+	return ast.String{Value: candidates[g.rand.Intn(len(candidates))]}, nil
+}
+
+func isOrderable(typ string) bool {
+	switch typ {
+	case "int", "nat", "mutez", "timestamp", "string", "bytes":
+		return true
+	}
+	return false
+}
+
+// compareKeys compares two keys of an orderable type
+func compareKeys(a ast.Node, b ast.Node) int {
+	switch left := a.(type) {
+	case ast.Int:
+		l, _ := new(big.Int).SetString(left.Value, 10)
+		r, _ := new(big.Int).SetString(b.(ast.Int).Value, 10)
+		return l.Cmp(r)
+	case ast.String:
+		return compareStrings(left.Value, b.(ast.String).Value)
+	case ast.Bytes:
+		// Hexadecimal strings of the same case preserve the byte ordering
+		return compareStrings(left.Value, b.(ast.Bytes).Value)
+	}
+	return 0
+}
+
+func compareStrings(a string, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func expectArguments(typ ast.Prim, count int) error {
+	if len(typ.Arguments) != count {
+		return fmt.Errorf("type (%s) expects %d argument(s).", typ.Prim, count)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/stretchr/testify/assert"
+)
+
+var addresses = []string{
+	"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx",
+	"KT1BEqzn5Wx8uJrZNvuS9DVHmLvG9td3fDLi",
+}
+
+func parseType(t *testing.T, micheline string) ast.Node {
+	typ, err := michelson.ParseMicheline(micheline)
+	assert.NoError(t, err)
+	return typ
+}
+
+func TestGenerate(t *testing.T) {
+	t.Run("Generation is deterministic", func(t *testing.T) {
+		typ := parseType(t, `(pair (list nat) (map string (option int)) (or address bytes))`)
+
+		g1 := InitGenerator(42, addresses, nil)
+		g2 := InitGenerator(42, []string{addresses[1], addresses[0]}, nil)
+		for i := 0; i < 20; i++ {
+			v1, err := g1.Generate(typ)
+			assert.NoError(t, err)
+			v2, err := g2.Generate(typ)
+			assert.NoError(t, err)
+			assert.Equal(t, v1, v2)
+		}
+	})
+	t.Run("Generate well-typed values", func(t *testing.T) {
+		g := InitGenerator(1, addresses, nil)
+		for i := 0; i < 50; i++ {
+			value, err := g.Generate(parseType(t, `(set nat)`))
+			assert.NoError(t, err)
+			elements := value.(ast.Sequence).Elements
+			for j := 1; j < len(elements); j++ {
+				assert.Negative(t, compareKeys(elements[j-1], elements[j]), "Set elements must be sorted and distinct")
+			}
+
+			value, err = g.Generate(parseType(t, `key_hash`))
+			assert.NoError(t, err)
+			assert.Equal(t, ast.String{Value: addresses[0]}, value)
+
+			value, err = g.Generate(parseType(t, `mutez`))
+			assert.NoError(t, err)
+			assert.NotEqual(t, byte('-'), value.(ast.Int).Value[0])
+		}
+	})
+	t.Run("Contracts are picked by parameter type", func(t *testing.T) {
+		g := InitGenerator(1, addresses, map[string]ast.Node{addresses[1]: parseType(t, `(or (nat %add) (unit %reset))`)})
+		for i := 0; i < 20; i++ {
+			value, err := g.Generate(parseType(t, `(contract unit)`))
+			assert.NoError(t, err)
+			assert.Equal(t, ast.String{Value: addresses[0]}, value)
+
+			value, err = g.Generate(parseType(t, `(contract (or nat unit))`))
+			assert.NoError(t, err)
+			assert.Equal(t, ast.String{Value: addresses[1]}, value)
+		}
+		_, err := g.Generate(parseType(t, `(contract nat)`))
+		assert.EqualError(t, err, "there are no known contracts that accept parameters of type (nat).")
+	})
+	t.Run("Unsupported types", func(t *testing.T) {
+		g := InitGenerator(1, addresses, nil)
+		_, err := g.Generate(parseType(t, `(lambda unit unit)`))
+		assert.EqualError(t, err, "cannot generate values of type (lambda).")
+
+		g = InitGenerator(1, []string{}, nil)
+		_, err = g.Generate(parseType(t, `address`))
+		assert.EqualError(t, err, "there are no known addresses to generate values from.")
+	})
+}
+
+func TestShrink(t *testing.T) {
+	t.Run("Shrink values", func(t *testing.T) {
+		value, err := michelson.ParseMicheline(`(Pair 10 (Some "abcd"))`)
+		assert.NoError(t, err)
+		candidates := Shrink(value, parseType(t, `(pair nat (option string))`))
+
+		printed := make([]string, len(candidates))
+		for i, candidate := range candidates {
+			printed[i] = candidate.String()
+		}
+		assert.Equal(
+			t,
+			[]string{
+				"Prim(Pair, [], [Int(0), Prim(Some, [], [String(abcd)])])",
+				"Prim(Pair, [], [Int(5), Prim(Some, [], [String(abcd)])])",
+				"Prim(Pair, [], [Int(10), Prim(None, [], [])])",
+				"Prim(Pair, [], [Int(10), Prim(Some, [], [String()])])",
+				"Prim(Pair, [], [Int(10), Prim(Some, [], [String(ab)])])",
+			},
+			printed,
+		)
+	})
+	t.Run("Minimal values cannot be shrunk", func(t *testing.T) {
+		assert.Empty(t, Shrink(ast.Int{Value: "0"}, parseType(t, `int`)))
+		assert.Empty(t, Shrink(ast.Sequence{}, parseType(t, `(list int)`)))
+		assert.Empty(t, Shrink(ast.Prim{Prim: "None"}, parseType(t, `(option int)`)))
+	})
+}
@@ -0,0 +1,137 @@
+package generator
+
+import (
+	"math/big"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+)
+
+// Shrink proposes simpler candidates for a value of a given type.
+// The candidates are ordered from the most aggressive to the least aggressive simplification.
+func Shrink(value ast.Node, t ast.Node) []ast.Node {
+	typ, ok := t.(ast.Prim)
+	if !ok {
+		return nil
+	}
+
+	candidates := make([]ast.Node, 0)
+	switch node := value.(type) {
+	case ast.Int:
+		v, ok := new(big.Int).SetString(node.Value, 10)
+		if !ok || v.Sign() == 0 {
+			return nil
+		}
+		candidates = append(candidates, ast.Int{Value: "0"})
+		if v.Sign() < 0 {
+			candidates = append(candidates, ast.Int{Value: new(big.Int).Neg(v).String()})
+		}
+		if half := new(big.Int).Quo(v, big.NewInt(2)); half.Sign() != 0 {
+			candidates = append(candidates, ast.Int{Value: half.String()})
+		}
+	case ast.String:
+		if typ.Prim != "string" || node.Value == "" {
+			return nil
+		}
+		candidates = append(candidates, ast.String{Value: ""})
+		if len(node.Value) > 1 {
+			candidates = append(candidates, ast.String{Value: node.Value[:len(node.Value)/2]})
+		}
+	case ast.Bytes:
+		if node.Value == "" {
+			return nil
+		}
+		candidates = append(candidates, ast.Bytes{Value: ""})
+		// Keep an even number of hexadecimal characters
+		if half := len(node.Value) / 4 * 2; half > 0 {
+			candidates = append(candidates, ast.Bytes{Value: node.Value[:half]})
+		}
+	case ast.Sequence:
+		candidates = append(candidates, shrinkSequence(node, typ)...)
+	case ast.Prim:
+		candidates = append(candidates, shrinkPrim(node, typ)...)
+	}
+
+	return candidates
+}
+
+func shrinkSequence(node ast.Sequence, typ ast.Prim) []ast.Node {
+	if len(node.Elements) == 0 {
+		return nil
+	}
+
+	candidates := []ast.Node{
+		ast.Sequence{Elements: []ast.Node{}},
+	}
+	// Removing elements preserves the ordering of sets and maps
+	if len(node.Elements) > 1 {
+		candidates = append(
+			candidates,
+			ast.Sequence{Elements: append([]ast.Node{}, node.Elements[1:]...)},
+			ast.Sequence{Elements: append([]ast.Node{}, node.Elements[:len(node.Elements)-1]...)},
+		)
+	}
+
+	// Shrink elements (Only list elements and map values, shrinking set elements
+	// or map keys could break the ordering)
+	for i, el := range node.Elements {
+		var elCandidates []ast.Node
+		switch {
+		case typ.Prim == "list" && len(typ.Arguments) == 1:
+			elCandidates = Shrink(el, typ.Arguments[0])
+		case (typ.Prim == "map" || typ.Prim == "big_map") && len(typ.Arguments) == 2:
+			elt, ok := el.(ast.Prim)
+			if !ok || len(elt.Arguments) != 2 {
+				continue
+			}
+			for _, v := range Shrink(elt.Arguments[1], typ.Arguments[1]) {
+				elCandidates = append(elCandidates, ast.Prim{Prim: "Elt", Arguments: []ast.Node{elt.Arguments[0], v}})
+			}
+		}
+		for _, candidate := range elCandidates {
+			elements := append([]ast.Node{}, node.Elements...)
+			elements[i] = candidate
+			candidates = append(candidates, ast.Sequence{Elements: elements})
+		}
+	}
+
+	return candidates
+}
+
+func shrinkPrim(node ast.Prim, typ ast.Prim) []ast.Node {
+	candidates := make([]ast.Node, 0)
+
+	switch node.Prim {
+	case "True":
+		candidates = append(candidates, ast.Prim{Prim: "False"})
+	case "Some":
+		candidates = append(candidates, ast.Prim{Prim: "None"})
+		if len(node.Arguments) == 1 && len(typ.Arguments) == 1 {
+			for _, v := range Shrink(node.Arguments[0], typ.Arguments[0]) {
+				candidates = append(candidates, ast.Prim{Prim: "Some", Arguments: []ast.Node{v}})
+			}
+		}
+	case "Left", "Right":
+		branch := 0
+		if node.Prim == "Right" {
+			branch = 1
+		}
+		if len(node.Arguments) == 1 && len(typ.Arguments) == 2 {
+			for _, v := range Shrink(node.Arguments[0], typ.Arguments[branch]) {
+				candidates = append(candidates, ast.Prim{Prim: node.Prim, Arguments: []ast.Node{v}})
+			}
+		}
+	case "Pair":
+		if len(node.Arguments) != len(typ.Arguments) {
+			return nil
+		}
+		for i, arg := range node.Arguments {
+			for _, v := range Shrink(arg, typ.Arguments[i]) {
+				arguments := append([]ast.Node{}, node.Arguments...)
+				arguments[i] = v
+				candidates = append(candidates, ast.Prim{Prim: "Pair", Arguments: arguments})
+			}
+		}
+	}
+
+	return candidates
+}
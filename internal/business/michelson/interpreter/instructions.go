@@ -0,0 +1,1118 @@
+package interpreter
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"blockwatch.cc/tzgo/tezos"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/base58"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/binary"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/macros"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+)
+
+// Number of stack elements consumed by each supported instruction
+// (instructions with a numeric argument, like DROP n or PAIR n, are checked when they run)
+var stackArity = map[string]int{
+	"DROP": 0, "DUP": 0, "SWAP": 2, "DIG": 0, "DUG": 0, "DIP": 0, "PUSH": 0, "UNIT": 0, "NEVER": 1,
+	"CAR": 1, "CDR": 1, "PAIR": 0, "UNPAIR": 1, "GET": 1, "UPDATE": 2, "GET_AND_UPDATE": 3,
+	"SOME": 1, "NONE": 0, "LEFT": 1, "RIGHT": 1, "IF_NONE": 1, "IF_LEFT": 1, "IF_CONS": 1, "IF": 1,
+	"LOOP": 1, "LOOP_LEFT": 1, "NIL": 0, "CONS": 2, "SIZE": 1, "MAP": 1, "ITER": 1,
+	"EMPTY_SET": 0, "EMPTY_MAP": 0, "EMPTY_BIG_MAP": 0, "MEM": 2,
+	"EXEC": 2, "APPLY": 2, "LAMBDA": 0, "LAMBDA_REC": 0, "FAILWITH": 1, "CAST": 1, "RENAME": 1,
+	"ADD": 2, "SUB": 2, "SUB_MUTEZ": 2, "MUL": 2, "EDIV": 2, "ABS": 1, "ISNAT": 1, "INT": 1, "NEG": 1,
+	"LSL": 2, "LSR": 2, "AND": 2, "OR": 2, "XOR": 2, "NOT": 1,
+	"COMPARE": 2, "EQ": 1, "NEQ": 1, "LT": 1, "GT": 1, "LE": 1, "GE": 1,
+	"CONCAT": 1, "SLICE": 3, "PACK": 1, "UNPACK": 1, "BLAKE2B": 1, "SHA256": 1, "SHA512": 1,
+	"NOW": 0, "AMOUNT": 0, "BALANCE": 0, "SENDER": 0, "SOURCE": 0, "SELF": 0, "SELF_ADDRESS": 0,
+	"LEVEL": 0, "CHAIN_ID": 0, "ADDRESS": 1, "CONTRACT": 1, "TRANSFER_TOKENS": 3, "SET_DELEGATE": 1,
+	"IMPLICIT_ACCOUNT": 1,
+}
+
+// instruction executes a single instruction
+func (in *interpreter) instruction(node ast.Node) error {
+	p, ok := node.(ast.Prim)
+	if !ok {
+		return RuntimeError{Message: fmt.Sprintf("unexpected instruction %s", micheline.Print(node, "")), Position: ast.PositionOf(node)}
+	}
+
+	in.steps++
+	if in.steps > in.ctx.MaxSteps {
+		return in.errorf(p, "execution exceeded the limit of %d steps", in.ctx.MaxSteps)
+	}
+	arity, ok := stackArity[p.Prim]
+	if !ok {
+		return in.errorf(p, "instruction (%s) is not supported by the offline interpreter", p.Prim)
+	}
+	if err := in.need(p, arity); err != nil {
+		return err
+	}
+
+	switch p.Prim {
+	// Stack manipulation
+	case "DROP":
+		n, err := in.count(p, 1)
+		if err != nil {
+			return err
+		}
+		in.stack = in.stack[:len(in.stack)-n]
+	case "DUP":
+		n, err := in.count(p, 1)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return in.errorf(p, "(DUP 0) is not allowed")
+		}
+		el := in.peek(n - 1)
+		in.push(el.value, el.typ)
+	case "SWAP":
+		top, next := in.pop(), in.pop()
+		in.push(top.value, top.typ)
+		in.push(next.value, next.typ)
+	case "DIG":
+		n, err := in.count(p, 0)
+		if err != nil || in.need(p, n+1) != nil {
+			return in.errorf(p, "invalid (DIG)")
+		}
+		index := len(in.stack) - 1 - n
+		el := in.stack[index]
+		in.stack = append(in.stack[:index], in.stack[index+1:]...)
+		in.push(el.value, el.typ)
+	case "DUG":
+		n, err := in.count(p, 0)
+		if err != nil || in.need(p, n+1) != nil {
+			return in.errorf(p, "invalid (DUG)")
+		}
+		top := in.pop()
+		index := len(in.stack) - n
+		in.stack = append(in.stack[:index], append([]item{top}, in.stack[index:]...)...)
+	case "DIP":
+		n, code := 1, argument(p, 0)
+		if len(p.Arguments) == 2 {
+			var err error
+			if n, err = in.count(p, 1); err != nil {
+				return err
+			}
+			code = p.Arguments[1]
+		}
+		if err := in.need(p, n); err != nil {
+			return err
+		}
+		protected := append([]item{}, in.stack[len(in.stack)-n:]...)
+		in.stack = in.stack[:len(in.stack)-n]
+		if err := in.exec(code); err != nil {
+			return err
+		}
+		in.stack = append(in.stack, protected...)
+	case "PUSH":
+		if len(p.Arguments) != 2 {
+			return in.errorf(p, "(PUSH) expects a type and a value")
+		}
+		value, err := michelson.Normalize(p.Arguments[1], p.Arguments[0], michelson.OptimizedLegacy)
+		if err != nil {
+			return in.errorf(p, "ill-typed value. %s", strings.TrimSuffix(err.Error(), "."))
+		}
+		in.push(value, p.Arguments[0])
+	case "UNIT":
+		in.push(ast.Prim{Prim: "Unit"}, newType("unit"))
+	case "NEVER":
+		return in.errorf(p, "(NEVER) cannot be executed")
+	case "CAST", "RENAME":
+		if p.Prim == "CAST" && len(p.Arguments) == 1 {
+			top := in.pop()
+			in.push(top.value, p.Arguments[0])
+		}
+
+	// Pairs
+	case "CAR", "CDR":
+		top := in.pop()
+		left, right, ok := unpair(top.value)
+		if !ok {
+			return in.unexpected(p, top)
+		}
+		leftType, rightType := unpairType(top.typ)
+		if p.Prim == "CAR" {
+			in.push(left, leftType)
+		} else {
+			in.push(right, rightType)
+		}
+	case "PAIR":
+		n, err := in.count(p, 2)
+		if err != nil || n < 2 || in.need(p, n) != nil {
+			return in.errorf(p, "invalid (PAIR)")
+		}
+		elements := make([]item, n)
+		for i := range elements {
+			elements[i] = in.pop()
+		}
+		value, t := elements[n-1].value, elements[n-1].typ
+		for i := n - 2; i >= 0; i-- {
+			value, t = pair(elements[i].value, value), newType("pair", elements[i].typ, t)
+		}
+		in.push(value, t)
+	case "UNPAIR":
+		n, err := in.count(p, 2)
+		if err != nil || n < 2 {
+			return in.errorf(p, "invalid (UNPAIR)")
+		}
+		top := in.pop()
+		value, t := top.value, top.typ
+		elements := make([]item, 0, n)
+		for i := 0; i < n-1; i++ {
+			left, right, ok := unpair(value)
+			if !ok {
+				return in.unexpected(p, top)
+			}
+			leftType, rightType := unpairType(t)
+			elements = append(elements, item{value: left, typ: leftType})
+			value, t = right, rightType
+		}
+		elements = append(elements, item{value: value, typ: t})
+		for i := len(elements) - 1; i >= 0; i-- {
+			in.push(elements[i].value, elements[i].typ)
+		}
+	case "GET":
+		if len(p.Arguments) == 1 {
+			return in.getPair(p)
+		}
+		return in.get(p)
+	case "UPDATE":
+		if len(p.Arguments) == 1 {
+			return in.updatePair(p)
+		}
+		return in.update(p, false)
+	case "GET_AND_UPDATE":
+		return in.update(p, true)
+
+	// Options, unions and lists
+	case "SOME":
+		top := in.pop()
+		in.push(some(top.value), newType("option", top.typ))
+	case "NONE":
+		in.push(none(), newType("option", argument(p, 0)))
+	case "LEFT":
+		top := in.pop()
+		in.push(ast.Prim{Prim: "Left", Arguments: []ast.Node{top.value}}, newType("or", top.typ, argument(p, 0)))
+	case "RIGHT":
+		top := in.pop()
+		in.push(ast.Prim{Prim: "Right", Arguments: []ast.Node{top.value}}, newType("or", argument(p, 0), top.typ))
+	case "IF_NONE":
+		top := in.pop()
+		switch prim(top.value) {
+		case "None":
+			return in.exec(argument(p, 0))
+		case "Some":
+			in.push(argument(top.value, 0), argument(top.typ, 0))
+			return in.exec(argument(p, 1))
+		}
+		return in.unexpected(p, top)
+	case "IF_LEFT":
+		top := in.pop()
+		switch prim(top.value) {
+		case "Left":
+			in.push(argument(top.value, 0), argument(top.typ, 0))
+			return in.exec(argument(p, 0))
+		case "Right":
+			in.push(argument(top.value, 0), argument(top.typ, 1))
+			return in.exec(argument(p, 1))
+		}
+		return in.unexpected(p, top)
+	case "IF_CONS":
+		top := in.pop()
+		elements, ok := elementsOf(top.value)
+		if !ok {
+			return in.unexpected(p, top)
+		}
+		if len(elements) == 0 {
+			return in.exec(argument(p, 1))
+		}
+		in.push(ast.Sequence{Elements: elements[1:]}, top.typ)
+		in.push(elements[0], argument(top.typ, 0))
+		return in.exec(argument(p, 0))
+	case "IF":
+		top := in.pop()
+		switch prim(top.value) {
+		case "True":
+			return in.exec(argument(p, 0))
+		case "False":
+			return in.exec(argument(p, 1))
+		}
+		return in.unexpected(p, top)
+	case "LOOP":
+		for {
+			if err := in.need(p, 1); err != nil {
+				return err
+			}
+			top := in.pop()
+			switch prim(top.value) {
+			case "True":
+				if err := in.exec(argument(p, 0)); err != nil {
+					return err
+				}
+			case "False":
+				return nil
+			default:
+				return in.unexpected(p, top)
+			}
+		}
+	case "LOOP_LEFT":
+		for {
+			if err := in.need(p, 1); err != nil {
+				return err
+			}
+			top := in.pop()
+			switch prim(top.value) {
+			case "Left":
+				in.push(argument(top.value, 0), argument(top.typ, 0))
+				if err := in.exec(argument(p, 0)); err != nil {
+					return err
+				}
+			case "Right":
+				in.push(argument(top.value, 0), argument(top.typ, 1))
+				return nil
+			default:
+				return in.unexpected(p, top)
+			}
+		}
+	case "NIL":
+		in.push(ast.Sequence{Elements: []ast.Node{}}, newType("list", argument(p, 0)))
+	case "CONS":
+		head, list := in.pop(), in.pop()
+		elements, ok := elementsOf(list.value)
+		if !ok {
+			return in.unexpected(p, list)
+		}
+		in.push(ast.Sequence{Elements: append([]ast.Node{head.value}, elements...)}, list.typ)
+	case "SIZE":
+		top := in.pop()
+		size := 0
+		switch v := top.value.(type) {
+		case ast.String:
+			size = utf8.RuneCountInString(v.Value)
+		case ast.Bytes:
+			size = len(v.Value) / 2
+		case ast.Sequence:
+			size = len(v.Elements)
+		default:
+			return in.unexpected(p, top)
+		}
+		in.push(integer(big.NewInt(int64(size))), newType("nat"))
+	case "MAP":
+		return in.mapCollection(p)
+	case "ITER":
+		top := in.pop()
+		elements, ok := elementsOf(top.value)
+		if !ok {
+			return in.unexpected(p, top)
+		}
+		for _, el := range elements {
+			if prim(top.typ) == "map" || prim(top.typ) == "big_map" {
+				in.push(pair(argument(el, 0), argument(el, 1)), newType("pair", argument(top.typ, 0), argument(top.typ, 1)))
+			} else {
+				in.push(el, argument(top.typ, 0))
+			}
+			if err := in.exec(argument(p, 0)); err != nil {
+				return err
+			}
+		}
+	case "EMPTY_SET":
+		in.push(ast.Sequence{Elements: []ast.Node{}}, newType("set", argument(p, 0)))
+	case "EMPTY_MAP", "EMPTY_BIG_MAP":
+		in.push(ast.Sequence{Elements: []ast.Node{}}, newType(strings.ToLower(strings.TrimPrefix(p.Prim, "EMPTY_")), argument(p, 0), argument(p, 1)))
+	case "MEM":
+		key, collection := in.pop(), in.pop()
+		elements, ok := elementsOf(collection.value)
+		if !ok {
+			return in.unexpected(p, collection)
+		}
+		keyOf := mapKey
+		if prim(collection.typ) == "set" {
+			keyOf = identity
+		}
+		_, found, err := search(elements, key.value, argument(collection.typ, 0), keyOf)
+		if err != nil {
+			return in.errorf(p, "%s", strings.TrimSuffix(err.Error(), "."))
+		}
+		in.push(boolean(found), newType("bool"))
+
+	// Lambdas
+	case "LAMBDA":
+		in.push(argument(p, 2), newType("lambda", argument(p, 0), argument(p, 1)))
+	case "LAMBDA_REC":
+		in.push(ast.Prim{Prim: "Lambda_rec", Arguments: []ast.Node{argument(p, 2)}}, newType("lambda", argument(p, 0), argument(p, 1)))
+	case "EXEC":
+		arg, lambda := in.pop(), in.pop()
+		result, err := in.call(p, lambda, arg)
+		if err != nil {
+			return err
+		}
+		in.push(result.value, result.typ)
+	case "APPLY":
+		arg, lambda := in.pop(), in.pop()
+		code, ok := lambda.value.(ast.Sequence)
+		if !ok {
+			return in.errorf(p, "(APPLY) is only supported on lambdas built with (LAMBDA)")
+		}
+		argType, rest := unpairType(argument(lambda.typ, 0))
+		pushed := readable(arg.value, argType)
+		in.push(
+			ast.Sequence{Elements: []ast.Node{
+				ast.Prim{Prim: "PUSH", Arguments: []ast.Node{argType, pushed}},
+				ast.Prim{Prim: "PAIR"},
+				code,
+			}},
+			newType("lambda", rest, argument(lambda.typ, 1)),
+		)
+	case "FAILWITH":
+		top := in.pop()
+		return FailwithError{Value: readable(top.value, top.typ), Position: p.Position}
+
+	default:
+		return in.execArithmetic(p)
+	}
+
+	return nil
+}
+
+// execArithmetic executes the instructions on numbers, strings, bytes and the chain context
+func (in *interpreter) execArithmetic(p ast.Prim) error {
+	switch p.Prim {
+	case "ADD", "SUB", "MUL", "SUB_MUTEZ":
+		return in.arithmetic(p)
+	case "EDIV":
+		return in.ediv(p)
+	case "ABS", "ISNAT", "INT", "NEG":
+		top := in.pop()
+		i, ok := intOf(top.value)
+		if !ok {
+			return in.unexpected(p, top)
+		}
+		switch p.Prim {
+		case "ABS":
+			in.push(integer(i.Abs(i)), newType("nat"))
+		case "ISNAT":
+			if i.Sign() < 0 {
+				in.push(none(), newType("option", newType("nat")))
+			} else {
+				in.push(some(integer(i)), newType("option", newType("nat")))
+			}
+		case "INT":
+			in.push(integer(i), newType("int"))
+		case "NEG":
+			in.push(integer(i.Neg(i)), newType("int"))
+		}
+	case "LSL", "LSR":
+		value, shift := in.pop(), in.pop()
+		v, okV := intOf(value.value)
+		s, okS := intOf(shift.value)
+		if !okV || !okS {
+			return in.unexpected(p, value)
+		}
+		if s.Cmp(big.NewInt(256)) > 0 {
+			return in.errorf(p, "shift overflow (%s)", s)
+		}
+		if p.Prim == "LSL" {
+			in.push(integer(v.Lsh(v, uint(s.Uint64()))), newType("nat"))
+		} else {
+			in.push(integer(v.Rsh(v, uint(s.Uint64()))), newType("nat"))
+		}
+	case "AND", "OR", "XOR":
+		a, b := in.pop(), in.pop()
+		if prim(a.typ) == "bool" || prim(a.value) == "True" || prim(a.value) == "False" {
+			x, y := prim(a.value) == "True", prim(b.value) == "True"
+			switch p.Prim {
+			case "AND":
+				in.push(boolean(x && y), newType("bool"))
+			case "OR":
+				in.push(boolean(x || y), newType("bool"))
+			case "XOR":
+				in.push(boolean(x != y), newType("bool"))
+			}
+			return nil
+		}
+		x, okX := intOf(a.value)
+		y, okY := intOf(b.value)
+		if !okX || !okY {
+			return in.unexpected(p, a)
+		}
+		switch p.Prim {
+		case "AND":
+			in.push(integer(x.And(x, y)), newType("nat"))
+		case "OR":
+			in.push(integer(x.Or(x, y)), newType("nat"))
+		case "XOR":
+			in.push(integer(x.Xor(x, y)), newType("nat"))
+		}
+	case "NOT":
+		top := in.pop()
+		switch prim(top.value) {
+		case "True", "False":
+			in.push(boolean(prim(top.value) == "False"), newType("bool"))
+			return nil
+		}
+		i, ok := intOf(top.value)
+		if !ok {
+			return in.unexpected(p, top)
+		}
+		in.push(integer(i.Not(i)), newType("int"))
+	case "COMPARE":
+		a, b := in.pop(), in.pop()
+		t := a.typ
+		if t == nil {
+			t = b.typ
+		}
+		cmp, err := michelson.CompareValues(a.value, b.value, t)
+		if err != nil {
+			return in.errorf(p, "%s", strings.TrimSuffix(err.Error(), "."))
+		}
+		in.push(integer(big.NewInt(int64(cmp))), newType("int"))
+	case "EQ", "NEQ", "LT", "GT", "LE", "GE":
+		top := in.pop()
+		i, ok := intOf(top.value)
+		if !ok {
+			return in.unexpected(p, top)
+		}
+		sign := i.Sign()
+		result := map[string]bool{
+			"EQ": sign == 0, "NEQ": sign != 0, "LT": sign < 0, "GT": sign > 0, "LE": sign <= 0, "GE": sign >= 0,
+		}[p.Prim]
+		in.push(boolean(result), newType("bool"))
+	case "CONCAT":
+		return in.concat(p)
+	case "SLICE":
+		offset, length, top := in.pop(), in.pop(), in.pop()
+		o, okO := intOf(offset.value)
+		l, okL := intOf(length.value)
+		if !okO || !okL {
+			return in.unexpected(p, offset)
+		}
+		end := new(big.Int).Add(o, l)
+		switch v := top.value.(type) {
+		case ast.String:
+			if end.Cmp(big.NewInt(int64(len(v.Value)))) > 0 {
+				in.push(none(), newType("option", newType("string")))
+			} else {
+				in.push(some(ast.String{Value: v.Value[o.Int64():end.Int64()]}), newType("option", newType("string")))
+			}
+		case ast.Bytes:
+			if end.Cmp(big.NewInt(int64(len(v.Value)/2))) > 0 {
+				in.push(none(), newType("option", newType("bytes")))
+			} else {
+				in.push(some(ast.Bytes{Value: v.Value[2*o.Int64() : 2*end.Int64()]}), newType("option", newType("bytes")))
+			}
+		default:
+			return in.unexpected(p, top)
+		}
+	case "PACK":
+		top := in.pop()
+		packed, err := pack(top)
+		if err != nil {
+			return in.errorf(p, "could not pack value. %s", strings.TrimSuffix(err.Error(), "."))
+		}
+		in.push(bytesOf(packed), newType("bytes"))
+	case "UNPACK":
+		top := in.pop()
+		t := argument(p, 0)
+		in.push(unpack(top.value, t), newType("option", t))
+	case "BLAKE2B", "SHA256", "SHA512":
+		top := in.pop()
+		b, ok := bytesValue(top.value)
+		if !ok {
+			return in.unexpected(p, top)
+		}
+		var digest []byte
+		switch p.Prim {
+		case "BLAKE2B":
+			sum := tezos.Digest(b)
+			digest = sum[:]
+		case "SHA256":
+			sum := sha256.Sum256(b)
+			digest = sum[:]
+		case "SHA512":
+			sum := sha512.Sum512(b)
+			digest = sum[:]
+		}
+		in.push(bytesOf(digest), newType("bytes"))
+	default:
+		return in.execContext(p)
+	}
+
+	return nil
+}
+
+// execContext executes the instructions that read the chain context or build operations
+func (in *interpreter) execContext(p ast.Prim) error {
+	switch p.Prim {
+	case "NOW":
+		in.push(integer(big.NewInt(in.ctx.Now)), newType("timestamp"))
+	case "AMOUNT":
+		in.push(integer(mutezOf(in.ctx.Amount)), newType("mutez"))
+	case "BALANCE":
+		in.push(integer(mutezOf(in.ctx.Balance)), newType("mutez"))
+	case "LEVEL":
+		in.push(integer(big.NewInt(in.ctx.Level)), newType("nat"))
+	case "SENDER", "SOURCE", "SELF_ADDRESS":
+		address := map[string]string{"SENDER": in.ctx.Sender, "SOURCE": in.ctx.Source, "SELF_ADDRESS": in.ctx.Self}[p.Prim]
+		value, err := addressValue(address)
+		if err != nil {
+			return in.errorf(p, "%s", err)
+		}
+		in.push(value, newType("address"))
+	case "CHAIN_ID":
+		b, err := base58.DecodeChainID(in.ctx.ChainID)
+		if err != nil {
+			return in.errorf(p, "invalid chain_id (%s)", in.ctx.ChainID)
+		}
+		in.push(bytesOf(b), newType("chain_id"))
+	case "SELF":
+		entrypoint := entrypointOf(p)
+		if entrypoint == "" {
+			entrypoint = michelson.DEFAULT_ENTRYPOINT
+		}
+		entrypointType, ok := michelson.GetEntrypoints(in.parameterType)[entrypoint]
+		if !ok {
+			return in.errorf(p, "contract does not have entrypoint (%s)", entrypoint)
+		}
+		value, err := addressValue(withEntrypoint(in.ctx.Self, entrypoint))
+		if err != nil {
+			return in.errorf(p, "%s", err)
+		}
+		in.push(value, newType("contract", entrypointType))
+	case "ADDRESS":
+		top := in.pop()
+		in.push(top.value, newType("address"))
+	case "CONTRACT":
+		top := in.pop()
+		t := argument(p, 0)
+		in.push(in.contract(top.value, entrypointOf(p), t), newType("option", newType("contract", t)))
+	case "IMPLICIT_ACCOUNT":
+		top := in.pop()
+		b, ok := bytesValue(top.value)
+		if !ok {
+			return in.unexpected(p, top)
+		}
+		in.push(bytesOf(append([]byte{0x00}, b...)), newType("contract", newType("unit")))
+	case "TRANSFER_TOKENS":
+		parameter, amount, contract := in.pop(), in.pop(), in.pop()
+		b, ok := bytesValue(contract.value)
+		if !ok {
+			return in.unexpected(p, contract)
+		}
+		address, err := base58.EncodeAddress(b)
+		if err != nil {
+			return in.errorf(p, "invalid contract. %s", strings.TrimSuffix(err.Error(), "."))
+		}
+		destination, entrypoint, _ := strings.Cut(address, "%")
+		if entrypoint == "" {
+			entrypoint = michelson.DEFAULT_ENTRYPOINT
+		}
+		mutez, ok := intOf(amount.value)
+		if !ok {
+			return in.unexpected(p, amount)
+		}
+		in.push(Operation{
+			Kind:        Transaction,
+			Destination: destination,
+			Entrypoint:  entrypoint,
+			Amount:      mutez,
+			Parameter:   parameter.value,
+		}, newType("operation"))
+	case "SET_DELEGATE":
+		top := in.pop()
+		op := Operation{Kind: Delegation}
+		if prim(top.value) == "Some" {
+			b, _ := bytesValue(argument(top.value, 0))
+			delegate, err := base58.EncodeKeyHash(b)
+			if err != nil {
+				return in.errorf(p, "invalid key_hash. %s", strings.TrimSuffix(err.Error(), "."))
+			}
+			op.Delegate = delegate
+		}
+		in.push(op, newType("operation"))
+	}
+
+	return nil
+}
+
+// arithmetic executes (ADD), (SUB), (SUB_MUTEZ) and (MUL), the type of the result depends on the types of the operands
+func (in *interpreter) arithmetic(p ast.Prim) error {
+	a, b := in.pop(), in.pop()
+	x, okX := intOf(a.value)
+	y, okY := intOf(b.value)
+	if !okX || !okY {
+		return in.unexpected(p, a)
+	}
+	ta, tb := prim(a.typ), prim(b.typ)
+
+	result := new(big.Int)
+	resultType := "int"
+	switch p.Prim {
+	case "ADD":
+		result.Add(x, y)
+		switch {
+		case ta == "nat" && tb == "nat":
+			resultType = "nat"
+		case ta == "timestamp" || tb == "timestamp":
+			resultType = "timestamp"
+		case ta == "mutez":
+			resultType = "mutez"
+		}
+	case "SUB", "SUB_MUTEZ":
+		result.Sub(x, y)
+		switch {
+		case ta == "timestamp" && tb == "timestamp":
+			resultType = "int"
+		case ta == "timestamp":
+			resultType = "timestamp"
+		case ta == "mutez":
+			resultType = "mutez"
+		}
+	case "MUL":
+		result.Mul(x, y)
+		switch {
+		case ta == "nat" && tb == "nat":
+			resultType = "nat"
+		case ta == "mutez" || tb == "mutez":
+			resultType = "mutez"
+		}
+	}
+
+	if resultType == "mutez" {
+		if p.Prim == "SUB_MUTEZ" {
+			if result.Sign() < 0 {
+				in.push(none(), newType("option", newType("mutez")))
+			} else {
+				in.push(some(integer(result)), newType("option", newType("mutez")))
+			}
+			return nil
+		}
+		if err := checkMutez(result); err != nil {
+			return in.errorf(p, "%s", err)
+		}
+	}
+	in.push(integer(result), newType(resultType))
+	return nil
+}
+
+// ediv executes the euclidean division, the remainder is always positive
+func (in *interpreter) ediv(p ast.Prim) error {
+	a, b := in.pop(), in.pop()
+	x, okX := intOf(a.value)
+	y, okY := intOf(b.value)
+	if !okX || !okY {
+		return in.unexpected(p, a)
+	}
+	ta, tb := prim(a.typ), prim(b.typ)
+
+	quotientType, remainderType := "int", "nat"
+	switch {
+	case ta == "nat" && tb == "nat":
+		quotientType = "nat"
+	case ta == "mutez" && tb == "mutez":
+		quotientType, remainderType = "nat", "mutez"
+	case ta == "mutez":
+		quotientType, remainderType = "mutez", "mutez"
+	}
+	resultType := newType("option", newType("pair", newType(quotientType), newType(remainderType)))
+
+	if y.Sign() == 0 {
+		in.push(none(), resultType)
+		return nil
+	}
+	quotient, remainder := new(big.Int).DivMod(x, y, new(big.Int))
+	in.push(some(pair(integer(quotient), integer(remainder))), resultType)
+	return nil
+}
+
+// concat executes (CONCAT) on two strings (or bytes), or on a list of strings (or bytes)
+func (in *interpreter) concat(p ast.Prim) error {
+	top := in.pop()
+	var parts []ast.Node
+	if elements, ok := top.value.(ast.Sequence); ok {
+		parts = elements.Elements
+		if len(parts) == 0 {
+			switch prim(argument(top.typ, 0)) {
+			case "string":
+				in.push(ast.String{Value: ""}, newType("string"))
+			case "bytes":
+				in.push(ast.Bytes{Value: ""}, newType("bytes"))
+			default:
+				return in.errorf(p, "cannot concatenate a list of elements of unknown type")
+			}
+			return nil
+		}
+	} else {
+		if err := in.need(p, 1); err != nil {
+			return err
+		}
+		parts = []ast.Node{top.value, in.pop().value}
+	}
+
+	var builder strings.Builder
+	for _, part := range parts {
+		switch v := part.(type) {
+		case ast.String:
+			builder.WriteString(v.Value)
+		case ast.Bytes:
+			builder.WriteString(strings.ToLower(v.Value))
+		default:
+			return in.errorf(p, "cannot concatenate %s", micheline.Print(part, ""))
+		}
+	}
+	if _, ok := parts[0].(ast.String); ok {
+		in.push(ast.String{Value: builder.String()}, newType("string"))
+	} else {
+		in.push(ast.Bytes{Value: builder.String()}, newType("bytes"))
+	}
+	return nil
+}
+
+// getPair executes (GET n) on right combs: (GET 0) is the whole pair, (GET 1) is its left element and (GET 2) its right element
+func (in *interpreter) getPair(p ast.Prim) error {
+	n, err := in.count(p, 0)
+	if err != nil {
+		return err
+	}
+	top := in.pop()
+	value, t := top.value, top.typ
+	for ; n > 0; n -= 2 {
+		left, right, ok := unpair(value)
+		if !ok {
+			return in.unexpected(p, top)
+		}
+		leftType, rightType := unpairType(t)
+		if n == 1 {
+			value, t = left, leftType
+			break
+		}
+		value, t = right, rightType
+	}
+	in.push(value, t)
+	return nil
+}
+
+// updatePair executes (UPDATE n) on right combs, the element (GET n) is replaced
+func (in *interpreter) updatePair(p ast.Prim) error {
+	n, err := in.count(p, 0)
+	if err != nil {
+		return err
+	}
+	element, top := in.pop(), in.pop()
+
+	var update func(value ast.Node, t ast.Node, n int) (ast.Node, ast.Node, bool)
+	update = func(value ast.Node, t ast.Node, n int) (ast.Node, ast.Node, bool) {
+		if n == 0 {
+			return element.value, element.typ, true
+		}
+		left, right, ok := unpair(value)
+		if !ok {
+			return nil, nil, false
+		}
+		leftType, rightType := unpairType(t)
+		if n == 1 {
+			return pair(element.value, right), newType("pair", element.typ, rightType), true
+		}
+		right, rightType, ok = update(right, rightType, n-2)
+		return pair(left, right), newType("pair", leftType, rightType), ok
+	}
+
+	value, t, ok := update(top.value, top.typ, n)
+	if !ok {
+		return in.unexpected(p, top)
+	}
+	in.push(value, t)
+	return nil
+}
+
+// get executes (GET) on maps
+func (in *interpreter) get(p ast.Prim) error {
+	key, collection := in.pop(), in.pop()
+	elements, ok := elementsOf(collection.value)
+	if !ok {
+		return in.unexpected(p, collection)
+	}
+	index, found, err := search(elements, key.value, argument(collection.typ, 0), mapKey)
+	if err != nil {
+		return in.errorf(p, "%s", strings.TrimSuffix(err.Error(), "."))
+	}
+	if found {
+		in.push(some(argument(elements[index], 1)), newType("option", argument(collection.typ, 1)))
+	} else {
+		in.push(none(), newType("option", argument(collection.typ, 1)))
+	}
+	return nil
+}
+
+// update executes (UPDATE) on sets and maps, and (GET_AND_UPDATE) on maps
+func (in *interpreter) update(p ast.Prim, getAndUpdate bool) error {
+	if err := in.need(p, 3); err != nil {
+		return err
+	}
+	key, element, collection := in.pop(), in.pop(), in.pop()
+	elements, ok := elementsOf(collection.value)
+	if !ok {
+		return in.unexpected(p, collection)
+	}
+
+	if prim(collection.typ) == "set" {
+		index, found, err := search(elements, key.value, argument(collection.typ, 0), identity)
+		if err != nil {
+			return in.errorf(p, "%s", strings.TrimSuffix(err.Error(), "."))
+		}
+		switch {
+		case prim(element.value) == "True" && !found:
+			elements = insert(elements, index, key.value, false)
+		case prim(element.value) == "False" && found:
+			elements = remove(elements, index)
+		}
+		in.push(ast.Sequence{Elements: elements}, collection.typ)
+		return nil
+	}
+
+	index, found, err := search(elements, key.value, argument(collection.typ, 0), mapKey)
+	if err != nil {
+		return in.errorf(p, "%s", strings.TrimSuffix(err.Error(), "."))
+	}
+	previous := ast.Node(none())
+	if found {
+		previous = some(argument(elements[index], 1))
+	}
+	switch prim(element.value) {
+	case "Some":
+		elements = insert(elements, index, ast.Prim{Prim: "Elt", Arguments: []ast.Node{key.value, argument(element.value, 0)}}, found)
+	case "None":
+		if found {
+			elements = remove(elements, index)
+		}
+	default:
+		return in.unexpected(p, element)
+	}
+	in.push(ast.Sequence{Elements: elements}, collection.typ)
+	if getAndUpdate {
+		in.push(previous, newType("option", argument(collection.typ, 1)))
+	}
+	return nil
+}
+
+// mapCollection executes (MAP) on lists, maps and options
+func (in *interpreter) mapCollection(p ast.Prim) error {
+	top := in.pop()
+	isMap := prim(top.typ) == "map" || prim(top.typ) == "big_map"
+
+	if prim(top.typ) == "option" || prim(top.value) == "Some" || prim(top.value) == "None" {
+		if prim(top.value) == "None" {
+			in.push(top.value, top.typ)
+			return nil
+		}
+		in.push(argument(top.value, 0), argument(top.typ, 0))
+		if err := in.exec(argument(p, 0)); err != nil {
+			return err
+		}
+		if err := in.need(p, 1); err != nil {
+			return err
+		}
+		result := in.pop()
+		in.push(some(result.value), newType("option", result.typ))
+		return nil
+	}
+
+	elements, ok := elementsOf(top.value)
+	if !ok {
+		return in.unexpected(p, top)
+	}
+	results := make([]ast.Node, len(elements))
+	var resultType ast.Node
+	for i, el := range elements {
+		if isMap {
+			in.push(pair(argument(el, 0), argument(el, 1)), newType("pair", argument(top.typ, 0), argument(top.typ, 1)))
+		} else {
+			in.push(el, argument(top.typ, 0))
+		}
+		if err := in.exec(argument(p, 0)); err != nil {
+			return err
+		}
+		if err := in.need(p, 1); err != nil {
+			return err
+		}
+		result := in.pop()
+		resultType = result.typ
+		if isMap {
+			results[i] = ast.Prim{Prim: "Elt", Arguments: []ast.Node{argument(el, 0), result.value}}
+		} else {
+			results[i] = result.value
+		}
+	}
+
+	if isMap {
+		in.push(ast.Sequence{Elements: results}, newType(prim(top.typ), argument(top.typ, 0), resultType))
+	} else {
+		in.push(ast.Sequence{Elements: results}, newType("list", resultType))
+	}
+	return nil
+}
+
+// call executes a lambda with an argument, the lambda runs on its own stack
+func (in *interpreter) call(p ast.Prim, lambda item, arg item) (item, error) {
+	code := lambda.value
+	stack := []item{arg}
+	if prim(code) == "Lambda_rec" {
+		// Recursive lambdas receive themselves below their argument
+		code = argument(code, 0)
+		stack = []item{lambda, arg}
+	}
+	code, err := macros.Expand(code)
+	if err != nil {
+		return item{}, in.errorf(p, "invalid lambda. %s", strings.TrimSuffix(err.Error(), "."))
+	}
+
+	caller := in.stack
+	in.stack = stack
+	defer func() { in.stack = caller }()
+
+	if err := in.exec(code); err != nil {
+		return item{}, err
+	}
+	if len(in.stack) != 1 {
+		return item{}, in.errorf(p, "lambdas must return a single element, but the stack has %d elements", len(in.stack))
+	}
+	result := in.stack[0]
+	if t := argument(lambda.typ, 1); t != nil {
+		result.typ = t
+	}
+	return result, nil
+}
+
+// contract executes (CONTRACT), the contract must exist and its entrypoint must have the expected type
+func (in *interpreter) contract(value ast.Node, entrypoint string, t ast.Node) ast.Node {
+	b, ok := bytesValue(value)
+	if !ok {
+		return none()
+	}
+	address, err := base58.EncodeAddress(b)
+	if err != nil {
+		return none()
+	}
+	address, addressEntrypoint, _ := strings.Cut(address, "%")
+	if addressEntrypoint != "" && entrypoint != "" {
+		return none()
+	}
+	if entrypoint == "" {
+		entrypoint = addressEntrypoint
+	}
+	if entrypoint == "" {
+		entrypoint = michelson.DEFAULT_ENTRYPOINT
+	}
+
+	var parameterType ast.Node = newType("unit")
+	if strings.HasPrefix(address, "KT1") {
+		if in.ctx.ParameterType == nil {
+			return none()
+		}
+		if parameterType, ok = in.ctx.ParameterType(address); !ok {
+			return none()
+		}
+	}
+	entrypointType, ok := michelson.GetEntrypoints(parameterType)[entrypoint]
+	if !ok || !sameType(entrypointType, t) {
+		return none()
+	}
+
+	contract, err := addressValue(withEntrypoint(address, entrypoint))
+	if err != nil {
+		return none()
+	}
+	return some(contract)
+}
+
+// need verifies that the stack has enough elements for an instruction
+func (in *interpreter) need(p ast.Prim, n int) error {
+	if len(in.stack) < n {
+		return in.errorf(p, "instruction (%s) expects %d element(s) on the stack, but the stack has %d", p.Prim, n, len(in.stack))
+	}
+	return nil
+}
+
+// count reads the numeric argument of an instruction (e.g. DROP n), the default is used when the argument is omitted
+// (DROP n) and (DUP n) need n elements on the stack, including when the default is used
+func (in *interpreter) count(p ast.Prim, defaultCount int) (int, error) {
+	count := defaultCount
+	if len(p.Arguments) > 0 {
+		n, ok := intOf(p.Arguments[0])
+		if !ok || !n.IsInt64() || n.Sign() < 0 || n.Int64() > 1023 {
+			return 0, in.errorf(p, "invalid argument for (%s)", p.Prim)
+		}
+		count = int(n.Int64())
+	}
+	if err := in.need(p, map[string]int{"DROP": count, "DUP": count}[p.Prim]); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (in *interpreter) errorf(p ast.Prim, format string, args ...interface{}) error {
+	return RuntimeError{Message: fmt.Sprintf(format, args...), Position: p.Position}
+}
+
+func (in *interpreter) unexpected(p ast.Prim, el item) error {
+	return in.errorf(p, "instruction (%s) cannot be applied to %s", p.Prim, micheline.Print(readable(el.value, el.typ), ""))
+}
+
+// pack serializes a value the same way (PACK) does
+func pack(el item) ([]byte, error) {
+	if el.typ != nil {
+		return binary.Pack(el.value, el.typ)
+	}
+	// Values of the stack are already optimized
+	b, err := binary.Encode(el.value)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{binary.PACK_PREFIX}, b...), nil
+}
+
+// unpack deserializes packed bytes, (None) is returned if the bytes are not a value of the expected type
+func unpack(value ast.Node, t ast.Node) ast.Node {
+	b, ok := bytesValue(value)
+	if !ok || len(b) == 0 || b[0] != binary.PACK_PREFIX {
+		return none()
+	}
+	decoded, err := binary.Decode(b[1:])
+	if err != nil {
+		return none()
+	}
+	normalized, err := michelson.Normalize(decoded, t, michelson.OptimizedLegacy)
+	if err != nil {
+		return none()
+	}
+	return some(normalized)
+}
+
+// addressValue converts a readable address (with an optional entrypoint) to its optimized representation
+func addressValue(address string) (ast.Node, error) {
+	b, err := base58.DecodeAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address (%s). %s", address, strings.TrimSuffix(err.Error(), "."))
+	}
+	return bytesOf(b), nil
+}
+
+func withEntrypoint(address string, entrypoint string) string {
+	if entrypoint == "" || entrypoint == michelson.DEFAULT_ENTRYPOINT {
+		return address
+	}
+	return address + "%" + entrypoint
+}
+
+func mutezOf(i *big.Int) *big.Int {
+	if i == nil {
+		return new(big.Int)
+	}
+	return i
+}
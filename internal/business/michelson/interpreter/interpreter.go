@@ -0,0 +1,253 @@
+package interpreter
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/binary"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/macros"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+)
+
+type (
+	OperationKind string
+	// Context is the state of the chain seen by a contract during a call
+	Context struct {
+		Self    string   // address of the called contract
+		Sender  string   // address of the caller
+		Source  string   // address of the account that signed the operation
+		Amount  *big.Int // amount (in mutez) transferred with the call
+		Balance *big.Int // balance (in mutez) of the called contract, the amount is already credited
+		Now     int64    // timestamp (in seconds) of the block that includes the operation
+		Level   int64    // level of the block that includes the operation
+		ChainID string
+		// ParameterType gives the parameter type of the contracts known by the chain (used by CONTRACT)
+		ParameterType func(address string) (ast.Node, bool)
+		// MaxSteps limits the number of instructions executed during a call (DEFAULT_MAX_STEPS when 0)
+		MaxSteps int
+	}
+	// Operation is an internal operation emitted by a contract
+	Operation struct {
+		Kind        OperationKind
+		Destination string   // address of the recipient (transactions)
+		Entrypoint  string   // entrypoint of the recipient (transactions)
+		Amount      *big.Int // amount (in mutez) transferred to the recipient (transactions)
+		Parameter   ast.Node // parameter of the recipient entrypoint (transactions)
+		Delegate    string   // new delegate, empty when the delegate is withdrawn (delegations)
+	}
+	// Result is the outcome of a contract call
+	Result struct {
+		Storage    ast.Node // new storage (optimized representation)
+		Operations []Operation
+	}
+	// FailwithError is returned when a contract reaches a (FAILWITH) instruction
+	FailwithError struct {
+		Value    ast.Node // value given to (FAILWITH), in its readable representation
+		Position ast.Position
+	}
+	// RuntimeError is returned when an instruction cannot be executed (overflows, unsupported instructions, ill-typed stacks, ...)
+	RuntimeError struct {
+		Message  string
+		Position ast.Position
+	}
+	// item is an element of the stack, values are kept in their optimized representation with binary pairs
+	item struct {
+		value ast.Node
+		typ   ast.Node // nil when the type cannot be inferred (e.g. elements of empty collections built by MAP)
+	}
+	interpreter struct {
+		ctx           Context
+		stack         []item
+		steps         int
+		parameterType ast.Node // parameter type of the running contract (used by SELF)
+	}
+)
+
+const (
+	Transaction OperationKind = "transaction"
+	Delegation  OperationKind = "delegation"
+	// Number of instructions a call can execute when the context does not define a limit
+	DEFAULT_MAX_STEPS = 1_000_000
+)
+
+// Run executes the code of a contract for a call to one of its entrypoints.
+//
+// The parameter and the storage can be written in their readable or optimized representations,
+// the returned storage is in its optimized representation (see michelson.Normalize to convert it).
+func Run(contract michelson.Contract, entrypoint string, parameter ast.Node, storage ast.Node, ctx Context) (Result, error) {
+	if entrypoint == "" {
+		entrypoint = michelson.DEFAULT_ENTRYPOINT
+	}
+	entrypointType, ok := contract.EntrypointType(entrypoint)
+	if !ok {
+		return Result{}, fmt.Errorf("contract does not have entrypoint (%s).", entrypoint)
+	}
+	parameter, err := michelson.Normalize(parameter, entrypointType, michelson.OptimizedLegacy)
+	if err != nil {
+		return Result{}, fmt.Errorf("ill-typed parameter. %s", err)
+	}
+	storage, err = michelson.Normalize(storage, contract.Storage, michelson.OptimizedLegacy)
+	if err != nil {
+		return Result{}, fmt.Errorf("ill-typed storage. %s", err)
+	}
+	code, err := macros.Expand(contract.Code)
+	if err != nil {
+		return Result{}, err
+	}
+
+	in := interpreter{
+		ctx:           ctx,
+		parameterType: contract.Parameter,
+	}
+	if in.ctx.MaxSteps == 0 {
+		in.ctx.MaxSteps = DEFAULT_MAX_STEPS
+	}
+	in.push(
+		pair(wrapEntrypoint(contract.Parameter, entrypoint, parameter), storage),
+		newType("pair", contract.Parameter, contract.Storage),
+	)
+
+	if err := in.run(code); err != nil {
+		return Result{}, err
+	}
+
+	if len(in.stack) != 1 {
+		return Result{}, fmt.Errorf("the code must return a single element, but the stack has %d elements.", len(in.stack))
+	}
+	result, ok := in.stack[0].value.(ast.Prim)
+	if !ok || result.Prim != "Pair" || len(result.Arguments) != 2 {
+		return Result{}, fmt.Errorf("the code must return a pair (list operation) storage.")
+	}
+	operations := make([]Operation, 0)
+	if list, ok := result.Arguments[0].(ast.Sequence); ok {
+		for _, el := range list.Elements {
+			if op, ok := el.(Operation); ok {
+				operations = append(operations, op)
+			}
+		}
+	}
+
+	return Result{
+		Storage:    result.Arguments[1],
+		Operations: operations,
+	}, nil
+}
+
+// String prints an operation (operations are values of the stack)
+func (o Operation) String() string {
+	switch o.Kind {
+	case Delegation:
+		return fmt.Sprintf("Operation(%s, %s)", o.Kind, o.Delegate)
+	}
+	return fmt.Sprintf("Operation(%s, %s%%%s, %s, %s)", o.Kind, o.Destination, o.Entrypoint, o.Amount, micheline.Print(o.Parameter, ""))
+}
+
+// Error prints the error the same way 'tezos-client' reports it, so that the value can be extracted from the message
+func (e FailwithError) Error() string {
+	return fmt.Sprintf("script reached FAILWITH instruction\nwith %s\n", micheline.Print(e.Value, ""))
+}
+
+func (e RuntimeError) Error() string {
+	if e.Position.Line > 0 {
+		return fmt.Sprintf("%s (line %d, column %d).", e.Message, e.Position.Line, e.Position.Column)
+	}
+	return e.Message + "."
+}
+
+// run executes the code of a contract, unexpected failures of the interpreter are reported as runtime errors
+func (in *interpreter) run(code ast.Node) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = RuntimeError{Message: fmt.Sprintf("the offline interpreter failed to execute the code (%v)", r)}
+		}
+	}()
+	return in.exec(code)
+}
+
+// exec runs a sequence of instructions (or a single instruction)
+func (in *interpreter) exec(code ast.Node) error {
+	seq, ok := code.(ast.Sequence)
+	if !ok {
+		return in.instruction(code)
+	}
+	for _, el := range seq.Elements {
+		if err := in.exec(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (in *interpreter) push(value ast.Node, typ ast.Node) {
+	in.stack = append(in.stack, item{value: value, typ: typ})
+}
+
+func (in *interpreter) pop() item {
+	top := in.stack[len(in.stack)-1]
+	in.stack = in.stack[:len(in.stack)-1]
+	return top
+}
+
+// peek returns the element at a given depth (0 is the top of the stack)
+func (in *interpreter) peek(depth int) item {
+	return in.stack[len(in.stack)-1-depth]
+}
+
+// wrapEntrypoint wraps the parameter of an entrypoint in the (Left) and (Right) constructors
+// leading to its branch of the parameter type
+func wrapEntrypoint(parameterType ast.Node, entrypoint string, value ast.Node) ast.Node {
+	path, ok := entrypointPath(parameterType, entrypoint)
+	if !ok {
+		// The root of the parameter type is the default entrypoint
+		return value
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		value = ast.Prim{Prim: path[i], Arguments: []ast.Node{value}}
+	}
+	return value
+}
+
+func entrypointPath(typ ast.Node, entrypoint string) ([]string, bool) {
+	prim, ok := typ.(ast.Prim)
+	if !ok {
+		return nil, false
+	}
+	for _, annotation := range prim.Annotations {
+		if annotation.Kind == ast.FieldAnnotation && annotation.Value == "%"+entrypoint {
+			return []string{}, true
+		}
+	}
+	if prim.Prim != "or" || len(prim.Arguments) != 2 {
+		return nil, false
+	}
+	for i, constructor := range []string{"Left", "Right"} {
+		if path, ok := entrypointPath(prim.Arguments[i], entrypoint); ok {
+			return append([]string{constructor}, path...), true
+		}
+	}
+	return nil, false
+}
+
+// readable converts a value to its readable representation (values of unknown types are kept as is)
+func readable(value ast.Node, typ ast.Node) ast.Node {
+	if typ == nil {
+		return value
+	}
+	if converted, err := binary.Readable(value, typ); err == nil {
+		return converted
+	}
+	return value
+}
+
+// entrypointOf gives the entrypoint selected by the field annotation of an instruction (SELF, CONTRACT)
+func entrypointOf(prim ast.Prim) string {
+	for _, annotation := range prim.Annotations {
+		if annotation.Kind == ast.FieldAnnotation {
+			return strings.TrimPrefix(annotation.Value, "%")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,301 @@
+package interpreter
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	alice = "tz1VSUr8wwNhLAzempoch5d6hLRiTh8Cjcjb"
+	bob   = "tz1aSkwEot3L2kmUvcoxzjMomb9mvBNuzFK6"
+	self  = "KT1RJ6PbjHpwc3M5rw5s2Nbmefwbuwbdxton"
+)
+
+func parse(t *testing.T, s string) ast.Node {
+	node, err := michelson.ParseMicheline(s)
+	assert.NoError(t, err, s)
+	return node
+}
+
+func contractOf(t *testing.T, code string) michelson.Contract {
+	contract, err := michelson.ParseContract(parse(t, code))
+	assert.NoError(t, err, code)
+	return contract
+}
+
+// run calls a contract and returns its new storage in the readable representation
+func run(t *testing.T, code string, entrypoint string, parameter string, storage string, ctx Context) (string, []Operation, error) {
+	contract := contractOf(t, code)
+	result, err := Run(contract, entrypoint, parse(t, parameter), parse(t, storage), ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	readableStorage, err := michelson.Normalize(result.Storage, contract.Storage, michelson.Readable)
+	assert.NoError(t, err)
+	return micheline.Print(readableStorage, ""), result.Operations, nil
+}
+
+// eval runs a sequence of instructions with a (unit) parameter, the result is the storage of type t
+func eval(t *testing.T, typ string, storage string, instructions string) (string, error) {
+	code := `{ parameter unit ; storage ` + typ + ` ; code { CDR ; ` + instructions + ` ; NIL operation ; PAIR } }`
+	result, _, err := run(t, code, "", "Unit", storage, Context{Self: self, Sender: alice, Source: alice})
+	return result, err
+}
+
+func TestRun(t *testing.T) {
+	t.Run("Stack and pair instructions", func(t *testing.T) {
+		for _, tc := range []struct {
+			typ, storage, instructions, expected string
+		}{
+			{"nat", "1", "DUP ; ADD", "2"},
+			{"nat", "1", "PUSH nat 2 ; PUSH nat 3 ; DIG 2 ; DROP 2", "2"},
+			{"nat", "1", "PUSH nat 2 ; PUSH nat 3 ; DUG 2 ; DROP ; DROP", "3"},
+			{"nat", "1", "PUSH nat 5 ; DIP { DROP } ", "5"},
+			{"nat", "1", "PUSH nat 5 ; PUSH nat 6 ; DIP 2 { PUSH nat 10 } ; DROP 2 ; ADD", "11"},
+			{"nat", "7", "DUP ; DUP 2 ; SWAP ; SUB ; ABS ; ADD", "7"},
+			{"(pair nat string bool)", `(Pair 1 "a" True)`, `UNPAIR 3 ; SWAP ; DROP ; PUSH string "z" ; SWAP ; PAIR 3`, `(Pair 1 "z" (True))`},
+			{"(pair nat string bool)", `(Pair 1 "a" True)`, `PUSH string "b" ; UPDATE 3`, `(Pair 1 "b" (True))`},
+			{"(pair nat string bool)", `(Pair 1 "a" True)`, `DUP ; GET 4 ; NOT ; UPDATE 4`, `(Pair 1 "a" (False))`},
+			{"(pair nat (pair string bool))", `(Pair 1 "a" True)`, `DUP ; CDR ; CAR ; PUSH string "!" ; SWAP ; CONCAT ; UPDATE 3`, `(Pair 1 "a!" (True))`},
+			{"(pair nat nat)", `(Pair 3 4)`, `UNPAIR ; SWAP ; PAIR`, `(Pair 4 3)`},
+		} {
+			result, err := eval(t, tc.typ, tc.storage, tc.instructions)
+			assert.NoError(t, err, tc.instructions)
+			assert.Equal(t, tc.expected, result, tc.instructions)
+		}
+	})
+	t.Run("Arithmetic instructions", func(t *testing.T) {
+		for _, tc := range []struct {
+			typ, storage, instructions, expected string
+		}{
+			{"int", "3", "PUSH int -5 ; ADD", "-2"},
+			{"int", "3", "PUSH nat 5 ; SWAP ; SUB", "-2"},
+			{"int", "-7", "PUSH int 2 ; SWAP ; EDIV ; IF_NONE { PUSH int 0 } { CAR }", "-4"},
+			{"nat", "7", "PUSH nat 0 ; SWAP ; EDIV ; IF_NONE { PUSH nat 42 } { CDR }", "42"},
+			{"nat", "7", "PUSH nat 3 ; SWAP ; EDIV ; IF_NONE { PUSH nat 0 } { CDR }", "1"},
+			{"int", "-7", "NEG ; PUSH int 6 ; MUL", "42"},
+			{"nat", "1", "PUSH nat 4 ; SWAP ; LSL", "16"},
+			{"nat", "12", "PUSH nat 10 ; AND ; PUSH nat 1 ; OR ; PUSH nat 3 ; XOR", "10"},
+			{"mutez", "1000", "PUSH mutez 15 ; ADD ; PUSH nat 2 ; MUL", "2030"},
+			{"mutez", "10", "PUSH mutez 15 ; SWAP ; SUB_MUTEZ ; IF_NONE { PUSH mutez 1 } {}", "1"},
+			{"timestamp", `"1970-01-01T00:00:00Z"`, "PUSH int 3600 ; ADD", `"1970-01-01T01:00:00Z"`},
+			{"int", "0", `DROP ; PUSH timestamp "1970-01-01T00:01:00Z" ; PUSH timestamp 0 ; SWAP ; SUB`, "60"},
+			{"(option nat)", "None", "DROP ; PUSH int -3 ; ISNAT", "(None)"},
+			{"bool", "False", "PUSH nat 2 ; PUSH nat 1 ; COMPARE ; LT ; OR", "(True)"},
+			{"bool", "True", `PUSH string "b" ; PUSH string "a" ; COMPARE ; GE ; AND`, "(False)"},
+		} {
+			result, err := eval(t, tc.typ, tc.storage, tc.instructions)
+			assert.NoError(t, err, tc.instructions)
+			assert.Equal(t, tc.expected, result, tc.instructions)
+		}
+	})
+	t.Run("Collection instructions", func(t *testing.T) {
+		for _, tc := range []struct {
+			typ, storage, instructions, expected string
+		}{
+			{"(list nat)", "{ 1 ; 2 ; 3 }", "MAP { PUSH nat 10 ; MUL }", "{ 10; 20; 30 }"},
+			{"(list nat)", "{ 1 ; 2 }", "PUSH nat 0 ; CONS", "{ 0; 1; 2 }"},
+			{"nat", "0", "DROP ; PUSH (list nat) { 1 ; 2 ; 3 } ; PUSH nat 0 ; SWAP ; ITER { ADD }", "6"},
+			{"(list nat)", "{ 1 ; 2 }", "IF_CONS { DROP } { NIL nat }", "{ 2 }"},
+			{"(set nat)", "{ 1 ; 5 }", "PUSH bool True ; PUSH nat 3 ; UPDATE ; PUSH bool False ; PUSH nat 1 ; UPDATE", "{ 3; 5 }"},
+			{"bool", "False", "DROP ; PUSH (set string) { \"a\" ; \"b\" } ; PUSH string \"b\" ; MEM", "(True)"},
+			{"(map string nat)", `{ Elt "b" 2 }`, `PUSH (option nat) (Some 1) ; PUSH string "a" ; UPDATE ; PUSH (option nat) (Some 3) ; PUSH string "b" ; UPDATE`, `{ Elt "a" 1; Elt "b" 3 }`},
+			{"(map string nat)", `{ Elt "a" 1; Elt "b" 2 }`, `NONE nat ; PUSH string "a" ; GET_AND_UPDATE ; IF_NONE { PUSH nat 0 } {} ; SOME ; PUSH string "c" ; UPDATE`, `{ Elt "b" 2; Elt "c" 1 }`},
+			{"(map string nat)", `{ Elt "a" 1; Elt "b" 2 }`, `MAP { CDR ; PUSH nat 1 ; ADD }`, `{ Elt "a" 2; Elt "b" 3 }`},
+			{"(option nat)", "None", `DROP ; PUSH (big_map nat nat) { Elt 1 10 } ; PUSH nat 1 ; GET`, "(Some 10)"},
+			{"nat", "0", `DROP ; PUSH (map nat unit) { Elt 1 Unit; Elt 2 Unit } ; SIZE`, "2"},
+			{"(option bytes)", "None", `DROP ; PUSH bytes 0x0a0b0c ; PUSH nat 2 ; PUSH nat 1 ; SLICE`, "(Some 0x0b0c)"},
+			{"string", `"a"`, `NIL string ; PUSH string "c" ; CONS ; PUSH string "b" ; CONS ; SWAP ; CONS ; CONCAT`, `"abc"`},
+		} {
+			result, err := eval(t, tc.typ, tc.storage, tc.instructions)
+			assert.NoError(t, err, tc.instructions)
+			assert.Equal(t, tc.expected, result, tc.instructions)
+		}
+	})
+	t.Run("Control flow and lambdas", func(t *testing.T) {
+		for _, tc := range []struct {
+			typ, storage, instructions, expected string
+		}{
+			{"nat", "5", "PUSH nat 1 ; SWAP ; PUSH bool True ; LOOP { DUP ; DIP { MUL } ; PUSH nat 1 ; SWAP ; SUB ; ISNAT ; IF_NONE { PUSH nat 0 } {} ; DUP ; INT ; NEQ } ; DROP", "120"},
+			{"nat", "0", "DROP ; PUSH nat 10 ; LEFT nat ; LOOP_LEFT { PUSH int 1 ; SWAP ; SUB ; ISNAT ; IF_NONE { PUSH nat 7 ; RIGHT nat } { LEFT nat } }", "7"},
+			{"nat", "4", "LAMBDA nat nat { PUSH nat 2 ; MUL } ; SWAP ; EXEC", "8"},
+			{"nat", "4", "LAMBDA (pair nat nat) nat { UNPAIR ; ADD } ; PUSH nat 10 ; APPLY ; SWAP ; EXEC", "14"},
+			{"nat", "5", "LAMBDA_REC nat nat { DUP ; INT ; EQ ; IF { DROP 2 ; PUSH nat 1 } { DUP ; DUP 3 ; PUSH nat 1 ; DIG 2 ; SUB ; ABS ; EXEC ; MUL ; DIP { DROP } } } ; SWAP ; EXEC", "120"},
+			{"(or nat string)", "(Left 1)", `IF_LEFT { DROP ; PUSH string "x" ; RIGHT nat } { LEFT string }`, `(Right "x")`},
+			{"(option bytes)", "None", `DROP ; PUSH (pair nat string) (Pair 1 "a") ; PACK ; UNPACK (pair nat string) ; IF_NONE { NONE bytes } { PACK ; SOME }`, `(Some 0x0507070001010000000161)`},
+		} {
+			result, err := eval(t, tc.typ, tc.storage, tc.instructions)
+			assert.NoError(t, err, tc.instructions)
+			assert.Equal(t, tc.expected, result, tc.instructions)
+		}
+	})
+}
+
+func TestRunErrors(t *testing.T) {
+	t.Run("FAILWITH reports its value like tezos-client", func(t *testing.T) {
+		_, err := eval(t, "nat", "1", `PUSH (pair nat string) (Pair 1 "error") ; FAILWITH`)
+		assert.IsType(t, FailwithError{}, err)
+		assert.Equal(t, "script reached FAILWITH instruction\nwith (Pair 1 \"error\")\n", err.Error())
+
+		_, err = eval(t, "address", `"`+alice+`"`, `FAILWITH`)
+		assert.Equal(t, "script reached FAILWITH instruction\nwith \""+alice+"\"\n", err.Error())
+	})
+	t.Run("Runtime errors", func(t *testing.T) {
+		_, err := eval(t, "mutez", "9223372036854775807", `PUSH mutez 1 ; ADD`)
+		assert.EqualError(t, err, "mutez overflow (line 1, column 64).")
+
+		_, err = eval(t, "nat", "1", `DROP 2`)
+		assert.EqualError(t, err, "instruction (DROP) expects 2 element(s) on the stack, but the stack has 1 (line 1, column 47).")
+
+		_, err = eval(t, "nat", "1", `PUSH nat 300 ; SWAP ; LSL`)
+		assert.EqualError(t, err, "shift overflow (300) (line 1, column 69).")
+
+		_, err = eval(t, "nat", "1", `PUSH (list nat) {} ; DROP ; VOTING_POWER`)
+		assert.EqualError(t, err, "instruction (VOTING_POWER) is not supported by the offline interpreter (line 1, column 75).")
+
+		_, err = Run(contractOf(t, `{ parameter unit ; storage nat ; code { CDR ; NIL operation ; PAIR } }`), "", parse(t, `"a"`), parse(t, "1"), Context{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "ill-typed parameter.")
+
+		_, err = Run(contractOf(t, `{ parameter unit ; storage nat ; code { CDR ; NIL operation ; PAIR } }`), "other", parse(t, `Unit`), parse(t, "1"), Context{})
+		assert.EqualError(t, err, "contract does not have entrypoint (other).")
+	})
+	t.Run("Instructions check the stack before using it", func(t *testing.T) {
+		for code, message := range map[string]string{
+			`{ parameter unit ; storage unit ; code { DROP ; DROP ; UNIT ; NIL operation ; PAIR } }`:          "instruction (DROP) expects 1 element(s) on the stack, but the stack has 0 (line 1, column 49).",
+			`{ parameter unit ; storage unit ; code { DROP ; DUP ; NIL operation ; PAIR } }`:                  "instruction (DUP) expects 1 element(s) on the stack, but the stack has 0 (line 1, column 49).",
+			`{ parameter unit ; storage unit ; code { DIP { DROP } ; CDR ; NIL operation ; PAIR } }`:          "instruction (DROP) expects 1 element(s) on the stack, but the stack has 0 (line 1, column 48).",
+			`{ parameter unit ; storage unit ; code { CDR ; PUSH nat 1 ; LOOP { } ; NIL operation ; PAIR } }`: "instruction (LOOP) cannot be applied to 1 (line 1, column 61).",
+		} {
+			_, _, err := run(t, code, "", "Unit", "Unit", Context{})
+			assert.IsType(t, RuntimeError{}, err, code)
+			assert.EqualError(t, err, message, code)
+		}
+	})
+	t.Run("Execution is limited", func(t *testing.T) {
+		code := `{ parameter unit ; storage nat ; code { CDR ; PUSH bool True ; LOOP { PUSH bool True } ; NIL operation ; PAIR } }`
+		_, _, err := run(t, code, "", "Unit", "0", Context{MaxSteps: 100})
+		assert.EqualError(t, err, "execution exceeded the limit of 100 steps (line 1, column 71).")
+	})
+}
+
+func TestRunContext(t *testing.T) {
+	ctx := Context{
+		Self:    self,
+		Sender:  alice,
+		Source:  bob,
+		Amount:  big.NewInt(10),
+		Balance: big.NewInt(110),
+		Now:     60,
+		Level:   12,
+		ChainID: "NetXynUjJNZm7wi",
+		ParameterType: func(address string) (ast.Node, bool) {
+			if address == self {
+				return parse(t, `(or (nat %deposit) (unit %withdraw))`), true
+			}
+			return nil, false
+		},
+	}
+	code := `{
+		parameter (or (nat %deposit) (unit %withdraw)) ;
+		storage (pair (address %sender) (address %source) (address %self) (mutez %amount) (mutez %balance) (timestamp %now) (nat %level) (chain_id %chain)) ;
+		code {
+			DROP ;
+			CHAIN_ID ; LEVEL ; NOW ; BALANCE ; AMOUNT ; SELF_ADDRESS ; SOURCE ; SENDER ; PAIR 8 ;
+			NIL operation ; PAIR
+		}
+	}`
+	storage, _, err := run(t, code, "withdraw", "Unit", `(Pair "`+alice+`" "`+alice+`" "`+alice+`" 0 0 0 0 "NetXynUjJNZm7wi")`, ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, `(Pair "`+alice+`" "`+bob+`" "`+self+`" 10 110 "1970-01-01T00:01:00Z" 12 "NetXynUjJNZm7wi")`, storage)
+
+	t.Run("Operations", func(t *testing.T) {
+		code := `{
+			parameter (or (nat %deposit) (unit %withdraw)) ;
+			storage unit ;
+			code {
+				CAR ;
+				IF_LEFT
+					{
+						DROP ;
+						NIL operation ;
+						SELF %withdraw ; PUSH mutez 5 ; UNIT ; TRANSFER_TOKENS ; CONS ;
+						SENDER ; CONTRACT unit ; IF_NONE { PUSH string "no contract" ; FAILWITH } {} ; PUSH mutez 1 ; UNIT ; TRANSFER_TOKENS ; CONS ;
+						PUSH key_hash "` + bob + `" ; SOME ; SET_DELEGATE ; CONS
+					}
+					{
+						DROP ;
+						SELF_ADDRESS ; CONTRACT %deposit nat ; IF_NONE { PUSH string "no deposit" ; FAILWITH } {} ; PUSH mutez 0 ; PUSH nat 3 ; TRANSFER_TOKENS ;
+						SELF_ADDRESS ; CONTRACT %deposit int ; IF_NONE {} { PUSH string "ill-typed contract" ; FAILWITH } ;
+						PUSH address "` + bob + `" ; CONTRACT nat ; IF_NONE {} { PUSH string "implicit accounts only accept unit" ; FAILWITH } ;
+						NIL operation ; SWAP ; CONS
+					} ;
+				UNIT ; SWAP ; PAIR
+			}
+		}`
+		_, operations, err := run(t, code, "deposit", "1", "Unit", ctx)
+		assert.NoError(t, err)
+		if assert.Len(t, operations, 3) {
+			assert.Equal(t, Operation{Kind: Delegation, Delegate: bob}, operations[0])
+			assert.Equal(t, "Operation(transaction, "+alice+"%default, 1, (Unit))", operations[1].String())
+			assert.Equal(t, "Operation(transaction, "+self+"%withdraw, 5, (Unit))", operations[2].String())
+		}
+
+		_, operations, err = run(t, code, "withdraw", "Unit", "Unit", ctx)
+		assert.NoError(t, err)
+		if assert.Len(t, operations, 1) {
+			assert.Equal(t, "Operation(transaction, "+self+"%deposit, 0, 3)", operations[0].String())
+		}
+	})
+	t.Run("Hashes", func(t *testing.T) {
+		result, err := eval(t, "(list bytes)", "{}", `DROP ; NIL bytes ; PUSH bytes 0x00 ; SHA512 ; CONS ; PUSH bytes 0x00 ; SHA256 ; CONS ; PUSH bytes 0x00 ; BLAKE2B ; CONS`)
+		assert.NoError(t, err)
+		assert.Equal(t, "{ 0x03170a2e7597b7b7e3d84c05391d139a62b157e78786d8c082f29dcf4c111314; 0x6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d; 0xb8244d028981d693af7b456af8efa4cad63d282e19ff14942c246e50d9351d22704a802a71c3580b6370de4ceb293c324a8423342557d4e5c38438f0e36910ee }", result)
+	})
+}
+
+func TestRunFA2(t *testing.T) {
+	code, err := os.ReadFile("../__test_data__/fa2_contract.tz")
+	assert.NoError(t, err)
+	contract := contractOf(t, string(code))
+
+	storage := parse(t, `(Pair (Pair "`+alice+`" False) (Pair {} (Pair {} (Pair {} (Pair {} 0)))) {})`)
+	call := func(entrypoint string, parameter string, sender string) (Result, error) {
+		result, err := Run(contract, entrypoint, parse(t, parameter), storage, Context{Self: self, Sender: sender, Source: sender})
+		if err == nil {
+			storage = result.Storage
+		}
+		return result, err
+	}
+
+	_, err = call("mint", `(Pair "`+alice+`" 100 1 {})`, alice)
+	assert.NoError(t, err)
+	_, err = call("mint", `(Pair "`+alice+`" 100 1 {})`, bob)
+	assert.EqualError(t, err, "script reached FAILWITH instruction\nwith \"FA2__NOT_ADMIN\"\n")
+
+	_, err = call("transfer", `{ Pair "`+alice+`" { Pair "`+bob+`" 1 30 } }`, alice)
+	assert.NoError(t, err)
+	_, err = call("transfer", `{ Pair "`+bob+`" { Pair "`+alice+`" 1 31 } }`, bob)
+	assert.EqualError(t, err, "script reached FAILWITH instruction\nwith \"FA2_INSUFFICIENT_BALANCE\"\n")
+
+	ledger, err := michelson.Normalize(storage, contract.Storage, michelson.Readable)
+	assert.NoError(t, err)
+	assert.Contains(t, micheline.Print(ledger, ""), `{ Elt (Pair "`+alice+`" 1) 70; Elt (Pair "`+bob+`" 1) 30 }`)
+
+	result, err := call("balance_of", `(Pair { Pair "`+bob+`" 1 } "`+self+`%callback")`, alice)
+	assert.NoError(t, err)
+	if assert.Len(t, result.Operations, 1) {
+		callback := result.Operations[0]
+		assert.Equal(t, self, callback.Destination)
+		assert.Equal(t, "callback", callback.Entrypoint)
+		response, err := michelson.Normalize(callback.Parameter, parse(t, `(list (pair (pair address nat) nat))`), michelson.Readable)
+		assert.NoError(t, err)
+		assert.Equal(t, `{ Pair (Pair "`+bob+`" 1) 30 }`, micheline.Print(response, ""))
+	}
+}
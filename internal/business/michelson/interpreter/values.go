@@ -0,0 +1,182 @@
+package interpreter
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+)
+
+// Maximum value of type (mutez)
+var maxMutez = new(big.Int).SetUint64(1<<63 - 1)
+
+func newType(name string, arguments ...ast.Node) ast.Prim {
+	return ast.Prim{Prim: name, Arguments: arguments}
+}
+
+func pair(left ast.Node, right ast.Node) ast.Prim {
+	return ast.Prim{Prim: "Pair", Arguments: []ast.Node{left, right}}
+}
+
+func some(value ast.Node) ast.Prim {
+	return ast.Prim{Prim: "Some", Arguments: []ast.Node{value}}
+}
+
+func none() ast.Prim {
+	return ast.Prim{Prim: "None"}
+}
+
+func boolean(b bool) ast.Prim {
+	if b {
+		return ast.Prim{Prim: "True"}
+	}
+	return ast.Prim{Prim: "False"}
+}
+
+func integer(i *big.Int) ast.Int {
+	return ast.Int{Value: i.String()}
+}
+
+func bytesOf(b []byte) ast.Bytes {
+	return ast.Bytes{Value: hex.EncodeToString(b)}
+}
+
+// prim returns the name of a primitive ("" for other nodes)
+func prim(node ast.Node) string {
+	if p, ok := node.(ast.Prim); ok {
+		return p.Prim
+	}
+	return ""
+}
+
+// argument returns the argument of a primitive (nil if it does not exist)
+func argument(node ast.Node, index int) ast.Node {
+	if p, ok := node.(ast.Prim); ok && index < len(p.Arguments) {
+		return p.Arguments[index]
+	}
+	return nil
+}
+
+// unpair splits a pair value (values use binary pairs)
+func unpair(value ast.Node) (ast.Node, ast.Node, bool) {
+	p, ok := value.(ast.Prim)
+	if !ok || p.Prim != "Pair" || len(p.Arguments) < 2 {
+		return nil, nil, false
+	}
+	if len(p.Arguments) > 2 {
+		return p.Arguments[0], ast.Prim{Prim: "Pair", Arguments: p.Arguments[1:]}, true
+	}
+	return p.Arguments[0], p.Arguments[1], true
+}
+
+// unpairType splits a pair type, right combs (pair a b c) are read as (pair a (pair b c))
+func unpairType(t ast.Node) (ast.Node, ast.Node) {
+	p, ok := t.(ast.Prim)
+	if !ok || p.Prim != "pair" || len(p.Arguments) < 2 {
+		return nil, nil
+	}
+	if len(p.Arguments) > 2 {
+		return p.Arguments[0], newType("pair", p.Arguments[1:]...)
+	}
+	return p.Arguments[0], p.Arguments[1]
+}
+
+func intOf(node ast.Node) (*big.Int, bool) {
+	i, ok := node.(ast.Int)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Int).SetString(i.Value, 10)
+}
+
+func bytesValue(node ast.Node) ([]byte, bool) {
+	b, ok := node.(ast.Bytes)
+	if !ok {
+		return nil, false
+	}
+	decoded, err := hex.DecodeString(b.Value)
+	return decoded, err == nil
+}
+
+// comparableType strips annotations and converts right combs to binary pairs, so that types can be compared
+func comparableType(t ast.Node) ast.Node {
+	p, ok := t.(ast.Prim)
+	if !ok {
+		return t
+	}
+	if p.Prim == "pair" && len(p.Arguments) > 2 {
+		return newType("pair", comparableType(p.Arguments[0]), comparableType(newType("pair", p.Arguments[1:]...)))
+	}
+	arguments := make([]ast.Node, len(p.Arguments))
+	for i, arg := range p.Arguments {
+		arguments[i] = comparableType(arg)
+	}
+	return newType(p.Prim, arguments...)
+}
+
+func sameType(a ast.Node, b ast.Node) bool {
+	return ast.Equal(comparableType(a), comparableType(b), ast.IgnorePositions, ast.IgnoreAnnotations)
+}
+
+// search finds the index of a key in a sorted collection (sets and maps), keyOf gives the key of an element
+func search(elements []ast.Node, key ast.Node, keyType ast.Node, keyOf func(ast.Node) ast.Node) (int, bool, error) {
+	var err error
+	index := sort.Search(len(elements), func(i int) bool {
+		cmp, cmpErr := michelson.CompareValues(keyOf(elements[i]), key, keyType)
+		if cmpErr != nil {
+			err = cmpErr
+		}
+		return cmp >= 0
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if index < len(elements) {
+		cmp, err := michelson.CompareValues(keyOf(elements[index]), key, keyType)
+		if err != nil {
+			return 0, false, err
+		}
+		return index, cmp == 0, nil
+	}
+	return index, false, nil
+}
+
+func identity(node ast.Node) ast.Node { return node }
+
+func mapKey(node ast.Node) ast.Node { return argument(node, 0) }
+
+func elementsOf(value ast.Node) ([]ast.Node, bool) {
+	seq, ok := value.(ast.Sequence)
+	return seq.Elements, ok
+}
+
+// insert returns a copy of a collection with an element inserted (or replaced) at a given index
+func insert(elements []ast.Node, index int, element ast.Node, replace bool) []ast.Node {
+	result := make([]ast.Node, 0, len(elements)+1)
+	result = append(result, elements[:index]...)
+	result = append(result, element)
+	if replace {
+		index++
+	}
+	return append(result, elements[index:]...)
+}
+
+// remove returns a copy of a collection without the element at a given index
+func remove(elements []ast.Node, index int) []ast.Node {
+	result := make([]ast.Node, 0, len(elements))
+	result = append(result, elements[:index]...)
+	return append(result, elements[index+1:]...)
+}
+
+func checkMutez(i *big.Int) error {
+	if i.Sign() < 0 {
+		return fmt.Errorf("mutez underflow")
+	}
+	if i.Cmp(maxMutez) > 0 {
+		return fmt.Errorf("mutez overflow")
+	}
+	return nil
+}
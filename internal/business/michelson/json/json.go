@@ -10,16 +10,3 @@ type (
 		Annots []string      `json:"annots,omitempty"`
 	}
 )
-
-func (json MichelsonJSON) isInt() bool {
-	return json.Int != ""
-}
-func (json MichelsonJSON) isString() bool {
-	return json.String != nil
-}
-func (json MichelsonJSON) isBytes() bool {
-	return json.Bytes != ""
-}
-func (json MichelsonJSON) isPrim() bool {
-	return json.Prim != ""
-}
@@ -4,98 +4,202 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
 )
 
+// Parser parses Michelson JSON into an AST.
+//
+// Nodes carry their position in the raw JSON and errors report the JSON pointer of the offending element.
 type Parser struct {
-	errors []string
+	source ast.Source
+	raw    []byte
+	offset int
+
+	errors []ast.SyntaxError
 }
 
+// errAbort interrupts parsing after a syntax error that cannot be recovered
+var errAbort = errors.New("abort")
+
 // Parse parses raw JSON into a Michelson AST
 func (p *Parser) Parse(raw []byte) (node ast.Node, err error) {
+	p.source = ast.NewSource(string(raw))
+	p.raw = raw
+	p.offset = 0
+	p.errors = nil
+
 	defer func() {
 		if len(p.errors) > 0 {
-			err = errors.New(strings.Join(p.errors, ";\n"))
+			err = p.Error()
 		}
 	}()
 
-	r, err := unmarshal(raw)
+	node, err = p.parseNode("")
 	if err != nil {
-		p.errorf("could not deserialize JSON: %s.", err)
-	}
-
-	switch obj := r.(type) {
-	case MichelsonJSON:
-		switch {
-		case obj.isInt():
-			node = ast.Int{
-				Value: obj.Int,
-			}
-		case obj.isString():
-			node = ast.String{
-				Value: *obj.String,
-			}
-		case obj.isBytes():
-			node = ast.Bytes{
-				Value: obj.Bytes,
-			}
-		case obj.isPrim():
-			prim := ast.Prim{
-				Prim: obj.Prim,
-			}
-			if len(obj.Annots) > 0 {
-				prim.Annotations = make([]ast.Annotation, len(obj.Annots))
-				for i, el := range obj.Annots {
-					prim.Annotations[i] = p.parseAnnotation(el)
-				}
-			}
-			if len(obj.Args) > 0 {
-				prim.Arguments = make([]ast.Node, len(obj.Args))
-				for i, el := range obj.Args {
-					o, err := json.Marshal(el)
-					if err != nil {
-						p.errorf("could not parse argument of prim: %s.", err)
-						break
-					}
-					prim.Arguments[i], _ = p.Parse(o)
+		return
+	}
+	if p.skipWhitespace(); p.offset < len(p.raw) {
+		p.errorf(p.offset, "", "unexpected data after Michelson JSON.")
+	}
+
+	// Errors found during parsing will be aggregated on defer
+	return
+}
+
+// Errors returns the syntax errors found while parsing
+func (p *Parser) Errors() []ast.SyntaxError {
+	return p.errors
+}
+
+// Error aggregates all syntax errors into a single error
+func (p *Parser) Error() error {
+	if len(p.errors) == 0 {
+		return nil
+	}
+	_errors := make([]string, 0, len(p.errors))
+	for _, err := range p.errors {
+		_errors = append(_errors, err.Error())
+	}
+	return errors.New(strings.Join(_errors, ";\n"))
+}
+
+// parseNode parses a Michelson node (an object or a sequence) located at a given JSON pointer
+func (p *Parser) parseNode(pointer string) (ast.Node, error) {
+	p.skipWhitespace()
+	switch p.peek() {
+	case '[':
+		return p.parseSequence(pointer)
+	case '{':
+		return p.parseObject(pointer)
+	case 0:
+		p.errorf(p.offset, pointer, "unexpected end of JSON.")
+		return nil, errAbort
+	default:
+		begin := p.offset
+		if err := p.skipValue(pointer); err != nil {
+			return nil, err
+		}
+		p.errorf(begin, pointer, "expected a Michelson object or sequence, but received (%s).", p.raw[begin:p.offset])
+		return nil, nil
+	}
+}
+
+func (p *Parser) parseSequence(pointer string) (ast.Node, error) {
+	begin := p.offset
+	elements := make([]ast.Node, 0)
+
+	err := p.parseArray(pointer, func(index int) error {
+		element, err := p.parseNode(fmt.Sprintf("%s/%d", pointer, index))
+		if element != nil {
+			elements = append(elements, element)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.Sequence{
+		Position: p.source.Span(begin, p.offset-1),
+		Elements: elements,
+	}, nil
+}
+
+func (p *Parser) parseObject(pointer string) (ast.Node, error) {
+	begin := p.offset
+
+	var (
+		prim, integer, str, bytes *string
+		args                      []ast.Node
+		annotations               []ast.Annotation
+	)
+	err := p.parseFields(pointer, func(key string, fieldPointer string) (err error) {
+		switch key {
+		case "prim":
+			prim, err = p.parseStringField(fieldPointer)
+		case "int":
+			integer, err = p.parseStringField(fieldPointer)
+		case "string":
+			str, err = p.parseStringField(fieldPointer)
+		case "bytes":
+			bytes, err = p.parseStringField(fieldPointer)
+		case "args":
+			args = make([]ast.Node, 0)
+			err = p.parseArray(fieldPointer, func(index int) error {
+				arg, err := p.parseNode(fmt.Sprintf("%s/%d", fieldPointer, index))
+				if arg != nil {
+					args = append(args, arg)
 				}
-			}
-			node = prim
+				return err
+			})
+		case "annots":
+			annotations = make([]ast.Annotation, 0)
+			err = p.parseArray(fieldPointer, func(index int) error {
+				annotation, err := p.parseAnnotation(fmt.Sprintf("%s/%d", fieldPointer, index))
+				annotations = append(annotations, annotation)
+				return err
+			})
 		default:
-			p.errorf("unexpected Michelson JSON: %s.", string(raw))
+			// Unknown fields are ignored
+			err = p.skipValue(fieldPointer)
 		}
-	case []json.RawMessage:
-		elements := make([]ast.Node, len(obj))
-		for i, el := range obj {
-			o, err := json.Marshal(el)
-			if err != nil {
-				p.errorf("could not parse element of sequence: %s.", err)
-				break
-			}
-			elements[i], _ = p.Parse(o)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	position := p.source.Span(begin, p.offset-1)
+	switch {
+	case integer != nil:
+		if *integer == "" {
+			p.errorf(begin, pointer+"/int", "unexpected empty integer.")
 		}
-		node = ast.Sequence{
-			Elements: elements,
+		return ast.Int{Position: position, Value: *integer}, nil
+	case str != nil:
+		return ast.String{Position: position, Value: *str}, nil
+	case bytes != nil:
+		return ast.Bytes{Position: position, Value: *bytes}, nil
+	case prim != nil:
+		if *prim == "" {
+			p.errorf(begin, pointer+"/prim", "unexpected empty primitive.")
 		}
-	default:
-		p.errorf("unexpected Michelson JSON: %s.", string(raw))
+		node := ast.Prim{
+			Position: position,
+			Prim:     *prim,
+		}
+		if len(annotations) > 0 {
+			node.Annotations = annotations
+		}
+		if len(args) > 0 {
+			node.Arguments = args
+		}
+		return node, nil
 	}
 
-	// Errors found during parsing will be aggregated on defer
-	return
+	p.errorf(begin, pointer, "unexpected Michelson JSON: %s.", p.raw[begin:p.offset])
+	return nil, nil
 }
 
-func (p *Parser) parseAnnotation(annot string) (annotation ast.Annotation) {
-	annotation.Value = annot
+func (p *Parser) parseAnnotation(pointer string) (annotation ast.Annotation, err error) {
+	begin := p.offset
+	value, err := p.parseStringField(pointer)
+	if err != nil || value == nil {
+		return
+	}
+
+	annotation.Position = p.source.Span(begin, p.offset-1)
+	annotation.Value = *value
 
-	if len(annot) == 0 {
-		p.errorf("Unexpected empty annotation.")
+	if len(annotation.Value) == 0 {
+		p.errorf(begin, pointer, "Unexpected empty annotation.")
 		return
 	}
 
-	switch annot[0] {
+	switch annotation.Value[0] {
 	case ':':
 		annotation.Kind = ast.TypeAnnotation
 	case '@':
@@ -103,24 +207,194 @@ func (p *Parser) parseAnnotation(annot string) (annotation ast.Annotation) {
 	case '%':
 		annotation.Kind = ast.FieldAnnotation
 	default:
-		p.errorf("Unexpected annotation (%s).", annot)
+		p.errorf(begin, pointer, "Unexpected annotation (%s).", annotation.Value)
 	}
 
 	return
 }
 
-func (p *Parser) errorf(format string, args ...interface{}) {
-	p.errors = append(p.errors, fmt.Sprintf(format, args...))
+// parseStringField parses a JSON string, nil is returned if the value is not a string
+func (p *Parser) parseStringField(pointer string) (*string, error) {
+	p.skipWhitespace()
+	begin := p.offset
+	if p.peek() != '"' {
+		if err := p.skipValue(pointer); err != nil {
+			return nil, err
+		}
+		p.errorf(begin, pointer, "expected a string, but received (%s).", p.raw[begin:p.offset])
+		return nil, nil
+	}
+
+	if err := p.skipString(pointer); err != nil {
+		return nil, err
+	}
+	var value string
+	if err := json.Unmarshal(p.raw[begin:p.offset], &value); err != nil {
+		p.errorf(begin, pointer, "invalid string: %s.", err)
+		return nil, nil
+	}
+	return &value, nil
+}
+
+// parseArray parses a JSON array, calling (parseElement) for each element
+func (p *Parser) parseArray(pointer string, parseElement func(index int) error) error {
+	p.skipWhitespace()
+	if err := p.expect('[', pointer); err != nil {
+		return err
+	}
+
+	p.skipWhitespace()
+	if p.peek() == ']' {
+		p.offset++
+		return nil
+	}
+	for index := 0; ; index++ {
+		if err := parseElement(index); err != nil {
+			return err
+		}
+		p.skipWhitespace()
+		if p.peek() == ']' {
+			p.offset++
+			return nil
+		}
+		if err := p.expect(',', pointer); err != nil {
+			return err
+		}
+	}
+}
+
+// parseFields parses a JSON object, calling (parseField) for each field
+func (p *Parser) parseFields(pointer string, parseField func(key string, pointer string) error) error {
+	p.skipWhitespace()
+	if err := p.expect('{', pointer); err != nil {
+		return err
+	}
+
+	p.skipWhitespace()
+	if p.peek() == '}' {
+		p.offset++
+		return nil
+	}
+	for {
+		p.skipWhitespace()
+		key, err := p.parseStringField(pointer)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return errAbort
+		}
+		p.skipWhitespace()
+		if err := p.expect(':', pointer); err != nil {
+			return err
+		}
+		if err := parseField(*key, pointer+"/"+escapePointer(*key)); err != nil {
+			return err
+		}
+		p.skipWhitespace()
+		if p.peek() == '}' {
+			p.offset++
+			return nil
+		}
+		if err := p.expect(',', pointer); err != nil {
+			return err
+		}
+	}
 }
 
-// Deserialize raw JSON into a Michelson JSON struct.
-// It can be single object or a slice.
-func unmarshal(raw json.RawMessage) (interface{}, error) {
-	var seq []json.RawMessage
-	if err := json.Unmarshal(raw, &seq); err == nil {
-		return seq, nil
+// skipValue skips any JSON value
+func (p *Parser) skipValue(pointer string) error {
+	p.skipWhitespace()
+	switch c := p.peek(); {
+	case c == '"':
+		return p.skipString(pointer)
+	case c == '[':
+		return p.parseArray(pointer, func(index int) error {
+			return p.skipValue(fmt.Sprintf("%s/%d", pointer, index))
+		})
+	case c == '{':
+		return p.parseFields(pointer, func(_ string, fieldPointer string) error {
+			return p.skipValue(fieldPointer)
+		})
+	case c == '-' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z':
+		// Numbers and literals (true, false, null)
+		begin := p.offset
+		for p.offset < len(p.raw) && strings.IndexByte("+-.0123456789eEabcdefghijklmnopqrstuvwxyz", p.raw[p.offset]) >= 0 {
+			p.offset++
+		}
+		literal := string(p.raw[begin:p.offset])
+		if _, err := strconv.ParseFloat(literal, 64); err != nil && literal != "true" && literal != "false" && literal != "null" {
+			p.errorf(begin, pointer, "invalid JSON value (%s).", literal)
+			return errAbort
+		}
+		return nil
+	case c == 0:
+		p.errorf(p.offset, pointer, "unexpected end of JSON.")
+		return errAbort
+	default:
+		p.errorf(p.offset, pointer, "unexpected character (%c).", c)
+		return errAbort
+	}
+}
+
+// skipString skips a JSON string (including its quotes)
+func (p *Parser) skipString(pointer string) error {
+	begin := p.offset
+	p.offset++ // Opening quote
+	for p.offset < len(p.raw) {
+		switch p.raw[p.offset] {
+		case '\\':
+			p.offset += 2
+			continue
+		case '"':
+			p.offset++
+			return nil
+		}
+		p.offset++
+	}
+	p.errorf(begin, pointer, "reached end of JSON while parsing a string.")
+	return errAbort
+}
+
+func (p *Parser) skipWhitespace() {
+	for p.offset < len(p.raw) {
+		switch p.raw[p.offset] {
+		case ' ', '\n', '\r', '\t':
+			p.offset++
+		default:
+			return
+		}
 	}
-	var prim MichelsonJSON
-	err := json.Unmarshal(raw, &prim)
-	return prim, err
+}
+
+// peek returns the current character without consuming it (0 if the end of the JSON was reached)
+func (p *Parser) peek() byte {
+	if p.offset < len(p.raw) {
+		return p.raw[p.offset]
+	}
+	return 0
+}
+
+func (p *Parser) expect(c byte, pointer string) error {
+	switch p.peek() {
+	case c:
+		p.offset++
+		return nil
+	case 0:
+		p.errorf(p.offset, pointer, "expected (%c), but reached end of JSON.", c)
+	default:
+		p.errorf(p.offset, pointer, "expected (%c), but received (%c).", c, p.peek())
+	}
+	return errAbort
+}
+
+func (p *Parser) errorf(offset int, pointer string, format string, args ...interface{}) {
+	err := p.source.Error(offset, fmt.Sprintf(format, args...))
+	err.Pointer = pointer
+	p.errors = append(p.errors, err)
+}
+
+// escapePointer escapes a key to be used as a JSON pointer reference token (RFC 6901)
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
 }
@@ -0,0 +1,46 @@
+package json
+
+import (
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("Nodes carry their position in the JSON", func(t *testing.T) {
+		parser := Parser{}
+		node, err := parser.Parse([]byte("[\n  {\"prim\": \"PUSH\", \"args\": [{\"prim\": \"nat\", \"annots\": [\":n\"]}, {\"int\": \"1\"}]},\n  {\"string\": \"a\\\"b\", \"unknown\": [1, true, null]}\n]"))
+		assert.NoError(t, err)
+		assert.Equal(t, `Sequence([Prim(PUSH, [], [Prim(nat, [:n], []), Int(1)]), String(a"b)])`, node.String())
+
+		seq := node.(ast.Sequence)
+		assert.Equal(t, ast.Position{Pos: 0, End: 130, Line: 1, Column: 1, EndLine: 4, EndColumn: 1}, seq.Position)
+		push := seq.Elements[0].(ast.Prim)
+		assert.Equal(t, ast.Position{Pos: 4, End: 78, Line: 2, Column: 3, EndLine: 2, EndColumn: 77}, push.Position)
+		assert.Equal(t, ast.Position{Pos: 65, End: 76, Line: 2, Column: 64, EndLine: 2, EndColumn: 75}, push.Arguments[1].(ast.Int).Position)
+		assert.Equal(t, ast.Position{Pos: 57, End: 60, Line: 2, Column: 56, EndLine: 2, EndColumn: 59}, push.Arguments[0].(ast.Prim).Annotations[0].Position)
+	})
+	t.Run("Errors report the JSON pointer of the offending element", func(t *testing.T) {
+		parser := Parser{}
+		_, err := parser.Parse([]byte("[\n  {\"prim\": \"PUSH\", \"args\": [{\"prim\": \"nat\"}, {\"int\": 1}]}\n]"))
+		assert.EqualError(t, err, "expected a string, but received (1). (at /0/args/1/int, line 2, column 54)\n2 |   {\"prim\": \"PUSH\", \"args\": [{\"prim\": \"nat\"}, {\"int\": 1}]}\n                                                         ^;\nunexpected Michelson JSON: {\"int\": 1}. (at /0/args/1, line 2, column 46)\n2 |   {\"prim\": \"PUSH\", \"args\": [{\"prim\": \"nat\"}, {\"int\": 1}]}\n                                                 ^")
+
+		errors := parser.Errors()
+		assert.Len(t, errors, 2)
+		assert.Equal(t, "/0/args/1/int", errors[0].Pointer)
+		assert.Equal(t, ast.Position{Pos: 55, End: 55, Line: 2, Column: 54, EndLine: 2, EndColumn: 54}, errors[0].Position)
+	})
+	t.Run("Malformed JSON", func(t *testing.T) {
+		tests := map[string]string{
+			`[{"int": "1"}`:                     "expected (,), but reached end of JSON. (line 1, column 14)\n1 | [{\"int\": \"1\"}\n                 ^",
+			`{"int": "1"} x`:                    "unexpected data after Michelson JSON. (line 1, column 14)\n1 | {\"int\": \"1\"} x\n                 ^",
+			`{"prim": "pair", "annots": ["x"]}`: "Unexpected annotation (x). (at /annots/0, line 1, column 29)\n1 | {\"prim\": \"pair\", \"annots\": [\"x\"]}\n                                ^",
+		}
+		for input, expected := range tests {
+			parser := Parser{}
+			_, err := parser.Parse([]byte(input))
+			assert.EqualError(t, err, expected, input)
+		}
+	})
+}
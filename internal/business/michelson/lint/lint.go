@@ -0,0 +1,283 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/binary"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/macros"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/primitives"
+)
+
+type (
+	WarningKind string
+	// Warning describes a suspicious construction found in a contract
+	Warning struct {
+		Kind     WarningKind  `json:"kind"`
+		Message  string       `json:"message"`
+		Position ast.Position `json:"position"`
+	}
+	// Metrics are static measures of a contract (instructions are counted after macros got expanded)
+	Metrics struct {
+		Instructions      int            `json:"instructions"`       // number of instructions in the code and views
+		InstructionCounts map[string]int `json:"instruction_counts"` // number of occurrences of each instruction
+		MaxNesting        int            `json:"max_nesting"`        // maximum depth of nested sequences
+		Size              int            `json:"size"`               // size (in bytes) of the binary representation of the script
+	}
+	// Report is the result of the analysis of a contract
+	Report struct {
+		Metrics  Metrics   `json:"metrics"`
+		Warnings []Warning `json:"warnings"`
+	}
+)
+
+const (
+	UnreachableCode             WarningKind = "unreachable_code"
+	UnusedBranch                WarningKind = "unused_branch"
+	DeprecatedInstruction       WarningKind = "deprecated_instruction"
+	MissingEntrypointAnnotation WarningKind = "missing_entrypoint_annotation"
+)
+
+var regex_instruction = regexp.MustCompile("^[A-Z][A-Z0-9_]*$")
+
+// Lint analyses a contract for a given protocol (the latest protocol is assumed when the protocol is unknown)
+func Lint(code ast.Node, protocol string) (Report, error) {
+	contract, err := michelson.ParseContract(code)
+	if err != nil {
+		return Report{}, fmt.Errorf("invalid contract. %s", err)
+	}
+	// Primitives unsupported by the protocol are reported as deprecations, other invalid primitives are rejected
+	if err := michelson.CheckSyntax(code); err != nil {
+		return Report{}, fmt.Errorf("invalid contract. %s", err)
+	}
+	expanded, err := macros.Expand(code)
+	if err != nil {
+		return Report{}, err
+	}
+	b, err := binary.Encode(expanded)
+	if err != nil {
+		return Report{}, fmt.Errorf("could not encode contract. %s", err)
+	}
+
+	report := Report{
+		Metrics: Metrics{
+			InstructionCounts: map[string]int{},
+			Size:              len(b),
+		},
+		Warnings: make([]Warning, 0),
+	}
+
+	// Expanded instructions keep the position of their macros, warnings point to the source
+	expandedContract, err := michelson.ParseContract(expanded)
+	if err != nil {
+		return Report{}, fmt.Errorf("invalid contract. %s", err)
+	}
+	bodies := []ast.Node{expandedContract.Code}
+	for _, view := range expandedContract.Views {
+		bodies = append(bodies, view.Body)
+	}
+	for _, body := range bodies {
+		report.measure(body, 0)
+		report.checkReachability(body)
+	}
+
+	report.checkDeprecations(expanded, primitives.ProtocolOf(protocol))
+	report.checkEntrypoints(contract.EntrypointTree(), true)
+
+	sort.SliceStable(report.Warnings, func(i, j int) bool {
+		return report.Warnings[i].Position.Pos < report.Warnings[j].Position.Pos
+	})
+
+	return report, nil
+}
+
+// measure counts the instructions of a code block and the depth of its sequences
+func (r *Report) measure(node ast.Node, depth int) {
+	switch n := node.(type) {
+	case ast.Sequence:
+		depth++
+		if depth > r.Metrics.MaxNesting {
+			r.Metrics.MaxNesting = depth
+		}
+		for _, el := range n.Elements {
+			r.measure(el, depth)
+		}
+	case ast.Prim:
+		if regex_instruction.MatchString(n.Prim) {
+			r.Metrics.Instructions++
+			r.Metrics.InstructionCounts[n.Prim]++
+		}
+		for _, arg := range n.Arguments {
+			r.measure(arg, depth)
+		}
+	}
+}
+
+// checkReachability reports the instructions written after an instruction that always fails,
+// and the branches that can never be taken because their condition is a constant
+func (r *Report) checkReachability(code ast.Node) {
+	ast.Walk(code, func(node ast.Node) bool {
+		seq, ok := node.(ast.Sequence)
+		if !ok {
+			return true
+		}
+		for i, el := range seq.Elements {
+			if i > 0 {
+				r.checkConstantBranch(seq.Elements[i-1], el)
+			}
+			if fails(el) && i < len(seq.Elements)-1 {
+				first := ast.PositionOf(seq.Elements[i+1])
+				last := ast.PositionOf(seq.Elements[len(seq.Elements)-1])
+				r.warn(UnreachableCode, spanOf(first, last), "instructions after (%s) are never executed", instructionOf(el))
+				break
+			}
+		}
+		return true
+	})
+}
+
+// checkConstantBranch reports the branch of a conditional instruction that is never taken
+// because the condition was pushed as a constant right before it (e.g. "PUSH bool True ; IF { ... } { ... }")
+func (r *Report) checkConstantBranch(previous ast.Node, node ast.Node) {
+	push, ok := previous.(ast.Prim)
+	if !ok || push.Prim != "PUSH" || len(push.Arguments) != 2 {
+		return
+	}
+	branching, ok := node.(ast.Prim)
+	if !ok || len(branching.Arguments) != 2 {
+		return
+	}
+
+	// Index of the branch taken with the pushed value
+	taken := -1
+	switch value := push.Arguments[1].(type) {
+	case ast.Prim:
+		switch {
+		case branching.Prim == "IF" && value.Prim == "True",
+			branching.Prim == "IF_NONE" && value.Prim == "None",
+			branching.Prim == "IF_LEFT" && value.Prim == "Left":
+			taken = 0
+		case branching.Prim == "IF" && value.Prim == "False",
+			branching.Prim == "IF_NONE" && value.Prim == "Some",
+			branching.Prim == "IF_LEFT" && value.Prim == "Right":
+			taken = 1
+		}
+	case ast.Sequence:
+		if branching.Prim == "IF_CONS" {
+			if len(value.Elements) > 0 {
+				taken = 0
+			} else {
+				taken = 1
+			}
+		}
+	}
+	if taken == -1 {
+		return
+	}
+
+	unused := branching.Arguments[1-taken]
+	r.warn(UnusedBranch, ast.PositionOf(unused), "branch of (%s) is never taken, the condition is always %s", branching.Prim, printConstant(push.Arguments[1]))
+}
+
+// checkDeprecations reports the instructions and types deprecated (or removed) in a given protocol
+func (r *Report) checkDeprecations(code ast.Node, protocol primitives.Protocol) {
+	ast.Walk(code, func(node ast.Node) bool {
+		if prim, ok := node.(ast.Prim); ok {
+			if p, ok := primitives.Lookup(prim.Prim); ok && p.DeprecatedIn(protocol) {
+				status := "deprecated"
+				if !p.SupportedBy(protocol) {
+					status = "removed"
+				}
+				r.warn(DeprecatedInstruction, prim.Position, "(%s) is %s in protocol (%s)", prim.Prim, status, protocol.Name)
+			}
+		}
+		return true
+	})
+}
+
+// checkEntrypoints reports the branches of the parameter type that cannot be called by name
+func (r *Report) checkEntrypoints(entrypoint michelson.Entrypoint, root bool) {
+	if len(entrypoint.Branches) == 0 {
+		if entrypoint.Name == "" && !root {
+			r.warn(MissingEntrypointAnnotation, ast.PositionOf(entrypoint.Type), "branch of the parameter type has no field annotation, it cannot be called as an entrypoint")
+		}
+		return
+	}
+	if entrypoint.Name != "" && !root {
+		return
+	}
+	for _, branch := range entrypoint.Branches {
+		r.checkEntrypoints(branch, false)
+	}
+}
+
+func (r *Report) warn(kind WarningKind, position ast.Position, format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, Warning{
+		Kind:     kind,
+		Message:  fmt.Sprintf(format, args...) + ".",
+		Position: position,
+	})
+}
+
+// fails checks if an instruction never returns (it fails in all its branches)
+func fails(node ast.Node) bool {
+	switch n := node.(type) {
+	case ast.Sequence:
+		for _, el := range n.Elements {
+			if fails(el) {
+				return true
+			}
+		}
+	case ast.Prim:
+		switch n.Prim {
+		case "FAILWITH", "NEVER":
+			return true
+		case "IF", "IF_NONE", "IF_LEFT", "IF_CONS":
+			return len(n.Arguments) == 2 && fails(n.Arguments[0]) && fails(n.Arguments[1])
+		}
+	}
+	return false
+}
+
+// instructionOf names the instruction that fails (the first failing instruction of a sequence)
+func instructionOf(node ast.Node) string {
+	switch n := node.(type) {
+	case ast.Sequence:
+		for _, el := range n.Elements {
+			if fails(el) {
+				return instructionOf(el)
+			}
+		}
+	case ast.Prim:
+		return n.Prim
+	}
+	return node.String()
+}
+
+func printConstant(node ast.Node) string {
+	switch n := node.(type) {
+	case ast.Prim:
+		return n.Prim
+	case ast.Sequence:
+		if len(n.Elements) == 0 {
+			return "an empty list"
+		}
+		return "a non-empty list"
+	}
+	return node.String()
+}
+
+// spanOf builds a position covering two positions
+func spanOf(first ast.Position, last ast.Position) ast.Position {
+	return ast.Position{
+		Pos:       first.Pos,
+		End:       last.End,
+		Line:      first.Line,
+		Column:    first.Column,
+		EndLine:   last.EndLine,
+		EndColumn: last.EndColumn,
+	}
+}
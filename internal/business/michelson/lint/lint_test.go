@@ -0,0 +1,91 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(t *testing.T, s string) ast.Node {
+	parser := micheline.InitParser(s)
+	node := parser.Parse()
+	assert.NoError(t, parser.Error(), s)
+	return node
+}
+
+func TestLint(t *testing.T) {
+	t.Run("Metrics", func(t *testing.T) {
+		report, err := Lint(parse(t, `{ parameter unit ; storage nat ; code { CDR ; PUSH nat 1 ; ADD ; NIL operation ; PAIR } }`), "")
+		assert.NoError(t, err)
+		assert.Equal(t, 5, report.Metrics.Instructions)
+		assert.Equal(t, map[string]int{"CDR": 1, "PUSH": 1, "ADD": 1, "NIL": 1, "PAIR": 1}, report.Metrics.InstructionCounts)
+		assert.Equal(t, 1, report.Metrics.MaxNesting)
+		assert.Equal(t, 36, report.Metrics.Size)
+		assert.Empty(t, report.Warnings)
+
+		// Macros are counted after being expanded
+		report, err = Lint(parse(t, `{ parameter unit ; storage unit ; code { IF_SOME { DROP } { FAIL } } }`), "")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{"IF_NONE": 1, "UNIT": 1, "FAILWITH": 1, "DROP": 1}, report.Metrics.InstructionCounts)
+		assert.Equal(t, 4, report.Metrics.MaxNesting)
+	})
+	t.Run("Unreachable code", func(t *testing.T) {
+		code := "{ parameter unit ;\n  storage unit ;\n  code { DROP ;\n         IF { FAIL } { FAILWITH } ;\n         UNIT ;\n         NIL operation ; PAIR } }"
+		report, err := Lint(parse(t, code), "")
+		assert.NoError(t, err)
+		assert.Len(t, report.Warnings, 1)
+		assert.Equal(t, UnreachableCode, report.Warnings[0].Kind)
+		assert.Equal(t, "instructions after (IF) are never executed.", report.Warnings[0].Message)
+		assert.Equal(t, 5, report.Warnings[0].Position.Line)
+		assert.Equal(t, 10, report.Warnings[0].Position.Column)
+		assert.Equal(t, 6, report.Warnings[0].Position.EndLine)
+	})
+	t.Run("Unused branches", func(t *testing.T) {
+		report, err := Lint(parse(t, `{ parameter unit ; storage unit ; code { PUSH bool True ; IF {} { UNIT ; DROP } ; PUSH (option nat) (Some 1) ; IF_NONE { UNIT ; DROP } { DROP } ; CDR ; NIL operation ; PAIR } }`), "")
+		assert.NoError(t, err)
+		assert.Len(t, report.Warnings, 2)
+		assert.Equal(t, UnusedBranch, report.Warnings[0].Kind)
+		assert.Equal(t, "branch of (IF) is never taken, the condition is always True.", report.Warnings[0].Message)
+		assert.Equal(t, "branch of (IF_NONE) is never taken, the condition is always Some.", report.Warnings[1].Message)
+	})
+	t.Run("Deprecated instructions", func(t *testing.T) {
+		code := parse(t, `{ parameter unit ; storage (option chest) ; code { CDR ; NIL operation ; PAIR } }`)
+
+		report, err := Lint(code, "PtLimaPtLMwfiLCZzo5V4bPXsNs3Kdm5ZKJDsTbkpS9cXNkvXQt")
+		assert.NoError(t, err)
+		assert.Len(t, report.Warnings, 1)
+		assert.Equal(t, DeprecatedInstruction, report.Warnings[0].Kind)
+		assert.Equal(t, "(chest) is deprecated in protocol (Lima).", report.Warnings[0].Message)
+
+		// Timelocks were enabled again in Oxford
+		report, err = Lint(code, "ProxfordYmVfjWnRcgjWH36fW6PArwqykTFzotUxRs6gmTcZDuH")
+		assert.NoError(t, err)
+		assert.Empty(t, report.Warnings)
+
+		report, err = Lint(parse(t, `{ parameter unit ; storage unit ; code { CREATE_ACCOUNT } }`), "")
+		assert.NoError(t, err)
+		assert.Equal(t, "(CREATE_ACCOUNT) is removed in protocol (Alpha).", report.Warnings[0].Message)
+	})
+	t.Run("Missing entrypoint annotations", func(t *testing.T) {
+		report, err := Lint(parse(t, `{ parameter (or (or (nat %a) int) (pair %c nat (or nat int))) ; storage unit ; code { CDR ; NIL operation ; PAIR } }`), "")
+		assert.NoError(t, err)
+		assert.Len(t, report.Warnings, 1)
+		assert.Equal(t, MissingEntrypointAnnotation, report.Warnings[0].Kind)
+		assert.Equal(t, 1, report.Warnings[0].Position.Line)
+		assert.Equal(t, 30, report.Warnings[0].Position.Column)
+
+		// Contracts with a single entrypoint do not need annotations
+		report, err = Lint(parse(t, `{ parameter nat ; storage unit ; code { CDR ; NIL operation ; PAIR } }`), "")
+		assert.NoError(t, err)
+		assert.Empty(t, report.Warnings)
+	})
+	t.Run("Invalid contracts", func(t *testing.T) {
+		_, err := Lint(parse(t, `{ parameter unit ; code {} }`), "")
+		assert.EqualError(t, err, "invalid contract. missing contract section (storage) (line 1, column 1).")
+
+		_, err = Lint(parse(t, `{ parameter unit ; storage unit ; code { IF } }`), "")
+		assert.EqualError(t, err, "invalid contract. Primitive (IF) expects 2 argument(s), but received 0. (line 1, column 42)")
+	})
+}
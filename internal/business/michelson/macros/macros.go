@@ -0,0 +1,380 @@
+package macros
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+)
+
+var (
+	comparisons = map[string]bool{
+		"EQ":  true,
+		"NEQ": true,
+		"LT":  true,
+		"GT":  true,
+		"LE":  true,
+		"GE":  true,
+	}
+	regex_duup     = regexp.MustCompile(`^DU(U+)P$`)
+	regex_diip     = regexp.MustCompile(`^DI(I+)P$`)
+	regex_pair     = regexp.MustCompile(`^P[PAI]{3,}R$`)
+	regex_unpair   = regexp.MustCompile(`^UNP[PAI]{3,}R$`)
+	regex_cadr     = regexp.MustCompile(`^C[AD]{2,}R$`)
+	regex_set_cr   = regexp.MustCompile(`^SET_C[AD]+R$`)
+	regex_map_cr   = regexp.MustCompile(`^MAP_C[AD]+R$`)
+	errNotAMacro   = fmt.Errorf("not a macro")
+	errNotAPairing = fmt.Errorf("not a pairing macro")
+)
+
+// Expand rewrites the macros of a Michelson expression into core instructions
+// (https://tezos.gitlab.io/active/michelson.html#macros).
+// Expanded instructions keep the position of the macro they were expanded from.
+func Expand(node ast.Node) (ast.Node, error) {
+	switch n := node.(type) {
+	case ast.Sequence:
+		elements := make([]ast.Node, len(n.Elements))
+		for i, el := range n.Elements {
+			var err error
+			if elements[i], err = Expand(el); err != nil {
+				return nil, err
+			}
+		}
+		n.Elements = elements
+		return n, nil
+	case ast.Prim:
+		arguments := make([]ast.Node, len(n.Arguments))
+		for i, arg := range n.Arguments {
+			var err error
+			if arguments[i], err = Expand(arg); err != nil {
+				return nil, err
+			}
+		}
+		n.Arguments = arguments
+
+		expanded, err := expandPrim(n)
+		if err == errNotAMacro {
+			return n, nil
+		}
+		return expanded, err
+	}
+
+	return node, nil
+}
+
+// IsMacro checks if a primitive is a macro
+func IsMacro(prim string) bool {
+	_, err := expandPrim(ast.Prim{Prim: prim})
+	return err != errNotAMacro
+}
+
+func expandPrim(macro ast.Prim) (ast.Node, error) {
+	b := builder{position: macro.Position}
+	name := macro.Prim
+
+	switch {
+	case name == "FAIL":
+		return expectArguments(macro, 0, func() ast.Node {
+			return b.seq(b.prim("UNIT"), b.prim("FAILWITH"))
+		})
+	case name == "ASSERT":
+		return expectArguments(macro, 0, func() ast.Node {
+			return b.seq(b.prim("IF", b.seq(), b.fail()))
+		})
+	case name == "ASSERT_NONE":
+		return expectArguments(macro, 0, func() ast.Node {
+			return b.seq(b.prim("IF_NONE", b.seq(), b.fail()))
+		})
+	case name == "ASSERT_SOME":
+		return expectArguments(macro, 0, func() ast.Node {
+			return b.seq(b.prim("IF_NONE", b.fail(), b.seq()))
+		})
+	case name == "ASSERT_LEFT":
+		return expectArguments(macro, 0, func() ast.Node {
+			return b.seq(b.prim("IF_LEFT", b.seq(), b.fail()))
+		})
+	case name == "ASSERT_RIGHT":
+		return expectArguments(macro, 0, func() ast.Node {
+			return b.seq(b.prim("IF_LEFT", b.fail(), b.seq()))
+		})
+	case strings.HasPrefix(name, "ASSERT_CMP") && comparisons[strings.TrimPrefix(name, "ASSERT_CMP")]:
+		return expectArguments(macro, 0, func() ast.Node {
+			op := strings.TrimPrefix(name, "ASSERT_CMP")
+			return b.seq(b.seq(b.prim("COMPARE"), b.prim(op)), b.prim("IF", b.seq(), b.fail()))
+		})
+	case strings.HasPrefix(name, "ASSERT_") && comparisons[strings.TrimPrefix(name, "ASSERT_")]:
+		return expectArguments(macro, 0, func() ast.Node {
+			return b.seq(b.prim(strings.TrimPrefix(name, "ASSERT_")), b.prim("IF", b.seq(), b.fail()))
+		})
+	case strings.HasPrefix(name, "CMP") && comparisons[strings.TrimPrefix(name, "CMP")]:
+		return expectArguments(macro, 0, func() ast.Node {
+			return b.seq(b.prim("COMPARE"), b.annotated(b.prim(strings.TrimPrefix(name, "CMP")), macro.Annotations))
+		})
+	case strings.HasPrefix(name, "IFCMP") && comparisons[strings.TrimPrefix(name, "IFCMP")]:
+		return expectArguments(macro, 2, func() ast.Node {
+			return b.seq(
+				b.prim("COMPARE"),
+				b.prim(strings.TrimPrefix(name, "IFCMP")),
+				b.annotated(b.prim("IF", macro.Arguments...), macro.Annotations),
+			)
+		})
+	case strings.HasPrefix(name, "IF") && comparisons[strings.TrimPrefix(name, "IF")]:
+		return expectArguments(macro, 2, func() ast.Node {
+			return b.seq(
+				b.prim(strings.TrimPrefix(name, "IF")),
+				b.annotated(b.prim("IF", macro.Arguments...), macro.Annotations),
+			)
+		})
+	case name == "IF_SOME":
+		return expectArguments(macro, 2, func() ast.Node {
+			return b.seq(b.annotated(b.prim("IF_NONE", macro.Arguments[1], macro.Arguments[0]), macro.Annotations))
+		})
+	case name == "IF_RIGHT":
+		return expectArguments(macro, 2, func() ast.Node {
+			return b.seq(b.annotated(b.prim("IF_LEFT", macro.Arguments[1], macro.Arguments[0]), macro.Annotations))
+		})
+	case regex_duup.MatchString(name):
+		return expectArguments(macro, 0, func() ast.Node {
+			n := len(regex_duup.FindStringSubmatch(name)[1]) + 1
+			return b.seq(b.annotated(b.prim("DUP", b.int(n)), macro.Annotations))
+		})
+	case regex_diip.MatchString(name):
+		return expectArguments(macro, 1, func() ast.Node {
+			n := len(regex_diip.FindStringSubmatch(name)[1]) + 1
+			return b.seq(b.dip(n, macro.Arguments[0]))
+		})
+	case regex_pair.MatchString(name):
+		tree, err := parsePairing(name[:len(name)-1])
+		if err != nil {
+			return nil, errNotAMacro
+		}
+		return expectArguments(macro, 0, func() ast.Node {
+			return b.seq(b.expandPairing(tree, 0, macro.Annotations)...)
+		})
+	case regex_unpair.MatchString(name):
+		tree, err := parsePairing(name[2 : len(name)-1])
+		if err != nil {
+			return nil, errNotAMacro
+		}
+		return expectArguments(macro, 0, func() ast.Node {
+			return b.seq(b.expandUnpairing(tree, 0)...)
+		})
+	case regex_cadr.MatchString(name):
+		return expectArguments(macro, 0, func() ast.Node {
+			instructions := make([]ast.Node, 0)
+			for _, c := range name[1 : len(name)-1] {
+				instructions = append(instructions, b.prim(accessor(c)))
+			}
+			// Annotations are attached to the last accessor
+			last := instructions[len(instructions)-1].(ast.Prim)
+			instructions[len(instructions)-1] = b.annotated(last, macro.Annotations)
+			return b.seq(instructions...)
+		})
+	case regex_set_cr.MatchString(name):
+		return expectArguments(macro, 0, func() ast.Node {
+			return b.expandSetCr(name[5:len(name)-1], macro.Annotations)
+		})
+	case regex_map_cr.MatchString(name):
+		return expectArguments(macro, 1, func() ast.Node {
+			return b.expandMapCr(name[5:len(name)-1], macro.Arguments[0])
+		})
+	}
+
+	return nil, errNotAMacro
+}
+
+func expectArguments(macro ast.Prim, count int, expand func() ast.Node) (ast.Node, error) {
+	if len(macro.Arguments) != count {
+		return nil, fmt.Errorf("macro (%s) expects %d argument(s).", macro.Prim, count)
+	}
+	return expand(), nil
+}
+
+func accessor(c rune) string {
+	if c == 'A' {
+		return "CAR"
+	}
+	return "CDR"
+}
+
+// pairing is the tree described by the letters of a pairing macro (P: pair, A: left leaf, I: right leaf)
+type pairing struct {
+	left  *pairing
+	right *pairing
+}
+
+// parsePairing parses the letters of (P[PAI]+) into a tree, the whole string must be consumed
+func parsePairing(letters string) (*pairing, error) {
+	tree, rest, err := parsePairingNode(letters)
+	if err != nil || rest != "" {
+		return nil, errNotAPairing
+	}
+	return tree, nil
+}
+
+func parsePairingNode(letters string) (*pairing, string, error) {
+	if letters == "" || letters[0] != 'P' {
+		return nil, "", errNotAPairing
+	}
+	node := &pairing{}
+	rest := letters[1:]
+
+	// Left component
+	switch {
+	case strings.HasPrefix(rest, "A"):
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "P"):
+		var err error
+		if node.left, rest, err = parsePairingNode(rest); err != nil {
+			return nil, "", err
+		}
+	default:
+		return nil, "", errNotAPairing
+	}
+
+	// Right component
+	switch {
+	case strings.HasPrefix(rest, "I"):
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "P"):
+		var err error
+		if node.right, rest, err = parsePairingNode(rest); err != nil {
+			return nil, "", err
+		}
+	default:
+		return nil, "", errNotAPairing
+	}
+
+	return node, rest, nil
+}
+
+type builder struct {
+	position ast.Position
+}
+
+func (b builder) prim(name string, arguments ...ast.Node) ast.Prim {
+	return ast.Prim{
+		Position:  b.position,
+		Prim:      name,
+		Arguments: arguments,
+	}
+}
+
+func (b builder) seq(elements ...ast.Node) ast.Sequence {
+	if elements == nil {
+		elements = []ast.Node{}
+	}
+	return ast.Sequence{
+		Position: b.position,
+		Elements: elements,
+	}
+}
+
+func (b builder) int(n int) ast.Int {
+	return ast.Int{
+		Position: b.position,
+		Value:    fmt.Sprint(n),
+	}
+}
+
+func (b builder) annotated(prim ast.Prim, annotations []ast.Annotation) ast.Prim {
+	prim.Annotations = annotations
+	return prim
+}
+
+// dip protects the top (n) elements of the stack, (DIP 1 code) is written (DIP code) like octez does
+func (b builder) dip(n int, code ast.Node) ast.Prim {
+	if n == 1 {
+		return b.prim("DIP", code)
+	}
+	return b.prim("DIP", b.int(n), code)
+}
+
+// fail builds the expansion of the (FAIL) macro wrapped in a sequence
+func (b builder) fail() ast.Sequence {
+	return b.seq(b.seq(b.prim("UNIT"), b.prim("FAILWITH")))
+}
+
+// expandPairing expands (P[PAI]+R) macros, the annotations are attached to the outermost PAIR
+func (b builder) expandPairing(tree *pairing, depth int, annotations []ast.Annotation) []ast.Node {
+	pair := b.prim("PAIR")
+	if depth == 0 {
+		pair = b.annotated(pair, annotations)
+	}
+
+	instructions := make([]ast.Node, 0)
+	// The right component is built first, then the left component, then both are paired
+	if tree.right != nil {
+		instructions = append(instructions, b.expandPairing(tree.right, depth+1, nil)...)
+	}
+	if tree.left != nil {
+		instructions = append(instructions, b.expandPairing(tree.left, depth, nil)...)
+	}
+	if depth == 0 {
+		return append(instructions, pair)
+	}
+	return append(instructions, b.dip(depth, b.seq(pair)))
+}
+
+// expandUnpairing expands (UNP[PAI]+R) macros
+func (b builder) expandUnpairing(tree *pairing, depth int) []ast.Node {
+	instructions := make([]ast.Node, 0)
+	if depth == 0 {
+		instructions = append(instructions, b.prim("UNPAIR"))
+	} else {
+		instructions = append(instructions, b.dip(depth, b.seq(b.prim("UNPAIR"))))
+	}
+	// The right component is unpaired first, so that the left component stays on top
+	if tree.right != nil {
+		instructions = append(instructions, b.expandUnpairing(tree.right, depth+1)...)
+	}
+	if tree.left != nil {
+		instructions = append(instructions, b.expandUnpairing(tree.left, depth)...)
+	}
+	return instructions
+}
+
+// expandSetCr expands (SET_C[AD]+R) macros
+func (b builder) expandSetCr(path string, annotations []ast.Annotation) ast.Node {
+	var acc ast.Node
+	switch path[len(path)-1] {
+	case 'A':
+		acc = b.seq(b.prim("CDR"), b.prim("SWAP"), b.annotated(b.prim("PAIR"), annotations))
+	default:
+		acc = b.seq(b.prim("CAR"), b.annotated(b.prim("PAIR"), annotations))
+	}
+
+	for i := len(path) - 2; i >= 0; i-- {
+		switch path[i] {
+		case 'A':
+			acc = b.seq(b.prim("DUP"), b.prim("DIP", b.seq(b.prim("CAR"), acc)), b.prim("CDR"), b.prim("SWAP"), b.prim("PAIR"))
+		default:
+			acc = b.seq(b.prim("DUP"), b.prim("DIP", b.seq(b.prim("CDR"), acc)), b.prim("CAR"), b.prim("PAIR"))
+		}
+	}
+
+	return acc
+}
+
+// expandMapCr expands (MAP_C[AD]+R code) macros
+func (b builder) expandMapCr(path string, code ast.Node) ast.Node {
+	var acc ast.Node
+	switch path[len(path)-1] {
+	case 'A':
+		acc = b.seq(b.prim("DUP"), b.prim("CDR"), b.prim("DIP", b.seq(b.prim("CAR"), code)), b.prim("SWAP"), b.prim("PAIR"))
+	default:
+		acc = b.seq(b.prim("DUP"), b.prim("CDR"), code, b.prim("SWAP"), b.prim("CAR"), b.prim("PAIR"))
+	}
+
+	for i := len(path) - 2; i >= 0; i-- {
+		switch path[i] {
+		case 'A':
+			acc = b.seq(b.prim("DUP"), b.prim("DIP", b.seq(b.prim("CAR"), acc)), b.prim("CDR"), b.prim("SWAP"), b.prim("PAIR"))
+		default:
+			acc = b.seq(b.prim("DUP"), b.prim("DIP", b.seq(b.prim("CDR"), acc)), b.prim("CAR"), b.prim("PAIR"))
+		}
+	}
+
+	return acc
+}
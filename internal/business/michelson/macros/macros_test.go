@@ -0,0 +1,83 @@
+package macros
+
+import (
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(t *testing.T, s string) ast.Node {
+	parser := micheline.InitParser(s)
+	node := parser.Parse()
+	assert.NoError(t, parser.Error(), s)
+	return node
+}
+
+func TestExpand(t *testing.T) {
+	t.Run("Expand macros", func(t *testing.T) {
+		tests := []struct {
+			Code     string
+			Expected string
+		}{
+			{Code: `{ FAIL }`, Expected: `{ { UNIT ; FAILWITH } }`},
+			{Code: `{ CMPEQ }`, Expected: `{ { COMPARE ; EQ } }`},
+			{Code: `{ CMPLT @lower }`, Expected: `{ { COMPARE ; LT @lower } }`},
+			{Code: `{ IFGT { UNIT } { FAIL } }`, Expected: `{ { GT ; IF { UNIT } { { UNIT ; FAILWITH } } } }`},
+			{Code: `{ IFCMPNEQ {} {} }`, Expected: `{ { COMPARE ; NEQ ; IF {} {} } }`},
+			{Code: `{ ASSERT }`, Expected: `{ { IF {} { { UNIT ; FAILWITH } } } }`},
+			{Code: `{ ASSERT_EQ }`, Expected: `{ { EQ ; IF {} { { UNIT ; FAILWITH } } } }`},
+			{Code: `{ ASSERT_CMPGE }`, Expected: `{ { { COMPARE ; GE } ; IF {} { { UNIT ; FAILWITH } } } }`},
+			{Code: `{ ASSERT_NONE }`, Expected: `{ { IF_NONE {} { { UNIT ; FAILWITH } } } }`},
+			{Code: `{ ASSERT_SOME }`, Expected: `{ { IF_NONE { { UNIT ; FAILWITH } } {} } }`},
+			{Code: `{ ASSERT_LEFT }`, Expected: `{ { IF_LEFT {} { { UNIT ; FAILWITH } } } }`},
+			{Code: `{ ASSERT_RIGHT }`, Expected: `{ { IF_LEFT { { UNIT ; FAILWITH } } {} } }`},
+			{Code: `{ IF_SOME { DROP } { UNIT } }`, Expected: `{ { IF_NONE { UNIT } { DROP } } }`},
+			{Code: `{ IF_RIGHT { DROP } { UNIT } }`, Expected: `{ { IF_LEFT { UNIT } { DROP } } }`},
+			{Code: `{ DUUP }`, Expected: `{ { DUP 2 } }`},
+			{Code: `{ DUUUP @x }`, Expected: `{ { DUP @x 3 } }`},
+			{Code: `{ DIIP { DROP } }`, Expected: `{ { DIP 2 { DROP } } }`},
+			{Code: `{ PAPAIR }`, Expected: `{ { DIP { PAIR } ; PAIR } }`},
+			{Code: `{ PPAIIR }`, Expected: `{ { PAIR ; PAIR } }`},
+			{Code: `{ PAPPAIIR }`, Expected: `{ { DIP { PAIR } ; DIP { PAIR } ; PAIR } }`},
+			{Code: `{ UNPAPAIR }`, Expected: `{ { UNPAIR ; DIP { UNPAIR } } }`},
+			{Code: `{ UNPPAIIR }`, Expected: `{ { UNPAIR ; UNPAIR } }`},
+			{Code: `{ CADR }`, Expected: `{ { CAR ; CDR } }`},
+			{Code: `{ CDDAR @x }`, Expected: `{ { CDR ; CDR ; CAR @x } }`},
+			{Code: `{ SET_CAR }`, Expected: `{ { CDR ; SWAP ; PAIR } }`},
+			{Code: `{ SET_CDR }`, Expected: `{ { CAR ; PAIR } }`},
+			{Code: `{ SET_CADR }`, Expected: `{ { DUP ; DIP { CAR ; { CAR ; PAIR } } ; CDR ; SWAP ; PAIR } }`},
+			{Code: `{ MAP_CAR { PUSH nat 1 ; ADD } }`, Expected: `{ { DUP ; CDR ; DIP { CAR ; { PUSH nat 1 ; ADD } } ; SWAP ; PAIR } }`},
+			{Code: `{ MAP_CDR { CMPEQ } }`, Expected: `{ { DUP ; CDR ; { { COMPARE ; EQ } } ; SWAP ; CAR ; PAIR } }`},
+			// Core instructions are not expanded
+			{Code: `{ UNPAIR ; PAIR ; CAR ; CDR ; DUP ; DIP { DROP } ; IF_NONE {} {} }`, Expected: `{ UNPAIR ; PAIR ; CAR ; CDR ; DUP ; DIP { DROP } ; IF_NONE {} {} }`},
+		}
+		for _, test := range tests {
+			expanded, err := Expand(parse(t, test.Code))
+			assert.NoError(t, err, test.Code)
+			assert.Equal(t, micheline.Print(parse(t, test.Expected), ""), micheline.Print(expanded, ""), test.Code)
+		}
+	})
+	t.Run("Expanded instructions keep the position of the macro", func(t *testing.T) {
+		expanded, err := Expand(parse(t, `{ DROP ; CMPEQ }`))
+		assert.NoError(t, err)
+
+		macro := expanded.(ast.Sequence).Elements[1].(ast.Sequence)
+		assert.Equal(t, ast.Position{Pos: 9, End: 13, Line: 1, Column: 10, EndLine: 1, EndColumn: 14}, macro.Position)
+		for _, instruction := range macro.Elements {
+			assert.Equal(t, ast.Position{Pos: 9, End: 13, Line: 1, Column: 10, EndLine: 1, EndColumn: 14}, instruction.(ast.Prim).Position)
+		}
+	})
+	t.Run("Invalid macros", func(t *testing.T) {
+		_, err := Expand(parse(t, `{ IFCMPEQ {} }`))
+		assert.EqualError(t, err, "macro (IFCMPEQ) expects 2 argument(s).")
+
+		_, err = Expand(parse(t, `{ FAIL 1 }`))
+		assert.EqualError(t, err, "macro (FAIL) expects 0 argument(s).")
+
+		assert.True(t, IsMacro("PAPAIR"))
+		assert.False(t, IsMacro("PAAIR"))
+		assert.False(t, IsMacro("UNPAIR"))
+	})
+}
@@ -0,0 +1,234 @@
+package micheline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+)
+
+// DEFAULT_WIDTH is the line width used when formatting without an explicit width
+const DEFAULT_WIDTH = 80
+
+type formatter struct {
+	width int
+}
+
+// Format pretty-prints a node with the layout used by octez-client, keeping the comments attached to nodes.
+//
+// Nodes are written on a single line when they fit in the given width, otherwise:
+// - sequence elements are written one per line, aligned after the opening brace;
+// - the arguments of a primitive are written one per line, indented by 2 spaces,
+// unless only its last argument is a sequence ("code { ...", "DIP { ...").
+func Format(node ast.Node, width int) string {
+	if width <= 0 {
+		width = DEFAULT_WIDTH
+	}
+	f := formatter{width: width}
+
+	var b strings.Builder
+	trivia := ast.TriviaOf(node)
+	if trivia != nil {
+		for _, comment := range trivia.Leading {
+			b.WriteString(comment.Text + "\n")
+		}
+	}
+	b.WriteString(f.format(node, 0, false))
+	if trivia != nil {
+		for _, comment := range trivia.Trailing {
+			b.WriteString("\n" + comment.Text)
+		}
+	}
+
+	return b.String()
+}
+
+// format lays out a node starting at a given column
+func (f formatter) format(node ast.Node, column int, wrap bool) string {
+	if flat, ok := formatFlat(node, wrap); ok && column+len(flat) <= f.width {
+		return flat
+	}
+
+	switch n := node.(type) {
+	case ast.Sequence:
+		return f.formatSequence(n, column)
+	case ast.Prim:
+		return f.formatPrim(n, column, wrap)
+	}
+
+	flat, _ := formatFlat(node, wrap)
+	return flat
+}
+
+func (f formatter) formatSequence(n ast.Sequence, column int) string {
+	indent := "\n" + strings.Repeat(" ", column+2)
+
+	var inner []ast.Comment
+	if n.Comments != nil {
+		inner = n.Comments.Inner
+	}
+	if len(n.Elements) == 0 && len(inner) == 0 {
+		return "{}"
+	}
+
+	var b strings.Builder
+	b.WriteString("{ ")
+
+	// (needsNewline) is set when the last written text is a line comment
+	needsNewline := false
+	for i, el := range n.Elements {
+		trivia := ast.TriviaOf(el)
+		if i > 0 {
+			b.WriteString(indent)
+		}
+		if trivia != nil {
+			for _, comment := range trivia.Leading {
+				b.WriteString(comment.Text + indent)
+			}
+		}
+
+		b.WriteString(f.format(el, column+2, false))
+		if i < len(n.Elements)-1 {
+			b.WriteString(" ;")
+		}
+
+		needsNewline = false
+		if trivia != nil {
+			for _, comment := range trivia.Trailing {
+				b.WriteString(" " + comment.Text)
+				needsNewline = isLineComment(comment)
+			}
+		}
+	}
+
+	for i, comment := range inner {
+		if i > 0 || len(n.Elements) > 0 {
+			b.WriteString(indent)
+		}
+		b.WriteString(comment.Text)
+		needsNewline = isLineComment(comment)
+	}
+
+	if needsNewline {
+		b.WriteString("\n" + strings.Repeat(" ", column) + "}")
+	} else {
+		b.WriteString(" }")
+	}
+
+	return b.String()
+}
+
+func (f formatter) formatPrim(n ast.Prim, column int, wrap bool) string {
+	hasParenthesis := wrap && (len(n.Arguments) > 0 || len(n.Annotations) > 0)
+
+	head, closing, base := formatPrimHead(n), "", column
+	if hasParenthesis {
+		head, closing, base = "("+head, ")", column+1
+	}
+
+	if last := len(n.Arguments) - 1; last >= 0 && isSequence(n.Arguments[last]) {
+		// The last argument is a sequence, it is kept on the same line if the other arguments fit ("code { ...", "DIP 2 { ...")
+		line, fits := head, !hasLeadingComments(n.Arguments[last])
+		for _, arg := range n.Arguments[:last] {
+			flat, ok := formatFlat(arg, true)
+			if !ok || hasLeadingComments(arg) {
+				fits = false
+				break
+			}
+			line += " " + flat
+		}
+		if fits && column+len(line)+len(" { ") <= f.width {
+			return line + " " + f.format(n.Arguments[last], column+len(line)+1, true) + closing
+		}
+	}
+
+	// One argument per line, indented by 2 spaces
+	var b strings.Builder
+	b.WriteString(head)
+
+	indent := "\n" + strings.Repeat(" ", base+2)
+	for _, arg := range n.Arguments {
+		b.WriteString(indent)
+		if trivia := ast.TriviaOf(arg); trivia != nil {
+			for _, comment := range trivia.Leading {
+				b.WriteString(comment.Text + indent)
+			}
+		}
+		b.WriteString(f.format(arg, base+2, true))
+	}
+	b.WriteString(closing)
+
+	return b.String()
+}
+
+// formatFlat writes a node on a single line, it fails if comments inside the node require line breaks
+func formatFlat(node ast.Node, wrap bool) (string, bool) {
+	// Comments attached to the node itself are written by its parent, but inner comments require line breaks
+	if trivia := ast.TriviaOf(node); trivia != nil && len(trivia.Inner) > 0 {
+		return "", false
+	}
+
+	switch n := node.(type) {
+	case ast.Bytes:
+		return fmt.Sprintf("0x%s", n.Value), true
+	case ast.Int:
+		return n.Value, true
+	case ast.String:
+		return fmt.Sprintf(`"%s"`, n.Value), true
+	case ast.Sequence:
+		if len(n.Elements) == 0 {
+			return "{}", true
+		}
+		elements := make([]string, len(n.Elements))
+		for i, el := range n.Elements {
+			if trivia := ast.TriviaOf(el); trivia != nil && (len(trivia.Leading) > 0 || len(trivia.Trailing) > 0) {
+				return "", false
+			}
+			var ok bool
+			if elements[i], ok = formatFlat(el, false); !ok {
+				return "", false
+			}
+		}
+		return fmt.Sprintf("{ %s }", strings.Join(elements, " ; ")), true
+	case ast.Prim:
+		parts := []string{formatPrimHead(n)}
+		for _, arg := range n.Arguments {
+			if hasLeadingComments(arg) {
+				return "", false
+			}
+			flat, ok := formatFlat(arg, true)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, flat)
+		}
+		if wrap && (len(n.Arguments) > 0 || len(n.Annotations) > 0) {
+			return fmt.Sprintf("(%s)", strings.Join(parts, " ")), true
+		}
+		return strings.Join(parts, " "), true
+	}
+
+	return "", false
+}
+
+func formatPrimHead(n ast.Prim) string {
+	head := []string{n.Prim}
+	for _, annotation := range n.Annotations {
+		head = append(head, annotation.Value)
+	}
+	return strings.Join(head, " ")
+}
+
+func isSequence(node ast.Node) bool {
+	_, ok := node.(ast.Sequence)
+	return ok
+}
+
+func hasLeadingComments(node ast.Node) bool {
+	trivia := ast.TriviaOf(node)
+	return trivia != nil && len(trivia.Leading) > 0
+}
+
+func isLineComment(comment ast.Comment) bool {
+	return strings.HasPrefix(comment.Text, "#")
+}
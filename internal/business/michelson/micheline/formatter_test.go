@@ -0,0 +1,53 @@
+package micheline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	format := func(t *testing.T, code string, width int) string {
+		parser := InitParser(code)
+		node := parser.Parse()
+		assert.NoError(t, parser.Error(), "Must not fail")
+		return Format(node, width)
+	}
+
+	t.Run("Nodes that fit in the width are written on a single line", func(t *testing.T) {
+		assert.Equal(t, "{ parameter unit ; storage unit ; code { CDR ; NIL operation ; PAIR } }", format(t, "{parameter unit;storage unit;code{CDR;NIL operation;PAIR}}", 80))
+		assert.Equal(t, "PUSH (pair (nat %a) nat) (Pair 1 2)", format(t, "PUSH (pair (nat %a) nat) (Pair 1 2)", 80))
+		assert.Equal(t, "{}", format(t, "{ }", 80))
+	})
+
+	t.Run("Nodes are broken in lines when they do not fit in the width", func(t *testing.T) {
+		expected := strings.Join([]string{
+			"{ parameter unit ;",
+			"  storage unit ;",
+			"  code { CDR ;",
+			"         NIL operation ;",
+			"         PAIR } }",
+		}, "\n")
+		assert.Equal(t, expected, format(t, "{parameter unit;storage unit;code{CDR;NIL operation;PAIR}}", 30))
+
+		expected = strings.Join([]string{
+			"IF_LEFT",
+			"  { DROP ; UNIT }",
+			"  { FAILWITH }",
+		}, "\n")
+		assert.Equal(t, expected, format(t, "IF_LEFT { DROP ; UNIT } { FAILWITH }", 20))
+	})
+
+	t.Run("Comments are preserved", func(t *testing.T) {
+		input, err := getTestData("format_input.tz")
+		assert.NoError(t, err, "Must not fail")
+		output, err := getTestData("format_output.tz")
+		assert.NoError(t, err, "Must not fail")
+
+		formatted := format(t, string(input), 80)
+		assert.Equal(t, strings.Trim(string(output), "\n"), formatted, "Validate snapshot")
+		// Formatting is idempotent
+		assert.Equal(t, formatted, format(t, formatted, 80))
+	})
+}
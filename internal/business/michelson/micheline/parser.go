@@ -3,26 +3,52 @@ package micheline
 import (
 	"errors"
 	"fmt"
-	"regexp"
+	"io"
 	"strings"
 
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline/token"
 )
 
-type Parser struct {
-	token_position int
-	token_kind     token.Kind
-	token_text     string
+type (
+	Parser struct {
+		token_position int
+		token_kind     token.Kind
+		token_text     string
 
-	scanner Scanner
+		scanner  Scanner
+		comments []ast.Comment // comments scanned but not yet attached to a node
+		stack    []*frame      // nodes being parsed (the innermost node is on top)
 
-	trace bool
-}
+		trace bool
+	}
+	frameKind uint8
+	// frame is a node being parsed.
+	//
+	// Nested nodes are parsed with an explicit stack of frames instead of recursive calls,
+	// so that deeply nested inputs cannot overflow the call stack.
+	frame struct {
+		kind     frameKind
+		begin    int
+		children []ast.Node    // elements of a sequence or arguments of a primitive
+		leading  []ast.Comment // leading comments of the child being parsed
+		waiting  bool          // a child frame was pushed, its node is given to the frame once parsed
+
+		// sequences
+		end token.Kind // token closing the sequence (Nul for sequences without braces)
+
+		// primitives
+		prim        string
+		annotations []ast.Annotation
+		primEnd     int // offset of the last character of the primitive (or of its last annotation)
+		parenthesis int // offset of the opening parenthesis (-1 without parenthesis)
+	}
+)
 
-var (
-	regex_bytes  = regexp.MustCompile("^0x[0-9a-fA-F]+$")
-	regex_number = regexp.MustCompile("^-?[0-9]+$")
+const (
+	rootFrame frameKind = iota
+	sequenceFrame
+	primFrame
 )
 
 func InitParser(micheline string) (parser Parser) {
@@ -30,46 +56,75 @@ func InitParser(micheline string) (parser Parser) {
 	return
 }
 
+// InitReaderParser initializes a parser that reads its source from a reader (see InitReaderScanner)
+func InitReaderParser(reader io.Reader) (parser Parser) {
+	parser.scanner = InitReaderScanner(reader)
+	return
+}
+
 func (p *Parser) Parse() ast.Node {
 	p.next()
 
-	switch kind := p.token_kind; {
-	case kind == token.Bytes:
-		return p.parseBytes()
-	case kind == token.String:
-		return p.parseString()
-	case kind == token.Int:
-		return p.parseInt()
-	case kind == token.Open_paren:
-		return p.parseParenthesis()
-	case kind == token.Identifier:
-		return p.parsePrim()
-	case kind == token.Open_brace:
-		return p.parseSequence()
-	default:
-		p.scanner.errorf("Unexpected token (%s) as sequence child.", kind.String())
-	}
+	p.stack = []*frame{{
+		kind:    rootFrame,
+		begin:   p.token_position,
+		leading: p.takeComments(),
+	}}
 
-	return nil
+	var node ast.Node // node of the last completed frame, given to the frame below it
+	for {
+		f := p.stack[len(p.stack)-1]
+
+		var done bool
+		switch f.kind {
+		case rootFrame:
+			node, done = p.stepRoot(f, node)
+		case sequenceFrame:
+			node, done = p.stepSequence(f, node)
+		case primFrame:
+			node, done = p.stepPrim(f, node)
+		}
+		if !done {
+			continue
+		}
+
+		p.stack = p.stack[:len(p.stack)-1]
+		if len(p.stack) == 0 {
+			return node
+		}
+	}
 }
 
 func (p *Parser) HasErrors() bool {
 	return len(p.scanner.errors) > 0
 }
 
+// Errors returns the syntax errors found while parsing, with their positions in the source
+func (p *Parser) Errors() []ast.SyntaxError {
+	return p.scanner.errors
+}
+
 func (p *Parser) Error() error {
 	if !p.HasErrors() {
 		return nil
 	}
-	_errors := make([]string, len(p.scanner.errors))
+	_errors := make([]string, 0, len(p.scanner.errors))
 	for _, err := range p.scanner.errors {
-		_errors = append(_errors, err.Message)
+		_errors = append(_errors, err.Error())
 	}
 	return errors.New(strings.Join(_errors, ";\n"))
 }
 
 func (p *Parser) next() {
 	p.token_position, p.token_kind, p.token_text = p.scanner.Scan()
+	// Comments are collected as trivia, to be attached to the surrounding nodes
+	for p.token_kind == token.Comment {
+		p.comments = append(p.comments, ast.Comment{
+			Position: p.scanner.Span(p.token_position, p.token_position+len(p.token_text)-1),
+			Text:     p.token_text,
+		})
+		p.token_position, p.token_kind, p.token_text = p.scanner.Scan()
+	}
 	if p.trace {
 		fmt.Printf("[Scanner] (%s) with text (%s)\n", p.token_kind.String(), p.token_text)
 	}
@@ -85,18 +140,15 @@ func (p *Parser) parseBytes() ast.Bytes {
 	defer p.next() // Consume next token
 
 	bytes := p.token_text
-	if isBytes(p.token_text) || len(p.token_text)%2 == 0 {
+	if isBytes(p.token_text) {
 		bytes = p.token_text[2:]
 	} else {
-		p.scanner.errorf("Invalid bytes: %s. %v", p.token_text, position)
+		p.errorf("Invalid bytes: %s.", p.token_text)
 	}
 
 	return ast.Bytes{
-		Position: ast.Position{
-			Pos: position,
-			End: position + len(p.token_text) - 1,
-		},
-		Value: bytes,
+		Position: p.scanner.Span(position, position+len(p.token_text)-1),
+		Value:    bytes,
 	}
 }
 
@@ -110,11 +162,8 @@ func (p *Parser) parseString() ast.String {
 	defer p.next() // Consume next token
 
 	return ast.String{
-		Position: ast.Position{
-			Pos: position,
-			End: position + len(p.token_text) + /* Count quotes */ 1,
-		},
-		Value: p.token_text,
+		Position: p.scanner.Span(position, position+len(p.token_text)+ /* Count quotes */ 1),
+		Value:    p.token_text,
 	}
 }
 
@@ -128,142 +177,263 @@ func (p *Parser) parseInt() ast.Int {
 	defer p.next() // Consume next token
 
 	if !isNumber(p.token_text) {
-		p.scanner.errorf("Invalid number: %s. %v", p.token_text, position)
+		p.errorf("Invalid number: %s.", p.token_text)
 	}
 
 	return ast.Int{
-		Position: ast.Position{
-			Pos: position,
-			End: position + len(p.token_text) - 1,
-		},
-		Value: p.token_text,
+		Position: p.scanner.Span(position, position+len(p.token_text)-1),
+		Value:    p.token_text,
 	}
 }
 
-func (p *Parser) parseSequence() ast.Sequence {
-	if p.trace {
-		fmt.Println("[Parsing|IN] Sequence")
-		defer fmt.Println("[Parsing|OUT] Sequence")
+// stepRoot parses the root expression, a sequence without braces is parsed if the expression is followed by a semicolon
+func (p *Parser) stepRoot(f *frame, child ast.Node) (ast.Node, bool) {
+	if !f.waiting {
+		switch kind := p.token_kind; {
+		case kind == token.Bytes:
+			child = p.parseBytes()
+		case kind == token.String:
+			child = p.parseString()
+		case kind == token.Int:
+			child = p.parseInt()
+		case kind == token.Open_paren:
+			return p.pushPrim(f, true)
+		case kind == token.Identifier:
+			return p.pushPrim(f, false)
+		case kind == token.Open_brace:
+			return p.pushSequence(f)
+		default:
+			p.errorf("Unexpected token (%s) as sequence child.", kind.String())
+			return nil, true
+		}
 	}
+	f.waiting = false
 
-	begin := p.expect(token.Open_brace)
-	p.next() // Consume next token
+	if len(f.children) > 0 {
+		// The child is the sequence without braces, its first element was already parsed
+		return attachComments(child, nil, p.takeComments()), true
+	}
+
+	switch p.token_kind {
+	case token.Nul:
+	case token.Semi:
+		// Scripts can be written as a sequence without braces ("parameter unit ; storage unit ; code { ... }")
+		p.next() // Consume next token
+		trailing := p.takeTrailingComments(ast.PositionOf(child).EndLine)
+		f.children = append(f.children, attachComments(child, f.leading, trailing))
+		f.leading = nil
+		f.waiting = true
+		p.stack = append(p.stack, &frame{
+			kind:     sequenceFrame,
+			begin:    f.begin,
+			end:      token.Nul,
+			children: []ast.Node{f.children[0]},
+		})
+		return nil, false
+	default:
+		p.errorf("Unexpected token (%s) after the end of the expression.", p.token_kind.String())
+	}
+
+	// Comments at the end of the source are attached to the root node
+	return attachComments(child, f.leading, p.takeComments()), true
+}
+
+// stepSequence parses the elements of a sequence (separated by semicolons) until the closing token is reached
+func (p *Parser) stepSequence(f *frame, child ast.Node) (ast.Node, bool) {
+	if f.waiting {
+		f.waiting = false
+		p.appendElement(f, child)
+	}
+
+	for p.token_kind != f.end {
+		if p.token_kind == token.Nul {
+			p.errorAt(f.begin, "Reached EOF while parsing a sequence.")
+			break
+		}
+		f.leading = p.takeComments()
 
-	elements := make([]ast.Node, 0)
-	for p.token_kind != token.Close_brace {
+		var element ast.Node
 		switch p.token_kind {
 		case token.Bytes:
-			elements = append(elements, p.parseBytes())
+			element = p.parseBytes()
 		case token.String:
-			elements = append(elements, p.parseString())
+			element = p.parseString()
 		case token.Int:
-			elements = append(elements, p.parseInt())
+			element = p.parseInt()
 		case token.Identifier:
-			elements = append(elements, p.parsePrim())
+			return p.pushPrim(f, false)
 		case token.Open_brace:
-			elements = append(elements, p.parseSequence())
+			return p.pushSequence(f)
 		default:
-			p.scanner.errorf("Unexpected token (%s) as sequence child.", p.token_kind.String())
+			p.errorf("Unexpected token (%s) as sequence child.", p.token_kind.String())
 		}
+		p.appendElement(f, element)
+	}
+
+	return p.closeSequence(f), true
+}
+
+// appendElement adds an element to a sequence, the semicolon separating it from the next element is consumed
+func (p *Parser) appendElement(f *frame, element ast.Node) {
+	if p.token_kind != f.end && p.token_kind != token.Nul {
+		p.expect(token.Semi) // Semicolon is used to separate elements in sequences
+		p.next()             // Consume next token
+	}
+
+	if element != nil {
+		trailing := p.takeTrailingComments(ast.PositionOf(element).EndLine)
+		f.children = append(f.children, attachComments(element, f.leading, trailing))
+	}
+}
 
-		if p.token_kind != token.Close_brace {
-			p.expect(token.Semi) // Semicolon is used to separate elements in sequences
-			p.next()             // Consume next token
+// pushSequence starts parsing a sequence, the sequence is given to the parent frame once parsed
+func (p *Parser) pushSequence(parent *frame) (ast.Node, bool) {
+	if p.trace {
+		fmt.Println("[Parsing|IN] Sequence")
+	}
+
+	begin := p.expect(token.Open_brace)
+	p.next() // Consume next token
+
+	parent.waiting = true
+	p.stack = append(p.stack, &frame{
+		kind:     sequenceFrame,
+		begin:    begin,
+		end:      token.Close_brace,
+		children: make([]ast.Node, 0),
+	})
+	return nil, false
+}
+
+func (p *Parser) closeSequence(f *frame) ast.Sequence {
+	if p.trace {
+		fmt.Println("[Parsing|OUT] Sequence")
+	}
+
+	if f.end == token.Nul {
+		// Sequence without braces
+		return ast.Sequence{
+			Position: p.scanner.Span(f.begin, ast.PositionOf(f.children[len(f.children)-1]).End),
+			Elements: f.children,
+		}
+	}
+	if p.token_kind == token.Nul {
+		return ast.Sequence{
+			Position: p.scanner.Span(f.begin, p.token_position),
+			Elements: f.children,
 		}
 	}
+	inner := p.takeComments()
 	end := p.expect(token.Close_brace)
-	defer p.next() // Consume next token
+	p.next() // Consume next token
 
 	// TODO: Sort sequences of comparable values
 	// Michelson enforces maps and sets to be sorted
 
-	return ast.Sequence{
-		Position: ast.Position{
-			Pos: begin,
-			End: end,
-		},
-		Elements: elements,
+	sequence := ast.Sequence{
+		Position: p.scanner.Span(f.begin, end),
+		Elements: f.children,
 	}
+	if len(inner) > 0 {
+		sequence.Comments = &ast.Trivia{Inner: inner}
+	}
+	return sequence
 }
 
-func (p *Parser) parsePrim() ast.Prim {
+// pushPrim starts parsing a primitive (with or without parenthesis), the primitive is given to the parent frame once parsed
+func (p *Parser) pushPrim(parent *frame, parenthesis bool) (ast.Node, bool) {
+	f := &frame{
+		kind:        primFrame,
+		children:    make([]ast.Node, 0),
+		parenthesis: -1,
+	}
+
+	if parenthesis {
+		f.parenthesis = p.expect(token.Open_paren)
+		p.next() // Consume next token
+		if p.token_kind != token.Identifier {
+			p.errorf("Expected token (%s), but received (%s).", token.Identifier.String(), p.token_kind.String())
+		}
+	}
+
 	if p.trace {
 		fmt.Printf("[Parsing|IN] Prim (%s)\n", p.token_text)
-		defer fmt.Printf("[Parsing|OUT] Prim (%s)\n", p.token_text)
 	}
 
-	begin := p.expect(token.Identifier)
-	identifier := p.token_text
+	f.begin = p.expect(token.Identifier)
+	f.prim = p.token_text
+	f.primEnd = f.begin + len(f.prim) - 1
 
 	p.next() // Consume next token
 
 	// Check annotations (Annotations can only appear right after an identifier)
-	annotations := p.parseAnnotations()
+	f.annotations = p.parseAnnotations()
+	if len(f.annotations) > 0 {
+		f.primEnd = f.annotations[len(f.annotations)-1].End
+	}
 
-	arguments := make([]ast.Node, 0)
+	parent.waiting = true
+	p.stack = append(p.stack, f)
+	return nil, false
+}
 
-	for {
+// stepPrim parses the arguments of a primitive
+func (p *Parser) stepPrim(f *frame, child ast.Node) (ast.Node, bool) {
+	if f.waiting {
+		f.waiting = false
+		f.children = append(f.children, attachComments(child, f.leading, nil))
+	}
+
+	for isArgument(p.token_kind) {
+		f.leading = p.takeComments()
+
+		var argument ast.Node
 		switch p.token_kind {
 		case token.Bytes:
-			arguments = append(arguments, p.parseBytes())
-			continue
+			argument = p.parseBytes()
 		case token.String:
-			arguments = append(arguments, p.parseString())
-			continue
+			argument = p.parseString()
 		case token.Int:
-			arguments = append(arguments, p.parseInt())
-			continue
+			argument = p.parseInt()
 		case token.Open_paren:
-			arguments = append(arguments, p.parseParenthesis())
-			continue
+			return p.pushPrim(f, true)
 		case token.Identifier:
-			for p.token_kind == token.Identifier {
-				identBegin := p.token_position
-				arguments = append(arguments, ast.Prim{
-					Position: ast.Position{
-						Pos: identBegin,
-						End: identBegin + len(p.token_text) - 1,
-					},
-					Prim:        p.token_text,
-					Annotations: p.parseAnnotations(),
-				})
-				p.next() // Consume next token
+			// Primitives without parenthesis do not have arguments (e.g. "nat" in "PUSH nat 1")
+			identBegin := p.token_position
+			argument = ast.Prim{
+				Position:    p.scanner.Span(identBegin, identBegin+len(p.token_text)-1),
+				Prim:        p.token_text,
+				Annotations: p.parseAnnotations(),
 			}
-			continue
+			p.next() // Consume next token
 		case token.Open_brace:
-			arguments = append(arguments, p.parseSequence())
-			continue
+			return p.pushSequence(f)
 		}
-		break
+		f.children = append(f.children, attachComments(argument, f.leading, nil))
 	}
 
-	return ast.Prim{
-		Position: ast.Position{
-			Pos: begin,
-			End: p.token_position,
-		},
-		Prim:        identifier,
-		Annotations: annotations,
-		Arguments:   arguments,
+	if p.trace {
+		fmt.Printf("[Parsing|OUT] Prim (%s)\n", f.prim)
 	}
-}
-
-func (p *Parser) parseParenthesis() ast.Prim {
-	begin := p.expect(token.Open_paren)
 
-	p.next() // Consume next token
-	if p.token_kind != token.Identifier {
-		p.scanner.errorf("Expected token (%s), but received (%s).", token.Identifier.String(), p.token_kind.String())
+	end := f.primEnd
+	if len(f.children) > 0 {
+		end = ast.PositionOf(f.children[len(f.children)-1]).End
+	}
+	prim := ast.Prim{
+		Position:    p.scanner.Span(f.begin, end),
+		Prim:        f.prim,
+		Annotations: f.annotations,
+		Arguments:   f.children,
 	}
 
-	node := p.parsePrim()
-	end := p.expect(token.Close_paren)
-	p.next() // Consume next token
-
-	node.Position.Pos = begin
-	node.Position.End = end
+	if f.parenthesis >= 0 {
+		end := p.expect(token.Close_paren)
+		p.next() // Consume next token
+		prim.Position = p.scanner.Span(f.parenthesis, end)
+	}
 
-	return node
+	return prim, true
 }
 
 // parseAnnotations parses annotations (Annotations can only appear right after an identifier)
@@ -281,7 +451,7 @@ func (p *Parser) parseAnnotation() ast.Annotation {
 	var annotationKind ast.AnnotationKind
 
 	if len(p.token_text) == 0 {
-		p.scanner.errorf("Unexpected empty")
+		p.errorf("Unexpected empty annotation.")
 	} else {
 		switch p.token_text[0] {
 		case ':':
@@ -291,27 +461,99 @@ func (p *Parser) parseAnnotation() ast.Annotation {
 		case '%':
 			annotationKind = ast.FieldAnnotation
 		default:
-			p.scanner.errorf("Unexpected annotation: (%s)", p.token_text)
+			p.errorf("Unexpected annotation (%s).", p.token_text)
 		}
 	}
 	return ast.Annotation{
-		Position: ast.Position{
-			Pos: position,
-			End: position + len(p.token_text) - 1,
-		},
-		Kind:  annotationKind,
-		Value: p.token_text,
+		Position: p.scanner.Span(position, position+len(p.token_text)-1),
+		Kind:     annotationKind,
+		Value:    p.token_text,
 	}
 }
 
+// errorAt records an error at a given offset
+func (p *Parser) errorAt(offset int, format string, args ...interface{}) {
+	p.scanner.errorAt(offset, format, args...)
+}
+
+// errorf records an error at the position of the current token
+func (p *Parser) errorf(format string, args ...interface{}) {
+	p.scanner.errorAt(p.token_position, format, args...)
+}
+
 func (p *Parser) expect(kind token.Kind) (pos int) {
 	if p.token_kind == kind {
 		pos = p.token_position
 	} else {
-		p.scanner.errorf("Expected token kind (%s), but received (%s).", kind.String(), p.token_kind.String())
+		p.errorf("Expected token kind (%s), but received (%s).", kind.String(), p.token_kind.String())
 	}
 	return
 }
 
-func isBytes(text string) bool  { return regex_bytes.MatchString(text) }
-func isNumber(text string) bool { return regex_number.MatchString(text) }
+// takeComments returns the comments that were not attached yet
+func (p *Parser) takeComments() (comments []ast.Comment) {
+	comments, p.comments = p.comments, nil
+	return
+}
+
+// takeTrailingComments returns the comments, not attached yet, that start on a given line
+func (p *Parser) takeTrailingComments(line int) (comments []ast.Comment) {
+	i := 0
+	for i < len(p.comments) && p.comments[i].Line == line {
+		i++
+	}
+	comments, p.comments = p.comments[:i:i], p.comments[i:]
+	return
+}
+
+// attachComments attaches leading and trailing comments to a node
+func attachComments(node ast.Node, leading []ast.Comment, trailing []ast.Comment) ast.Node {
+	if len(leading) == 0 && len(trailing) == 0 {
+		return node
+	}
+	trivia := ast.Trivia{}
+	if existing := ast.TriviaOf(node); existing != nil {
+		trivia = *existing
+	}
+	trivia.Leading = append(leading, trivia.Leading...)
+	trivia.Trailing = append(trivia.Trailing, trailing...)
+	return ast.WithTrivia(node, &trivia)
+}
+
+func isArgument(kind token.Kind) bool {
+	switch kind {
+	case token.Bytes, token.String, token.Int, token.Open_paren, token.Identifier, token.Open_brace:
+		return true
+	}
+	return false
+}
+
+// isBytes checks if a text is written as bytes (0x followed by pairs of hexadecimal digits)
+func isBytes(text string) bool {
+	if len(text) < 2 || text[:2] != "0x" || len(text)%2 != 0 {
+		return false
+	}
+	for i := 2; i < len(text); i++ {
+		c := text[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// isNumber checks if a text is a (signed) decimal integer
+func isNumber(text string) bool {
+	if len(text) > 0 && text[0] == '-' {
+		text = text[1:]
+	}
+	if len(text) == 0 {
+		return false
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] < '0' || text[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
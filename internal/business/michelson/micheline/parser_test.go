@@ -1,8 +1,12 @@
 package micheline
 
 import (
+	"fmt"
+	"runtime/debug"
+	"strings"
 	"testing"
 
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -135,4 +139,172 @@ func TestParseContracts(t *testing.T) {
 			},
 		})
 	})
+
+	t.Run("Parse Contract without braces (.tz files)", func(t *testing.T) {
+		runTests(t, []test{
+			{
+				Input: `
+				# Toplevel sections
+				parameter unit;
+				storage unit;
+				code { CDR ; NIL operation ; PAIR };
+				`,
+				Output: "Sequence([Prim(parameter, [], [Prim(unit, [], [])]), Prim(storage, [], [Prim(unit, [], [])]), Prim(code, [], [Sequence([Prim(CDR, [], []), Prim(NIL, [], [Prim(operation, [], [])]), Prim(PAIR, [], [])])])])",
+			},
+			{
+				Input:  `parameter unit ; storage unit ; code {}`,
+				Output: "Sequence([Prim(parameter, [], [Prim(unit, [], [])]), Prim(storage, [], [Prim(unit, [], [])]), Prim(code, [], [Sequence([])])])",
+			},
+		})
+	})
+
+	t.Run("Tokens after the end of the expression are reported", func(t *testing.T) {
+		parser := InitParser("{ UNIT } }")
+		parser.Parse()
+		assert.EqualError(t, parser.Error(), "Unexpected token (Close_brace) after the end of the expression. (line 1, column 10)\n1 | { UNIT } }\n             ^")
+	})
+}
+
+func TestParsePositions(t *testing.T) {
+	t.Run("Nodes carry line/column spans", func(t *testing.T) {
+		parser := InitParser("{ PUSH nat 1 ;\n  DROP }")
+		node := parser.Parse()
+		assert.NoError(t, parser.Error())
+
+		seq := node.(ast.Sequence)
+		assert.Equal(t, ast.Position{Pos: 0, End: 22, Line: 1, Column: 1, EndLine: 2, EndColumn: 8}, seq.Position)
+		assert.Equal(t, ast.Position{Pos: 2, End: 11, Line: 1, Column: 3, EndLine: 1, EndColumn: 12}, seq.Elements[0].(ast.Prim).Position)
+		assert.Equal(t, ast.Position{Pos: 17, End: 20, Line: 2, Column: 3, EndLine: 2, EndColumn: 6}, seq.Elements[1].(ast.Prim).Position)
+	})
+	t.Run("Errors include the position and a source excerpt", func(t *testing.T) {
+		parser := InitParser("{ PUSH nat 1 ;\n  DROP ) }")
+		parser.Parse()
+
+		errors := parser.Errors()
+		assert.Len(t, errors, 1)
+		assert.Equal(t, ast.Position{Pos: 22, End: 22, Line: 2, Column: 8, EndLine: 2, EndColumn: 8}, errors[0].Position)
+		assert.EqualError(t, parser.Error(), "Expected token kind (Semi), but received (Close_paren). (line 2, column 8)\n2 |   DROP ) }\n           ^")
+	})
+	t.Run("Unterminated sequences and invalid bytes are reported", func(t *testing.T) {
+		parser := InitParser("{ 1 ;\n  0xz1")
+		parser.Parse()
+		assert.EqualError(t, parser.Error(), "Invalid bytes: 0xz1. (line 2, column 3)\n2 |   0xz1\n      ^;\nReached EOF while parsing a sequence. (line 1, column 1)\n1 | { 1 ;\n    ^")
+	})
+}
+
+func TestParseComments(t *testing.T) {
+	parser := InitParser("# leading\n{ UNIT ; # after unit\n  /* before drop */ DROP\n  # inner\n}")
+	node := parser.Parse()
+	assert.NoError(t, parser.Error())
+
+	seq := node.(ast.Sequence)
+	assert.Equal(t, []string{"# leading"}, commentTexts(seq.Comments.Leading))
+	assert.Equal(t, []string{"# inner"}, commentTexts(seq.Comments.Inner))
+	assert.Equal(t, []string{"# after unit"}, commentTexts(ast.TriviaOf(seq.Elements[0]).Trailing))
+	assert.Equal(t, []string{"/* before drop */"}, commentTexts(ast.TriviaOf(seq.Elements[1]).Leading))
+	assert.Equal(t, ast.Position{Pos: 34, End: 50, Line: 3, Column: 3, EndLine: 3, EndColumn: 19}, ast.TriviaOf(seq.Elements[1]).Leading[0].Position)
+}
+
+func TestParseReader(t *testing.T) {
+	t.Run("Parsing from a reader gives the same tree", func(t *testing.T) {
+		source := largeMap(100)
+
+		parser := InitParser(source)
+		expected := parser.Parse()
+		assert.NoError(t, parser.Error())
+
+		parser = InitReaderParser(strings.NewReader(source))
+		node := parser.Parse()
+		assert.NoError(t, parser.Error())
+		assert.Equal(t, expected, node)
+	})
+	t.Run("Errors on the line being read include an excerpt", func(t *testing.T) {
+		parser := InitReaderParser(strings.NewReader("{ 1 ;\n  0xz1"))
+		parser.Parse()
+		// The first line was already read when the end of the sequence was expected
+		assert.EqualError(t, parser.Error(), "Invalid bytes: 0xz1. (line 2, column 3)\n2 |   0xz1\n      ^;\nReached EOF while parsing a sequence. (line 1, column 1)")
+	})
+}
+
+func TestParseDeeplyNested(t *testing.T) {
+	// The parser does not recurse, deeply nested inputs are parsed with a small call stack
+	defer debug.SetMaxStack(debug.SetMaxStack(1 << 20))
+
+	const depth = 100000
+	tests := []struct {
+		Input string
+		Child func(node ast.Node) ast.Node
+	}{
+		{
+			Input: strings.Repeat("{ ", depth) + strings.Repeat("} ", depth),
+			Child: func(node ast.Node) ast.Node { return node.(ast.Sequence).Elements[0] },
+		},
+		{
+			Input: strings.Repeat("Some (", depth) + "Unit" + strings.Repeat(")", depth),
+			Child: func(node ast.Node) ast.Node { return node.(ast.Prim).Arguments[0] },
+		},
+	}
+
+	for _, test := range tests {
+		parser := InitParser(test.Input)
+		node := parser.Parse()
+		assert.NoError(t, parser.Error())
+
+		for i := 0; i < depth-1; i++ {
+			node = test.Child(node)
+		}
+		assert.NotNil(t, node)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	source := largeMap(10000)
+	b.SetBytes(int64(len(source)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		parser := InitParser(source)
+		parser.Parse()
+	}
+}
+
+func BenchmarkParseReader(b *testing.B) {
+	source := largeMap(10000)
+	b.SetBytes(int64(len(source)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		parser := InitReaderParser(strings.NewReader(source))
+		parser.Parse()
+	}
+}
+
+func BenchmarkParseDeeplyNested(b *testing.B) {
+	source := strings.Repeat("Some (", 10000) + "Unit" + strings.Repeat(")", 10000)
+	b.SetBytes(int64(len(source)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		parser := InitParser(source)
+		parser.Parse()
+	}
+}
+
+// largeMap builds a map literal with (n) elements (similar to the storage of a token contract)
+func largeMap(n int) string {
+	var b strings.Builder
+	b.WriteString("{ ")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(" ;\n  ")
+		}
+		fmt.Fprintf(&b, `Elt "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx_%06d" (Pair %d 0x%064x { "metadata" ; "ipfs://QmbKq2KBuGSuqpHRcGeSLXApC9Xo3qnRWUaRJq6xi8Ytvg" })`, i, i, i)
+	}
+	b.WriteString(" }")
+	return b.String()
+}
+
+func commentTexts(comments []ast.Comment) []string {
+	texts := make([]string, len(comments))
+	for i, comment := range comments {
+		texts[i] = comment.Text
+	}
+	return texts
 }
@@ -1,26 +1,35 @@
 package micheline
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"unicode"
 
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline/token"
 )
 
 type (
-	Error struct {
-		Message string
-	}
+	// Error is a syntax error located in the scanned source
+	Error   = ast.SyntaxError
 	Scanner struct {
 		// immutable state
-		source string
+		reader   io.Reader  // reader of the source (nil when the whole source is in the buffer)
+		streamed bool       // the source is read from a reader, it is not kept in memory
+		lines    ast.Source // line index of the source (used to compute line/column positions)
+
+		// reading state
+		buffer       []byte // bytes read from the source
+		bufferOffset int    // offset of the next byte in the buffer
 
 		// scanning state
-		char         rune // current character
-		offset       int  // character offset
-		rdOffset     int  // reading offset (position after current character)
-		lineOffset   int  // current line offset
-		columnOffset int  // current column offset
+		char      rune   // current character
+		offset    int    // character offset
+		rdOffset  int    // reading offset (position after current character)
+		lineBegin int    // offset of the beginning of the current line (only for streamed sources)
+		line      []byte // text of the current line read so far (only for streamed sources, used for excerpts)
+		text      []byte // text of the token being scanned
 
 		panicOnError bool
 		errors       []Error
@@ -29,14 +38,26 @@ type (
 
 const (
 	NUL = 0
+	// Number of bytes read at once when the source is read from a reader
+	READ_BUFFER_SIZE = 64 * 1024
 )
 
+// InitScanner initializes a scanner over a source text
 func InitScanner(micheline string) (scanner Scanner) {
-	scanner.source = micheline
-	scanner.offset = 0
-	scanner.rdOffset = 0
-	scanner.lineOffset = 0
-	scanner.columnOffset = 0
+	scanner.buffer = []byte(micheline)
+	scanner.lines = ast.NewSource(micheline)
+	return
+}
+
+// InitReaderScanner initializes a scanner that reads its source from a reader.
+//
+// The source is read while tokens are scanned, it is never fully loaded in memory.
+// Errors only include an excerpt when they are located on the line being read.
+func InitReaderScanner(reader io.Reader) (scanner Scanner) {
+	scanner.reader = reader
+	scanner.streamed = true
+	scanner.buffer = make([]byte, 0, READ_BUFFER_SIZE)
+	scanner.lines = ast.NewPartialSource()
 	return
 }
 
@@ -45,7 +66,7 @@ func (s *Scanner) Scan() (pos int, tk token.Kind, text string) {
 
 	s.next()
 	pos = s.offset
-	text = string(s.char)
+	s.text = append(s.text[:0], byte(s.char))
 
 	switch s.char {
 	case NUL:
@@ -60,71 +81,67 @@ func (s *Scanner) Scan() (pos int, tk token.Kind, text string) {
 		tk = token.Close_paren
 	case ';':
 		tk = token.Semi
-	case '#':
+	case '#': // Line comment (ends at the end of the line or at EOF)
+		tk = token.Comment
+		for s.peek() != '\n' && !s.isAtEnd() {
+			s.consume()
+		}
+		return pos, tk, string(bytes.TrimRight(s.text, "\r"))
+	case '/': // Block comment (/* ... */)
+		if s.peek() != '*' {
+			tk = token.Identifier
+			s.errorf("Unexpected character (/).")
+			break
+		}
 		tk = token.Comment
-		for s.peek() != '\n' {
+		s.consume() // Consume "*"
+		for !bytes.HasSuffix(s.text, []byte("*/")) || len(s.text) < 4 {
 			if s.isAtEnd() {
-				s.errorf(`Reached EOF while parsing comment. %s`, s.lineInfo())
+				s.errorAt(pos, `Reached EOF while parsing comment.`)
 				break
 			}
-			s.next()
-			text = text + string(s.char)
+			s.consume()
 		}
 	case '%', ':', '@':
 		tk = token.Annot
 		for isIdentifier(s.peek()) {
-			if s.isAtEnd() {
-				s.errorf(`Reached EOF while parsing annotation. %s`, s.lineInfo())
-				break
-			}
-			s.next()
-			text = text + string(s.char)
+			s.consume()
 		}
 	case '"': // String
 		tk = token.String
-		text = ""
+		s.text = s.text[:0]
 		for s.peek() != '"' {
 			if s.isAtEnd() {
-				s.errorf(`Reached EOF while parsing a string. %s`, s.lineInfo())
+				s.errorAt(pos, `Reached EOF while parsing a string.`)
 				break
 			}
-			s.next()
-			text = text + string(s.char)
+			s.consume()
 		}
 		s.next() // Consume closing quote
 	case '0': // Can be an integer or bytes
 		for isIdentifier(s.peek()) {
-			if s.isAtEnd() {
-				s.errorf(`Reached EOF while parsing hexadecimal. %s`, s.lineInfo())
-				break
-			}
-			s.next()
-			text = text + string(s.char)
+			s.consume()
 		}
 
-		if len(text) > 1 && text[0:2] == "0x" {
+		if bytes.HasPrefix(s.text, []byte("0x")) {
 			tk = token.Bytes
 		} else {
 			tk = token.Int
 		}
 	default:
 		for isIdentifier(s.peek()) {
-			if s.isAtEnd() {
-				s.errorf(`Reached EOF while parsing value. %s`, s.lineInfo())
-				break
-			}
-			s.next()
-			text = text + string(s.char)
+			s.consume()
 		}
 
-		if unicode.IsDigit(s.char) {
+		// Identifiers can end with digits (e.g. SHA256), the first character tells numbers apart
+		if first := rune(s.text[0]); first == '-' || unicode.IsDigit(first) {
 			tk = token.Int
 		} else {
 			tk = token.Identifier
 		}
 	}
 
-	return
+	return pos, tk, string(s.text)
 }
 
 func (s *Scanner) skipWhitespace() {
@@ -133,19 +150,23 @@ func (s *Scanner) skipWhitespace() {
 	}
 }
 
-func (s *Scanner) incrementLine()   { s.lineOffset = s.lineOffset + 1 }
-func (s *Scanner) incrementColumn() { s.columnOffset = s.columnOffset + 1 }
-func (s *Scanner) resetColumn()     { s.columnOffset = 0 }
-func (s *Scanner) incrementPosition() {
-	if s.char == '\n' {
-		s.incrementLine()
-		s.resetColumn()
-	} else {
-		s.incrementColumn()
-	}
-
+// incrementPosition reads the next byte of the source and indexes the lines
+func (s *Scanner) incrementPosition() byte {
+	c := s.buffer[s.bufferOffset]
+	s.bufferOffset++
 	s.offset = s.rdOffset
 	s.rdOffset = s.rdOffset + 1
+	if !s.streamed {
+		return c
+	}
+	if c == '\n' {
+		s.lineBegin = s.rdOffset
+		s.lines.AddLine(s.rdOffset)
+		s.line = s.line[:0]
+	} else {
+		s.line = append(s.line, c)
+	}
+	return c
 }
 
 // next reads the next byte in the scanner sequence and increments the current position.
@@ -155,31 +176,64 @@ func (s *Scanner) next() {
 	}
 }
 
+// consume reads the next byte and appends it to the text of the current token
+func (s *Scanner) consume() {
+	s.next()
+	s.text = append(s.text, byte(s.char))
+}
+
 // peek returns the next byte in the scanner sequence without
 // incrementing the current position. Returns (0 => NUL) if the scanning is over.
-func (s Scanner) peek() rune {
+func (s *Scanner) peek() rune {
 	if s.isAtEnd() {
 		return NUL
 	}
-	return rune(s.source[s.rdOffset])
+	return rune(s.buffer[s.bufferOffset])
 }
 
-func (s Scanner) isAtEnd() bool {
-	// len(s.source) is cached and there is no overhead
-	return s.rdOffset == len(s.source)
+func (s *Scanner) isAtEnd() bool {
+	return s.bufferOffset == len(s.buffer) && !s.fill()
 }
 
-func (s Scanner) lineInfo() string {
-	return fmt.Sprintf("(line: %d, column: %d)", s.lineOffset, s.columnOffset)
+// fill reads the next chunk of the source into the buffer, it returns false if the source is over
+func (s *Scanner) fill() bool {
+	for s.reader != nil {
+		n, err := s.reader.Read(s.buffer[:cap(s.buffer)])
+		s.buffer = s.buffer[:n]
+		s.bufferOffset = 0
+		if err != nil {
+			// The source is over (read errors are reported as the end of the source)
+			s.reader = nil
+		}
+		if n > 0 {
+			return true
+		}
+	}
+	return false
 }
 
 func isWhitespace(c rune) bool { return c == ' ' || c == '\n' || c == '\r' || c == '\t' }
 func isIdentifier(c rune) bool { return '0' <= c && c <= '9' || c == '_' || 'A' <= c && c <= 'z' }
 
+// Span converts byte offsets into a position with lines and columns
+func (s Scanner) Span(pos int, end int) ast.Position {
+	return s.lines.Span(pos, end)
+}
+
+// errorf records an error at the current offset
 func (s *Scanner) errorf(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
+	s.errorAt(s.offset, format, args...)
+}
+
+// errorAt records an error at a given offset
+func (s *Scanner) errorAt(offset int, format string, args ...interface{}) {
+	err := s.lines.Error(offset, fmt.Sprintf(format, args...))
+	if s.streamed && offset >= s.lineBegin {
+		// Sources read from a reader are not kept, only the current line can be rendered
+		err.Excerpt = ast.ExcerptOf(string(s.line), err.Position)
+	}
 	if s.panicOnError {
-		panic(msg)
+		panic(err.Error())
 	}
-	s.errors = append(s.errors, Error{Message: msg})
+	s.errors = append(s.errors, err)
 }
@@ -251,4 +251,44 @@ func TestTokenize(t *testing.T) {
 			},
 		})
 	})
+	t.Run("Tokenize Comments", func(t *testing.T) {
+		runTests(t, []test{
+			{
+				Input: "UNIT /* multi\nline é */ # end",
+				Output: []output{
+					{
+						kind: token.Identifier,
+						text: "UNIT",
+					},
+					{
+						position: 5,
+						kind:     token.Comment,
+						text:     "/* multi\nline é */",
+					},
+					{
+						kind: token.Comment,
+						text: "# end",
+					},
+					{
+						kind: token.Nul,
+					},
+				},
+			},
+		})
+	})
+	t.Run("Tokenize identifiers ending with digits", func(t *testing.T) {
+		runTests(t, []test{
+			{
+				Input: "SHA256 ; -12 ; 7",
+				Output: []output{
+					{kind: token.Identifier, text: "SHA256"},
+					{kind: token.Semi},
+					{kind: token.Int, text: "-12"},
+					{kind: token.Semi},
+					{kind: token.Int, text: "7"},
+					{kind: token.Nul},
+				},
+			},
+		})
+	})
 }
@@ -1,11 +1,17 @@
 package michelson
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
 
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/binary"
 	MichelsonJSON "github.com/romarq/tezos-sc-tester/internal/business/michelson/json"
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/primitives"
 )
 
 // JSONOfMicheline converts Michelson from "micheline" to "json" format
@@ -26,15 +32,95 @@ func MichelineOfJSON(michelsonJSON json.RawMessage) (string, error) {
 	return micheline.Print(ast, ""), nil
 }
 
+// PrintMicheline prints a value of a given type in "micheline" format,
+// optimized domain specific values (addresses, keys, signatures, ...) are written in their readable form
+func PrintMicheline(value ast.Node, typ ast.Node, indent string) (string, error) {
+	readable, err := binary.Readable(value, typ)
+	if err != nil {
+		return "", err
+	}
+	return micheline.Print(readable, indent), nil
+}
+
+// PrintJSON prints a value of a given type in "json" format,
+// optimized domain specific values (addresses, keys, signatures, ...) are written in their readable form
+func PrintJSON(value ast.Node, typ ast.Node, prefix string, indent string) (json.RawMessage, error) {
+	readable, err := binary.Readable(value, typ)
+	if err != nil {
+		return nil, err
+	}
+	return MichelsonJSON.Print(readable, prefix, indent)
+}
+
 // ParseJSON parses Michelson from "json" format into an AST
 func ParseJSON(michelsonJSON json.RawMessage) (ast.Node, error) {
 	parser := MichelsonJSON.Parser{}
 	return parser.Parse(michelsonJSON)
 }
 
+// ParseJSONOrMicheline parses Michelson written in "json" format, or in "micheline" format if the JSON is a string
+func ParseJSONOrMicheline(raw json.RawMessage) (ast.Node, error) {
+	if michelsonMicheline, ok := michelineOf(raw); ok {
+		return ParseMicheline(michelsonMicheline)
+	}
+	return ParseJSON(raw)
+}
+
 // ParseMicheline parses Michelson from "micheline" format into an AST
 func ParseMicheline(michelsonMicheline string) (ast.Node, error) {
 	parser := micheline.InitParser(michelsonMicheline)
 	ast := parser.Parse()
 	return ast, parser.Error()
 }
+
+// ParseMichelineReader parses Michelson from "micheline" format into an AST, the source is read while it is parsed
+func ParseMichelineReader(reader io.Reader) (ast.Node, error) {
+	parser := micheline.InitReaderParser(reader)
+	ast := parser.Parse()
+	return ast, parser.Error()
+}
+
+// CheckPrimitives verifies that the primitives of a node are supported by a protocol (the latest protocol is assumed when the protocol is unknown),
+// unknown primitives and primitives receiving a wrong number of arguments or annotations are reported with their positions
+func CheckPrimitives(node ast.Node, protocol string) error {
+	return primitivesError(primitives.NewRegistry(protocol).Check(node))
+}
+
+// CheckSyntax verifies that the primitives of a node exist and receive the expected number of arguments and annotations,
+// regardless of the protocol
+func CheckSyntax(node ast.Node) error {
+	return primitivesError(primitives.CheckSyntax(node))
+}
+
+func primitivesError(errors []ast.SyntaxError) error {
+	if len(errors) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(errors))
+	for _, err := range errors {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Errorf("%s", strings.Join(messages, ";\n"))
+}
+
+// FormatMicheline pretty-prints Michelson written in "micheline" format, comments are preserved
+func FormatMicheline(michelsonMicheline string, width int) (string, []ast.SyntaxError) {
+	parser := micheline.InitParser(michelsonMicheline)
+	ast := parser.Parse()
+	if parser.HasErrors() {
+		return "", parser.Errors()
+	}
+	return micheline.Format(ast, width), nil
+}
+
+// michelineOf extracts Michelson written in "micheline" format from a JSON string (Michelson JSON is never a string)
+func michelineOf(raw json.RawMessage) (string, bool) {
+	if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte(`"`)) {
+		return "", false
+	}
+	var michelsonMicheline string
+	if err := json.Unmarshal(raw, &michelsonMicheline); err != nil {
+		return "", false
+	}
+	return michelsonMicheline, true
+}
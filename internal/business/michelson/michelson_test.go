@@ -71,6 +71,61 @@ func TestJSONOfMicheline(t *testing.T) {
 	})
 }
 
+func TestParseJSONOrMicheline(t *testing.T) {
+	t.Run("Michelson JSON", func(t *testing.T) {
+		node, err := ParseJSONOrMicheline(json.RawMessage(`{ "prim": "Pair", "args": [ { "int": "1" }, { "string": "a" } ] }`))
+		assert.NoError(t, err)
+		assert.Equal(t, `Prim(Pair, [], [Int(1), String(a)])`, node.String())
+	})
+	t.Run("Micheline text", func(t *testing.T) {
+		node, err := ParseJSONOrMicheline(json.RawMessage(` "Pair 1 \"a\"" `))
+		assert.NoError(t, err)
+		assert.Equal(t, `Prim(Pair, [], [Int(1), String(a)])`, node.String())
+	})
+	t.Run("Errors are reported against the micheline text", func(t *testing.T) {
+		_, err := ParseJSONOrMicheline(json.RawMessage(`"{ UNIT ;\n  DROP ) }"`))
+		assert.EqualError(t, err, "Expected token kind (Semi), but received (Close_paren). (line 2, column 8)\n2 |   DROP ) }\n           ^")
+	})
+}
+
+func TestCheckSyntax(t *testing.T) {
+	// Parsing does not check primitives (outputs of 'tezos-client' or of a node may use primitives of newer protocols)
+	node, err := ParseMicheline("{ parameter unit ; storage unit ; code { IF } }")
+	assert.NoError(t, err)
+	assert.EqualError(t, CheckSyntax(node), "Primitive (IF) expects 2 argument(s), but received 0. (line 1, column 42)")
+
+	node, err = ParseJSON(json.RawMessage(`{ "prim": "Some", "args": [ { "int": "1" }, { "int": "2" } ] }`))
+	assert.NoError(t, err)
+	assert.Error(t, CheckSyntax(node))
+}
+
+func TestPrintReadable(t *testing.T) {
+	value, err := ParseMicheline(`(Pair 0x000002298c03ed7d454a101eb7022bc95f7e5f41ac78 (Pair 0x7a06a770 0x00))`)
+	assert.NoError(t, err)
+	typ, err := ParseMicheline(`(pair address chain_id bytes)`)
+	assert.NoError(t, err)
+
+	t.Run("Micheline", func(t *testing.T) {
+		s, err := PrintMicheline(value, typ, "")
+		assert.NoError(t, err)
+		assert.Equal(t, `(Pair "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" "NetXdQprcVkpaWU" 0x00)`, s)
+	})
+	t.Run("JSON", func(t *testing.T) {
+		j, err := PrintJSON(value, typ, "", "")
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{ "prim": "Pair", "args": [ { "string": "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" }, { "string": "NetXdQprcVkpaWU" }, { "bytes": "00" } ] }`, string(j))
+	})
+	t.Run("Invalid bytes", func(t *testing.T) {
+		value, err := ParseMicheline(`0x00`)
+		assert.NoError(t, err)
+		typ, err := ParseMicheline(`key_hash`)
+		assert.NoError(t, err)
+
+		_, err = PrintMicheline(value, typ, "")
+		assert.EqualError(t, err, `invalid optimized key_hash (0x00). expected 21 bytes.`)
+	})
+}
+
 func getTestData(fileName string) ([]byte, error) {
 	wd, _ := os.Getwd()
 	contract_file_path := path.Join(wd, "__test_data__", fileName)
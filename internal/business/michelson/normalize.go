@@ -0,0 +1,146 @@
+package michelson
+
+import (
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/binary"
+)
+
+// ParsingMode defines how values are represented (https://tezos.gitlab.io/active/michelson.html#unparsing-modes)
+type ParsingMode string
+
+const (
+	Readable        ParsingMode = "Readable"
+	Optimized       ParsingMode = "Optimized"
+	OptimizedLegacy ParsingMode = "Optimized_legacy"
+)
+
+// Normalize converts a value of a given type to the representation of a given mode (the same way "tezos-client normalize data" does).
+//
+// - Readable: domain specific values are written as strings and right combs are written as flat pairs (Pair a b c);
+// - Optimized: domain specific values are written as bytes (or integers for timestamps), right combs with more
+// than 3 elements are written as sequences { a ; b ; c ; d };
+// - Optimized_legacy: same as optimized, but all pairs are binary (Pair a (Pair b c)).
+func Normalize(value ast.Node, typ ast.Node, mode ParsingMode) (ast.Node, error) {
+	if err := ValidateValue(value, typ); err != nil {
+		return nil, err
+	}
+	return normalize(value, typ, mode)
+}
+
+func normalize(value ast.Node, typ ast.Node, mode ParsingMode) (ast.Node, error) {
+	t := typ.(ast.Prim)
+
+	switch t.Prim {
+	case "address", "contract", "key_hash", "key", "signature", "chain_id", "timestamp":
+		// Leaves are optimized first, so that readable values have a canonical representation
+		optimized, err := binary.Optimize(value, t)
+		if err != nil {
+			return nil, err
+		}
+		if mode == Readable {
+			return binary.Readable(optimized, t)
+		}
+		return optimized, nil
+	case "option":
+		if isPrim(value, "Some", 1) {
+			arg, err := normalize(value.(ast.Prim).Arguments[0], t.Arguments[0], mode)
+			if err != nil {
+				return nil, err
+			}
+			return ast.Prim{Prim: "Some", Arguments: []ast.Node{arg}}, nil
+		}
+		return ast.Prim{Prim: "None"}, nil
+	case "or":
+		prim := value.(ast.Prim)
+		branch := t.Arguments[0]
+		if prim.Prim == "Right" {
+			branch = t.Arguments[1]
+		}
+		arg, err := normalize(prim.Arguments[0], branch, mode)
+		if err != nil {
+			return nil, err
+		}
+		return ast.Prim{Prim: prim.Prim, Arguments: []ast.Node{arg}}, nil
+	case "pair":
+		return normalizePair(value, t, mode)
+	case "list", "set":
+		seq := value.(ast.Sequence)
+		elements := make([]ast.Node, len(seq.Elements))
+		for i, el := range seq.Elements {
+			var err error
+			if elements[i], err = normalize(el, t.Arguments[0], mode); err != nil {
+				return nil, err
+			}
+		}
+		return ast.Sequence{Elements: elements}, nil
+	case "map", "big_map":
+		seq, ok := value.(ast.Sequence)
+		if !ok {
+			// Big map identifier
+			return value, nil
+		}
+		elements := make([]ast.Node, len(seq.Elements))
+		for i, el := range seq.Elements {
+			elt := el.(ast.Prim)
+			key, err := normalize(elt.Arguments[0], t.Arguments[0], mode)
+			if err != nil {
+				return nil, err
+			}
+			val, err := normalize(elt.Arguments[1], t.Arguments[1], mode)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = ast.Prim{Prim: "Elt", Arguments: []ast.Node{key, val}}
+		}
+		return ast.Sequence{Elements: elements}, nil
+	}
+
+	return value, nil
+}
+
+// normalizePair normalizes a pair (written as (Pair a b ...), (Pair a (Pair b ...)) or { a ; b ; ... })
+func normalizePair(value ast.Node, t ast.Prim, mode ParsingMode) (ast.Node, error) {
+	elements := pairElements(value)
+
+	left, err := normalize(elements[0], t.Arguments[0], mode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Right combs: (pair a b c) == (pair a (pair b c))
+	rightType := combOf("pair", t.Arguments[1:])
+	right, err := normalize(combOf("Pair", elements[1:]), rightType, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if isCombType(rightType) {
+		switch mode {
+		case Readable:
+			// Flat pairs: (Pair a b c)
+			if prim, ok := right.(ast.Prim); ok && prim.Prim == "Pair" {
+				return ast.Prim{Prim: "Pair", Arguments: append([]ast.Node{left}, prim.Arguments...)}, nil
+			}
+		case Optimized:
+			// Combs of more than 3 elements are written as sequences
+			if seq, ok := right.(ast.Sequence); ok {
+				return ast.Sequence{Elements: append([]ast.Node{left}, seq.Elements...)}, nil
+			}
+			rt := rightType.(ast.Prim)
+			if isCombType(combOf("pair", rt.Arguments[1:])) && isPrim(right, "Pair", 2) {
+				inner := right.(ast.Prim).Arguments
+				if isPrim(inner[1], "Pair", 2) {
+					last := inner[1].(ast.Prim).Arguments
+					return ast.Sequence{Elements: []ast.Node{left, inner[0], last[0], last[1]}}, nil
+				}
+			}
+		}
+	}
+
+	return ast.Prim{Prim: "Pair", Arguments: []ast.Node{left, right}}, nil
+}
+
+func isCombType(typ ast.Node) bool {
+	prim, ok := typ.(ast.Prim)
+	return ok && prim.Prim == "pair"
+}
@@ -0,0 +1,122 @@
+package michelson
+
+import (
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	type test struct {
+		Value    string
+		Type     string
+		Expected map[ParsingMode]string
+	}
+
+	tests := []test{
+		{
+			Value: `(Pair 1 2)`,
+			Type:  `(pair nat nat)`,
+			Expected: map[ParsingMode]string{
+				Readable:        `(Pair 1 2)`,
+				Optimized:       `(Pair 1 2)`,
+				OptimizedLegacy: `(Pair 1 2)`,
+			},
+		},
+		{
+			Value: `{ 1 ; 2 ; 3 }`,
+			Type:  `(pair nat nat nat)`,
+			Expected: map[ParsingMode]string{
+				Readable:        `(Pair 1 2 3)`,
+				Optimized:       `(Pair 1 (Pair 2 3))`,
+				OptimizedLegacy: `(Pair 1 (Pair 2 3))`,
+			},
+		},
+		{
+			Value: `(Pair 1 (Pair 2 (Pair 3 4)))`,
+			Type:  `(pair nat nat nat nat)`,
+			Expected: map[ParsingMode]string{
+				Readable:        `(Pair 1 2 3 4)`,
+				Optimized:       `{ 1 ; 2 ; 3 ; 4 }`,
+				OptimizedLegacy: `(Pair 1 (Pair 2 (Pair 3 4)))`,
+			},
+		},
+		{
+			Value: `(Pair 1 2 3 4 5)`,
+			Type:  `(pair nat (pair nat (pair nat (pair nat nat))))`,
+			Expected: map[ParsingMode]string{
+				Readable:        `(Pair 1 2 3 4 5)`,
+				Optimized:       `{ 1 ; 2 ; 3 ; 4 ; 5 }`,
+				OptimizedLegacy: `(Pair 1 (Pair 2 (Pair 3 (Pair 4 5))))`,
+			},
+		},
+		{
+			// Pairs nested in the left component are not part of the comb
+			Value: `(Pair (Pair 1 2 3) 4)`,
+			Type:  `(pair (pair nat nat nat) nat)`,
+			Expected: map[ParsingMode]string{
+				Readable:  `(Pair (Pair 1 2 3) 4)`,
+				Optimized: `(Pair (Pair 1 (Pair 2 3)) 4)`,
+			},
+		},
+		{
+			Value: `(Pair "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" "KT1BEqzn5Wx8uJrZNvuS9DVHmLvG9td3fDLi%transfer")`,
+			Type:  `(pair key_hash (contract nat))`,
+			Expected: map[ParsingMode]string{
+				Readable:  `(Pair "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" "KT1BEqzn5Wx8uJrZNvuS9DVHmLvG9td3fDLi%transfer")`,
+				Optimized: `(Pair 0x0002298c03ed7d454a101eb7022bc95f7e5f41ac78 0x011d23c1d3d2f8a4ea5e8784b8f7ecf2ad304c0fe6007472616e73666572)`,
+			},
+		},
+		{
+			Value: `0x000002298c03ed7d454a101eb7022bc95f7e5f41ac78`,
+			Type:  `address`,
+			Expected: map[ParsingMode]string{
+				Readable:  `"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"`,
+				Optimized: `0x000002298c03ed7d454a101eb7022bc95f7e5f41ac78`,
+			},
+		},
+		{
+			Value: `{ "2019-09-26T12:59:51+02:00" ; 0 }`,
+			Type:  `(list timestamp)`,
+			Expected: map[ParsingMode]string{
+				Readable:  `{ "2019-09-26T10:59:51Z" ; "1970-01-01T00:00:00Z" }`,
+				Optimized: `{ 1569495591 ; 0 }`,
+			},
+		},
+		{
+			Value: `{ Elt "NetXdQprcVkpaWU" (Some (Left { 1 ; 2 ; 3 ; 4 })) }`,
+			Type:  `(map chain_id (option (or (pair nat nat nat nat) unit)))`,
+			Expected: map[ParsingMode]string{
+				Readable:  `{ Elt "NetXdQprcVkpaWU" (Some (Left (Pair 1 2 3 4))) }`,
+				Optimized: `{ Elt 0x7a06a770 (Some (Left { 1 ; 2 ; 3 ; 4 })) }`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		value, err := ParseMicheline(test.Value)
+		assert.NoError(t, err)
+		typ, err := ParseMicheline(test.Type)
+		assert.NoError(t, err)
+
+		for mode, expected := range test.Expected {
+			normalized, err := Normalize(value, typ, mode)
+			assert.NoError(t, err, test.Value)
+
+			expectedNode, err := ParseMicheline(expected)
+			assert.NoError(t, err)
+			assert.Equal(t, micheline.Print(expectedNode, ""), micheline.Print(normalized, ""), "%s (%s)", test.Value, mode)
+		}
+	}
+
+	t.Run("Ill-typed values", func(t *testing.T) {
+		value, err := ParseMicheline(`(Pair 1 "a")`)
+		assert.NoError(t, err)
+		typ, err := ParseMicheline(`(pair nat nat)`)
+		assert.NoError(t, err)
+
+		_, err = Normalize(value, typ, Readable)
+		assert.EqualError(t, err, `value "a" is not of type (nat) (at /args/1, line 1, column 9).`)
+	})
+}
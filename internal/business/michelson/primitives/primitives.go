@@ -0,0 +1,417 @@
+package primitives
+
+import (
+	"fmt"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/macros"
+)
+
+type (
+	Kind uint8
+	// Annotations are the maximum number of annotations of each kind accepted by a primitive
+	Annotations struct {
+		Type     int
+		Variable int
+		Field    int
+	}
+	// Primitive describes a Michelson primitive
+	Primitive struct {
+		Name         string
+		Code         byte // code used by the binary encoding of Micheline
+		Kind         Kind
+		MinArguments int
+		MaxArguments int // -1 if the number of arguments is not bounded (e.g. pair)
+		Annotations  Annotations
+		Since        int    // first protocol supporting the primitive (0 if it predates the listed protocols)
+		Removed      int    // first protocol rejecting the primitive (0 if it is still supported)
+		Deprecated   int    // first protocol deprecating the primitive (0 if it was never deprecated)
+		Until        int    // first protocol where the primitive is not deprecated anymore (0 if it is still deprecated)
+		Former       string // name of the primitive before it got renamed (e.g. TICKET became TICKET_DEPRECATED in Lima)
+		Renamed      int    // first protocol using the current name (0 if the primitive was never renamed)
+	}
+	// Registry gives the primitives supported by a protocol.
+	// The zero registry supports the primitives of the latest protocol.
+	Registry struct {
+		Protocol Protocol
+	}
+)
+
+const (
+	Keyword Kind = iota // sections of a script (parameter, storage, code, view) and global constants
+	Instruction
+	Type
+	Data
+)
+
+// primitives are sorted by code (the order is defined by the protocol)
+var primitives = []Primitive{
+	keyword("parameter", 1).annotations(0, 0, 1),
+	keyword("storage", 1),
+	keyword("code", 1),
+	data("False", 0, 0),
+	data("Elt", 2, 2),
+	data("Left", 1, 1),
+	data("None", 0, 0),
+	data("Pair", 2, -1),
+	data("Right", 1, 1),
+	data("Some", 1, 1),
+	data("True", 0, 0),
+	data("Unit", 0, 0),
+	instruction("PACK", 0, 0),
+	instruction("UNPACK", 1, 1),
+	instruction("BLAKE2B", 0, 0),
+	instruction("SHA256", 0, 0),
+	instruction("SHA512", 0, 0),
+	instruction("ABS", 0, 0),
+	instruction("ADD", 0, 0),
+	instruction("AMOUNT", 0, 0),
+	instruction("AND", 0, 0),
+	instruction("BALANCE", 0, 0),
+	instruction("CAR", 0, 0).annotations(0, 1, 1),
+	instruction("CDR", 0, 0).annotations(0, 1, 1),
+	instruction("CHECK_SIGNATURE", 0, 0),
+	instruction("COMPARE", 0, 0),
+	instruction("CONCAT", 0, 0),
+	instruction("CONS", 0, 0),
+	instruction("CREATE_ACCOUNT", 0, 0).deprecated(Babylon, 0).removed(Babylon),
+	instruction("CREATE_CONTRACT", 1, 1).annotations(0, 2, 0),
+	instruction("IMPLICIT_ACCOUNT", 0, 0),
+	instruction("DIP", 1, 2).annotations(0, 0, 0),
+	instruction("DROP", 0, 1).annotations(0, 0, 0),
+	instruction("DUP", 0, 1),
+	instruction("EDIV", 0, 0),
+	instruction("EMPTY_MAP", 2, 2),
+	instruction("EMPTY_SET", 1, 1),
+	instruction("EQ", 0, 0),
+	instruction("EXEC", 0, 0),
+	instruction("FAILWITH", 0, 0).annotations(0, 0, 0),
+	instruction("GE", 0, 0),
+	instruction("GET", 0, 1),
+	instruction("GT", 0, 0),
+	instruction("HASH_KEY", 0, 0),
+	instruction("IF", 2, 2).annotations(0, 0, 0),
+	instruction("IF_CONS", 2, 2).annotations(0, 0, 0),
+	instruction("IF_LEFT", 2, 2).annotations(0, 0, 0),
+	instruction("IF_NONE", 2, 2).annotations(0, 0, 0),
+	instruction("INT", 0, 0),
+	instruction("LAMBDA", 3, 3),
+	instruction("LE", 0, 0),
+	instruction("LEFT", 1, 1).annotations(1, 1, 2),
+	instruction("LOOP", 1, 1).annotations(0, 0, 0),
+	instruction("LSL", 0, 0),
+	instruction("LSR", 0, 0),
+	instruction("LT", 0, 0),
+	instruction("MAP", 1, 1),
+	instruction("MEM", 0, 0),
+	instruction("MUL", 0, 0),
+	instruction("NEG", 0, 0),
+	instruction("NEQ", 0, 0),
+	instruction("NIL", 1, 1),
+	instruction("NONE", 1, 1),
+	instruction("NOT", 0, 0),
+	instruction("NOW", 0, 0),
+	instruction("OR", 0, 0),
+	instruction("PAIR", 0, 1).annotations(1, 1, 2),
+	instruction("PUSH", 2, 2),
+	instruction("RIGHT", 1, 1).annotations(1, 1, 2),
+	instruction("SIZE", 0, 0),
+	instruction("SOME", 0, 0).annotations(1, 1, 1),
+	instruction("SOURCE", 0, 0),
+	instruction("SENDER", 0, 0),
+	instruction("SELF", 0, 0).annotations(0, 1, 1),
+	instruction("STEPS_TO_QUOTA", 0, 0).deprecated(Babylon, 0).removed(Babylon),
+	instruction("SUB", 0, 0),
+	instruction("SWAP", 0, 0).annotations(0, 0, 0),
+	instruction("TRANSFER_TOKENS", 0, 0),
+	instruction("SET_DELEGATE", 0, 0),
+	instruction("UNIT", 0, 0),
+	instruction("UPDATE", 0, 1),
+	instruction("XOR", 0, 0),
+	instruction("ITER", 1, 1).annotations(0, 0, 0),
+	instruction("LOOP_LEFT", 1, 1).annotations(0, 0, 0),
+	instruction("ADDRESS", 0, 0),
+	instruction("CONTRACT", 1, 1).annotations(0, 1, 1),
+	instruction("ISNAT", 0, 0),
+	instruction("CAST", 1, 1),
+	instruction("RENAME", 0, 0),
+	typ("bool", 0, 0),
+	typ("contract", 1, 1),
+	typ("int", 0, 0),
+	typ("key", 0, 0),
+	typ("key_hash", 0, 0),
+	typ("lambda", 2, 2),
+	typ("list", 1, 1),
+	typ("map", 2, 2),
+	typ("big_map", 2, 2),
+	typ("nat", 0, 0),
+	typ("option", 1, 1),
+	typ("or", 2, 2),
+	typ("pair", 2, -1),
+	typ("set", 1, 1),
+	typ("signature", 0, 0),
+	typ("string", 0, 0),
+	typ("bytes", 0, 0),
+	typ("mutez", 0, 0),
+	typ("timestamp", 0, 0),
+	typ("unit", 0, 0),
+	typ("operation", 0, 0),
+	typ("address", 0, 0),
+	instruction("SLICE", 0, 0),
+	instruction("DIG", 1, 1).annotations(0, 0, 0),
+	instruction("DUG", 1, 1).annotations(0, 0, 0),
+	instruction("EMPTY_BIG_MAP", 2, 2),
+	instruction("APPLY", 0, 0),
+	typ("chain_id", 0, 0),
+	instruction("CHAIN_ID", 0, 0),
+	instruction("LEVEL", 0, 0).since(Edo),
+	instruction("SELF_ADDRESS", 0, 0).since(Edo),
+	typ("never", 0, 0).since(Edo),
+	instruction("NEVER", 0, 0).annotations(0, 0, 0).since(Edo),
+	instruction("UNPAIR", 0, 1).annotations(0, 2, 2).since(Edo),
+	instruction("VOTING_POWER", 0, 0).since(Edo),
+	instruction("TOTAL_VOTING_POWER", 0, 0).since(Edo),
+	instruction("KECCAK", 0, 0).since(Edo),
+	instruction("SHA3", 0, 0).since(Edo),
+	instruction("PAIRING_CHECK", 0, 0).since(Edo),
+	typ("bls12_381_g1", 0, 0).since(Edo),
+	typ("bls12_381_g2", 0, 0).since(Edo),
+	typ("bls12_381_fr", 0, 0).since(Edo),
+	typ("sapling_state", 1, 1).since(Edo),
+	typ("sapling_transaction_deprecated", 1, 1).since(Edo).renamed("sapling_transaction", Jakarta).deprecated(Jakarta, 0),
+	instruction("SAPLING_EMPTY_STATE", 1, 1).since(Edo),
+	instruction("SAPLING_VERIFY_UPDATE", 0, 0).since(Edo),
+	typ("ticket", 1, 1).since(Edo),
+	instruction("TICKET_DEPRECATED", 0, 0).since(Edo).renamed("TICKET", Lima).deprecated(Lima, 0),
+	instruction("READ_TICKET", 0, 0).since(Edo),
+	instruction("SPLIT_TICKET", 0, 0).since(Edo),
+	instruction("JOIN_TICKETS", 0, 0).since(Edo),
+	instruction("GET_AND_UPDATE", 0, 0).since(Edo),
+	typ("chest", 0, 0).since(Hangzhou).deprecated(Lima, Oxford),
+	typ("chest_key", 0, 0).since(Hangzhou).deprecated(Lima, Oxford),
+	instruction("OPEN_CHEST", 0, 0).since(Hangzhou).deprecated(Lima, Oxford),
+	instruction("VIEW", 2, 2).since(Hangzhou),
+	keyword("view", 4).since(Hangzhou),
+	keyword("constant", 1).since(Hangzhou),
+	instruction("SUB_MUTEZ", 0, 0).since(Ithaca),
+	typ("tx_rollup_l2_address", 0, 0).since(Jakarta).deprecated(Mumbai, 0).removed(Oxford),
+	instruction("MIN_BLOCK_TIME", 0, 0).since(Jakarta),
+	typ("sapling_transaction", 1, 1).since(Jakarta),
+	instruction("EMIT", 0, 1).annotations(0, 1, 1).since(Kathmandu),
+	data("Lambda_rec", 1, 1).since(Lima),
+	instruction("LAMBDA_REC", 3, 3).since(Lima),
+	instruction("TICKET", 0, 0).since(Lima),
+	instruction("BYTES", 0, 0).since(Mumbai),
+	instruction("NAT", 0, 0).since(Mumbai),
+}
+
+var byName = func() map[string]Primitive {
+	names := make(map[string]Primitive, len(primitives))
+	for code := range primitives {
+		primitives[code].Code = byte(code)
+		names[primitives[code].Name] = primitives[code]
+	}
+	return names
+}()
+
+// formerNames indexes the renamed primitives by their former name
+var formerNames = func() map[string]Primitive {
+	names := map[string]Primitive{}
+	for _, p := range byName {
+		if p.Former != "" {
+			names[p.Former] = p
+		}
+	}
+	return names
+}()
+
+// Lookup finds a primitive by name (regardless of the protocol)
+func Lookup(name string) (Primitive, bool) {
+	p, ok := byName[name]
+	return p, ok
+}
+
+// ByCode finds a primitive by its binary code
+func ByCode(code byte) (Primitive, bool) {
+	if int(code) >= len(primitives) {
+		return Primitive{}, false
+	}
+	return primitives[code], true
+}
+
+// NewRegistry creates the registry of a protocol (the latest protocol is used if the protocol hash is unknown)
+func NewRegistry(protocol string) Registry {
+	return Registry{Protocol: ProtocolOf(protocol)}
+}
+
+func (r Registry) protocol() Protocol {
+	if r.Protocol.Number == 0 {
+		return LatestProtocol()
+	}
+	return r.Protocol
+}
+
+// Lookup finds a primitive supported by the protocol of the registry
+func (r Registry) Lookup(name string) (Primitive, bool) {
+	return resolve(name, r.protocol())
+}
+
+// resolve finds the primitive named by a protocol,
+// renamed primitives are found by their former name in the protocols preceding the renaming
+func resolve(name string, protocol Protocol) (Primitive, bool) {
+	if p, ok := formerNames[name]; ok && protocol.Number < p.Renamed && p.SupportedBy(protocol) {
+		return p, true
+	}
+	p, ok := byName[name]
+	if !ok || !p.SupportedBy(protocol) || protocol.Number < p.Renamed {
+		return Primitive{}, false
+	}
+	return p, true
+}
+
+// Check verifies that all primitives of a node are supported by the protocol of the registry,
+// and that they receive the expected number of arguments and annotations (macros are accepted without being checked)
+func (r Registry) Check(node ast.Node) []ast.SyntaxError {
+	protocol := r.protocol()
+	return check(node, &protocol)
+}
+
+// CheckSyntax verifies that all primitives of a node exist and receive the expected number of arguments and annotations,
+// regardless of the protocols supporting them (macros are accepted without being checked)
+func CheckSyntax(node ast.Node) []ast.SyntaxError {
+	return check(node, nil)
+}
+
+func check(node ast.Node, protocol *Protocol) []ast.SyntaxError {
+	errors := make([]ast.SyntaxError, 0)
+	report := func(prim ast.Prim, format string, args ...interface{}) {
+		errors = append(errors, ast.SyntaxError{
+			Message:  fmt.Sprintf(format, args...),
+			Position: prim.Position,
+		})
+	}
+
+	ast.Walk(node, func(node ast.Node) bool {
+		prim, ok := node.(ast.Prim)
+		if !ok {
+			return true
+		}
+
+		p, ok := byName[prim.Prim]
+		switch {
+		case !ok && macros.IsMacro(prim.Prim):
+			return true
+		case !ok:
+			report(prim, "Unknown primitive (%s).", prim.Prim)
+			return true
+		case protocol != nil:
+			if p, ok = resolve(prim.Prim, *protocol); !ok {
+				report(prim, "Primitive (%s) is not supported by protocol (%s).", prim.Prim, protocol.Name)
+				return true
+			}
+		}
+
+		if !p.AcceptsArguments(len(prim.Arguments)) {
+			report(prim, "Primitive (%s) expects %s, but received %d.", prim.Prim, p.arityString(), len(prim.Arguments))
+		}
+		counts := Annotations{}
+		for _, annotation := range prim.Annotations {
+			switch annotation.Kind {
+			case ast.TypeAnnotation:
+				counts.Type++
+			case ast.VariableAnnotation:
+				counts.Variable++
+			case ast.FieldAnnotation:
+				counts.Field++
+			}
+		}
+		for _, check := range []struct {
+			kind     string
+			count    int
+			accepted int
+		}{
+			{kind: "type", count: counts.Type, accepted: p.Annotations.Type},
+			{kind: "variable", count: counts.Variable, accepted: p.Annotations.Variable},
+			{kind: "field", count: counts.Field, accepted: p.Annotations.Field},
+		} {
+			if check.count > check.accepted {
+				report(prim, "Primitive (%s) accepts at most %d %s annotation(s), but received %d.", prim.Prim, check.accepted, check.kind, check.count)
+			}
+		}
+		return true
+	})
+
+	return errors
+}
+
+// SupportedBy checks if a primitive can be used in a given protocol
+func (p Primitive) SupportedBy(protocol Protocol) bool {
+	return protocol.Number >= p.Since && (p.Removed == 0 || protocol.Number < p.Removed)
+}
+
+// DeprecatedIn checks if a primitive is deprecated in a given protocol (removed primitives stay deprecated)
+func (p Primitive) DeprecatedIn(protocol Protocol) bool {
+	return p.Deprecated != 0 && protocol.Number >= p.Deprecated && (p.Until == 0 || protocol.Number < p.Until)
+}
+
+// AcceptsArguments checks if a primitive accepts a given number of arguments
+func (p Primitive) AcceptsArguments(count int) bool {
+	return count >= p.MinArguments && (p.MaxArguments == -1 || count <= p.MaxArguments)
+}
+
+func (p Primitive) arityString() string {
+	switch {
+	case p.MaxArguments == -1:
+		return fmt.Sprintf("at least %d argument(s)", p.MinArguments)
+	case p.MinArguments == p.MaxArguments:
+		return fmt.Sprintf("%d argument(s)", p.MinArguments)
+	}
+	return fmt.Sprintf("between %d and %d arguments", p.MinArguments, p.MaxArguments)
+}
+
+// Shorthands used to declare the primitives
+
+func keyword(name string, arguments int) Primitive {
+	return Primitive{Name: name, Kind: Keyword, MinArguments: arguments, MaxArguments: arguments}
+}
+
+// Instructions accept a type annotation and a variable annotation by default
+func instruction(name string, min int, max int) Primitive {
+	return Primitive{Name: name, Kind: Instruction, MinArguments: min, MaxArguments: max, Annotations: Annotations{Type: 1, Variable: 1}}
+}
+
+// Types accept a type annotation and a field annotation
+func typ(name string, min int, max int) Primitive {
+	return Primitive{Name: name, Kind: Type, MinArguments: min, MaxArguments: max, Annotations: Annotations{Type: 1, Field: 1}}
+}
+
+func data(name string, min int, max int) Primitive {
+	return Primitive{Name: name, Kind: Data, MinArguments: min, MaxArguments: max}
+}
+
+func (p Primitive) annotations(typ int, variable int, field int) Primitive {
+	p.Annotations = Annotations{Type: typ, Variable: variable, Field: field}
+	return p
+}
+
+func (p Primitive) since(protocol int) Primitive {
+	p.Since = protocol
+	return p
+}
+
+func (p Primitive) deprecated(since int, until int) Primitive {
+	p.Deprecated = since
+	p.Until = until
+	return p
+}
+
+func (p Primitive) renamed(former string, protocol int) Primitive {
+	p.Former = former
+	p.Renamed = protocol
+	return p
+}
+
+func (p Primitive) removed(protocol int) Primitive {
+	p.Removed = protocol
+	return p
+}
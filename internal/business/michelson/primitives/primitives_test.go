@@ -0,0 +1,155 @@
+package primitives
+
+import (
+	"os"
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(t *testing.T, s string) ast.Node {
+	parser := micheline.InitParser(s)
+	node := parser.Parse()
+	assert.NoError(t, parser.Error(), s)
+	return node
+}
+
+func TestPrimitives(t *testing.T) {
+	t.Run("Primitives are indexed by name and by binary code", func(t *testing.T) {
+		p, ok := Lookup("parameter")
+		assert.True(t, ok)
+		assert.Equal(t, byte(0x00), p.Code)
+		assert.Equal(t, Keyword, p.Kind)
+
+		p, ok = Lookup("PUSH")
+		assert.True(t, ok)
+		assert.Equal(t, byte(0x43), p.Code)
+		assert.Equal(t, Instruction, p.Kind)
+
+		p, ok = ByCode(0x9c)
+		assert.True(t, ok)
+		assert.Equal(t, "NAT", p.Name)
+
+		_, ok = Lookup("PUHS")
+		assert.False(t, ok)
+		_, ok = ByCode(0xff)
+		assert.False(t, ok)
+	})
+	t.Run("Primitives depend on the protocol", func(t *testing.T) {
+		lima := NewRegistry("PtLimaPtLMwfiLCZzo5V4bPXsNs3Kdm5ZKJDsTbkpS9cXNkvXQt")
+		assert.Equal(t, "Lima", lima.Protocol.Name)
+		_, ok := lima.Lookup("LAMBDA_REC")
+		assert.True(t, ok)
+		_, ok = lima.Lookup("BYTES")
+		assert.False(t, ok)
+		_, ok = lima.Lookup("tx_rollup_l2_address")
+		assert.True(t, ok)
+
+		// Unknown protocols default to the latest protocol
+		latest := NewRegistry("")
+		assert.Equal(t, "Alpha", latest.Protocol.Name)
+		_, ok = latest.Lookup("BYTES")
+		assert.True(t, ok)
+		_, ok = latest.Lookup("tx_rollup_l2_address")
+		assert.False(t, ok)
+		_, ok = Registry{}.Lookup("BYTES")
+		assert.True(t, ok)
+	})
+	t.Run("Well-formed primitives", func(t *testing.T) {
+		for _, code := range []string{
+			`{ parameter (or (nat %add) (unit %reset)) ; storage (pair nat nat nat) ; code { UNPAIR ; DIP { DUP } ; DROP 2 ; NIL operation ; PAIR } }`,
+			`{ PUSH @x (option :o nat) (Some 1) ; IF_SOME { DROP } { FAIL } ; CAR %a @b ; PAIR :t @p %l %r }`,
+			`(Pair 1 2 3)`,
+		} {
+			assert.Empty(t, Registry{}.Check(parse(t, code)), code)
+		}
+
+		contract, err := os.ReadFile("../__test_data__/fa2_contract.tz")
+		assert.NoError(t, err)
+		assert.Empty(t, Registry{}.Check(parse(t, string(contract))))
+	})
+	t.Run("Errors are reported with their positions", func(t *testing.T) {
+		errors := Registry{}.Check(parse(t, "{ UNIT ;\n  PUHS nat 1 ;\n  PUSH nat ;\n  DIP 1 {} {} }"))
+		assert.Len(t, errors, 3)
+		assert.Equal(t, "Unknown primitive (PUHS).", errors[0].Message)
+		assert.Equal(t, 2, errors[0].Position.Line)
+		assert.Equal(t, 3, errors[0].Position.Column)
+		assert.Equal(t, "Primitive (PUSH) expects 2 argument(s), but received 1.", errors[1].Message)
+		assert.Equal(t, 3, errors[1].Position.Line)
+		assert.Equal(t, "Primitive (DIP) expects between 1 and 2 arguments, but received 3.", errors[2].Message)
+		assert.Equal(t, 4, errors[2].Position.Line)
+
+		errors = Registry{}.Check(parse(t, `(pair nat)`))
+		assert.Len(t, errors, 1)
+		assert.Equal(t, "Primitive (pair) expects at least 2 argument(s), but received 1.", errors[0].Message)
+	})
+	t.Run("Annotations are limited", func(t *testing.T) {
+		errors := Registry{}.Check(parse(t, `{ DROP @x ; CAR :t ; UNIT %a %b }`))
+		assert.Len(t, errors, 3)
+		assert.Equal(t, "Primitive (DROP) accepts at most 0 variable annotation(s), but received 1.", errors[0].Message)
+		assert.Equal(t, "Primitive (CAR) accepts at most 0 type annotation(s), but received 1.", errors[1].Message)
+		assert.Equal(t, "Primitive (UNIT) accepts at most 0 field annotation(s), but received 2.", errors[2].Message)
+	})
+	t.Run("Primitives unsupported by the protocol", func(t *testing.T) {
+		errors := NewRegistry("PtLimaPtLMwfiLCZzo5V4bPXsNs3Kdm5ZKJDsTbkpS9cXNkvXQt").Check(parse(t, `{ UNIT ; PACK ; NAT }`))
+		assert.Len(t, errors, 1)
+		assert.Equal(t, "Primitive (NAT) is not supported by protocol (Lima).", errors[0].Message)
+		assert.Equal(t, 17, errors[0].Position.Column)
+
+		errors = Registry{}.Check(parse(t, `{ STEPS_TO_QUOTA }`))
+		assert.Len(t, errors, 1)
+		assert.Equal(t, "Primitive (STEPS_TO_QUOTA) is not supported by protocol (Alpha).", errors[0].Message)
+	})
+	t.Run("Syntax checks do not depend on the protocol", func(t *testing.T) {
+		assert.Empty(t, CheckSyntax(parse(t, `{ STEPS_TO_QUOTA ; NAT }`)))
+
+		errors := CheckSyntax(parse(t, `{ IF ; PUHS }`))
+		assert.Len(t, errors, 2)
+		assert.Equal(t, "Primitive (IF) expects 2 argument(s), but received 0.", errors[0].Message)
+		assert.Equal(t, "Unknown primitive (PUHS).", errors[1].Message)
+	})
+	t.Run("Renamed primitives", func(t *testing.T) {
+		kathmandu := NewRegistry("PtKathmaiVu5ruXc2bq7PXtzJ5GqzJobRdXJnajk6GcyBeUV48U")
+		p, ok := kathmandu.Lookup("TICKET")
+		assert.True(t, ok)
+		assert.Equal(t, "TICKET_DEPRECATED", p.Name)
+		_, ok = kathmandu.Lookup("TICKET_DEPRECATED")
+		assert.False(t, ok)
+
+		p, ok = Registry{}.Lookup("TICKET")
+		assert.True(t, ok)
+		assert.Equal(t, byte(0x9a), p.Code)
+
+		ithaca := NewRegistry("Psithaca2MLRFYargivpo7YvUr7wUDqyxrdhC5CQq78mRvimz6A")
+		p, ok = ithaca.Lookup("sapling_transaction")
+		assert.True(t, ok)
+		assert.Equal(t, "sapling_transaction_deprecated", p.Name)
+		p, ok = NewRegistry("PtJakart2xVj7pYXJBXrqHgd82rdkLey5ZeeGikgPdjYqkFGvC").Lookup("sapling_transaction")
+		assert.True(t, ok)
+		assert.Equal(t, byte(0x96), p.Code)
+
+		assert.Empty(t, kathmandu.Check(parse(t, `{ TICKET }`)))
+		errors := kathmandu.Check(parse(t, `{ TICKET_DEPRECATED }`))
+		assert.Len(t, errors, 1)
+		assert.Equal(t, "Primitive (TICKET_DEPRECATED) is not supported by protocol (Kathmandu).", errors[0].Message)
+	})
+	t.Run("Deprecated primitives", func(t *testing.T) {
+		chest, _ := Lookup("chest")
+		assert.False(t, chest.DeprecatedIn(ProtocolOf("PtKathmaiVu5ruXc2bq7PXtzJ5GqzJobRdXJnajk6GcyBeUV48U")))
+		assert.True(t, chest.DeprecatedIn(ProtocolOf("PtLimaPtLMwfiLCZzo5V4bPXsNs3Kdm5ZKJDsTbkpS9cXNkvXQt")))
+		assert.False(t, chest.DeprecatedIn(ProtocolOf("ProxfordYmVfjWnRcgjWH36fW6PArwqykTFzotUxRs6gmTcZDuH")))
+
+		// Removed primitives stay deprecated
+		rollup, _ := Lookup("tx_rollup_l2_address")
+		assert.True(t, rollup.DeprecatedIn(LatestProtocol()))
+		assert.False(t, rollup.SupportedBy(LatestProtocol()))
+
+		push, _ := Lookup("PUSH")
+		assert.False(t, push.DeprecatedIn(LatestProtocol()))
+	})
+	t.Run("Macros are not checked", func(t *testing.T) {
+		assert.Empty(t, Registry{}.Check(parse(t, `{ IF_SOME { FAIL } {} ; CMPEQ ; DUUP ; PAPAIR ; SET_CAR }`)))
+	})
+}
@@ -0,0 +1,65 @@
+package primitives
+
+import "strings"
+
+// Protocol is a Tezos protocol, primitives are introduced and removed by protocol amendments
+type Protocol struct {
+	Prefix string // prefix of the protocol hash
+	Name   string
+	Number int
+}
+
+// Protocol numbers
+const (
+	Babylon = iota + 5
+	Carthage
+	Delphi
+	Edo
+	Florence
+	Granada
+	Hangzhou
+	Ithaca
+	Jakarta
+	Kathmandu
+	Lima
+	Mumbai
+	Nairobi
+	Oxford
+	Paris
+	Alpha
+)
+
+// Protocols are sorted by protocol number
+var Protocols = []Protocol{
+	{Prefix: "PsBabyM1", Name: "Babylon", Number: Babylon},
+	{Prefix: "PsCARTHA", Name: "Carthage", Number: Carthage},
+	{Prefix: "PsDELPH1", Name: "Delphi", Number: Delphi},
+	{Prefix: "PtEdo2Zk", Name: "Edo", Number: Edo},
+	{Prefix: "PsFLoren", Name: "Florence", Number: Florence},
+	{Prefix: "PtGRANAD", Name: "Granada", Number: Granada},
+	{Prefix: "PtHangz2", Name: "Hangzhou", Number: Hangzhou},
+	{Prefix: "Psithaca", Name: "Ithaca", Number: Ithaca},
+	{Prefix: "PtJakart", Name: "Jakarta", Number: Jakarta},
+	{Prefix: "PtKathma", Name: "Kathmandu", Number: Kathmandu},
+	{Prefix: "PtLimaPt", Name: "Lima", Number: Lima},
+	{Prefix: "PtMumbai", Name: "Mumbai", Number: Mumbai},
+	{Prefix: "PtNairob", Name: "Nairobi", Number: Nairobi},
+	{Prefix: "Proxford", Name: "Oxford", Number: Oxford},
+	{Prefix: "PtParisB", Name: "Paris", Number: Paris},
+	{Prefix: "ProtoALpha", Name: "Alpha", Number: Alpha},
+}
+
+// ProtocolOf finds a protocol from its hash, the latest protocol is returned if the hash is unknown
+func ProtocolOf(hash string) Protocol {
+	for _, p := range Protocols {
+		if strings.HasPrefix(hash, p.Prefix) {
+			return p
+		}
+	}
+	return LatestProtocol()
+}
+
+// LatestProtocol returns the most recent protocol
+func LatestProtocol() Protocol {
+	return Protocols[len(Protocols)-1]
+}
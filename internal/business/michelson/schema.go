@@ -0,0 +1,216 @@
+package michelson
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+)
+
+// JSON_SCHEMA_DRAFT is the JSON Schema dialect of the generated schemas
+const JSON_SCHEMA_DRAFT = "http://json-schema.org/draft-07/schema#"
+
+const (
+	base58Pattern  = "[1-9A-HJ-NP-Za-km-z]"
+	natPattern     = "^[0-9]+$"
+	intPattern     = "^-?[0-9]+$"
+	bytesPattern   = "^(0x)?([0-9a-fA-F]{2})*$"
+	addressPattern = "^(tz1|tz2|tz3|tz4|KT1|txr1|sr1)" + base58Pattern + "{33}(%[^%]+)?$"
+)
+
+// JSONSchema describes the values of a Michelson type in annotated JSON (see AnnotatedJSONOf)
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	PropertyNames        *JSONSchema            `json:"propertyNames,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"` // (false) or a schema
+	Items                *JSONSchema            `json:"items,omitempty"`
+	UniqueItems          bool                   `json:"uniqueItems,omitempty"`
+	OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+}
+
+// JSONSchemaOf builds the JSON Schema of the values of a given type, written in annotated JSON
+func JSONSchemaOf(typ ast.Node) (*JSONSchema, error) {
+	// Types with unknown primitives or a wrong number of arguments are rejected before being described
+	if err := CheckPrimitives(typ, ""); err != nil {
+		return nil, err
+	}
+	schema, err := schemaOf(typ)
+	if err != nil {
+		return nil, err
+	}
+	schema.Schema = JSON_SCHEMA_DRAFT
+	return schema, nil
+}
+
+func schemaOf(typ ast.Node) (*JSONSchema, error) {
+	t, ok := typ.(ast.Prim)
+	if !ok {
+		return nil, fmt.Errorf("invalid type: %s.", typ.String())
+	}
+
+	schema, err := schemaOfPrim(t)
+	if err != nil {
+		return nil, err
+	}
+	if schema.Title == "" {
+		schema.Title = fieldOf(t)
+	}
+	if len(t.Arguments) == 0 || t.Prim == "lambda" {
+		// Composite types are already described by their children
+		schema.Description = micheline.Format(stripAnnotations(t), math.MaxInt32)
+	}
+	return schema, nil
+}
+
+func schemaOfPrim(t ast.Prim) (*JSONSchema, error) {
+	switch t.Prim {
+	case "unit":
+		return &JSONSchema{Type: "object", AdditionalProperties: false}, nil
+	case "bool":
+		return &JSONSchema{Type: "boolean"}, nil
+	case "int", "nat", "mutez":
+		pattern := natPattern
+		if t.Prim == "int" {
+			pattern = intPattern
+		}
+		return &JSONSchema{Type: "string", Format: t.Prim, Pattern: pattern}, nil
+	case "string":
+		return &JSONSchema{Type: "string"}, nil
+	case "bytes", "chest", "chest_key", "bls12_381_g1", "bls12_381_g2":
+		return &JSONSchema{Type: "string", Format: "bytes", Pattern: bytesPattern}, nil
+	case "bls12_381_fr":
+		return &JSONSchema{Type: "string", Format: t.Prim, Pattern: "^(-?[0-9]+|0x([0-9a-fA-F]{2})*)$"}, nil
+	case "timestamp":
+		return &JSONSchema{Type: "string", Format: "date-time"}, nil
+	case "address", "contract":
+		return &JSONSchema{Type: "string", Format: "address", Pattern: addressPattern}, nil
+	case "key_hash":
+		return &JSONSchema{Type: "string", Format: t.Prim, Pattern: "^(tz1|tz2|tz3|tz4)" + base58Pattern + "{33}$"}, nil
+	case "key":
+		return &JSONSchema{Type: "string", Format: t.Prim, Pattern: "^(edpk|sppk|p2pk|BLpk)" + base58Pattern + "+$"}, nil
+	case "signature":
+		return &JSONSchema{Type: "string", Format: t.Prim, Pattern: "^(edsig|spsig1|p2sig|BLsig|sig)" + base58Pattern + "+$"}, nil
+	case "chain_id":
+		return &JSONSchema{Type: "string", Format: t.Prim, Pattern: "^Net" + base58Pattern + "+$"}, nil
+	case "tx_rollup_l2_address":
+		return &JSONSchema{Type: "string", Format: t.Prim, Pattern: "^tz4" + base58Pattern + "{33}$"}, nil
+	case "option":
+		schema, err := schemaOf(t.Arguments[0])
+		if err != nil {
+			return nil, err
+		}
+		if isOptionType(t.Arguments[0]) {
+			// Options of options are wrapped (see toAnnotated)
+			schema = &JSONSchema{
+				Type:                 "object",
+				Properties:           map[string]*JSONSchema{"Some": schema},
+				Required:             []string{"Some"},
+				AdditionalProperties: false,
+			}
+		}
+		return &JSONSchema{OneOf: []*JSONSchema{{Type: "null"}, schema}}, nil
+	case "or":
+		branches := make([]*JSONSchema, 0, 2)
+		for i, key := range []string{"Left", "Right"} {
+			schema, err := schemaOf(t.Arguments[i])
+			if err != nil {
+				return nil, err
+			}
+			branches = append(branches, &JSONSchema{
+				Type:                 "object",
+				Properties:           map[string]*JSONSchema{key: schema},
+				Required:             []string{key},
+				AdditionalProperties: false,
+			})
+		}
+		return &JSONSchema{OneOf: branches}, nil
+	case "pair":
+		if _, err := pairFieldsOf(t); err != nil {
+			return nil, err
+		}
+		schema := &JSONSchema{
+			Type:                 "object",
+			Properties:           map[string]*JSONSchema{},
+			Required:             []string{},
+			AdditionalProperties: false,
+		}
+		index := 0
+		if err := collectPairSchemas(t, schema, &index); err != nil {
+			return nil, err
+		}
+		return schema, nil
+	case "list", "set":
+		items, err := schemaOf(t.Arguments[0])
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "array", Items: items, UniqueItems: t.Prim == "set"}, nil
+	case "map", "big_map":
+		values, err := schemaOf(t.Arguments[1])
+		if err != nil {
+			return nil, err
+		}
+		schema := &JSONSchema{
+			Type:                 "object",
+			PropertyNames:        keySchemaOf(t.Arguments[0]),
+			AdditionalProperties: values,
+		}
+		if t.Prim == "big_map" {
+			// Big maps can also be referenced by their identifier
+			return &JSONSchema{OneOf: []*JSONSchema{schema, {Type: "string", Format: "big_map_id", Pattern: natPattern}}}, nil
+		}
+		return schema, nil
+	case "lambda":
+		return &JSONSchema{Type: "array", Format: "michelson"}, nil
+	}
+
+	return nil, fmt.Errorf("values of type (%s) cannot be written in annotated JSON.", t.Prim)
+}
+
+// collectPairSchemas collects the leaves of a pair type as object properties (see pairToAnnotated)
+func collectPairSchemas(t ast.Prim, schema *JSONSchema, index *int) error {
+	for _, typ := range []ast.Node{t.Arguments[0], combOf("pair", t.Arguments[1:])} {
+		field := fieldOf(typ)
+		if field == "" && isPairType(typ) {
+			if err := collectPairSchemas(typ.(ast.Prim), schema, index); err != nil {
+				return err
+			}
+			continue
+		}
+
+		property, err := schemaOf(typ)
+		if err != nil {
+			return err
+		}
+		if field == "" {
+			field = fmt.Sprint(*index)
+		}
+		*index++
+		schema.Properties[field] = property
+		schema.Required = append(schema.Required, field)
+	}
+	return nil
+}
+
+// keySchemaOf describes the map keys of a given type (see annotatedKeyOf)
+func keySchemaOf(typ ast.Node) *JSONSchema {
+	t, _ := typ.(ast.Prim)
+	switch t.Prim {
+	case "int", "nat", "mutez", "bytes", "string", "address", "key", "key_hash", "signature", "chain_id", "timestamp":
+		schema, _ := schemaOfPrim(t)
+		return schema
+	case "bool":
+		return &JSONSchema{Type: "string", Enum: []interface{}{"true", "false"}}
+	}
+	// Other keys are written in "micheline" format
+	return &JSONSchema{Type: "string", Format: "micheline"}
+}
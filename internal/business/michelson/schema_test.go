@@ -0,0 +1,138 @@
+package michelson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONSchemaOf(t *testing.T) {
+	type test struct {
+		Type   string
+		Schema string
+	}
+
+	tests := []test{
+		{
+			Type:   `nat`,
+			Schema: `{"description":"nat","type":"string","format":"nat","pattern":"^[0-9]+$"}`,
+		},
+		{
+			Type:   `(bool %active)`,
+			Schema: `{"title":"active","description":"bool","type":"boolean"}`,
+		},
+		{
+			Type: `(pair (address %owner) (pair (int %value) bytes))`,
+			Schema: `{
+				"type": "object",
+				"properties": {
+					"owner": {"title":"owner","description":"address","type":"string","format":"address","pattern":"^(tz1|tz2|tz3|tz4|KT1|txr1|sr1)[1-9A-HJ-NP-Za-km-z]{33}(%[^%]+)?$"},
+					"value": {"title":"value","description":"int","type":"string","format":"int","pattern":"^-?[0-9]+$"},
+					"2": {"description":"bytes","type":"string","format":"bytes","pattern":"^(0x)?([0-9a-fA-F]{2})*$"}
+				},
+				"required": ["owner", "value", "2"],
+				"additionalProperties": false
+			}`,
+		},
+		{
+			Type: `(or (unit %reset) (option %set string))`,
+			Schema: `{
+				"oneOf": [
+					{"type":"object","properties":{"Left":{"title":"reset","description":"unit","type":"object","additionalProperties":false}},"required":["Left"],"additionalProperties":false},
+					{"type":"object","properties":{"Right":{"title":"set","oneOf":[{"type":"null"},{"description":"string","type":"string"}]}},"required":["Right"],"additionalProperties":false}
+				]
+			}`,
+		},
+		{
+			Type: `(option (option nat))`,
+			Schema: `{
+				"oneOf": [
+					{"type":"null"},
+					{"type":"object","properties":{"Some":{"oneOf":[{"type":"null"},{"description":"nat","type":"string","format":"nat","pattern":"^[0-9]+$"}]}},"required":["Some"],"additionalProperties":false}
+				]
+			}`,
+		},
+		{
+			Type: `(map bool (set mutez))`,
+			Schema: `{
+				"type": "object",
+				"propertyNames": {"type":"string","enum":["true","false"]},
+				"additionalProperties": {"type":"array","items":{"description":"mutez","type":"string","format":"mutez","pattern":"^[0-9]+$"},"uniqueItems":true}
+			}`,
+		},
+		{
+			Type: `(big_map (pair nat nat) unit)`,
+			Schema: `{
+				"oneOf": [
+					{"type":"object","propertyNames":{"type":"string","format":"micheline"},"additionalProperties":{"description":"unit","type":"object","additionalProperties":false}},
+					{"type":"string","format":"big_map_id","pattern":"^[0-9]+$"}
+				]
+			}`,
+		},
+		{
+			Type:   `(lambda unit nat)`,
+			Schema: `{"description":"lambda unit nat","type":"array","format":"michelson"}`,
+		},
+	}
+
+	for _, test := range tests {
+		typ, err := ParseMicheline(test.Type)
+		assert.NoError(t, err)
+
+		schema, err := schemaOf(typ)
+		if assert.NoError(t, err, test.Type) {
+			b, err := json.Marshal(schema)
+			assert.NoError(t, err)
+			assert.JSONEq(t, test.Schema, string(b), test.Type)
+		}
+	}
+
+	t.Run("The root declares the JSON Schema dialect", func(t *testing.T) {
+		typ, err := ParseMicheline(`unit`)
+		assert.NoError(t, err)
+
+		schema, err := JSONSchemaOf(typ)
+		assert.NoError(t, err)
+		assert.Equal(t, JSON_SCHEMA_DRAFT, schema.Schema)
+	})
+
+	t.Run("Unsupported types", func(t *testing.T) {
+		typ, err := ParseMicheline(`(pair nat (ticket nat))`)
+		assert.NoError(t, err)
+
+		_, err = JSONSchemaOf(typ)
+		assert.EqualError(t, err, `values of type (ticket) cannot be written in annotated JSON.`)
+	})
+
+	t.Run("Invalid types", func(t *testing.T) {
+		for source, message := range map[string]string{
+			`(option)`:       `Primitive (option) expects 1 argument(s), but received 0. (line 1, column 1)`,
+			`(map nat)`:      `Primitive (map) expects 2 argument(s), but received 1. (line 1, column 1)`,
+			`(or (nat %a))`:  `Primitive (or) expects 2 argument(s), but received 1. (line 1, column 1)`,
+			`(pair nat)`:     `Primitive (pair) expects at least 2 argument(s), but received 1. (line 1, column 1)`,
+			`(list (nat 1))`: `Primitive (nat) expects 0 argument(s), but received 1. (line 1, column 7)`,
+		} {
+			typ, err := ParseMicheline(source)
+			assert.NoError(t, err, source)
+
+			_, err = JSONSchemaOf(typ)
+			assert.EqualError(t, err, message, source)
+		}
+	})
+
+	t.Run("Duplicate fields", func(t *testing.T) {
+		typ, err := ParseMicheline(`(pair (nat %a) (pair (string %b) (int %a)))`)
+		assert.NoError(t, err)
+
+		_, err = JSONSchemaOf(typ)
+		assert.EqualError(t, err, `duplicate field (a) in type (pair (nat %a) (pair (string %b) (int %a))).`)
+
+		// Leaves without annotations are keyed by their index
+		typ, err = ParseMicheline(`(pair (nat %1) string)`)
+		assert.NoError(t, err)
+
+		_, err = JSONSchemaOf(typ)
+		assert.EqualError(t, err, `duplicate field (1) in type (pair (nat %1) (string)).`)
+	})
+}
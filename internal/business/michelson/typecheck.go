@@ -0,0 +1,454 @@
+package michelson
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/binary"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/macros"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/primitives"
+)
+
+type (
+	// TypeError is returned when a value does not match its type
+	TypeError struct {
+		Position ast.Position
+		Path     string // JSON pointer of the ill-typed node (relative to the Michelson JSON of the value)
+		Message  string
+	}
+	// Typechecker validates values against Michelson types
+	Typechecker struct {
+		// Values for which IgnoreValue returns true are accepted without being checked (e.g. placeholders)
+		IgnoreValue func(value ast.Node) bool
+		// Primitives supported by the protocol (the primitives of the latest protocol are used by default)
+		Primitives primitives.Registry
+	}
+)
+
+// Maximum value of type (mutez)
+var maxMutez = new(big.Int).SetUint64(1<<63 - 1)
+
+func (e TypeError) Error() string {
+	location := make([]string, 0)
+	if e.Path != "" {
+		location = append(location, "at "+e.Path)
+	}
+	if e.Position.Line > 0 {
+		location = append(location, fmt.Sprintf("line %d, column %d", e.Position.Line, e.Position.Column))
+	}
+	if len(location) == 0 {
+		return e.Message + "."
+	}
+	return fmt.Sprintf("%s (%s).", e.Message, strings.Join(location, ", "))
+}
+
+// ValidateValue verifies that a value is compatible with a given type
+func ValidateValue(value ast.Node, typ ast.Node) error {
+	return Typechecker{}.Check(value, typ)
+}
+
+// Check verifies that a value is compatible with a given type
+func (tc Typechecker) Check(value ast.Node, typ ast.Node) error {
+	// Unknown primitives and primitives with a wrong number of arguments are reported before the value is typechecked
+	if errors := tc.Primitives.Check(value); len(errors) > 0 {
+		return TypeError{Position: errors[0].Position, Message: strings.TrimSuffix(errors[0].Message, ".")}
+	}
+	return tc.check(value, typ, "")
+}
+
+func (tc Typechecker) check(value ast.Node, typ ast.Node, path string) error {
+	t, ok := typ.(ast.Prim)
+	if !ok {
+		return TypeError{Message: fmt.Sprintf("invalid type: %s", micheline.Print(typ, ""))}
+	}
+	if p, ok := tc.Primitives.Lookup(t.Prim); !ok || p.Kind != primitives.Type || !p.AcceptsArguments(len(t.Arguments)) {
+		return TypeError{Message: fmt.Sprintf("invalid type: %s", micheline.Print(typ, ""))}
+	}
+
+	if tc.IgnoreValue != nil && tc.IgnoreValue(value) {
+		return nil
+	}
+
+	mismatch := func() error {
+		return typeError(value, path, "value %s is not of type %s", micheline.Print(value, ""), micheline.Print(stripAnnotations(t), ""))
+	}
+
+	switch t.Prim {
+	case "unit":
+		if !isPrim(value, "Unit", 0) {
+			return mismatch()
+		}
+	case "bool":
+		if !isPrim(value, "True", 0) && !isPrim(value, "False", 0) {
+			return mismatch()
+		}
+	case "int":
+		if _, ok := value.(ast.Int); !ok {
+			return mismatch()
+		}
+	case "nat":
+		if v, ok := value.(ast.Int); !ok || strings.HasPrefix(v.Value, "-") {
+			return mismatch()
+		}
+	case "mutez":
+		v, ok := value.(ast.Int)
+		if !ok {
+			return mismatch()
+		}
+		if n, ok := new(big.Int).SetString(v.Value, 10); !ok || n.Sign() < 0 || n.Cmp(maxMutez) > 0 {
+			return typeError(value, path, "value %s is out of the range of type (mutez)", v.Value)
+		}
+	case "string":
+		if _, ok := value.(ast.String); !ok {
+			return mismatch()
+		}
+	case "bytes", "bls12_381_g1", "bls12_381_g2", "chest", "chest_key":
+		if _, ok := value.(ast.Bytes); !ok {
+			return mismatch()
+		}
+	case "bls12_381_fr":
+		switch value.(type) {
+		case ast.Int, ast.Bytes:
+		default:
+			return mismatch()
+		}
+	case "timestamp":
+		switch v := value.(type) {
+		case ast.Int:
+		case ast.String:
+			if _, err := time.Parse(time.RFC3339, v.Value); err != nil {
+				return typeError(value, path, "value %s is not a valid timestamp", micheline.Print(value, ""))
+			}
+		default:
+			return mismatch()
+		}
+	case "address", "contract", "key_hash", "key", "signature", "chain_id":
+		switch value.(type) {
+		case ast.String, ast.Bytes:
+			// The optimized/readable conversion validates the encoding of domain specific values
+			if _, err := binary.Optimize(value, ast.Prim{Prim: t.Prim, Arguments: t.Arguments}); err != nil {
+				return typeError(value, path, "value %s is not a valid %s", micheline.Print(value, ""), t.Prim)
+			}
+			if _, err := binary.Readable(value, ast.Prim{Prim: t.Prim, Arguments: t.Arguments}); err != nil {
+				return typeError(value, path, "value %s is not a valid %s", micheline.Print(value, ""), t.Prim)
+			}
+		default:
+			return mismatch()
+		}
+	case "tx_rollup_l2_address":
+		switch value.(type) {
+		case ast.String, ast.Bytes:
+		default:
+			return mismatch()
+		}
+	case "never", "operation", "ticket", "sapling_state", "sapling_transaction", "sapling_transaction_deprecated":
+		return typeError(value, path, "values of type (%s) cannot be written", t.Prim)
+	case "option":
+		if isPrim(value, "None", 0) {
+			return nil
+		}
+		if !isPrim(value, "Some", 1) {
+			return mismatch()
+		}
+		return tc.check(value.(ast.Prim).Arguments[0], t.Arguments[0], path+"/args/0")
+	case "or":
+		switch {
+		case isPrim(value, "Left", 1):
+			return tc.check(value.(ast.Prim).Arguments[0], t.Arguments[0], path+"/args/0")
+		case isPrim(value, "Right", 1):
+			return tc.check(value.(ast.Prim).Arguments[0], t.Arguments[1], path+"/args/0")
+		}
+		return mismatch()
+	case "pair":
+		return tc.checkPair(value, t, path)
+	case "list":
+		seq, ok := value.(ast.Sequence)
+		if !ok {
+			return mismatch()
+		}
+		for i, el := range seq.Elements {
+			if err := tc.check(el, t.Arguments[0], fmt.Sprintf("%s/%d", path, i)); err != nil {
+				return err
+			}
+		}
+	case "set":
+		seq, ok := value.(ast.Sequence)
+		if !ok {
+			return mismatch()
+		}
+		for i, el := range seq.Elements {
+			elPath := fmt.Sprintf("%s/%d", path, i)
+			if err := tc.check(el, t.Arguments[0], elPath); err != nil {
+				return err
+			}
+			if i > 0 {
+				if err := tc.checkOrdering(seq.Elements[i-1], el, t.Arguments[0], elPath, "set elements"); err != nil {
+					return err
+				}
+			}
+		}
+	case "map", "big_map":
+		if _, ok := value.(ast.Int); ok && t.Prim == "big_map" {
+			// Big map identifier
+			return nil
+		}
+		seq, ok := value.(ast.Sequence)
+		if !ok {
+			return mismatch()
+		}
+		for i, el := range seq.Elements {
+			elPath := fmt.Sprintf("%s/%d", path, i)
+			if !isPrim(el, "Elt", 2) {
+				return typeError(el, elPath, "value %s is not a map entry", micheline.Print(el, ""))
+			}
+			elt := el.(ast.Prim)
+			if err := tc.check(elt.Arguments[0], t.Arguments[0], elPath+"/args/0"); err != nil {
+				return err
+			}
+			if err := tc.check(elt.Arguments[1], t.Arguments[1], elPath+"/args/1"); err != nil {
+				return err
+			}
+			if i > 0 {
+				previous, ok := seq.Elements[i-1].(ast.Prim)
+				if !ok {
+					continue
+				}
+				if err := tc.checkOrdering(previous.Arguments[0], elt.Arguments[0], t.Arguments[0], elPath+"/args/0", "map keys"); err != nil {
+					return err
+				}
+			}
+		}
+	case "lambda":
+		// Lambdas are checked loosely, the code must be a sequence of instructions (with well-formed macros)
+		if _, ok := value.(ast.Sequence); ok {
+			if _, err := macros.Expand(value); err != nil {
+				return typeError(value, path, "invalid lambda. %s", strings.TrimSuffix(err.Error(), "."))
+			}
+			return nil
+		}
+		if isPrim(value, "Lambda_rec", 1) {
+			return nil
+		}
+		return mismatch()
+	}
+
+	return nil
+}
+
+// checkPair validates pairs written as (Pair a b ...), (Pair a (Pair b ...)) or { a ; b ; ... }
+func (tc Typechecker) checkPair(value ast.Node, t ast.Prim, path string) error {
+	elements := pairElements(value)
+	if len(elements) < 2 || len(t.Arguments) < 2 {
+		return typeError(value, path, "value %s is not of type %s", micheline.Print(value, ""), micheline.Print(stripAnnotations(t), ""))
+	}
+
+	if err := tc.check(elements[0], t.Arguments[0], path+pairStep(value, 0)); err != nil {
+		return err
+	}
+
+	// Right combs: (pair a b c) == (pair a (pair b c))
+	if len(elements) == 2 {
+		return tc.check(elements[1], combOf("pair", t.Arguments[1:]), path+pairStep(value, 1))
+	}
+	return tc.checkPairTail(value, elements, 1, combOf("pair", t.Arguments[1:]), path)
+}
+
+// checkPairTail validates the elements of a flat pair starting at a given index
+func (tc Typechecker) checkPairTail(value ast.Node, elements []ast.Node, index int, typ ast.Node, path string) error {
+	if index == len(elements)-1 {
+		return tc.check(elements[index], typ, path+pairStep(value, index))
+	}
+
+	t, ok := typ.(ast.Prim)
+	if !ok || t.Prim != "pair" || len(t.Arguments) < 2 {
+		rest := ast.Prim{Prim: "Pair", Arguments: elements[index:]}
+		return typeError(elements[index], path+pairStep(value, index), "value %s is not of type %s", micheline.Print(rest, ""), micheline.Print(stripAnnotations(typ), ""))
+	}
+
+	if err := tc.check(elements[index], t.Arguments[0], path+pairStep(value, index)); err != nil {
+		return err
+	}
+	return tc.checkPairTail(value, elements, index+1, combOf("pair", t.Arguments[1:]), path)
+}
+
+// checkOrdering verifies that set elements and map keys are in strictly increasing order
+func (tc Typechecker) checkOrdering(previous ast.Node, current ast.Node, typ ast.Node, path string, what string) error {
+	if tc.containsIgnoredValue(previous) || tc.containsIgnoredValue(current) {
+		// Ignored values cannot be ordered
+		return nil
+	}
+	cmp, err := CompareValues(previous, current, typ)
+	if err != nil {
+		return typeError(current, path, "%s", err)
+	}
+	if cmp >= 0 {
+		return typeError(current, path, "%s must be in strictly increasing order, %s appears after %s", what, micheline.Print(current, ""), micheline.Print(previous, ""))
+	}
+	return nil
+}
+
+func (tc Typechecker) containsIgnoredValue(value ast.Node) bool {
+	if tc.IgnoreValue == nil {
+		return false
+	}
+	if tc.IgnoreValue(value) {
+		return true
+	}
+	var children []ast.Node
+	switch node := value.(type) {
+	case ast.Prim:
+		children = node.Arguments
+	case ast.Sequence:
+		children = node.Elements
+	}
+	for _, child := range children {
+		if tc.containsIgnoredValue(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// CompareValues compares two values of a comparable type
+// (-1 if a < b, 0 if a == b, 1 if a > b)
+func CompareValues(a ast.Node, b ast.Node, typ ast.Node) (int, error) {
+	// Domain specific values are compared on their optimized representation
+	left, err := binary.Optimize(a, typ)
+	if err != nil {
+		return 0, err
+	}
+	right, err := binary.Optimize(b, typ)
+	if err != nil {
+		return 0, err
+	}
+	return compareNodes(left, right)
+}
+
+func compareNodes(a ast.Node, b ast.Node) (int, error) {
+	switch left := a.(type) {
+	case ast.Int:
+		if right, ok := b.(ast.Int); ok {
+			l, okL := new(big.Int).SetString(left.Value, 10)
+			r, okR := new(big.Int).SetString(right.Value, 10)
+			if okL && okR {
+				return l.Cmp(r), nil
+			}
+		}
+	case ast.String:
+		if right, ok := b.(ast.String); ok {
+			return strings.Compare(left.Value, right.Value), nil
+		}
+	case ast.Bytes:
+		if right, ok := b.(ast.Bytes); ok {
+			// Hexadecimal strings of the same case preserve the byte ordering
+			return strings.Compare(strings.ToLower(left.Value), strings.ToLower(right.Value)), nil
+		}
+	case ast.Prim:
+		if right, ok := b.(ast.Prim); ok {
+			return comparePrims(left, right)
+		}
+	}
+	return 0, fmt.Errorf("values %s and %s are not comparable", micheline.Print(a, ""), micheline.Print(b, ""))
+}
+
+// Ordering of the constructors of comparable values
+var constructorOrder = map[string]int{
+	"Unit":  0,
+	"False": 0,
+	"True":  1,
+	"None":  0,
+	"Some":  1,
+	"Left":  0,
+	"Right": 1,
+}
+
+func comparePrims(a ast.Prim, b ast.Prim) (int, error) {
+	if a.Prim == "Pair" && b.Prim == "Pair" {
+		left, right := a.Arguments, b.Arguments
+		for i := 0; i < len(left) && i < len(right); i++ {
+			cmp, err := compareNodes(left[i], right[i])
+			if err != nil || cmp != 0 {
+				return cmp, err
+			}
+		}
+		return 0, nil
+	}
+
+	orderA, okA := constructorOrder[a.Prim]
+	orderB, okB := constructorOrder[b.Prim]
+	if !okA || !okB {
+		return 0, fmt.Errorf("values %s and %s are not comparable", micheline.Print(a, ""), micheline.Print(b, ""))
+	}
+	if orderA != orderB {
+		if orderA < orderB {
+			return -1, nil
+		}
+		return 1, nil
+	}
+	if len(a.Arguments) == 1 && len(b.Arguments) == 1 {
+		return compareNodes(a.Arguments[0], b.Arguments[0])
+	}
+	return 0, nil
+}
+
+func typeError(value ast.Node, path string, format string, args ...interface{}) TypeError {
+	return TypeError{
+		Position: ast.PositionOf(value),
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// pairStep gives the JSON pointer step of a pair element
+func pairStep(value ast.Node, index int) string {
+	if _, ok := value.(ast.Sequence); ok {
+		return fmt.Sprintf("/%d", index)
+	}
+	return fmt.Sprintf("/args/%d", index)
+}
+
+// pairElements gives the elements of a pair value (written with a Pair prim or as a sequence)
+func pairElements(value ast.Node) []ast.Node {
+	switch node := value.(type) {
+	case ast.Prim:
+		if node.Prim == "Pair" {
+			return node.Arguments
+		}
+	case ast.Sequence:
+		return node.Elements
+	}
+	return nil
+}
+
+// combOf builds a right comb from a list of nodes, a single node is returned as is
+func combOf(prim string, nodes []ast.Node) ast.Node {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return ast.Prim{
+		Prim:      prim,
+		Arguments: nodes,
+	}
+}
+
+func isPrim(node ast.Node, prim string, arguments int) bool {
+	p, ok := node.(ast.Prim)
+	return ok && p.Prim == prim && len(p.Arguments) == arguments
+}
+
+func stripAnnotations(node ast.Node) ast.Node {
+	if prim, ok := node.(ast.Prim); ok {
+		prim.Annotations = nil
+		arguments := make([]ast.Node, len(prim.Arguments))
+		for i, arg := range prim.Arguments {
+			arguments[i] = stripAnnotations(arg)
+		}
+		prim.Arguments = arguments
+		return prim
+	}
+	return node
+}
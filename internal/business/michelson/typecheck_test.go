@@ -0,0 +1,104 @@
+package michelson
+
+import (
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/primitives"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateValue(t *testing.T) {
+	type test struct {
+		Value string
+		Type  string
+		Error string
+	}
+
+	runTests := func(t *testing.T, list []test) {
+		for _, test := range list {
+			value, err := ParseMicheline(test.Value)
+			assert.NoError(t, err)
+			typ, err := ParseMicheline(test.Type)
+			assert.NoError(t, err)
+
+			err = ValidateValue(value, typ)
+			if test.Error == "" {
+				assert.NoError(t, err, test.Value)
+			} else {
+				assert.EqualError(t, err, test.Error, test.Value)
+			}
+		}
+	}
+
+	t.Run("Well-typed values", func(t *testing.T) {
+		runTests(t, []test{
+			{Value: `Unit`, Type: `unit`},
+			{Value: `10`, Type: `nat`},
+			{Value: `-10`, Type: `int`},
+			{Value: `"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"`, Type: `address`},
+			{Value: `(Pair 1 "a" 0x00)`, Type: `(pair nat string bytes)`},
+			{Value: `(Pair 1 (Pair "a" 0x00))`, Type: `(pair nat string bytes)`},
+			{Value: `{ 1 ; "a" ; 0x00 }`, Type: `(pair nat (pair string bytes))`},
+			{Value: `(Some (Left 1))`, Type: `(option (or nat string))`},
+			{Value: `{ Elt 1 { True ; False } }`, Type: `(map nat (list bool))`},
+			{Value: `10`, Type: `(big_map nat nat)`},
+			{Value: `{ DROP ; UNIT }`, Type: `(lambda nat unit)`},
+			{Value: `{ "a" ; "b" }`, Type: `(set string)`},
+			{Value: `{ Elt (Pair 1 "b") 0 ; Elt (Pair 2 "a") 0 }`, Type: `(map (pair nat string) nat)`},
+			{Value: `{ "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx" ; "KT1BEqzn5Wx8uJrZNvuS9DVHmLvG9td3fDLi" }`, Type: `(set address)`},
+			{Value: `{ None ; Some 1 }`, Type: `(set (option nat))`},
+			{Value: `9223372036854775807`, Type: `mutez`},
+			{Value: `"2022-01-01T00:00:00Z"`, Type: `timestamp`},
+			{Value: `"NetXdQprcVkpaWU"`, Type: `chain_id`},
+			{Value: `"edpkuBknW28nW72KG6RoHtYW7p12T6GKc7nAbwYX5m8Wd9sDVC9yav"`, Type: `key`},
+		})
+	})
+	t.Run("Ill-typed values", func(t *testing.T) {
+		runTests(t, []test{
+			{Value: `-10`, Type: `nat`, Error: "value -10 is not of type (nat) (line 1, column 1)."},
+			{Value: `(Pair 1 "a")`, Type: `(pair nat string bytes)`, Error: `value "a" is not of type (pair (string) (bytes)) (at /args/1, line 1, column 9).`},
+			{Value: `(Right 1)`, Type: `(or %x (nat %a) (string %b))`, Error: "value 1 is not of type (string) (at /args/0, line 1, column 8)."},
+			{Value: `{ 1 }`, Type: `(map nat nat)`, Error: "value 1 is not a map entry (at /0, line 1, column 3)."},
+			{Value: `{ 2 ; 1 }`, Type: `(set nat)`, Error: "set elements must be in strictly increasing order, 1 appears after 2 (at /1, line 1, column 7)."},
+			{Value: `{ Elt "b" 1 ; Elt "a" 2 }`, Type: `(map string nat)`, Error: `map keys must be in strictly increasing order, "a" appears after "b" (at /1/args/0, line 1, column 19).`},
+			{Value: `{ Elt "a" 1 ; Elt "a" 2 }`, Type: `(big_map string nat)`, Error: `map keys must be in strictly increasing order, "a" appears after "a" (at /1/args/0, line 1, column 19).`},
+			{Value: `9223372036854775808`, Type: `mutez`, Error: "value 9223372036854775808 is out of the range of type (mutez) (line 1, column 1)."},
+			{Value: `"tz1invalid"`, Type: `address`, Error: `value "tz1invalid" is not a valid address (line 1, column 1).`},
+			{Value: `0x00`, Type: `key_hash`, Error: "value 0x00 is not a valid key_hash (line 1, column 1)."},
+			{Value: `"yesterday"`, Type: `timestamp`, Error: `value "yesterday" is not a valid timestamp (line 1, column 1).`},
+			{Value: `(Pair 1 2 3)`, Type: `(pair nat nat)`, Error: "value (Pair 2 3) is not of type (nat) (at /args/1, line 1, column 9)."},
+			{Value: `{ 1 ; 2 ; "a" }`, Type: `(pair nat nat nat)`, Error: `value "a" is not of type (nat) (at /2, line 1, column 11).`},
+			{Value: `Unit`, Type: `operation`, Error: "values of type (operation) cannot be written (line 1, column 1)."},
+			{Value: `None`, Type: `option`, Error: "invalid type: (option)."},
+			{Value: `(Some 1 2)`, Type: `(option nat)`, Error: "Primitive (Some) expects 1 argument(s), but received 2 (line 1, column 1)."},
+			{Value: `{ UNIT ; PUHS nat 1 }`, Type: `(lambda unit unit)`, Error: "Unknown primitive (PUHS) (line 1, column 10)."},
+			{Value: `{ IFCMPEQ {} }`, Type: `(lambda unit unit)`, Error: "invalid lambda. macro (IFCMPEQ) expects 2 argument(s) (line 1, column 1)."},
+		})
+	})
+	t.Run("Types depend on the protocol", func(t *testing.T) {
+		value, err := ParseMicheline(`"tz4HVR6aty9KwsQFHh81C1G7gBdhxT8kuytm"`)
+		assert.NoError(t, err)
+		typ, err := ParseMicheline(`tx_rollup_l2_address`)
+		assert.NoError(t, err)
+
+		tc := Typechecker{Primitives: primitives.NewRegistry("PtJakart2xVj7pYXJBXrqHgd82rdkLey5ZeeGikDKFnoXz3HYE9i")}
+		assert.NoError(t, tc.Check(value, typ))
+		assert.EqualError(t, ValidateValue(value, typ), "invalid type: (tx_rollup_l2_address).")
+	})
+	t.Run("Ignored values", func(t *testing.T) {
+		value, err := ParseMicheline(`(Pair "PLACEHOLDER" 1)`)
+		assert.NoError(t, err)
+		typ, err := ParseMicheline(`(pair address nat)`)
+		assert.NoError(t, err)
+
+		tc := Typechecker{
+			IgnoreValue: func(value ast.Node) bool {
+				s, ok := value.(ast.String)
+				return ok && s.Value == "PLACEHOLDER"
+			},
+		}
+		assert.NoError(t, tc.Check(value, typ))
+		assert.EqualError(t, ValidateValue(value, typ), `value "PLACEHOLDER" is not a valid address (at /args/0, line 1, column 7).`)
+	})
+}
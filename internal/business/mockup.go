@@ -2,11 +2,13 @@ package business
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"math/big"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 
 	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
@@ -18,7 +20,7 @@ import (
 
 type (
 	TezosClientArgumentKind int8
-	ParsingMode             string
+	ParsingMode             = michelson.ParsingMode
 	MichelsonFormat         string
 	TezosClientArgument     struct {
 		Kind       TezosClientArgumentKind
@@ -31,15 +33,15 @@ type (
 		Amount     Mutez
 		Parameter  string
 	}
-	ContractCache struct {
-		StorageType ast.Node
-	}
+	// Mockup executes the operations with 'tezos-client' in mockup mode
 	Mockup struct {
-		TaskID    string
-		Protocol  string
-		Config    config.Config
-		Addresses map[string]string
-		contracts map[string]ContractCache
+		Session
+		fees map[string]Mutez
+	}
+	// mockupSnapshot is a copy of the task directory (the mockup state lives in it) and of the fees paid
+	mockupSnapshot struct {
+		directory string
+		fees      map[string]Mutez
 	}
 )
 
@@ -57,19 +59,19 @@ const (
 	Entrypoint
 	UnparsingMode
 	// Parsing modes
-	Readable  ParsingMode = "Readable"
-	Optimized ParsingMode = "Optimized"
+	Readable        = michelson.Readable
+	Optimized       = michelson.Optimized
+	OptimizedLegacy = michelson.OptimizedLegacy
 	// Michelson Formats
 	Michelson MichelsonFormat = "michelson"
 	JSON      MichelsonFormat = "json"
+	Annotated MichelsonFormat = "annotated" // JSON keyed by the field annotations of the type
 )
 
-func InitMockup(taskID string, protocol string, cfg config.Config) Mockup {
-	return Mockup{
-		TaskID:    taskID,
-		Protocol:  protocol,
-		Config:    cfg,
-		contracts: map[string]ContractCache{},
+func InitMockup(taskID string, protocol string, cfg config.Config) *Mockup {
+	return &Mockup{
+		Session: InitSession(taskID, protocol, cfg),
+		fees:    map[string]Mutez{},
 	}
 }
 
@@ -89,7 +91,7 @@ func (m *Mockup) Bootstrap() error {
 		},
 		TezosClientArgument{
 			Kind:       Protocol,
-			Parameters: []string{m.getProtocol()},
+			Parameters: []string{m.GetProtocol()},
 		},
 		TezosClientArgument{
 			Kind:       COMMAND,
@@ -121,9 +123,63 @@ func (m Mockup) Teardown() error {
 	temporaryDirectory := m.getTaskDirectory()
 	logger.Debug("[Task #%s] - Deleting task directory (%s).", m.TaskID, temporaryDirectory)
 
+	if err := os.RemoveAll(m.getSnapshotsDirectory()); err != nil {
+		return err
+	}
 	return os.RemoveAll(temporaryDirectory)
 }
 
+// Snapshot copies the task directory, the copies are deleted on teardown
+func (m Mockup) Snapshot() (StateSnapshot, error) {
+	if err := os.MkdirAll(m.getSnapshotsDirectory(), os.ModePerm); err != nil {
+		return nil, err
+	}
+	directory, err := os.MkdirTemp(m.getSnapshotsDirectory(), "snapshot_")
+	if err != nil {
+		return nil, err
+	}
+	if err := copyDirectory(m.getTaskDirectory(), directory); err != nil {
+		return nil, fmt.Errorf("could not snapshot mockup. %s", err)
+	}
+
+	fees := make(map[string]Mutez, len(m.fees))
+	for name, fee := range m.fees {
+		fees[name] = fee
+	}
+	return mockupSnapshot{directory: directory, fees: fees}, nil
+}
+
+// Restore replaces the task directory by a copy made with Snapshot
+func (m Mockup) Restore(snapshot StateSnapshot) error {
+	state, ok := snapshot.(mockupSnapshot)
+	if !ok {
+		return fmt.Errorf("invalid snapshot.")
+	}
+	if err := os.RemoveAll(m.getTaskDirectory()); err != nil {
+		return err
+	}
+	if err := copyDirectory(state.directory, m.getTaskDirectory()); err != nil {
+		return fmt.Errorf("could not restore mockup. %s", err)
+	}
+
+	for name := range m.fees {
+		delete(m.fees, name)
+	}
+	for name, fee := range state.fees {
+		m.fees[name] = fee
+	}
+	return nil
+}
+
+// Discard deletes a copy made with Snapshot
+func (m Mockup) Discard(snapshot StateSnapshot) error {
+	state, ok := snapshot.(mockupSnapshot)
+	if !ok {
+		return fmt.Errorf("invalid snapshot.")
+	}
+	return os.RemoveAll(state.directory)
+}
+
 // UpdateChainID updates the chain identifier in the mockup context
 func (m Mockup) UpdateChainID(chainID string) error {
 	logger.Debug("[Task #%s] - Updating chain_id to (%s).", m.TaskID, chainID)
@@ -219,7 +275,7 @@ func (m Mockup) ImportSecret(privateKey string, walletName string) error {
 		},
 		TezosClientArgument{
 			Kind:       Protocol,
-			Parameters: []string{m.getProtocol()},
+			Parameters: []string{m.GetProtocol()},
 		},
 		TezosClientArgument{
 			Kind:       COMMAND,
@@ -248,7 +304,7 @@ func (m Mockup) Transfer(arg CallContractArgument) error {
 		},
 		TezosClientArgument{
 			Kind:       Protocol,
-			Parameters: []string{m.getProtocol()},
+			Parameters: []string{m.GetProtocol()},
 		},
 		TezosClientArgument{
 			Kind:       COMMAND,
@@ -283,9 +339,48 @@ func (m Mockup) Transfer(arg CallContractArgument) error {
 	)
 	arguments := composeArguments(args...)
 
-	_, err := m.runTezosClient(m.getTezosClientPath(), arguments)
+	output, err := m.runTezosClient(m.getTezosClientPath(), arguments)
+	if err != nil {
+		return err
+	}
 
-	return err
+	// Keep track of the fees paid by the source
+	m.recordFees(arg.Source, output)
+
+	return nil
+}
+
+// GetFeesPaid gives the total of fees (baker fees and storage burns) paid by a given account
+func (m Mockup) GetFeesPaid(name string) Mutez {
+	if fees, ok := m.fees[name]; ok {
+		return fees
+	}
+	return MutezOfFloat(big.NewFloat(0))
+}
+
+// recordFees extracts the fees from the output of an operation and accumulates them to the source
+func (m Mockup) recordFees(source string, output string) {
+	if m.fees == nil {
+		return
+	}
+
+	fees := m.GetFeesPaid(source)
+	patterns := []*regexp.Regexp{
+		regexp.MustCompile(`Fee to the baker:\sꜩ(\d+(?:\.\d+)?)`),
+		regexp.MustCompile(`storage fees\s\.*\s\+ꜩ(\d+(?:\.\d+)?)`),
+	}
+	for _, pattern := range patterns {
+		for _, match := range pattern.FindAllStringSubmatch(output, -1) {
+			fee, err := TezOfString(match[1])
+			if err != nil {
+				logger.Debug("[Task #%s] - could not parse fee (%s). %s", m.TaskID, match[1], err)
+				continue
+			}
+			fees = AddMutez(fees, fee.ToMutez())
+		}
+	}
+
+	m.fees[source] = fees
 }
 
 // RevealWallet reveals wallet
@@ -303,7 +398,7 @@ func (m Mockup) RevealWallet(walletName string, revealFee Mutez) error {
 		},
 		TezosClientArgument{
 			Kind:       Protocol,
-			Parameters: []string{m.getProtocol()},
+			Parameters: []string{m.GetProtocol()},
 		},
 		TezosClientArgument{
 			Kind:       COMMAND,
@@ -315,8 +410,15 @@ func (m Mockup) RevealWallet(walletName string, revealFee Mutez) error {
 		},
 	)
 
-	_, err := m.runTezosClient(m.getTezosClientPath(), arguments)
-	return err
+	output, err := m.runTezosClient(m.getTezosClientPath(), arguments)
+	if err != nil {
+		return err
+	}
+
+	// Keep track of the fees paid by the revealed wallet
+	m.recordFees(walletName, output)
+
+	return nil
 }
 
 // Originate deploys a smart contract
@@ -334,7 +436,7 @@ func (m *Mockup) Originate(sender string, contractName string, amount Mutez, cod
 		},
 		TezosClientArgument{
 			Kind:       Protocol,
-			Parameters: []string{m.getProtocol()},
+			Parameters: []string{m.GetProtocol()},
 		},
 		TezosClientArgument{
 			Kind: COMMAND,
@@ -359,6 +461,9 @@ func (m *Mockup) Originate(sender string, contractName string, amount Mutez, cod
 		return "", err
 	}
 
+	// Keep track of the fees paid by the originator
+	m.recordFees(sender, output)
+
 	// Extract contract address
 	pattern := regexp.MustCompile(`New\scontract\s(\w+)\soriginated`)
 	match := pattern.FindStringSubmatch(output)
@@ -384,7 +489,7 @@ func (m *Mockup) SerializeData(dataNode string, typeNode string) (string, error)
 		},
 		TezosClientArgument{
 			Kind:       Protocol,
-			Parameters: []string{m.getProtocol()},
+			Parameters: []string{m.GetProtocol()},
 		},
 		TezosClientArgument{
 			Kind: COMMAND,
@@ -411,7 +516,7 @@ func (m *Mockup) SerializeData(dataNode string, typeNode string) (string, error)
 }
 
 // GetBalance fetches the balance of a given address (implicit account or originated contract)
-func (m Mockup) GetBalance(name string) Mutez {
+func (m Mockup) GetBalance(name string) (Mutez, error) {
 	logger.Debug("[Task #%s] - Get balance of (%s).", m.TaskID, name)
 
 	arguments := composeArguments(
@@ -425,7 +530,7 @@ func (m Mockup) GetBalance(name string) Mutez {
 		},
 		TezosClientArgument{
 			Kind:       Protocol,
-			Parameters: []string{m.getProtocol()},
+			Parameters: []string{m.GetProtocol()},
 		},
 		TezosClientArgument{
 			Kind:       COMMAND,
@@ -436,22 +541,22 @@ func (m Mockup) GetBalance(name string) Mutez {
 	// Execute command
 	output, err := m.runTezosClient(m.getTezosClientPath(), arguments)
 	if err != nil {
-		return MutezOfFloat(big.NewFloat(0))
+		return Mutez{}, fmt.Errorf("could not fetch balance of (%s). %w", name, err)
 	}
 
 	// Extract balance in ꜩ
 	pattern := regexp.MustCompile(`(\d*.?\d*)\sꜩ`)
 	match := pattern.FindStringSubmatch(output)
 	if len(match) < 2 {
-		return MutezOfFloat(big.NewFloat(0))
+		return Mutez{}, fmt.Errorf("could not extract the balance of (%s).", name)
 	}
 
 	balance, err := TezOfString(match[1])
 	if err != nil {
-		return MutezOfFloat(big.NewFloat(0))
+		return Mutez{}, fmt.Errorf("could not extract the balance of (%s). %s", name, err)
 	}
 
-	return balance.ToMutez()
+	return balance.ToMutez(), nil
 }
 
 // GetContractStorage fetches the storage of a given contract
@@ -469,7 +574,7 @@ func (m Mockup) GetContractStorage(contractName string) (ast.Node, error) {
 		},
 		TezosClientArgument{
 			Kind:       Protocol,
-			Parameters: []string{m.getProtocol()},
+			Parameters: []string{m.GetProtocol()},
 		},
 		TezosClientArgument{
 			Kind: COMMAND,
@@ -483,51 +588,22 @@ func (m Mockup) GetContractStorage(contractName string) (ast.Node, error) {
 		},
 	)
 
-	output, err := m.runTezosClient(m.getTezosClientPath(), arguments)
-	if err != nil {
-		return nil, fmt.Errorf("could not fetch storage from contract (%s). %s", contractName, err)
-	}
-
-	ast, err := michelson.ParseMicheline(output)
+	// Storages can be large (e.g. big maps), they are parsed while 'tezos-client' writes them
+	var (
+		storage  ast.Node
+		parseErr error
+	)
+	err := m.streamTezosClient(m.getTezosClientPath(), arguments, func(output io.Reader) {
+		storage, parseErr = michelson.ParseMichelineReader(output)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("could not parse contract (%s) storage from 'micheline' format. %s", contractName, err)
+		return nil, fmt.Errorf("could not fetch storage from contract (%s). %w", contractName, err)
 	}
-
-	return ast, nil
-}
-
-// ContainsAddress checks if address exists
-func (m Mockup) ContainsAddress(name string) bool {
-	return m.Addresses[name] != ""
-}
-
-// CacheAddress caches the address of a contract by name
-func (m Mockup) CacheAccountAddress(name string, address string) {
-	m.Addresses[name] = address
-}
-
-// CacheContract caches contract information
-func (m Mockup) CacheContract(name string, code ast.Node) error {
-	switch seq := code.(type) {
-	case ast.Sequence:
-		for _, node := range seq.Elements {
-			switch prim := node.(type) {
-			case ast.Prim:
-				if prim.Prim == "storage" {
-					m.contracts[name] = ContractCache{
-						StorageType: prim.Arguments[0],
-					}
-				}
-				return nil
-			}
-		}
+	if parseErr != nil {
+		return nil, fmt.Errorf("could not parse contract (%s) storage from 'micheline' format. %s", contractName, parseErr)
 	}
-	return fmt.Errorf("could not cache contract. michelson is invalid.")
-}
 
-// GetCachedContract get contract from cache
-func (m Mockup) GetCachedContract(name string) ContractCache {
-	return m.contracts[name]
+	return storage, nil
 }
 
 // NormalizeData normalize a data expression against a gicen type
@@ -543,7 +619,7 @@ func (m Mockup) NormalizeData(data string, dataType string, mode ParsingMode) (a
 		},
 		TezosClientArgument{
 			Kind:       Protocol,
-			Parameters: []string{m.getProtocol()},
+			Parameters: []string{m.GetProtocol()},
 		},
 		TezosClientArgument{
 			Kind: COMMAND,
@@ -559,7 +635,7 @@ func (m Mockup) NormalizeData(data string, dataType string, mode ParsingMode) (a
 
 	output, err := m.runTezosClient(m.getTezosClientPath(), arguments)
 	if err != nil {
-		return nil, fmt.Errorf("could not normalize data %s against type %s. %s", data, dataType, err)
+		return nil, fmt.Errorf("could not normalize data %s against type %s. %w", data, dataType, err)
 	}
 
 	ast, err := michelson.ParseMicheline(output)
@@ -570,31 +646,53 @@ func (m Mockup) NormalizeData(data string, dataType string, mode ParsingMode) (a
 	return ast, nil
 }
 
-// runTezosClient executes a "tezos-client" command
+// runTezosClient executes a "tezos-client" command, failures are returned as a *ClientError
 func (m Mockup) runTezosClient(command string, args []string) (string, error) {
+	var output string
+	err := m.streamTezosClient(command, args, func(stdout io.Reader) {
+		b, _ := io.ReadAll(stdout)
+		output = string(b)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(output) > 0 {
+		logger.Debug("Got the following output:\n\n%s\nwhen executing command: %s.", output, append([]string{command}, args...))
+	}
+
+	return output, nil
+}
+
+// streamTezosClient runs 'tezos-client' and gives its output to a consumer while it is written (the output is not kept in memory),
+// failures are returned as a *ClientError
+func (m Mockup) streamTezosClient(command string, args []string, consume func(output io.Reader)) error {
 	cmd := exec.Command(command, args...)
 
 	var errBuffer bytes.Buffer
-	var outBuffer bytes.Buffer
-	cmd.Stdout = &outBuffer
 	cmd.Stderr = &errBuffer
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	consume(stdout)
+	// The remaining output must be read for the command to terminate
+	_, _ = io.Copy(io.Discard, stdout)
 
-	if err := cmd.Run(); err != nil {
+	if err := cmd.Wait(); err != nil {
 		if errBuffer.Len() > 0 {
 			msg := errBuffer.String()
 			logger.Error("Got the following error:\n\n%s\nwhen executing command: %s.", msg, cmd.Args)
-			err = errors.New(msg)
-			return "", err
+			return ParseClientError(msg)
 		}
-		return "", err
-	}
-
-	output := outBuffer.String()
-	if len(output) > 0 {
-		logger.Debug("Got the following output:\n\n%s\nwhen executing command: %s.", output, cmd.Args)
+		return err
 	}
 
-	return output, nil
+	return nil
 }
 
 // fetchKnownAddresses gets all accounts known by 'tezos-client"
@@ -610,7 +708,7 @@ func (m Mockup) fetchKnownAddresses() map[string]string {
 		},
 		TezosClientArgument{
 			Kind:       Protocol,
-			Parameters: []string{m.getProtocol()},
+			Parameters: []string{m.GetProtocol()},
 		},
 		TezosClientArgument{
 			Kind:       COMMAND,
@@ -640,17 +738,40 @@ func (m Mockup) getTaskDirectory() string {
 	return fmt.Sprintf("%s/_tmp/%s", m.Config.Tezos.BaseDirectory, m.TaskID)
 }
 
+// getSnapshotsDirectory gives the path to the folder that keeps the snapshots of the task directory
+func (m Mockup) getSnapshotsDirectory() string {
+	return fmt.Sprintf("%s_snapshots", m.getTaskDirectory())
+}
+
 // getTezosClientPath gives the path to the 'tezos-client' binary
 func (m Mockup) getTezosClientPath() string {
 	return m.Config.Tezos.TezosClient
 }
 
-// getProtocol gives the protocol being used in the mockup
-func (m Mockup) getProtocol() string {
-	if m.Protocol == "" {
-		return m.Config.Tezos.DefaultProtocol
-	}
-	return m.Protocol
+// copyDirectory copies the content of a directory (recursively)
+func copyDirectory(source string, destination string) error {
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, relativePath)
+		if entry.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, info.Mode())
+	})
 }
 
 // composeArguments prepares the arguments for using with 'tezos-client'
@@ -10,18 +10,20 @@ import (
 
 func runMockupAndTeardown(t *testing.T, testFunc func(mockup Mockup)) {
 	mockup := Mockup{
-		TaskID: "task",
-		Config: config.Config{
-			Log: config.LogConfig{
-				Location: "../../.tmp_test/api.log",
-				Level:    "debug",
-			},
-			Tezos: config.TezosConfig{
-				TezosClient:     "../../tezos-bin/amd64/tezos-client",
-				DefaultProtocol: "ProtoALphaALphaALphaALphaALphaALphaALphaALphaDdp3zK",
-				BaseDirectory:   "../../tezos-bin",
-				RevealFee:       1000,
-				Originator:      "bootstrap2",
+		Session: Session{
+			TaskID: "task",
+			Config: config.Config{
+				Log: config.LogConfig{
+					Location: "../../.tmp_test/api.log",
+					Level:    "debug",
+				},
+				Tezos: config.TezosConfig{
+					TezosClient:     "../../tezos-bin/amd64/tezos-client",
+					DefaultProtocol: "ProtoALphaALphaALphaALphaALphaALphaALphaALphaDdp3zK",
+					BaseDirectory:   "../../tezos-bin",
+					RevealFee:       1000,
+					Originator:      "bootstrap2",
+				},
 			},
 		},
 	}
@@ -95,3 +97,91 @@ func TestComposeArguments(t *testing.T) {
 			)
 		})
 }
+
+func TestRecordFees(t *testing.T) {
+	t.Run("Accumulate fees paid by the source", func(t *testing.T) {
+		mockup := InitMockup("task", "", config.Config{})
+		output := `
+Estimated gas: 2047.122 units (will add 100 for safety)
+Estimated storage: 63 bytes added (will add 20 for safety)
+Operation successfully injected in the node.
+Manager signed operations:
+  From: tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx
+  Fee to the baker: ꜩ0.000476
+  Expected counter: 2
+  Gas limit: 2148
+  Storage limit: 83 bytes
+  Balance updates:
+    tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx ... -ꜩ0.000476
+    payload fees(the block proposer) ....... +ꜩ0.000476
+  Transaction:
+    Amount: ꜩ1
+    This transaction was successfully applied
+    Balance updates:
+      tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx ... -ꜩ0.01575
+      storage fees ........................... +ꜩ0.01575
+`
+		mockup.recordFees("bob", output)
+		assert.Equal(t, "16226", mockup.GetFeesPaid("bob").Int().String())
+		mockup.recordFees("bob", output)
+		assert.Equal(t, "32452", mockup.GetFeesPaid("bob").Int().String())
+		assert.Equal(t, "0", mockup.GetFeesPaid("alice").Int().String())
+	})
+	t.Run("Originations burn the storage of the contract and its allocation", func(t *testing.T) {
+		mockup := InitMockup("task", "", config.Config{})
+		output := `
+Operation successfully injected in the node.
+Manager signed operations:
+  From: tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx
+  Fee to the baker: ꜩ0.000399
+  Expected counter: 3
+  Gas limit: 1501
+  Storage limit: 295 bytes
+  Balance updates:
+    tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx ... -ꜩ0.000399
+    payload fees(the block proposer) ....... +ꜩ0.000399
+  Origination:
+    From: tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx
+    Credit: ꜩ0
+    This origination was successfully applied
+    Originated contracts:
+      KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1
+    Storage size: 38 bytes
+    Paid storage size diff: 38 bytes
+    Consumed gas: 1400.894
+    Balance updates:
+      tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx ... -ꜩ0.0095
+      storage fees ........................... +ꜩ0.0095
+      tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx ... -ꜩ0.06425
+      storage fees ........................... +ꜩ0.06425
+
+New contract KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1 originated.
+`
+		mockup.recordFees("bob", output)
+		assert.Equal(t, "74149", mockup.GetFeesPaid("bob").Int().String())
+	})
+	t.Run("Amounts are not followed by a decimal part", func(t *testing.T) {
+		mockup := InitMockup("task", "", config.Config{})
+		output := `
+  Fee to the baker: ꜩ1 (estimated)
+    Balance updates:
+      tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx ... -ꜩ2
+      storage fees ........................... +ꜩ2
+`
+		mockup.recordFees("bob", output)
+		assert.Equal(t, "3000000", mockup.GetFeesPaid("bob").Int().String())
+	})
+}
+
+func TestRunTezosClient(t *testing.T) {
+	mockup := InitMockup("task", "", config.Config{})
+
+	output, err := mockup.runTezosClient("sh", []string{"-c", "echo 'Operation successfully injected in the node.'"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Operation successfully injected in the node.\n", output)
+
+	_, err = mockup.runTezosClient("sh", []string{"-c", "echo 'Error:\n  Gas limit exceeded during typechecking or execution.' >&2; exit 1"})
+	assert.Equal(t, GasExhausted, ClassifyError(err).Kind)
+	var clientErr *ClientError
+	assert.ErrorAs(t, err, &clientErr, "Failures are returned as a *ClientError")
+}
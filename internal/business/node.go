@@ -0,0 +1,762 @@
+package business
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"blockwatch.cc/tzgo/tezos"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	MichelsonJSON "github.com/romarq/tezos-sc-tester/internal/business/michelson/json"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/macros"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/romarq/tezos-sc-tester/internal/config"
+	"github.com/romarq/tezos-sc-tester/internal/logger"
+)
+
+const (
+	// Limits used to simulate operations (the hard limits of the protocol)
+	simulationGasLimit     = 1040000
+	simulationStorageLimit = 60000
+	// Storage paid when an account is allocated or a contract is originated
+	allocationStorage = 257
+	// Fees required by the default mempool filter (in mutez)
+	minimalFees       = 100
+	feePerGasUnit     = 0.1
+	feePerByte        = 1
+	signatureSize     = 64
+	defaultPollPeriod = 500 * time.Millisecond
+	defaultTimeout    = 60 * time.Second
+	// Simulated operations do not need a valid signature
+	zeroSignature = "sigUHx32f9wesZ1n2BWpixXz4AQaZggEtchaQNHYGRCoWNAXx45WGW2ua3apUUUAGMLPwAU41QoaFCzVSL61VaessLg4YbbP"
+)
+
+// rpcErrorKinds classifies the errors of the node by the suffix of their identifier (the prefix names the protocol, e.g. proto.016-PtMumbai),
+// kinds are tried in the order of the patterns of 'tezos-client' errors
+var rpcErrorKinds = []struct {
+	kind     ClientErrorKind
+	suffixes []string
+}{
+	{ScriptRejected, []string{".michelson_v1.script_rejected"}},
+	{BalanceTooLow, []string{".contract.balance_too_low"}},
+	{GasExhausted, []string{".gas_exhausted.operation", ".gas_exhausted.block"}},
+	{StorageExhausted, []string{".storage_exhausted.operation"}},
+	{IllTypedParameter, []string{".michelson_v1.bad_contract_parameter"}},
+	{IllTypedContract, []string{".michelson_v1.ill_typed_contract"}},
+	{IllTypedData, []string{".michelson_v1.ill_typed_data", ".michelson_v1.invalid_constant"}},
+	{UnknownContract, []string{".contract.non_existing_contract"}},
+	{CounterInThePast, []string{".contract.counter_in_the_past"}},
+}
+
+type (
+	// Node executes operations on a Tezos node through its RPC (e.g. a sandboxed node),
+	// blocks are produced by the node so operations are only applied once they are included
+	Node struct {
+		Session
+		client  *http.Client
+		keys    map[string]tezos.PrivateKey // name -> secret key (accounts that can sign operations)
+		aliases map[string]string           // name -> address
+		fees    map[string]Mutez
+		chainID string
+	}
+	// rpcError is an error reported by the node, the fields after the identifier only apply to some errors
+	rpcError struct {
+		Kind           string          `json:"kind"`
+		ID             string          `json:"id"`
+		With           json.RawMessage `json:"with,omitempty"`
+		Contract       string          `json:"contract,omitempty"`
+		ContractHandle string          `json:"contract_handle,omitempty"`
+		Balance        string          `json:"balance,omitempty"`
+		Amount         string          `json:"amount,omitempty"`
+		Expected       string          `json:"expected,omitempty"`
+		Found          string          `json:"found,omitempty"`
+	}
+	balanceUpdate struct {
+		Kind     string `json:"kind"`
+		Category string `json:"category"`
+		Contract string `json:"contract"`
+		Change   string `json:"change"`
+	}
+	operationResult struct {
+		Status                       string          `json:"status"`
+		ConsumedMilligas             string          `json:"consumed_milligas"`
+		PaidStorageSizeDiff          string          `json:"paid_storage_size_diff"`
+		AllocatedDestinationContract bool            `json:"allocated_destination_contract"`
+		OriginatedContracts          []string        `json:"originated_contracts"`
+		BalanceUpdates               []balanceUpdate `json:"balance_updates"`
+		Errors                       []rpcError      `json:"errors"`
+	}
+	// operationReceipt is a manager operation with the metadata of its application
+	operationReceipt struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			BalanceUpdates           []balanceUpdate `json:"balance_updates"`
+			OperationResult          operationResult `json:"operation_result"`
+			InternalOperationResults []struct {
+				Result operationResult `json:"result"`
+			} `json:"internal_operation_results"`
+		} `json:"metadata"`
+	}
+)
+
+// InitNode creates a backend that executes operations on the node configured in "tezos.node"
+func InitNode(taskID string, protocol string, cfg config.Config) *Node {
+	return &Node{
+		Session: InitSession(taskID, protocol, cfg),
+		client:  &http.Client{Timeout: 30 * time.Second},
+		keys:    map[string]tezos.PrivateKey{},
+		aliases: map[string]string{},
+		fees:    map[string]Mutez{},
+	}
+}
+
+// Bootstrap checks that the node is reachable and imports the secret keys of the bootstrap accounts
+func (n *Node) Bootstrap() error {
+	if n.Config.Tezos.Node.RPC == "" {
+		return fmt.Errorf("the node backend is not configured.")
+	}
+	if err := n.get("/chains/main/chain_id", &n.chainID); err != nil {
+		return fmt.Errorf("could not reach node (%s). %s", n.Config.Tezos.Node.RPC, err)
+	}
+
+	var accounts []bootstrapAccount
+	if err := readJSON(fmt.Sprintf("%s/bootstrap-accounts.json", n.Config.Tezos.BaseDirectory), &accounts); err != nil {
+		return fmt.Errorf("could not bootstrap node backend. %s", err)
+	}
+	for _, account := range accounts {
+		if err := n.ImportSecret(strings.TrimPrefix(account.SkURI, "unencrypted:"), account.Name); err != nil {
+			return fmt.Errorf("invalid secret key for bootstrap account (%s). %s", account.Name, err)
+		}
+		n.Addresses[account.Name] = n.aliases[account.Name]
+	}
+
+	return nil
+}
+
+// Teardown does nothing, the chain belongs to the node
+func (n *Node) Teardown() error {
+	return nil
+}
+
+// UpdateChainID fails, the chain identifier is defined by the node
+func (n *Node) UpdateChainID(chainID string) error {
+	return fmt.Errorf("the chain identifier cannot be modified with the node backend.")
+}
+
+// UpdateHeadBlockLevel waits for the node to produce blocks until the head block reaches a given level
+func (n *Node) UpdateHeadBlockLevel(level int32) error {
+	head, err := n.headLevel()
+	if err != nil {
+		return err
+	}
+	if head > int64(level) {
+		return fmt.Errorf("could not modify block level, the head block (%d) is already past level (%d).", head, level)
+	}
+
+	deadline := time.Now().Add(n.timeout())
+	for head < int64(level) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("could not modify block level, the head block is still at level (%d).", head)
+		}
+		time.Sleep(n.pollPeriod())
+		if head, err = n.headLevel(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateHeadBlockTimestamp fails, block timestamps are defined by the node
+func (n *Node) UpdateHeadBlockTimestamp(timestamp string) error {
+	return fmt.Errorf("the block timestamp cannot be modified with the node backend.")
+}
+
+// ImportSecret registers a secret key under a name, operations from this account are signed with it
+func (n *Node) ImportSecret(privateKey string, walletName string) error {
+	key, err := tezos.ParsePrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("invalid secret key. %s", err)
+	}
+	n.keys[walletName] = key
+	n.aliases[walletName] = key.Address().String()
+	return nil
+}
+
+// RevealWallet reveals the public key of an account, paying a given fee
+func (n *Node) RevealWallet(walletName string, revealFee Mutez) error {
+	logger.Debug("[Task #%s] - Revealing wallet (%s).", n.TaskID, walletName)
+
+	key, ok := n.keys[walletName]
+	if !ok {
+		return fmt.Errorf("unknown account (%s).", walletName)
+	}
+	_, err := n.inject(walletName, map[string]interface{}{
+		"kind":       "reveal",
+		"public_key": key.Public().String(),
+		"fee":        revealFee.Int().String(),
+	})
+	return err
+}
+
+// Transfer calls a given address
+func (n *Node) Transfer(arg CallContractArgument) error {
+	logger.Debug("[Task #%s] - Calling contract %s. %v", n.TaskID, arg.Recipient, arg)
+
+	recipient, ok := n.resolve(arg.Recipient)
+	if !ok {
+		return fmt.Errorf("unknown account (%s).", arg.Recipient)
+	}
+	content := map[string]interface{}{
+		"kind":        "transaction",
+		"amount":      arg.Amount.Int().String(),
+		"destination": recipient,
+	}
+	if arg.Parameter != "" {
+		entrypoint := arg.Entrypoint
+		if entrypoint == "" {
+			entrypoint = michelson.DEFAULT_ENTRYPOINT
+		}
+		value, err := michelsonToJSON(arg.Parameter)
+		if err != nil {
+			return fmt.Errorf("invalid parameter. %s", err)
+		}
+		content["parameters"] = map[string]interface{}{
+			"entrypoint": entrypoint,
+			"value":      value,
+		}
+	}
+
+	_, err := n.inject(arg.Source, content)
+	return err
+}
+
+// Originate deploys a smart contract
+func (n *Node) Originate(sender string, contractName string, amount Mutez, code string, storage string) (string, error) {
+	logger.Debug("[Task #%s] - Originating contract (%s).", n.TaskID, contractName)
+
+	codeJSON, err := michelsonToJSON(code)
+	if err != nil {
+		return "", fmt.Errorf("invalid code. %s", err)
+	}
+	storageJSON, err := michelsonToJSON(storage)
+	if err != nil {
+		return "", fmt.Errorf("invalid storage. %s", err)
+	}
+
+	receipt, err := n.inject(sender, map[string]interface{}{
+		"kind":    "origination",
+		"balance": amount.Int().String(),
+		"script": map[string]interface{}{
+			"code":    codeJSON,
+			"storage": storageJSON,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	originated := receipt.Metadata.OperationResult.OriginatedContracts
+	if len(originated) == 0 {
+		return "", fmt.Errorf("could not extract the address of contract (%s).", contractName)
+	}
+
+	n.aliases[contractName] = originated[0]
+	return originated[0], nil
+}
+
+// GetBalance fetches the balance of a given address (implicit account or originated contract)
+func (n *Node) GetBalance(name string) (Mutez, error) {
+	address, ok := n.resolve(name)
+	if !ok {
+		return Mutez{}, fmt.Errorf("unknown account (%s).", name)
+	}
+
+	var value string
+	if err := n.get(fmt.Sprintf("/chains/main/blocks/head/context/contracts/%s/balance", address), &value); err != nil {
+		return Mutez{}, fmt.Errorf("could not fetch balance of (%s). %w", name, err)
+	}
+	balance, err := MutezOfString(value)
+	if err != nil {
+		return Mutez{}, fmt.Errorf("could not fetch balance of (%s). %s", name, err)
+	}
+	return balance, nil
+}
+
+// GetFeesPaid gives the total of fees (baker fees and storage burns) paid by a given account
+func (n *Node) GetFeesPaid(name string) Mutez {
+	if fees, ok := n.fees[name]; ok {
+		return fees
+	}
+	return MutezOfFloat(big.NewFloat(0))
+}
+
+// GetContractStorage fetches the storage of a given contract (in readable form)
+func (n *Node) GetContractStorage(contractName string) (ast.Node, error) {
+	address, ok := n.resolve(contractName)
+	if !ok {
+		return nil, fmt.Errorf("could not fetch storage from contract (%s). contract does not exist.", contractName)
+	}
+
+	var script struct {
+		Code    json.RawMessage `json:"code"`
+		Storage json.RawMessage `json:"storage"`
+	}
+	if err := n.get(fmt.Sprintf("/chains/main/blocks/head/context/contracts/%s/script", address), &script); err != nil {
+		return nil, fmt.Errorf("could not fetch storage from contract (%s). %s", contractName, err)
+	}
+	code, err := michelson.ParseJSON(script.Code)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse contract (%s) code. %s", contractName, err)
+	}
+	contract, err := michelson.ParseContract(code)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse contract (%s) code. %s", contractName, err)
+	}
+	storage, err := michelson.ParseJSON(script.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse contract (%s) storage. %s", contractName, err)
+	}
+
+	// The node returns the storage in optimized form
+	return michelson.Normalize(storage, contract.Storage, Readable)
+}
+
+// NormalizeData normalizes a data expression against a given type
+func (n *Node) NormalizeData(data string, dataType string, mode ParsingMode) (ast.Node, error) {
+	dataJSON, typeJSON, err := dataAndTypeToJSON(data, dataType)
+	if err != nil {
+		return nil, fmt.Errorf("could not normalize data %s against type %s. %s", data, dataType, err)
+	}
+
+	var result struct {
+		Normalized json.RawMessage `json:"normalized"`
+	}
+	err = n.post("/chains/main/blocks/head/helpers/scripts/normalize_data", map[string]interface{}{
+		"data":           dataJSON,
+		"type":           typeJSON,
+		"unparsing_mode": mode,
+	}, &result)
+	if err != nil {
+		return nil, fmt.Errorf("could not normalize data %s against type %s. %s", data, dataType, err)
+	}
+	return michelson.ParseJSON(result.Normalized)
+}
+
+// SerializeData serializes a michelson value
+func (n *Node) SerializeData(data string, dataType string) (string, error) {
+	dataJSON, typeJSON, err := dataAndTypeToJSON(data, dataType)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Packed string `json:"packed"`
+	}
+	err = n.post("/chains/main/blocks/head/helpers/scripts/pack_data", map[string]interface{}{
+		"data": dataJSON,
+		"type": typeJSON,
+	}, &result)
+	if err != nil {
+		return "", fmt.Errorf("could not serialize data %s. %s", data, err)
+	}
+	return "0x" + result.Packed, nil
+}
+
+// Snapshot fails, the chain belongs to the node and cannot be reverted
+func (n *Node) Snapshot() (StateSnapshot, error) {
+	return nil, fmt.Errorf("the state of the chain cannot be restored with the node backend.")
+}
+
+// Restore fails, the chain belongs to the node and cannot be reverted
+func (n *Node) Restore(snapshot StateSnapshot) error {
+	return fmt.Errorf("the state of the chain cannot be restored with the node backend.")
+}
+
+// Discard does nothing, snapshots cannot be made with the node backend
+func (n *Node) Discard(snapshot StateSnapshot) error {
+	return nil
+}
+
+// inject simulates a manager operation to set its limits and fee, then signs it, injects it and waits for its inclusion
+func (n *Node) inject(source string, content map[string]interface{}) (operationReceipt, error) {
+	key, ok := n.keys[source]
+	if !ok {
+		return operationReceipt{}, fmt.Errorf("account (%s) cannot sign operations, its secret key is unknown.", source)
+	}
+
+	var (
+		branch  string
+		counter string
+	)
+	if err := n.get("/chains/main/blocks/head/hash", &branch); err != nil {
+		return operationReceipt{}, err
+	}
+	if err := n.get(fmt.Sprintf("/chains/main/blocks/head/context/contracts/%s/counter", key.Address()), &counter); err != nil {
+		return operationReceipt{}, err
+	}
+	nextCounter, ok := new(big.Int).SetString(counter, 10)
+	if !ok {
+		return operationReceipt{}, fmt.Errorf("invalid counter (%s).", counter)
+	}
+	content["source"] = key.Address().String()
+	content["counter"] = nextCounter.Add(nextCounter, big.NewInt(1)).String()
+	content["gas_limit"] = fmt.Sprint(simulationGasLimit)
+	content["storage_limit"] = fmt.Sprint(simulationStorageLimit)
+	_, hasFee := content["fee"]
+	if !hasFee {
+		content["fee"] = "0"
+	}
+
+	// Simulate the operation
+	var simulation struct {
+		Contents []operationReceipt `json:"contents"`
+	}
+	err := n.post("/chains/main/blocks/head/helpers/scripts/run_operation", map[string]interface{}{
+		"operation": map[string]interface{}{
+			"branch":    branch,
+			"contents":  []interface{}{content},
+			"signature": zeroSignature,
+		},
+		"chain_id": n.chainID,
+	}, &simulation)
+	if err != nil {
+		return operationReceipt{}, fmt.Errorf("could not simulate operation. %s", err)
+	}
+	if len(simulation.Contents) != 1 {
+		return operationReceipt{}, fmt.Errorf("unexpected simulation result.")
+	}
+	if err := operationError(simulation.Contents[0]); err != nil {
+		return operationReceipt{}, err
+	}
+	gas, storage := simulation.Contents[0].consumed()
+	content["gas_limit"] = fmt.Sprint(gas)
+	content["storage_limit"] = fmt.Sprint(storage)
+
+	// Forge the operation, the fee depends on the size of the operation
+	forged, err := n.forge(branch, content)
+	if err != nil {
+		return operationReceipt{}, err
+	}
+	if !hasFee {
+		// Leave some room for the bytes taken by the fee itself
+		fee := minimalFees + int64(feePerGasUnit*float64(gas)) + feePerByte*int64(len(forged)+signatureSize+10)
+		content["fee"] = fmt.Sprint(fee)
+		if forged, err = n.forge(branch, content); err != nil {
+			return operationReceipt{}, err
+		}
+	}
+
+	// Sign and inject the operation
+	digest := tezos.Digest(append([]byte{0x03}, forged...))
+	signature, err := key.Sign(digest[:])
+	if err != nil {
+		return operationReceipt{}, fmt.Errorf("could not sign operation. %s", err)
+	}
+	level, err := n.headLevel()
+	if err != nil {
+		return operationReceipt{}, err
+	}
+	var hash string
+	if err := n.post("/injection/operation?chain=main", hex.EncodeToString(append(forged, signature.Data...)), &hash); err != nil {
+		return operationReceipt{}, fmt.Errorf("could not inject operation. %s", err)
+	}
+	logger.Debug("[Task #%s] - Injected operation (%s).", n.TaskID, hash)
+
+	receipt, err := n.waitFor(hash, level+1)
+	if err != nil {
+		return operationReceipt{}, err
+	}
+	if err := operationError(receipt); err != nil {
+		return operationReceipt{}, err
+	}
+	n.recordFees(source, key.Address().String(), receipt)
+
+	return receipt, nil
+}
+
+// forge serializes an operation (the node does it, so that the encoding always matches its protocol)
+func (n *Node) forge(branch string, content map[string]interface{}) ([]byte, error) {
+	var forged string
+	err := n.post("/chains/main/blocks/head/helpers/forge/operations", map[string]interface{}{
+		"branch":   branch,
+		"contents": []interface{}{content},
+	}, &forged)
+	if err != nil {
+		return nil, fmt.Errorf("could not forge operation. %s", err)
+	}
+	return hex.DecodeString(forged)
+}
+
+// waitFor waits for an operation to be included in a block, starting from a given level
+func (n *Node) waitFor(hash string, level int64) (operationReceipt, error) {
+	deadline := time.Now().Add(n.timeout())
+	for {
+		head, err := n.headLevel()
+		if err != nil {
+			return operationReceipt{}, err
+		}
+		for ; level <= head; level++ {
+			// Manager operations are in the 4th validation pass
+			var hashes []string
+			if err := n.get(fmt.Sprintf("/chains/main/blocks/%d/operation_hashes/3", level), &hashes); err != nil {
+				return operationReceipt{}, err
+			}
+			for index, h := range hashes {
+				if h != hash {
+					continue
+				}
+				var operation struct {
+					Contents []operationReceipt `json:"contents"`
+				}
+				if err := n.get(fmt.Sprintf("/chains/main/blocks/%d/operations/3/%d", level, index), &operation); err != nil {
+					return operationReceipt{}, err
+				}
+				if len(operation.Contents) != 1 {
+					return operationReceipt{}, fmt.Errorf("unexpected receipt for operation (%s).", hash)
+				}
+				return operation.Contents[0], nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return operationReceipt{}, fmt.Errorf("operation (%s) was not included after %s.", hash, n.timeout())
+		}
+		time.Sleep(n.pollPeriod())
+	}
+}
+
+// recordFees accumulates the baker fee and the storage burns paid by the source of an operation
+func (n *Node) recordFees(source string, address string, receipt operationReceipt) {
+	fees := n.GetFeesPaid(source).Int()
+	for _, update := range receipt.Metadata.BalanceUpdates {
+		if update.Kind == "contract" && update.Contract == address {
+			fees.Sub(fees, parseInt(update.Change))
+		}
+	}
+	for _, result := range receipt.results() {
+		for _, update := range result.BalanceUpdates {
+			if update.Kind == "burned" && update.Category == "storage fees" {
+				fees.Add(fees, parseInt(update.Change))
+			}
+		}
+	}
+	n.fees[source] = MutezOfFloat(new(big.Float).SetInt(fees))
+}
+
+func (n *Node) headLevel() (int64, error) {
+	var header struct {
+		Level int64 `json:"level"`
+	}
+	if err := n.get("/chains/main/blocks/head/header", &header); err != nil {
+		return 0, err
+	}
+	return header.Level, nil
+}
+
+// resolve gives the address of an account from its name (addresses are resolved to themselves)
+func (n *Node) resolve(name string) (string, bool) {
+	if address, ok := n.aliases[name]; ok {
+		return address, true
+	}
+	if address, ok := n.Addresses[name]; ok {
+		return address, true
+	}
+	if _, err := tezos.ParseAddress(name); err == nil {
+		return name, true
+	}
+	return "", false
+}
+
+func (n *Node) timeout() time.Duration {
+	if n.Config.Tezos.Node.BlockTimeout > 0 {
+		return time.Duration(n.Config.Tezos.Node.BlockTimeout) * time.Second
+	}
+	return defaultTimeout
+}
+
+func (n *Node) pollPeriod() time.Duration {
+	if n.Config.Tezos.Node.PollInterval > 0 {
+		return time.Duration(n.Config.Tezos.Node.PollInterval) * time.Millisecond
+	}
+	return defaultPollPeriod
+}
+
+func (n *Node) get(path string, result interface{}) error {
+	return n.request(http.MethodGet, path, nil, result)
+}
+
+func (n *Node) post(path string, body interface{}, result interface{}) error {
+	return n.request(http.MethodPost, path, body, result)
+}
+
+// request calls an RPC of the node, errors reported by the node are included in the returned error
+func (n *Node) request(method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(n.Config.Tezos.Node.RPC, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("RPC (%s) failed. %s", path, err)
+	}
+	defer res.Body.Close()
+	output, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("RPC (%s) failed. %s", path, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		message := strings.TrimSpace(string(output))
+		var rpcErrors []rpcError
+		if json.Unmarshal(output, &rpcErrors) == nil {
+			if clientErr := clientErrorOf(rpcErrors, message); clientErr.Kind != UnclassifiedError {
+				return fmt.Errorf("RPC (%s) failed with status (%d). %w", path, res.StatusCode, clientErr)
+			}
+		}
+		return fmt.Errorf("RPC (%s) failed with status (%d). %s", path, res.StatusCode, message)
+	}
+	if err := json.Unmarshal(output, result); err != nil {
+		return fmt.Errorf("RPC (%s) returned an invalid response. %s", path, err)
+	}
+	return nil
+}
+
+// results gives the result of the operation followed by the results of its internal operations
+func (r operationReceipt) results() []operationResult {
+	results := []operationResult{r.Metadata.OperationResult}
+	for _, internal := range r.Metadata.InternalOperationResults {
+		results = append(results, internal.Result)
+	}
+	return results
+}
+
+// consumed gives the gas and storage limits required by an operation (with a safety margin for the gas)
+func (r operationReceipt) consumed() (int64, int64) {
+	milligas, storage := new(big.Int), new(big.Int)
+	for _, result := range r.results() {
+		milligas.Add(milligas, parseInt(result.ConsumedMilligas))
+		storage.Add(storage, parseInt(result.PaidStorageSizeDiff))
+		if result.AllocatedDestinationContract {
+			storage.Add(storage, big.NewInt(allocationStorage))
+		}
+		storage.Add(storage, big.NewInt(int64(allocationStorage*len(result.OriginatedContracts))))
+	}
+	gas := new(big.Int).Div(milligas.Add(milligas, big.NewInt(999)), big.NewInt(1000)).Int64()
+	return gas + 100, storage.Int64()
+}
+
+// operationError gives the error of a failed operation,
+// (FAILWITH) errors are written like 'tezos-client' does so that the emitted value can be extracted
+func operationError(receipt operationReceipt) error {
+	messages := make([]string, 0)
+	rpcErrors := make([]rpcError, 0)
+	for _, result := range receipt.results() {
+		if result.Status == "" || result.Status == "applied" {
+			continue
+		}
+		for _, e := range result.Errors {
+			messages = append(messages, e.ID)
+		}
+		if len(result.Errors) == 0 {
+			messages = append(messages, result.Status)
+		}
+		rpcErrors = append(rpcErrors, result.Errors...)
+	}
+	if len(messages) > 0 {
+		return clientErrorOf(rpcErrors, fmt.Sprintf("operation failed. [%s]", strings.Join(messages, ", ")))
+	}
+	return nil
+}
+
+// clientErrorOf classifies the errors reported by the node like the failures of 'tezos-client' (see ParseClientError)
+func clientErrorOf(rpcErrors []rpcError, message string) *ClientError {
+	e := &ClientError{
+		Kind:    UnclassifiedError,
+		Message: message,
+	}
+
+	for _, k := range rpcErrorKinds {
+		for i, rpcErr := range rpcErrors {
+			if !hasSuffix(rpcErr.ID, k.suffixes) {
+				continue
+			}
+			e.Kind = k.kind
+			e.Contract = rpcErr.Contract
+			e.Expected = rpcErr.Expected
+			e.Found = rpcErr.Found
+			if balance, err := MutezOfString(rpcErr.Balance); err == nil && rpcErr.Balance != "" {
+				e.Balance = &balance
+			}
+			if amount, err := MutezOfString(rpcErr.Amount); err == nil && rpcErr.Amount != "" {
+				e.Amount = &amount
+			}
+			if k.kind == ScriptRejected {
+				// The rejecting contract is given by the runtime error that precedes the rejection
+				for j := i - 1; j >= 0 && e.Contract == ""; j-- {
+					e.Contract = rpcErrors[j].ContractHandle
+				}
+				if value, err := michelson.ParseJSON(rpcErr.With); err == nil {
+					e.With = value
+					e.Message = fmt.Sprintf("script reached FAILWITH instruction\nwith %s\n", micheline.Print(value, ""))
+				}
+			}
+			return e
+		}
+	}
+
+	return e
+}
+
+func hasSuffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// michelsonToJSON converts micheline to the JSON expected by the node (macros are expanded, like 'tezos-client' does)
+func michelsonToJSON(michelsonMicheline string) (json.RawMessage, error) {
+	node, err := michelson.ParseMicheline(michelsonMicheline)
+	if err != nil {
+		return nil, err
+	}
+	if node, err = macros.Expand(node); err != nil {
+		return nil, err
+	}
+	return MichelsonJSON.Print(node, "", "")
+}
+
+func dataAndTypeToJSON(data string, dataType string) (json.RawMessage, json.RawMessage, error) {
+	dataJSON, err := michelsonToJSON(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	typeJSON, err := michelsonToJSON(dataType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dataJSON, typeJSON, nil
+}
+
+func parseInt(s string) *big.Int {
+	if v, ok := new(big.Int).SetString(s, 10); ok {
+		return v
+	}
+	return new(big.Int)
+}
@@ -0,0 +1,385 @@
+package business
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"blockwatch.cc/tzgo/tezos"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/binary"
+	MichelsonJSON "github.com/romarq/tezos-sc-tester/internal/business/michelson/json"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/romarq/tezos-sc-tester/internal/logger"
+	"github.com/romarq/tezos-sc-tester/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNode implements the RPCs used by the node backend.
+//
+// Operations are forged as JSON, every injected operation is included in a new block.
+// Contracts replace their storage with the parameter they receive, and fail with "ZERO" if the parameter is 0.
+type fakeNode struct {
+	mu       sync.Mutex
+	level    int64
+	autoBake bool // a block is produced every time the head is requested
+	balances map[string]*big.Int
+	scripts  map[string]map[string]json.RawMessage
+	blocks   map[int64][]fakeOperation
+	nonce    int
+	lastFee  string
+}
+
+// fakeBaker receives the fees of the operations
+const fakeBaker = "tz1ddb9NMYHZi5UzPdzTZMYQQZoMub195zgv"
+
+type fakeOperation struct {
+	hash    string
+	receipt map[string]interface{}
+}
+
+func newFakeNode() *fakeNode {
+	return &fakeNode{
+		level:    1,
+		balances: map[string]*big.Int{"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx": big.NewInt(100_000_000)},
+		scripts:  map[string]map[string]json.RawMessage{},
+		blocks:   map[int64][]fakeOperation{},
+	}
+}
+
+func (f *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	raw, _ := io.ReadAll(r.Body)
+	var body map[string]json.RawMessage
+	_ = json.Unmarshal(raw, &body)
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	reply := func(v interface{}) {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	switch {
+	case r.URL.Path == "/chains/main/chain_id":
+		reply("NetXdQprcVkpaWU")
+	case r.URL.Path == "/chains/main/blocks/head/hash":
+		reply("BLockGenesisGenesisGenesisGenesisGenesisf79b5d1CoW2")
+	case r.URL.Path == "/chains/main/blocks/head/header":
+		if f.autoBake {
+			f.level++
+		}
+		reply(map[string]int64{"level": f.level})
+	case len(path) == 8 && path[5] == "contracts" && path[7] == "counter":
+		reply("7")
+	case len(path) == 8 && path[5] == "contracts" && path[7] == "balance":
+		if _, ok := f.scripts[path[6]]; !ok && strings.HasPrefix(path[6], "KT1") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		reply(f.balanceOf(path[6]).String())
+	case len(path) == 8 && path[5] == "contracts" && path[7] == "script":
+		script, ok := f.scripts[path[6]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		reply(script)
+	case r.URL.Path == "/chains/main/blocks/head/helpers/scripts/normalize_data":
+		value, _ := michelson.ParseJSON(body["data"])
+		typ, _ := michelson.ParseJSON(body["type"])
+		var mode ParsingMode
+		_ = json.Unmarshal(body["unparsing_mode"], &mode)
+		normalized, err := michelson.Normalize(value, typ, mode)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			reply([]map[string]string{{"kind": "permanent", "id": "proto.alpha.michelson_v1.invalid_expression"}})
+			return
+		}
+		normalizedJSON, _ := MichelsonJSON.Print(normalized, "", "")
+		reply(map[string]json.RawMessage{"normalized": normalizedJSON})
+	case r.URL.Path == "/chains/main/blocks/head/helpers/scripts/pack_data":
+		value, _ := michelson.ParseJSON(body["data"])
+		typ, _ := michelson.ParseJSON(body["type"])
+		packed, _ := binary.Pack(value, typ)
+		reply(map[string]string{"packed": hex.EncodeToString(packed), "gas": "1000"})
+	case r.URL.Path == "/chains/main/blocks/head/helpers/forge/operations":
+		b, _ := json.Marshal(body)
+		reply(hex.EncodeToString(b))
+	case r.URL.Path == "/chains/main/blocks/head/helpers/scripts/run_operation":
+		var operation struct {
+			Contents []map[string]interface{} `json:"contents"`
+		}
+		_ = json.Unmarshal(body["operation"], &operation)
+		reply(map[string]interface{}{
+			"contents": []interface{}{f.apply(operation.Contents[0], true)},
+		})
+	case r.URL.Path == "/injection/operation":
+		var signed string
+		_ = json.Unmarshal(raw, &signed)
+		reply(f.inject(signed))
+	case len(path) == 6 && path[4] == "operation_hashes":
+		hashes := make([]string, 0)
+		for _, op := range f.blocks[parseInt(path[3]).Int64()] {
+			hashes = append(hashes, op.hash)
+		}
+		reply(hashes)
+	case len(path) == 7 && path[4] == "operations":
+		op := f.blocks[parseInt(path[3]).Int64()][parseInt(path[6]).Int64()]
+		reply(map[string]interface{}{"contents": []interface{}{op.receipt}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// inject includes an operation in a new block
+func (f *fakeNode) inject(signedHex string) string {
+	signed, _ := hex.DecodeString(signedHex)
+	var operation struct {
+		Contents []map[string]interface{} `json:"contents"`
+	}
+	_ = json.Unmarshal(signed[:len(signed)-signatureSize], &operation)
+
+	hash := binary.OperationHash(signed)
+	f.level++
+	f.blocks[f.level] = []fakeOperation{{hash: hash, receipt: f.apply(operation.Contents[0], false)}}
+	return hash
+}
+
+// apply applies a manager operation and gives its receipt (the state is left untouched when simulating)
+func (f *fakeNode) apply(content map[string]interface{}, simulate bool) map[string]interface{} {
+	source := content["source"].(string)
+	fee := parseInt(content["fee"].(string))
+	result := map[string]interface{}{"status": "applied", "consumed_milligas": "1500100"}
+
+	var (
+		amount      = new(big.Int)
+		destination string
+		burn        = new(big.Int)
+	)
+	switch content["kind"] {
+	case "transaction":
+		amount = parseInt(content["amount"].(string))
+		destination = content["destination"].(string)
+		if balance := f.balanceOf(source); balance.Cmp(amount) < 0 {
+			return map[string]interface{}{"kind": "transaction", "metadata": map[string]interface{}{"operation_result": map[string]interface{}{
+				"status": "failed",
+				"errors": []interface{}{
+					map[string]interface{}{"kind": "temporary", "id": "proto.alpha.contract.balance_too_low", "contract": source, "balance": balance.String(), "amount": amount.String()},
+				},
+			}}}
+		}
+		if parameters, ok := content["parameters"].(map[string]interface{}); ok {
+			value, _ := json.Marshal(parameters["value"])
+			if string(value) == `{"int":"0"}` {
+				return map[string]interface{}{"kind": "transaction", "metadata": map[string]interface{}{"operation_result": map[string]interface{}{
+					"status": "failed",
+					"errors": []interface{}{
+						map[string]interface{}{"kind": "temporary", "id": "proto.alpha.michelson_v1.runtime_error", "contract_handle": destination},
+						map[string]interface{}{"kind": "temporary", "id": "proto.alpha.michelson_v1.script_rejected", "with": map[string]string{"string": "ZERO"}},
+					},
+				}}}
+			}
+			if !simulate {
+				f.scripts[destination]["storage"] = value
+			}
+		}
+	case "origination":
+		amount = parseInt(content["balance"].(string))
+		f.nonce++
+		hash := tezos.Digest([]byte(fmt.Sprintf("fake/%d", f.nonce)))
+		destination = tezos.NewAddress(tezos.AddressTypeContract, hash[:20]).String()
+		result["originated_contracts"] = []string{destination}
+		result["paid_storage_size_diff"] = "40"
+		burn = big.NewInt((40 + allocationStorage) * 250)
+		result["balance_updates"] = []interface{}{
+			map[string]string{"kind": "contract", "contract": source, "change": new(big.Int).Neg(burn).String()},
+			map[string]string{"kind": "burned", "category": "storage fees", "change": burn.String()},
+		}
+		if !simulate {
+			var script map[string]json.RawMessage
+			b, _ := json.Marshal(content["script"])
+			_ = json.Unmarshal(b, &script)
+			f.scripts[destination] = script
+		}
+	}
+
+	if !simulate {
+		f.lastFee = fee.String()
+		f.balances[source] = new(big.Int).Sub(f.balanceOf(source), new(big.Int).Add(fee, new(big.Int).Add(amount, burn)))
+		if destination != "" {
+			f.balances[destination] = new(big.Int).Add(f.balanceOf(destination), amount)
+		}
+	}
+	return map[string]interface{}{
+		"kind": content["kind"],
+		"metadata": map[string]interface{}{
+			"balance_updates": []interface{}{
+				map[string]string{"kind": "contract", "contract": source, "change": new(big.Int).Neg(fee).String()},
+				map[string]string{"kind": "contract", "contract": fakeBaker, "change": fee.String()},
+			},
+			"operation_result": result,
+		},
+	}
+}
+
+func (f *fakeNode) balanceOf(address string) *big.Int {
+	if balance, ok := f.balances[address]; ok {
+		return balance
+	}
+	return new(big.Int)
+}
+
+func TestNode(t *testing.T) {
+	logger.SetupLogger(testConfig.Log.Location, testConfig.Log.Level)
+
+	fake := newFakeNode()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cfg := testConfig
+	cfg.Tezos.Node.RPC = server.URL
+	cfg.Tezos.Node.BlockTimeout = 1
+	cfg.Tezos.Node.PollInterval = 1
+	node := InitNode("task", "", cfg)
+	assert.NoError(t, node.Bootstrap())
+	defer node.Teardown()
+
+	t.Run("Bootstrap accounts are imported", func(t *testing.T) {
+		assert.Equal(t, "NetXdQprcVkpaWU", node.chainID)
+		assert.Equal(t, "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", node.GetAddresses()["bootstrap1"])
+		assert.Equal(t, "100000000", getBalance(t, node, "bootstrap1").String())
+	})
+	t.Run("Balances that cannot be fetched are errors", func(t *testing.T) {
+		_, err := node.GetBalance("bob")
+		assert.EqualError(t, err, "unknown account (bob).")
+		_, err = node.GetBalance("KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1")
+		assert.ErrorContains(t, err, "could not fetch balance of (KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1).")
+	})
+	t.Run("Operations are simulated, signed, injected and included", func(t *testing.T) {
+		key, err := utils.GenerateKey()
+		assert.NoError(t, err)
+		assert.NoError(t, node.ImportSecret(key.String(), "alice"))
+		assert.NoError(t, node.Transfer(CallContractArgument{Recipient: "alice", Source: "bootstrap1", Amount: mutez(t, "2000")}))
+		fee := fake.lastFee
+		// The fee credited to the baker is not paid by the source
+		assert.Equal(t, fee, node.GetFeesPaid("bootstrap1").String())
+		// 100 (minimal fee) + 160 (1601 gas units) + the size of the signed operation
+		assert.Greater(t, parseInt(fee).Int64(), int64(260))
+
+		assert.NoError(t, node.RevealWallet("alice", mutez(t, "1000")))
+		assert.Equal(t, "1000", fake.lastFee)
+		assert.Equal(t, "1000", getBalance(t, node, "alice").String())
+	})
+	t.Run("Contracts are originated and called", func(t *testing.T) {
+		feesPaid := parseInt(node.GetFeesPaid("bootstrap1").String())
+		address, err := node.Originate("bootstrap1", "store", mutez(t, "10"), `{ parameter (pair nat nat nat) ; storage (pair nat nat nat) ; code { FAIL } }`, `{ 1 ; 2 ; 3 }`)
+		assert.NoError(t, err)
+		assert.Len(t, fake.scripts, 1)
+		assert.Contains(t, fake.scripts, address)
+		assert.Equal(t, "10", getBalance(t, node, "store").String())
+		// Macros are expanded before the code is sent to the node
+		assert.Contains(t, string(fake.scripts[address]["code"]), `{"prim":"UNIT"}`)
+		// Storage burns are part of the fees
+		burn := big.NewInt((40 + allocationStorage) * 250)
+		feesPaid.Add(feesPaid, burn.Add(burn, parseInt(fake.lastFee)))
+		assert.Equal(t, feesPaid.String(), node.GetFeesPaid("bootstrap1").String())
+
+		// The node gives storages in optimized form
+		storage, err := node.GetContractStorage("store")
+		assert.NoError(t, err)
+		assert.Equal(t, `(Pair 1 2 3)`, micheline.Print(storage, ""))
+
+		assert.NoError(t, node.Transfer(CallContractArgument{Recipient: address, Source: "bootstrap1", Entrypoint: "default", Amount: mutez(t, "0"), Parameter: `{ 2 ; 3 ; 4 }`}))
+		storage, err = node.GetContractStorage("store")
+		assert.NoError(t, err)
+		assert.Equal(t, `(Pair 2 3 4)`, micheline.Print(storage, ""))
+
+		level := fake.level
+		transferErr := node.Transfer(CallContractArgument{Recipient: "store", Source: "bootstrap1", Amount: mutez(t, "0"), Parameter: "0"})
+		assert.Error(t, transferErr)
+		failwith, err := utils.ExtractFailWithError(transferErr.Error())
+		assert.NoError(t, err)
+		assert.Equal(t, `"ZERO"`, micheline.Print(failwith, ""))
+		clientErr := ClassifyError(transferErr)
+		assert.Equal(t, ScriptRejected, clientErr.Kind)
+		assert.Equal(t, address, clientErr.Contract)
+		assert.Equal(t, `"ZERO"`, micheline.Print(clientErr.With, ""))
+		assert.Equal(t, level, fake.level, "Failed simulations are not injected")
+
+		err = node.Transfer(CallContractArgument{Recipient: "bootstrap1", Source: "alice", Amount: mutez(t, "5000")})
+		clientErr = ClassifyError(err)
+		assert.Equal(t, BalanceTooLow, clientErr.Kind)
+		alice, _ := node.resolve("alice")
+		assert.Equal(t, alice, clientErr.Contract)
+		assert.Equal(t, "1000", clientErr.Balance.String())
+		assert.Equal(t, "5000", clientErr.Amount.String())
+
+		err = node.Transfer(CallContractArgument{Recipient: "bob", Source: "bootstrap1", Amount: mutez(t, "1")})
+		assert.EqualError(t, err, "unknown account (bob).")
+		err = node.Transfer(CallContractArgument{Recipient: "bootstrap1", Source: "store", Amount: mutez(t, "1")})
+		assert.EqualError(t, err, "account (store) cannot sign operations, its secret key is unknown.")
+	})
+	t.Run("Data is normalized and serialized by the node", func(t *testing.T) {
+		packed, err := node.SerializeData(`(Pair 1 "a")`, `(pair nat string)`)
+		assert.NoError(t, err)
+		assert.Equal(t, "0x0507070001010000000161", packed)
+
+		normalized, err := node.NormalizeData(`(Pair 1 2 3 4)`, `(pair nat nat nat nat)`, Optimized)
+		assert.NoError(t, err)
+		assert.Equal(t, "{ 1; 2; 3; 4 }", micheline.Print(normalized, ""))
+		_, err = node.NormalizeData(`"a"`, `nat`, Optimized)
+		assert.ErrorContains(t, err, "invalid_expression")
+	})
+	t.Run("Block levels are reached by waiting for the node", func(t *testing.T) {
+		assert.Error(t, node.UpdateHeadBlockLevel(1))
+		fake.mu.Lock()
+		fake.autoBake = true
+		target := int32(fake.level + 3)
+		fake.mu.Unlock()
+		assert.NoError(t, node.UpdateHeadBlockLevel(target))
+		assert.EqualError(t, node.UpdateChainID("NetXynUjJNZm7wi"), "the chain identifier cannot be modified with the node backend.")
+		assert.EqualError(t, node.UpdateHeadBlockTimestamp("2022-01-01T00:00:00Z"), "the block timestamp cannot be modified with the node backend.")
+	})
+}
+
+func TestClientErrorOf(t *testing.T) {
+	t.Run("Errors are classified by identifier", func(t *testing.T) {
+		for id, kind := range map[string]ClientErrorKind{
+			"proto.016-PtMumbai.gas_exhausted.operation":             GasExhausted,
+			"proto.016-PtMumbai.storage_exhausted.operation":         StorageExhausted,
+			"proto.016-PtMumbai.michelson_v1.bad_contract_parameter": IllTypedParameter,
+			"proto.016-PtMumbai.michelson_v1.ill_typed_contract":     IllTypedContract,
+			"proto.016-PtMumbai.michelson_v1.invalid_constant":       IllTypedData,
+			"proto.016-PtMumbai.contract.non_existing_contract":      UnknownContract,
+			"proto.016-PtMumbai.michelson_v1.runtime_error":          UnclassifiedError,
+		} {
+			e := clientErrorOf([]rpcError{{Kind: "temporary", ID: id}}, "operation failed.")
+			assert.Equal(t, kind, e.Kind, id)
+			assert.Equal(t, "operation failed.", e.Message, id)
+		}
+	})
+	t.Run("Fields are read from the errors", func(t *testing.T) {
+		e := clientErrorOf([]rpcError{{Kind: "branch", ID: "proto.016-PtMumbai.contract.counter_in_the_past", Contract: "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", Expected: "7", Found: "5"}}, "")
+		assert.Equal(t, CounterInThePast, e.Kind)
+		assert.Equal(t, "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx", e.Contract)
+		assert.Equal(t, "7", e.Expected)
+		assert.Equal(t, "5", e.Found)
+
+		// A rejected script is reported as such even if other errors are listed
+		e = clientErrorOf([]rpcError{
+			{Kind: "temporary", ID: "proto.016-PtMumbai.michelson_v1.runtime_error", ContractHandle: "KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1"},
+			{Kind: "temporary", ID: "proto.016-PtMumbai.michelson_v1.script_rejected", With: json.RawMessage(`{"int":"1"}`)},
+			{Kind: "temporary", ID: "proto.016-PtMumbai.gas_exhausted.operation"},
+		}, "")
+		assert.Equal(t, ScriptRejected, e.Kind)
+		assert.Equal(t, "KT1TezoooozzSmartPyzzSTATiCzzzwwBFA1", e.Contract)
+		assert.Equal(t, "script reached FAILWITH instruction\nwith 1\n", e.Message)
+	})
+}
@@ -0,0 +1,430 @@
+package business
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"blockwatch.cc/tzgo/tezos"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/ast"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/binary"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/interpreter"
+	"github.com/romarq/tezos-sc-tester/internal/config"
+	"github.com/romarq/tezos-sc-tester/internal/logger"
+)
+
+type (
+	// offlineContract is a contract originated in the offline chain
+	offlineContract struct {
+		contract michelson.Contract
+		storage  ast.Node // optimized representation
+	}
+	// OfflineMockup simulates a chain in memory, contracts are executed by the offline interpreter (no 'tezos-client' is needed)
+	OfflineMockup struct {
+		Session
+		aliases    map[string]string          // name -> address
+		balances   map[string]*big.Int        // address -> balance (in mutez)
+		originated map[string]offlineContract // address -> contract
+		delegates  map[string]string
+		level      int64 // level of the head block
+		timestamp  int64 // timestamp (in seconds) of the head block
+		chainID    string
+		nonce      int // number of originated contracts (used to derive their addresses)
+	}
+	bootstrapAccount struct {
+		Name   string `json:"name"`
+		SkURI  string `json:"sk_uri"`
+		Amount string `json:"amount"`
+	}
+	protocolConstants struct {
+		ChainID          string `json:"chain_id"`
+		InitialTimestamp string `json:"initial_timestamp"`
+	}
+)
+
+// InitOfflineMockup creates a backend that executes operations with the offline interpreter instead of 'tezos-client'
+func InitOfflineMockup(taskID string, protocol string, cfg config.Config) *OfflineMockup {
+	return &OfflineMockup{
+		Session:    InitSession(taskID, protocol, cfg),
+		aliases:    map[string]string{},
+		balances:   map[string]*big.Int{},
+		originated: map[string]offlineContract{},
+		delegates:  map[string]string{},
+	}
+}
+
+// Bootstrap creates the bootstrap accounts and loads the protocol constants used by 'tezos-client'
+func (c *OfflineMockup) Bootstrap() error {
+	baseDirectory := c.Config.Tezos.BaseDirectory
+	var accounts []bootstrapAccount
+	if err := readJSON(fmt.Sprintf("%s/bootstrap-accounts.json", baseDirectory), &accounts); err != nil {
+		return fmt.Errorf("could not bootstrap mockup. %s", err)
+	}
+	var constants protocolConstants
+	if err := readJSON(fmt.Sprintf("%s/protocol-constants.json", baseDirectory), &constants); err != nil {
+		return fmt.Errorf("could not bootstrap mockup. %s", err)
+	}
+
+	for _, account := range accounts {
+		key, err := tezos.ParsePrivateKey(strings.TrimPrefix(account.SkURI, "unencrypted:"))
+		if err != nil {
+			return fmt.Errorf("invalid secret key for bootstrap account (%s). %s", account.Name, err)
+		}
+		amount, ok := new(big.Int).SetString(account.Amount, 10)
+		if !ok {
+			return fmt.Errorf("invalid amount for bootstrap account (%s).", account.Name)
+		}
+		address := key.Address().String()
+		c.aliases[account.Name] = address
+		c.balances[address] = amount
+		c.Addresses[account.Name] = address
+	}
+
+	c.chainID = constants.ChainID
+	if constants.InitialTimestamp != "" {
+		timestamp, err := time.Parse(time.RFC3339, constants.InitialTimestamp)
+		if err != nil {
+			return fmt.Errorf("invalid initial timestamp (%s). %s", constants.InitialTimestamp, err)
+		}
+		c.timestamp = timestamp.Unix()
+	}
+
+	return nil
+}
+
+// Teardown does nothing, the chain only lives in memory
+func (c *OfflineMockup) Teardown() error {
+	return nil
+}
+
+// UpdateChainID updates the chain identifier
+func (c *OfflineMockup) UpdateChainID(chainID string) error {
+	c.chainID = chainID
+	return nil
+}
+
+// UpdateHeadBlockLevel updates the level of the head block
+func (c *OfflineMockup) UpdateHeadBlockLevel(level int32) error {
+	c.level = int64(level)
+	return nil
+}
+
+// ImportSecret registers the address of a secret key under a name
+func (c *OfflineMockup) ImportSecret(privateKey string, walletName string) error {
+	key, err := tezos.ParsePrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("invalid secret key. %s", err)
+	}
+	c.aliases[walletName] = key.Address().String()
+	return nil
+}
+
+// Transfer executes a transaction and the internal operations it emits, the chain is left untouched if any of them fails
+func (c *OfflineMockup) Transfer(arg CallContractArgument) error {
+	source, ok := c.resolve(arg.Source)
+	if !ok {
+		return fmt.Errorf("unknown account (%s).", arg.Source)
+	}
+	recipient, ok := c.resolve(arg.Recipient)
+	if !ok {
+		return fmt.Errorf("unknown account (%s).", arg.Recipient)
+	}
+	var parameter ast.Node = ast.Prim{Prim: "Unit"}
+	if arg.Parameter != "" {
+		var err error
+		if parameter, err = michelson.ParseMicheline(arg.Parameter); err != nil {
+			return fmt.Errorf("invalid parameter. %s", err)
+		}
+	}
+
+	snapshot := c.snapshot()
+	err := c.apply(source, source, interpreter.Operation{
+		Kind:        interpreter.Transaction,
+		Destination: recipient,
+		Entrypoint:  arg.Entrypoint,
+		Amount:      arg.Amount.Int(),
+		Parameter:   parameter,
+	})
+	if err != nil {
+		c.restore(snapshot)
+	}
+	return err
+}
+
+// apply executes an operation, the internal operations are executed depth-first (like the protocol does)
+func (c *OfflineMockup) apply(source string, sender string, op interpreter.Operation) error {
+	if op.Kind == interpreter.Delegation {
+		c.delegates[sender] = op.Delegate
+		return nil
+	}
+
+	if err := c.debit(sender, op.Amount); err != nil {
+		return err
+	}
+	c.credit(op.Destination, op.Amount)
+
+	contract, isContract := c.originated[op.Destination]
+	if !isContract {
+		if strings.HasPrefix(op.Destination, "KT1") {
+			return &ClientError{
+				Kind:     UnknownContract,
+				Message:  fmt.Sprintf("contract (%s) does not exist.", op.Destination),
+				Contract: op.Destination,
+			}
+		}
+		if op.Entrypoint != "" && op.Entrypoint != michelson.DEFAULT_ENTRYPOINT {
+			return fmt.Errorf("implicit account (%s) does not have entrypoint (%s).", op.Destination, op.Entrypoint)
+		}
+		return nil
+	}
+
+	result, err := interpreter.Run(contract.contract, op.Entrypoint, op.Parameter, contract.storage, interpreter.Context{
+		Self:          op.Destination,
+		Sender:        sender,
+		Source:        source,
+		Amount:        op.Amount,
+		Balance:       c.balanceOf(op.Destination),
+		Now:           c.timestamp + 1,
+		Level:         c.level + 1,
+		ChainID:       c.chainID,
+		ParameterType: c.parameterType,
+	})
+	if err != nil {
+		return fmt.Errorf("call to contract (%s) failed. %w", op.Destination, err)
+	}
+	contract.storage = result.Storage
+	c.originated[op.Destination] = contract
+
+	for _, internal := range result.Operations {
+		if err := c.apply(source, op.Destination, internal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevealWallet debits the reveal fee from an account
+func (c *OfflineMockup) RevealWallet(walletName string, revealFee Mutez) error {
+	address, ok := c.resolve(walletName)
+	if !ok {
+		return fmt.Errorf("unknown account (%s).", walletName)
+	}
+	return c.debit(address, revealFee.Int())
+}
+
+// Originate deploys a smart contract
+func (c *OfflineMockup) Originate(sender string, contractName string, amount Mutez, code string, storage string) (string, error) {
+	source, ok := c.resolve(sender)
+	if !ok {
+		return "", fmt.Errorf("unknown account (%s).", sender)
+	}
+	codeNode, err := michelson.ParseMicheline(code)
+	if err != nil {
+		return "", err
+	}
+	contract, err := michelson.ParseContract(codeNode)
+	if err != nil {
+		return "", err
+	}
+	storageNode, err := michelson.ParseMicheline(storage)
+	if err != nil {
+		return "", err
+	}
+	storageNode, err = michelson.Normalize(storageNode, contract.Storage, michelson.OptimizedLegacy)
+	if err != nil {
+		return "", fmt.Errorf("ill-typed storage. %s", err)
+	}
+
+	if err := c.debit(source, amount.Int()); err != nil {
+		return "", err
+	}
+	// Contract addresses are derived from a counter, the same suite always gets the same addresses
+	c.nonce++
+	hash := tezos.Digest([]byte(fmt.Sprintf("offline/%d", c.nonce)))
+	address := tezos.NewAddress(tezos.AddressTypeContract, hash[:20]).String()
+
+	c.credit(address, amount.Int())
+	c.originated[address] = offlineContract{contract: contract, storage: storageNode}
+	c.aliases[contractName] = address
+	return address, nil
+}
+
+// GetBalance gives the balance of an account (implicit account or originated contract)
+func (c *OfflineMockup) GetBalance(name string) (Mutez, error) {
+	address, ok := c.resolve(name)
+	if !ok {
+		return Mutez{}, fmt.Errorf("unknown account (%s).", name)
+	}
+	return MutezOfFloat(new(big.Float).SetInt(c.balanceOf(address))), nil
+}
+
+// GetFeesPaid gives the fees paid by an account, operations are free in the offline chain
+func (c *OfflineMockup) GetFeesPaid(name string) Mutez {
+	return MutezOfFloat(big.NewFloat(0))
+}
+
+// GetContractStorage gives the storage of a contract
+func (c *OfflineMockup) GetContractStorage(contractName string) (ast.Node, error) {
+	address, _ := c.resolve(contractName)
+	contract, ok := c.originated[address]
+	if !ok {
+		return nil, fmt.Errorf("could not fetch storage from contract (%s). contract does not exist.", contractName)
+	}
+	return michelson.Normalize(contract.storage, contract.contract.Storage, michelson.Readable)
+}
+
+// NormalizeData normalizes a data expression against a given type
+func (c *OfflineMockup) NormalizeData(data string, dataType string, mode ParsingMode) (ast.Node, error) {
+	value, typ, err := parseDataAndType(data, dataType)
+	if err != nil {
+		return nil, fmt.Errorf("could not normalize data %s against type %s. %s", data, dataType, err)
+	}
+	normalized, err := michelson.Normalize(value, typ, mode)
+	if err != nil {
+		return nil, fmt.Errorf("could not normalize data %s against type %s. %s", data, dataType, err)
+	}
+	return normalized, nil
+}
+
+// SerializeData serializes a michelson value
+func (c *OfflineMockup) SerializeData(data string, dataType string) (string, error) {
+	value, typ, err := parseDataAndType(data, dataType)
+	if err != nil {
+		return "", err
+	}
+	packed, err := binary.Pack(value, typ)
+	if err != nil {
+		return "", err
+	}
+	return "0x" + hex.EncodeToString(packed), nil
+}
+
+// UpdateHeadBlockTimestamp updates the timestamp of the head block
+func (c *OfflineMockup) UpdateHeadBlockTimestamp(timestamp string) error {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return fmt.Errorf("could not modify block timestamp.")
+	}
+	c.timestamp = t.Unix()
+	return nil
+}
+
+// Snapshot copies the state of the chain
+func (c *OfflineMockup) Snapshot() (StateSnapshot, error) {
+	return c.snapshot(), nil
+}
+
+// Restore restores a state given by Snapshot
+func (c *OfflineMockup) Restore(snapshot StateSnapshot) error {
+	state, ok := snapshot.(OfflineMockup)
+	if !ok {
+		return fmt.Errorf("invalid snapshot.")
+	}
+	// The snapshot can be restored more than once, it must not share its maps with the chain
+	c.restore(state.snapshot())
+	return nil
+}
+
+// Discard does nothing, snapshots only live in memory
+func (c *OfflineMockup) Discard(snapshot StateSnapshot) error {
+	return nil
+}
+
+// resolve gives the address of an account from its name (addresses are resolved to themselves)
+func (c *OfflineMockup) resolve(name string) (string, bool) {
+	if address, ok := c.aliases[name]; ok {
+		return address, true
+	}
+	if _, err := tezos.ParseAddress(name); err == nil {
+		return name, true
+	}
+	return "", false
+}
+
+func (c *OfflineMockup) parameterType(address string) (ast.Node, bool) {
+	contract, ok := c.originated[address]
+	return contract.contract.Parameter, ok
+}
+
+func (c *OfflineMockup) balanceOf(address string) *big.Int {
+	if balance, ok := c.balances[address]; ok {
+		return new(big.Int).Set(balance)
+	}
+	return new(big.Int)
+}
+
+func (c *OfflineMockup) debit(address string, amount *big.Int) error {
+	balance := c.balanceOf(address)
+	if balance.Cmp(amount) < 0 {
+		// Classified like the failures of 'tezos-client', so that both backends report the same kind of error
+		balanceMutez := MutezOfFloat(new(big.Float).SetInt(balance))
+		amountMutez := MutezOfFloat(new(big.Float).SetInt(amount))
+		return &ClientError{
+			Kind:     BalanceTooLow,
+			Message:  fmt.Sprintf("balance of (%s) is too low (%s) to spend (%s).", address, balance, amount),
+			Contract: address,
+			Balance:  &balanceMutez,
+			Amount:   &amountMutez,
+		}
+	}
+	c.balances[address] = balance.Sub(balance, amount)
+	return nil
+}
+
+func (c *OfflineMockup) credit(address string, amount *big.Int) {
+	balance := c.balanceOf(address)
+	c.balances[address] = balance.Add(balance, amount)
+}
+
+// snapshot copies the state that operations can modify
+func (c *OfflineMockup) snapshot() OfflineMockup {
+	snapshot := *c
+	snapshot.balances = make(map[string]*big.Int, len(c.balances))
+	for address, balance := range c.balances {
+		snapshot.balances[address] = balance
+	}
+	snapshot.originated = make(map[string]offlineContract, len(c.originated))
+	for address, contract := range c.originated {
+		snapshot.originated[address] = contract
+	}
+	snapshot.delegates = make(map[string]string, len(c.delegates))
+	for address, delegate := range c.delegates {
+		snapshot.delegates[address] = delegate
+	}
+	return snapshot
+}
+
+func (c *OfflineMockup) restore(snapshot OfflineMockup) {
+	c.balances = snapshot.balances
+	c.originated = snapshot.originated
+	c.delegates = snapshot.delegates
+}
+
+func parseDataAndType(data string, dataType string) (ast.Node, ast.Node, error) {
+	value, err := michelson.ParseMicheline(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	typ, err := michelson.ParseMicheline(dataType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, typ, nil
+}
+
+func readJSON(path string, v interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		logger.Debug("could not open %s: %s", path, err)
+		return fmt.Errorf("could not read (%s).", path)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("could not parse (%s). %s", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,501 @@
+package business
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson"
+	"github.com/romarq/tezos-sc-tester/internal/business/michelson/micheline"
+	"github.com/romarq/tezos-sc-tester/internal/config"
+	"github.com/romarq/tezos-sc-tester/internal/logger"
+	"github.com/romarq/tezos-sc-tester/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+var testConfig = config.Config{
+	Log: config.LogConfig{
+		Location: "../../.tmp_test/api.log",
+		Level:    "debug",
+	},
+	Tezos: config.TezosConfig{
+		TezosClient:     "../../tezos-bin/amd64/tezos-client",
+		DefaultProtocol: "ProtoALphaALphaALphaALphaALphaALphaALphaALphaDdp3zK",
+		BaseDirectory:   "../../tezos-bin",
+		RevealFee:       1000,
+		Originator:      "bootstrap2",
+	},
+}
+
+// A contract that counts its calls and forwards the transferred amount to the sender
+const counterContract = `
+	parameter (or (nat %add) (unit %forward)) ;
+	storage (pair (nat %counter) (address %last_sender)) ;
+	code {
+		UNPAIR ;
+		IF_LEFT
+			{
+				DUP ; PUSH nat 10 ; COMPARE ; LT ; IF { PUSH string "TOO_LARGE" ; FAILWITH } {} ;
+				SWAP ; CAR ; ADD ; SENDER ; SWAP ; PAIR ;
+				NIL operation ; PAIR
+			}
+			{
+				DROP ;
+				NIL operation ; SENDER ; CONTRACT unit ; IF_NONE { PUSH string "NOT_IMPLICIT" ; FAILWITH } {} ; AMOUNT ; UNIT ; TRANSFER_TOKENS ; CONS ;
+				PAIR
+			}
+	}
+`
+
+func mutez(t *testing.T, value string) Mutez {
+	m, err := MutezOfString(value)
+	assert.NoError(t, err)
+	return m
+}
+
+func getBalance(t *testing.T, mockup Backend, name string) Mutez {
+	balance, err := mockup.GetBalance(name)
+	assert.NoError(t, err)
+	return balance
+}
+
+// scenario runs operations that both backends must execute the same way
+func scenario(t *testing.T, mockup Backend) {
+	bootstrap1 := mockup.GetAddresses()["bootstrap1"]
+	address, err := mockup.Originate("bootstrap2", "counter", mutez(t, "1000"), `{`+counterContract+`}`, `(Pair 0 "`+bootstrap1+`")`)
+	assert.NoError(t, err)
+	mockup.CacheAccountAddress("counter", address)
+
+	err = mockup.Transfer(CallContractArgument{Recipient: "counter", Source: "bootstrap1", Entrypoint: "add", Amount: mutez(t, "0"), Parameter: "3"})
+	assert.NoError(t, err)
+	err = mockup.Transfer(CallContractArgument{Recipient: "counter", Source: "bootstrap3", Entrypoint: "add", Amount: mutez(t, "0"), Parameter: "11"})
+	assert.Error(t, err)
+	failwith, err := utils.ExtractFailWithError(err.Error())
+	assert.NoError(t, err)
+	assert.Equal(t, `"TOO_LARGE"`, micheline.Print(failwith, ""))
+
+	storage, err := mockup.GetContractStorage("counter")
+	assert.NoError(t, err)
+	assert.Equal(t, `(Pair 3 "`+bootstrap1+`")`, micheline.Print(storage, ""))
+
+	balance := getBalance(t, mockup, "bootstrap4")
+	err = mockup.Transfer(CallContractArgument{Recipient: "counter", Source: "bootstrap4", Entrypoint: "forward", Amount: mutez(t, "500"), Parameter: "Unit"})
+	assert.NoError(t, err)
+	// The amount goes back to the sender, only fees are paid
+	assert.Equal(t, "1000", getBalance(t, mockup, "counter").Int().String())
+	assert.Equal(t, new(big.Int).Sub(balance.Int(), mockup.GetFeesPaid("bootstrap4").Int()).String(), getBalance(t, mockup, "bootstrap4").Int().String())
+
+	packed, err := mockup.SerializeData(`(Pair 1 "a")`, `(pair nat string)`)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x0507070001010000000161", packed)
+
+	normalized, err := mockup.NormalizeData(`(Pair 1 2 3 4)`, `(pair nat nat nat nat)`, Optimized)
+	assert.NoError(t, err)
+	assert.Equal(t, "{ 1; 2; 3; 4 }", micheline.Print(normalized, ""))
+}
+
+func TestOfflineMockup(t *testing.T) {
+	logger.SetupLogger(testConfig.Log.Location, testConfig.Log.Level)
+
+	mockup := InitOfflineMockup("task", "", testConfig)
+	assert.NoError(t, mockup.Bootstrap())
+	defer mockup.Teardown()
+
+	t.Run("Bootstrap accounts are derived from their secret keys", func(t *testing.T) {
+		assert.Equal(t, mockup.Addresses, map[string]string{
+			"bootstrap1": "tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx",
+			"bootstrap2": "tz1gjaF81ZRRvdzjobyfVNsAeSC6PScjfQwN",
+			"bootstrap3": "tz1faswCTDciRzE4oJ9jn2Vm2dvjeyA9fUzU",
+			"bootstrap4": "tz1b7tUupMgCNw2cCLpKTkSD1NZzB5TkP2sv",
+			"bootstrap5": "tz1ddb9NMYHZi5UzPdzTZMYQQZoMub195zgv",
+		})
+		assert.Equal(t, "100000000000000", getBalance(t, mockup, "bootstrap1").Int().String())
+	})
+	t.Run("Contracts are executed by the interpreter", func(t *testing.T) {
+		scenario(t, mockup)
+	})
+	t.Run("Failed operations do not modify the chain", func(t *testing.T) {
+		balance := getBalance(t, mockup, "bootstrap1").Int().String()
+		err := mockup.Transfer(CallContractArgument{Recipient: "counter", Source: "bootstrap1", Entrypoint: "add", Amount: mutez(t, "10"), Parameter: "20"})
+		assert.Error(t, err)
+		assert.Equal(t, balance, getBalance(t, mockup, "bootstrap1").Int().String())
+		assert.Equal(t, "1000", getBalance(t, mockup, "counter").Int().String())
+
+		err = mockup.Transfer(CallContractArgument{Recipient: "bootstrap1", Source: "counter", Amount: mutez(t, "1001")})
+		assert.EqualError(t, err, "balance of ("+mockup.Addresses["counter"]+") is too low (1000) to spend (1001).")
+		assert.Equal(t, BalanceTooLow, ClassifyError(err).Kind)
+		err = mockup.Transfer(CallContractArgument{Recipient: "unknown", Source: "bootstrap1", Amount: mutez(t, "1")})
+		assert.EqualError(t, err, "unknown account (unknown).")
+	})
+	t.Run("Implicit accounts and chain context", func(t *testing.T) {
+		key, err := utils.GenerateKey()
+		assert.NoError(t, err)
+		assert.NoError(t, mockup.ImportSecret(key.String(), "alice"))
+		assert.NoError(t, mockup.Transfer(CallContractArgument{Recipient: "alice", Source: "bootstrap1", Amount: mutez(t, "2000")}))
+		assert.NoError(t, mockup.RevealWallet("alice", mutez(t, "1000")))
+		assert.Equal(t, "1000", getBalance(t, mockup, "alice").Int().String())
+
+		assert.NoError(t, mockup.UpdateHeadBlockLevel(99))
+		assert.NoError(t, mockup.UpdateHeadBlockTimestamp("2022-01-01T00:00:00Z"))
+		assert.NoError(t, mockup.UpdateChainID("NetXdQprcVkpaWU"))
+		code := `{ parameter unit ; storage (pair nat timestamp chain_id) ; code { DROP ; CHAIN_ID ; NOW ; LEVEL ; PAIR 3 ; NIL operation ; PAIR } }`
+		_, err = mockup.Originate("alice", "context", mutez(t, "0"), code, `(Pair 0 0 "NetXynUjJNZm7wi")`)
+		assert.NoError(t, err)
+		assert.NoError(t, mockup.Transfer(CallContractArgument{Recipient: "context", Source: "alice", Amount: mutez(t, "0")}))
+		storage, err := mockup.GetContractStorage("context")
+		assert.NoError(t, err)
+		assert.Equal(t, `(Pair 100 "2022-01-01T00:00:01Z" "NetXdQprcVkpaWU")`, micheline.Print(storage, ""))
+	})
+}
+
+// differentialCase is a contract and the calls made to it, every backend must give the same observations
+type differentialCase struct {
+	Name       string
+	Originator string
+	Balance    string
+	Code       string
+	Storage    string
+	Calls      []differentialCall
+}
+
+type differentialCall struct {
+	Source     string
+	Entrypoint string
+	Amount     string
+	Parameter  string
+}
+
+// observation is the state after an origination or a call, balances are compared without the fees
+// (fees and storage burns depend on the backend)
+type observation struct {
+	Storage  string
+	Error    ClientErrorKind
+	FailWith string
+	Balance  string // Balance of the contract
+	Spent    string // Amount spent by the source of the operation
+}
+
+// differentialObservations gives the observations of each case and the packed bytes of values ("<value> : <type>")
+type differentialObservations struct {
+	Operations map[string][]observation
+	Packed     map[string]string
+}
+
+var differentialCases = []differentialCase{
+	{
+		Name:       "counter",
+		Originator: "bootstrap2",
+		Balance:    "1000",
+		Code:       `{` + counterContract + `}`,
+		Storage:    `(Pair 0 "TEST__ADDRESS_OF_ACCOUNT__bootstrap1")`,
+		Calls: []differentialCall{
+			{Source: "bootstrap1", Entrypoint: "add", Amount: "0", Parameter: "3"},
+			{Source: "bootstrap3", Entrypoint: "add", Amount: "0", Parameter: "11"},
+			{Source: "bootstrap4", Entrypoint: "forward", Amount: "500", Parameter: "Unit"},
+		},
+	},
+	{
+		// Maps, sets, lists and iterations
+		Name:       "collections",
+		Originator: "bootstrap2",
+		Balance:    "0",
+		Code: `{
+			parameter (or (or (pair %put string nat) (string %remove)) (or (int %toggle) (list %append nat))) ;
+			storage (pair (map string nat) (set int) (list nat)) ;
+			code {
+				UNPAIR ; DIP { UNPAIR 3 } ;
+				IF_LEFT
+					{ IF_LEFT { UNPAIR ; DIP { SOME } ; UPDATE } { DIP { NONE nat } ; UPDATE } }
+					{ IF_LEFT { DIG 2 ; DUP ; DUP 3 ; MEM ; NOT ; DIG 2 ; UPDATE ; SWAP } { ITER { DIG 3 ; SWAP ; CONS ; DUG 2 } } } ;
+				PAIR 3 ; NIL operation ; PAIR
+			}
+		}`,
+		Storage: `(Pair {} {} {})`,
+		Calls: []differentialCall{
+			{Source: "bootstrap1", Entrypoint: "put", Amount: "0", Parameter: `(Pair "b" 2)`},
+			{Source: "bootstrap1", Entrypoint: "put", Amount: "0", Parameter: `(Pair "a" 1)`},
+			{Source: "bootstrap1", Entrypoint: "toggle", Amount: "0", Parameter: "5"},
+			{Source: "bootstrap1", Entrypoint: "toggle", Amount: "0", Parameter: "-3"},
+			{Source: "bootstrap1", Entrypoint: "toggle", Amount: "0", Parameter: "5"},
+			{Source: "bootstrap1", Entrypoint: "append", Amount: "0", Parameter: "{ 1 ; 2 ; 3 }"},
+			{Source: "bootstrap1", Entrypoint: "remove", Amount: "0", Parameter: `"a"`},
+			{Source: "bootstrap1", Entrypoint: "remove", Amount: "0", Parameter: `"zz"`},
+		},
+	},
+	{
+		// Integer arithmetic, the quotient and remainder of EDIV follow the sign rules of Michelson
+		Name:       "arithmetic",
+		Originator: "bootstrap2",
+		Balance:    "0",
+		Code: `{
+			parameter (pair int int) ;
+			storage (pair bool int nat int (option (pair int nat))) ;
+			code {
+				CAR ; UNPAIR ;
+				DUP 2 ; DUP 2 ; EDIV ;
+				DUP 3 ; DUP 3 ; MUL ;
+				DUP 3 ; ABS ;
+				DUP 5 ; NEG ; DUP 5 ; SUB ;
+				DUP 6 ; DUP 6 ; COMPARE ; LT ;
+				DIP 5 { DROP 2 } ;
+				PAIR 5 ; NIL operation ; PAIR
+			}
+		}`,
+		Storage: `(Pair False 0 0 0 None)`,
+		Calls: []differentialCall{
+			{Source: "bootstrap1", Amount: "0", Parameter: "(Pair 7 -2)"},
+			{Source: "bootstrap1", Amount: "0", Parameter: "(Pair -7 2)"},
+			{Source: "bootstrap1", Amount: "0", Parameter: "(Pair 3 0)"},
+		},
+	},
+	{
+		// Bitwise operations on naturals, shifts larger than 256 bits overflow
+		Name:       "bits",
+		Originator: "bootstrap2",
+		Balance:    "0",
+		Code: `{
+			parameter (pair nat nat) ;
+			storage (pair (option nat) nat nat nat nat nat) ;
+			code {
+				CAR ; UNPAIR ;
+				DUP 2 ; DUP 2 ; LSL ;
+				DUP 3 ; DUP 3 ; LSR ;
+				DUP 4 ; DUP 4 ; AND ;
+				DUP 5 ; DUP 5 ; OR ;
+				DUP 6 ; DUP 6 ; XOR ;
+				DUP 7 ; DUP 7 ; SUB ; ISNAT ;
+				DIP 6 { DROP 2 } ;
+				PAIR 6 ; NIL operation ; PAIR
+			}
+		}`,
+		Storage: `(Pair None 0 0 0 0 0)`,
+		Calls: []differentialCall{
+			{Source: "bootstrap1", Amount: "0", Parameter: "(Pair 12 3)"},
+			{Source: "bootstrap1", Amount: "0", Parameter: "(Pair 5 6)"},
+			{Source: "bootstrap1", Amount: "0", Parameter: "(Pair 1 257)"},
+		},
+	},
+	{
+		// Strings, bytes, serialization and hashing
+		Name:       "strings",
+		Originator: "bootstrap2",
+		Balance:    "0",
+		Code: `{
+			parameter (pair string bytes) ;
+			storage (pair (option (pair string bytes)) bytes bytes (option string) nat string) ;
+			code {
+				CAR ; UNPAIR ;
+				DUP ; PUSH string "tezos-" ; CONCAT ;
+				DUP ; SIZE ;
+				DUP 2 ; PUSH nat 3 ; PUSH nat 2 ; SLICE ;
+				DUP 5 ; DUP 5 ; PAIR ; PACK ;
+				DUP ; BLAKE2B ;
+				DUP 2 ; UNPACK (pair string bytes) ;
+				DIP 6 { DROP 2 } ;
+				PAIR 6 ; NIL operation ; PAIR
+			}
+		}`,
+		Storage: `(Pair None 0x 0x None 0 "")`,
+		Calls: []differentialCall{
+			{Source: "bootstrap1", Amount: "0", Parameter: `(Pair "abc" 0x00ff)`},
+			{Source: "bootstrap1", Amount: "0", Parameter: `(Pair "" 0x)`},
+		},
+	},
+	{
+		// Lambdas, partial application and loops
+		Name:       "lambdas",
+		Originator: "bootstrap2",
+		Balance:    "0",
+		Code: `{
+			parameter nat ;
+			storage (pair nat (list nat) int) ;
+			code {
+				CAR ;
+				LAMBDA (pair nat nat) nat { UNPAIR ; MUL } ;
+				DUP 2 ; APPLY ;
+				PUSH nat 1 ; DUP 3 ;
+				DUP ; PUSH nat 0 ; COMPARE ; LT ;
+				LOOP { DUP ; DIP { MUL } ; PUSH nat 1 ; SWAP ; SUB ; ABS ; DUP ; PUSH nat 0 ; COMPARE ; LT } ;
+				DROP ;
+				PUSH (list nat) { 1 ; 2 ; 3 } ; MAP { DUP 3 ; SWAP ; EXEC } ;
+				DUP 4 ; INT ; LEFT int ;
+				LOOP_LEFT { DUP ; GT ; IF { PUSH int -2 ; ADD ; LEFT int } { RIGHT int } } ;
+				DIG 3 ; DROP ; DIG 3 ; DROP ;
+				DIG 2 ; DIP { SWAP } ;
+				PAIR 3 ; NIL operation ; PAIR
+			}
+		}`,
+		Storage: `(Pair 0 {} 0)`,
+		Calls: []differentialCall{
+			{Source: "bootstrap1", Amount: "0", Parameter: "4"},
+			{Source: "bootstrap1", Amount: "0", Parameter: "5"},
+			{Source: "bootstrap1", Amount: "0", Parameter: "0"},
+		},
+	},
+	{
+		// Balances and transfers to implicit accounts, the internal transfer fails when the contract cannot pay
+		Name:       "wallet",
+		Originator: "bootstrap2",
+		Balance:    "100",
+		Code: `{
+			parameter (or (mutez %send) (unit %deposit)) ;
+			storage (pair (mutez %amount) (mutez %balance)) ;
+			code {
+				UNPAIR ;
+				IF_LEFT
+					{
+						DIP { DROP } ;
+						SENDER ; CONTRACT unit ; IF_NONE { PUSH string "NOT_IMPLICIT" ; FAILWITH } {} ;
+						SWAP ; UNIT ; TRANSFER_TOKENS ; NIL operation ; SWAP ; CONS
+					}
+					{ DROP 2 ; NIL operation } ;
+				BALANCE ; AMOUNT ; PAIR ; SWAP ; PAIR
+			}
+		}`,
+		Storage: `(Pair 0 0)`,
+		Calls: []differentialCall{
+			{Source: "bootstrap1", Entrypoint: "deposit", Amount: "50", Parameter: "Unit"},
+			{Source: "bootstrap3", Entrypoint: "send", Amount: "0", Parameter: "30"},
+			{Source: "bootstrap3", Entrypoint: "send", Amount: "0", Parameter: "500"},
+			{Source: "bootstrap4", Entrypoint: "send", Amount: "5", Parameter: "10"},
+		},
+	},
+}
+
+// Values packed by every backend
+var differentialPackedValues = [][2]string{
+	{`(Pair 1 "a")`, `(pair nat string)`},
+	{`{ Elt "a" 1 ; Elt "b" 2 }`, `(map string nat)`},
+	{`(Left (Some -5))`, `(or (option int) unit)`},
+	{`{ -1 ; 0 ; 70000 }`, `(set int)`},
+	{`"tz1KqTpEZ7Yob7QbPE4Hy4Wo8fHG8LhKxZSx"`, `address`},
+	{`"2019-09-26T10:59:51Z"`, `timestamp`},
+	{`{ DUP ; ADD }`, `(lambda int int)`},
+	{`(Pair True 0x00ff Unit)`, `(pair bool bytes unit)`},
+}
+
+// observe runs the differential cases on a backend
+func observe(t *testing.T, mockup Backend) differentialObservations {
+	assert.NoError(t, mockup.Bootstrap())
+	defer mockup.Teardown()
+
+	// Fees depend on the backend, they are added back to the balances
+	funds := func(name string) *big.Int {
+		return new(big.Int).Add(getBalance(t, mockup, name).Int(), mockup.GetFeesPaid(name).Int())
+	}
+	observations := differentialObservations{
+		Operations: map[string][]observation{},
+		Packed:     map[string]string{},
+	}
+	for _, c := range differentialCases {
+		code, err := michelson.ParseMicheline(c.Code)
+		assert.NoError(t, err, c.Name)
+		contract, err := michelson.ParseContract(code)
+		assert.NoError(t, err, c.Name)
+
+		record := func(source string, before *big.Int, err error) {
+			o := observation{
+				Balance: getBalance(t, mockup, c.Name).Int().String(),
+				Spent:   new(big.Int).Sub(before, funds(source)).String(),
+			}
+			if err != nil {
+				clientErr := ClassifyError(err)
+				o.Error = clientErr.Kind
+				if clientErr.With != nil {
+					o.FailWith = micheline.Print(clientErr.With, "")
+				}
+			}
+			storage, err := mockup.GetContractStorage(c.Name)
+			if assert.NoError(t, err, c.Name) {
+				if normalized, err := michelson.Normalize(storage, contract.Storage, Readable); assert.NoError(t, err, c.Name) {
+					o.Storage = micheline.Print(normalized, "")
+				}
+			}
+			observations.Operations[c.Name] = append(observations.Operations[c.Name], o)
+		}
+
+		before := funds(c.Originator)
+		storage := ExpandAccountPlaceholders(mockup.GetAddresses(), []byte(c.Storage))
+		address, err := mockup.Originate(c.Originator, c.Name, mutez(t, c.Balance), c.Code, string(storage))
+		if !assert.NoError(t, err, c.Name) {
+			continue
+		}
+		mockup.CacheAccountAddress(c.Name, address)
+		record(c.Originator, before, nil)
+
+		for _, call := range c.Calls {
+			before := funds(call.Source)
+			err := mockup.Transfer(CallContractArgument{
+				Recipient:  c.Name,
+				Source:     call.Source,
+				Entrypoint: call.Entrypoint,
+				Amount:     mutez(t, call.Amount),
+				Parameter:  call.Parameter,
+			})
+			record(call.Source, before, err)
+		}
+	}
+
+	for _, value := range differentialPackedValues {
+		packed, err := mockup.SerializeData(value[0], value[1])
+		if assert.NoError(t, err, value[0]) {
+			observations.Packed[value[0]+" : "+value[1]] = packed
+		}
+	}
+
+	return observations
+}
+
+// The observations of 'tezos-client' are recorded, so that the interpreter can be compared with them when 'tezos-client' is not available
+const recordedObservations = "backends_agree.json"
+
+// TestBackendsAgree runs the same operations with 'tezos-client' and with the offline interpreter
+func TestBackendsAgree(t *testing.T) {
+	if _, err := os.Stat(testConfig.Tezos.TezosClient); err != nil {
+		t.Skip("'tezos-client' is not available.")
+	}
+	logger.SetupLogger(testConfig.Log.Location, testConfig.Log.Level)
+
+	expected := observe(t, InitMockup("task_differential", "", testConfig))
+	observed := observe(t, InitOfflineMockup("task_differential", "", testConfig))
+	assertAgree(t, expected, observed)
+
+	// The recorded observations are only updated by successful runs
+	if t.Failed() {
+		return
+	}
+	snapshotBytes, err := json.MarshalIndent(expected, "", "  ")
+	assert.NoError(t, err)
+	assert.NoError(t, saveSnapshot(recordedObservations, snapshotBytes))
+}
+
+// TestInterpreterMatchesRecordedObservations compares the offline interpreter with the recorded observations of 'tezos-client'
+func TestInterpreterMatchesRecordedObservations(t *testing.T) {
+	snapshotBytes, err := os.ReadFile(path.Join("__test_data__/snapshots", recordedObservations))
+	if os.IsNotExist(err) {
+		t.Skip("the observations of 'tezos-client' are not recorded (they are recorded by TestBackendsAgree).")
+	}
+	assert.NoError(t, err)
+	logger.SetupLogger(testConfig.Log.Location, testConfig.Log.Level)
+
+	var expected differentialObservations
+	assert.NoError(t, json.Unmarshal(snapshotBytes, &expected))
+	assertAgree(t, expected, observe(t, InitOfflineMockup("task_differential", "", testConfig)))
+}
+
+func assertAgree(t *testing.T, expected differentialObservations, observed differentialObservations) {
+	assert.Equal(t, expected.Packed, observed.Packed)
+	for _, c := range differentialCases {
+		assert.Equal(t, expected.Operations[c.Name], observed.Operations[c.Name], c.Name)
+	}
+}
+
+// Utility for saving test snapshots
+func saveSnapshot(fileName string, bytes []byte) error {
+	if err := os.MkdirAll("__test_data__/snapshots", 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join("__test_data__/snapshots", fileName), bytes, 0644)
+}
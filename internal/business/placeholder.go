@@ -2,13 +2,18 @@ package business
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"regexp"
 )
 
+// Prefix shared by all placeholders
+const PLACEHOLDER_PREFIX = "TEST__"
+
 var (
 	PLACEHOLDER__ADDRESS_OF_ACCOUNT = "TEST__ADDRESS_OF_ACCOUNT__"
 	PLACEHOLDER__BALANCE_OF_ACCOUNT = "TEST__BALANCE_OF_ACCOUNT__"
+	PLACEHOLDER__VARIABLE           = "TEST__VARIABLE__"
 )
 
 // ExpandAccountPlaceholders expands the real account address from a placeholder that identifies the account
@@ -26,15 +31,52 @@ func ExpandAccountPlaceholders(addresses map[string]string, b []byte) []byte {
 }
 
 // ExpandBalancePlaceholders expands the account balance from a placeholder that identifies the account
-func ExpandBalancePlaceholders(mockup Mockup, b []byte) []byte {
+func ExpandBalancePlaceholders(mockup Backend, b []byte) ([]byte, error) {
 	regex := regexp.MustCompile(fmt.Sprintf("%s([a-zA-Z0-9_]+)", PLACEHOLDER__BALANCE_OF_ACCOUNT))
 
 	placeholders := regex.FindAll(b, -1)
 	for _, placeholder := range placeholders {
 		accountID := bytes.Replace(placeholder, []byte(PLACEHOLDER__BALANCE_OF_ACCOUNT), []byte{}, 1)
-		balance := mockup.GetBalance(string(accountID))
+		balance, err := mockup.GetBalance(string(accountID))
+		if err != nil {
+			return nil, err
+		}
 		b = bytes.ReplaceAll(b, placeholder, []byte(balance.String()))
 	}
 
-	return b
+	return b, nil
+}
+
+// ExpandVariablePlaceholders expands the value of a variable from a placeholder that identifies the variable.
+// A placeholder that fills an entire JSON string is replaced by the raw JSON value of the variable,
+// otherwise the placeholder is replaced inline.
+// The variable name can be delimited with braces (e.g. TEST__VARIABLE__{name}_suffix), since an undelimited name
+// extends to the last identifier character that follows the prefix.
+func ExpandVariablePlaceholders(variables map[string]json.RawMessage, b []byte) []byte {
+	regex := regexp.MustCompile(fmt.Sprintf(`("?)%s(?:\{([a-zA-Z0-9_]+)\}|([a-zA-Z0-9_]+))("?)`, PLACEHOLDER__VARIABLE))
+
+	return regex.ReplaceAllFunc(b, func(placeholder []byte) []byte {
+		match := regex.FindSubmatch(placeholder)
+		openQuote, variableName, closeQuote := match[1], string(match[2])+string(match[3]), match[4]
+
+		value, ok := variables[variableName]
+		if !ok {
+			return placeholder
+		}
+
+		var str string
+		if err := json.Unmarshal(value, &str); err != nil {
+			if len(openQuote) > 0 && len(closeQuote) > 0 {
+				// The placeholder is the entire string, replace it with the raw JSON value
+				return bytes.TrimSpace(value)
+			}
+			str = string(bytes.TrimSpace(value))
+		}
+
+		// Escape the value, since it will be placed inside a JSON string
+		escaped, _ := json.Marshal(str)
+		escaped = escaped[1 : len(escaped)-1]
+
+		return bytes.Join([][]byte{openQuote, escaped, closeQuote}, []byte{})
+	})
 }
@@ -1,6 +1,7 @@
 package business
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,4 +16,25 @@ func TestExpandPlaceholders(t *testing.T) {
 		bytes := []byte("TEST__ADDRESS_OF_ACCOUNT__a1----TEST__ADDRESS_OF_ACCOUNT__a2")
 		assert.Equal(t, string(ExpandAccountPlaceholders(addresses, bytes)), "tz1----tz2")
 	})
+	t.Run("Expand Variable Placeholders", func(t *testing.T) {
+		variables := map[string]json.RawMessage{
+			"amount":    json.RawMessage(`"10"`),
+			"parameter": json.RawMessage(`{ "prim": "Unit" }`),
+			"level":     json.RawMessage(`5`),
+		}
+		bytes := []byte(`{"int": "TEST__VARIABLE__amount", "parameter": "TEST__VARIABLE__parameter", "s": "L_TEST__VARIABLE__level", "u": "TEST__VARIABLE__unknown"}`)
+		assert.Equal(
+			t,
+			`{"int": "10", "parameter": { "prim": "Unit" }, "s": "L_5", "u": "TEST__VARIABLE__unknown"}`,
+			string(ExpandVariablePlaceholders(variables, bytes)),
+		)
+
+		// Names followed by identifier characters must be delimited
+		bytes = []byte(`{"s": "TEST__VARIABLE__{level}_1", "t": "TEST__VARIABLE__level_1", "int": "TEST__VARIABLE__{amount}", "u": "TEST__VARIABLE__{unknown}"}`)
+		assert.Equal(
+			t,
+			`{"s": "5_1", "t": "TEST__VARIABLE__level_1", "int": "10", "u": "TEST__VARIABLE__{unknown}"}`,
+			string(ExpandVariablePlaceholders(variables, bytes)),
+		)
+	})
 }
@@ -53,6 +53,11 @@ func AddMutez(m1 Mutez, m2 Mutez) Mutez {
 	return MutezOfFloat(new(big.Float).Add(m1.v, m2.v))
 }
 
+// SubMutez subtracts two Mutez values and returns a the result
+func SubMutez(m1 Mutez, m2 Mutez) Mutez {
+	return MutezOfFloat(new(big.Float).Sub(m1.v, m2.v))
+}
+
 // ToTez convert mutez (uꜩ) to tez (ꜩ)
 // 1 uꜩ => 0.000001 ꜩ
 func (m Mutez) ToTez() Tez {
@@ -64,6 +69,16 @@ func (m Mutez) String() string {
 	return m.v.String()
 }
 
+// Int rounds the value to the nearest integer
+func (m Mutez) Int() *big.Int {
+	half := big.NewFloat(0.5)
+	if m.v.Sign() < 0 {
+		half.Neg(half)
+	}
+	i, _ := new(big.Float).Add(m.v, half).Int(nil)
+	return i
+}
+
 // ToMutez convert tez (ꜩ) to mutez (uꜩ)
 // 1 ꜩ => 1000000 uꜩ
 func (t Tez) ToMutez() Mutez {
@@ -0,0 +1,26 @@
+package business
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMutez(t *testing.T) {
+	t.Run("Subtract mutez", func(t *testing.T) {
+		m1, err := MutezOfString("10")
+		assert.NoError(t, err)
+		m2, err := MutezOfString("25")
+		assert.NoError(t, err)
+		assert.Equal(t, "-15", SubMutez(m1, m2).String())
+	})
+	t.Run("Round mutez to integer", func(t *testing.T) {
+		tez, err := TezOfString("0.000476")
+		assert.NoError(t, err)
+		assert.Equal(t, "476", tez.ToMutez().Int().String())
+
+		tez, err = TezOfString("-3999.99")
+		assert.NoError(t, err)
+		assert.Equal(t, "-3999990000", tez.ToMutez().Int().String())
+	})
+}
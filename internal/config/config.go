@@ -25,11 +25,19 @@ type LogConfig struct {
 
 // TezosConfig holds tezos configurations
 type TezosConfig struct {
-	TezosClient     string  `yaml:"tezos_client"`
-	BaseDirectory   string  `yaml:"dir"`
-	DefaultProtocol string  `yaml:"default_protocol"`
-	RevealFee       float64 `yaml:"reveal_fee"`
-	Originator      string  `yaml:"originator"`
+	TezosClient     string     `yaml:"tezos_client"`
+	BaseDirectory   string     `yaml:"dir"`
+	DefaultProtocol string     `yaml:"default_protocol"`
+	RevealFee       float64    `yaml:"reveal_fee"`
+	Originator      string     `yaml:"originator"`
+	Node            NodeConfig `yaml:"node,omitempty"`
+}
+
+// NodeConfig holds the configuration of the node used by the "node" backend (e.g. a sandboxed node)
+type NodeConfig struct {
+	RPC          string `yaml:"rpc"`           // e.g. http://localhost:8732
+	BlockTimeout int    `yaml:"block_timeout"` // seconds to wait for an operation to be included
+	PollInterval int    `yaml:"poll_interval"` // milliseconds between two head requests
 }
 
 // EnvironmentProperty - Known environment properties
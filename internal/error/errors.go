@@ -29,3 +29,13 @@ func DetailedHttpError(status int, msg string, details interface{}) *echo.HTTPEr
 	}
 	return echo.NewHTTPError(status, e)
 }
+
+// Message extracts the message of an error (including HTTP errors built by this package)
+func Message(err error) string {
+	if httpErr, ok := err.(*echo.HTTPError); ok {
+		if e, ok := httpErr.Message.(Error); ok {
+			return e.Message
+		}
+	}
+	return err.Error()
+}
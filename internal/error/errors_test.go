@@ -1,6 +1,7 @@
 package error
 
 import (
+	"errors"
 	"net/http"
 	"testing"
 
@@ -27,3 +28,15 @@ func TestHTTPError(t *testing.T) {
 			)
 		})
 }
+
+func TestMessage(t *testing.T) {
+	t.Run("Extract message from HTTP Error",
+		func(t *testing.T) {
+			err := DetailedHttpError(http.StatusBadRequest, "Some Error", [...]interface{}{})
+			assert.Equal(t, "Some Error", Message(err))
+		})
+	t.Run("Extract message from plain error",
+		func(t *testing.T) {
+			assert.Equal(t, "Some Error", Message(errors.New("Some Error")))
+		})
+}
@@ -9,6 +9,11 @@ export enum ActionKind {
     ModifyBlockLevel = 'modify_block_level',
     ModifyBlockTimestamp = 'modify_block_timestamp',
     PackData = 'pack_data',
+    ForEach = 'for_each',
+    FuzzEntrypoint = 'fuzz_entrypoint',
+    AssertBalanceChanges = 'assert_balance_changes',
+    GetEntrypoints = 'get_entrypoints',
+    LintContract = 'lint_contract',
 }
 
 // Action result status
@@ -17,6 +22,16 @@ export enum ActionResultStatus {
     Failure = 'failure',
 }
 
+// Format of the Michelson fields in a payload (detected automatically when omitted, strings are parsed as Micheline)
+export enum MichelsonFormat {
+    JSON = 'json',
+    Michelson = 'michelson',
+    Annotated = 'annotated',
+}
+
+// Value in annotated format (keyed by the field annotations of its type)
+export type AnnotatedValue = string | number | boolean | null | AnnotatedValue[] | { [key: string]: AnnotatedValue };
+
 export type IAction =
     | ICreateImplicitAccountAction
     | IOriginateContractAction
@@ -26,12 +41,18 @@ export type IAction =
     | IModifyChainIDAction
     | IModifyBlockLevelAction
     | IModifyBlockTimestampAction
-    | IPackDataAction;
+    | IPackDataAction
+    | IForEachAction
+    | IFuzzEntrypointAction
+    | IAssertBalanceChangesAction
+    | IGetEntrypointsAction
+    | ILintContractAction;
 
 export interface IActionResult {
     status: ActionResultStatus;
     action: IAction;
     result: Record<string, unknown>;
+    broken_invariants?: IActionResult[];
 }
 
 // create_implicit_account
@@ -50,8 +71,9 @@ export interface ICreateImplicitAccountAction {
 export interface IOriginateContractPayload {
     name: string;
     balance: string;
-    code: Record<string, unknown> | Record<string, unknown>[];
-    storage: Record<string, unknown> | Record<string, unknown>[];
+    code: Record<string, unknown> | Record<string, unknown>[] | string;
+    storage: Record<string, unknown> | Record<string, unknown>[] | AnnotatedValue;
+    format?: MichelsonFormat;
 }
 export interface IOriginateContractAction {
     kind: ActionKind.OriginateContract;
@@ -67,8 +89,10 @@ export interface ICallContractPayload {
     level?: number;
     timestamp?: string;
     entrypoint: string;
-    parameter: Record<string, unknown> | Record<string, unknown>[];
-    expect_failwith?: Record<string, unknown> | Record<string, unknown>[];
+    parameter: Record<string, unknown> | Record<string, unknown>[] | AnnotatedValue;
+    expect_failwith?: Record<string, unknown> | Record<string, unknown>[] | string;
+    expect_balance_changes?: IBalanceChange[];
+    format?: MichelsonFormat;
 }
 export interface ICallContractAction {
     kind: ActionKind.CallContract;
@@ -90,7 +114,8 @@ export interface IAssertAccountBalanceAction {
 
 export interface IAssertContractStoragePayload {
     contract_name: string;
-    storage: Record<string, unknown> | Record<string, unknown>[];
+    storage: Record<string, unknown> | Record<string, unknown>[] | AnnotatedValue;
+    format?: MichelsonFormat;
 }
 export interface IAssertContractStorageAction {
     kind: ActionKind.AssertContractStorage;
@@ -129,10 +154,74 @@ export interface IModifyBlockTimestampAction {
 // pack_data
 
 export interface IPackDataPayload {
-    data: Record<string, unknown> | Record<string, unknown>[];
-    type: Record<string, unknown> | Record<string, unknown>[];
+    data: Record<string, unknown> | Record<string, unknown>[] | AnnotatedValue;
+    type: Record<string, unknown> | Record<string, unknown>[] | string;
+    format?: MichelsonFormat;
 }
 export interface IPackDataAction {
     kind: ActionKind.PackData;
     payload: IPackDataPayload;
 }
+
+// for_each
+
+export interface IForEachPayload {
+    bindings: Record<string, unknown>[];
+    actions: IAction[];
+}
+export interface IForEachAction {
+    kind: ActionKind.ForEach;
+    payload: IForEachPayload;
+}
+
+// fuzz_entrypoint
+
+export interface IFuzzEntrypointPayload {
+    contract_name: string;
+    sender: string;
+    entrypoint?: string;
+    amount?: string;
+    seed?: number;
+    iterations?: number;
+    invariants?: IAction[];
+}
+export interface IFuzzEntrypointAction {
+    kind: ActionKind.FuzzEntrypoint;
+    payload: IFuzzEntrypointPayload;
+}
+
+// assert_balance_changes
+
+export interface IBalanceChange {
+    account_name: string;
+    change: string;
+    exclude_fees?: boolean;
+}
+export interface IAssertBalanceChangesPayload {
+    changes: IBalanceChange[];
+    actions: IAction[];
+}
+export interface IAssertBalanceChangesAction {
+    kind: ActionKind.AssertBalanceChanges;
+    payload: IAssertBalanceChangesPayload;
+}
+
+// get_entrypoints
+
+export interface IGetEntrypointsPayload {
+    contract_name: string;
+}
+export interface IGetEntrypointsAction {
+    kind: ActionKind.GetEntrypoints;
+    payload: IGetEntrypointsPayload;
+}
+
+// lint_contract
+
+export interface ILintContractPayload {
+    code: Record<string, unknown> | Record<string, unknown>[] | string;
+}
+export interface ILintContractAction {
+    kind: ActionKind.LintContract;
+    payload: ILintContractPayload;
+}
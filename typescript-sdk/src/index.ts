@@ -2,7 +2,14 @@ import { ActionKind, IAction } from './action';
 
 export interface TestSuite {
     protocol?: string;
+    /**
+     * "mockup" (default) executes the operations with 'tezos-client',
+     * "interpreter" executes a subset of Michelson in process (no 'tezos-client' required),
+     * "node" injects the operations in the node configured by the server (e.g. a sandboxed node)
+     */
+    backend?: 'mockup' | 'interpreter' | 'node';
     actions: IAction[];
+    invariants?: IAction[];
 }
 
 /**